@@ -0,0 +1,140 @@
+package gottyclient
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AdminPasswordEncoding is how the admin password is put in the
+// X-Admin-Password header. HTTP headers only carry printable ASCII safely, so
+// passwords with other characters, or surrounding whitespace that proxies
+// strip, are encoded for servers supporting it.
+type AdminPasswordEncoding string
+
+const (
+	// AdminPasswordAuto sends the password as is when it is safe to, and
+	// otherwise in the first encoding the server advertises in /api/info
+	AdminPasswordAuto AdminPasswordEncoding = ""
+	// AdminPasswordPlain sends the password as is
+	AdminPasswordPlain AdminPasswordEncoding = "plain"
+	// AdminPasswordRFC8187 percent-encodes the UTF-8 password after an
+	// RFC 8187 UTF-8'' marker
+	AdminPasswordRFC8187 AdminPasswordEncoding = "rfc8187"
+	// AdminPasswordBase64 sends the base64 of the password after a base64:
+	// marker
+	AdminPasswordBase64 AdminPasswordEncoding = "base64"
+)
+
+// adminPasswordEncodings are the encodings auto mode can negotiate, in order
+// of preference
+var adminPasswordEncodings = []AdminPasswordEncoding{AdminPasswordRFC8187, AdminPasswordBase64}
+
+// ParseAdminPasswordEncoding parses an --admin-password-encoding value
+func ParseAdminPasswordEncoding(s string) (AdminPasswordEncoding, error) {
+	switch encoding := AdminPasswordEncoding(strings.ToLower(strings.TrimSpace(s))); encoding {
+	case "auto":
+		return AdminPasswordAuto, nil
+	case AdminPasswordAuto, AdminPasswordPlain, AdminPasswordRFC8187, AdminPasswordBase64:
+		return encoding, nil
+	}
+	return AdminPasswordAuto, fmt.Errorf("invalid admin password encoding %q (expected auto, plain, rfc8187 or base64)", s)
+}
+
+// CheckAdminPassword reports why password cannot be sent safely as is in a
+// header, or nil if it can
+func CheckAdminPassword(password string) error {
+	for _, r := range password {
+		switch {
+		case r > 0x7e:
+			return fmt.Errorf("admin password contains non-ASCII characters")
+		case r < 0x20 && r != '\t', r == 0x7f:
+			return fmt.Errorf("admin password contains control characters")
+		}
+	}
+	if strings.TrimSpace(password) != password {
+		return fmt.Errorf("admin password starts or ends with whitespace")
+	}
+	if strings.HasPrefix(password, "UTF-8''") || strings.HasPrefix(password, "base64:") {
+		return fmt.Errorf("admin password looks like an encoded one")
+	}
+	return nil
+}
+
+// Encode returns password as sent in the X-Admin-Password header
+func (e AdminPasswordEncoding) Encode(password string) string {
+	switch e {
+	case AdminPasswordRFC8187:
+		var encoded strings.Builder
+		encoded.WriteString("UTF-8''")
+		for _, b := range []byte(password) {
+			if isAttrChar(b) {
+				encoded.WriteByte(b)
+			} else {
+				fmt.Fprintf(&encoded, "%%%02X", b)
+			}
+		}
+		return encoded.String()
+	case AdminPasswordBase64:
+		return "base64:" + base64.StdEncoding.EncodeToString([]byte(password))
+	}
+	return password
+}
+
+// isAttrChar reports whether b is an RFC 8187 attr-char, sent unescaped
+func isAttrChar(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
+
+// adminPasswordEncoding returns the encoding of the client's admin password,
+// asking the server which it supports the first time one is needed
+func (c *Client) adminPasswordEncoding(password string) AdminPasswordEncoding {
+	if c.AdminPasswordEncoding != AdminPasswordAuto || password == "" {
+		return c.AdminPasswordEncoding
+	}
+	problem := CheckAdminPassword(password)
+	if problem == nil {
+		return AdminPasswordPlain
+	}
+
+	c.adminEncodingOnce.Do(func() {
+		c.adminEncoding = AdminPasswordPlain
+		// The info endpoint is asked without the admin password, which is
+		// what is being negotiated
+		var auth AuthProvider = c.Credentials()
+		if c.Auth != nil {
+			auth = MultiAuth{auth, c.Auth}
+		}
+		sessions := &SessionsClient{
+			BaseURL:     c.URL,
+			Auth:        auth,
+			HTTPClient:  c.httpClient(),
+			RetryPolicy: c.retryPolicy(),
+			HostHeader:  c.HostHeader,
+			UserAgent:   c.UserAgent,
+			InstanceID:  c.InstanceID,
+			affinity:    c.affinity(),
+		}
+		info, err := sessions.ServerInfo()
+		if err == nil {
+			for _, encoding := range adminPasswordEncodings {
+				for _, supported := range info.AdminPasswordEncodings {
+					if strings.EqualFold(supported, string(encoding)) {
+						logrus.Debugf("Sending the admin password %s encoded", encoding)
+						c.adminEncoding = encoding
+						return
+					}
+				}
+			}
+			err = fmt.Errorf("server supports no admin password encoding")
+		}
+		logrus.Warnf("%v and it cannot be encoded (%v): sending it as is", problem, err)
+	})
+	return c.adminEncoding
+}
@@ -0,0 +1,86 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAdminPasswordEncoding(t *testing.T) {
+	Convey("Testing admin password encodings", t, func() {
+		Convey("Parse encodings", func() {
+			encoding, err := ParseAdminPasswordEncoding("RFC8187")
+			So(err, ShouldBeNil)
+			So(encoding, ShouldEqual, AdminPasswordRFC8187)
+
+			encoding, err = ParseAdminPasswordEncoding("auto")
+			So(err, ShouldBeNil)
+			So(encoding, ShouldEqual, AdminPasswordAuto)
+
+			_, err = ParseAdminPasswordEncoding("rot13")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Check passwords", func() {
+			So(CheckAdminPassword("s3cret!pass"), ShouldBeNil)
+			So(CheckAdminPassword("pass word"), ShouldBeNil)
+			So(CheckAdminPassword("pässwörd"), ShouldNotBeNil)
+			So(CheckAdminPassword(" padded "), ShouldNotBeNil)
+			So(CheckAdminPassword("line\nbreak"), ShouldNotBeNil)
+			So(CheckAdminPassword("base64:abc"), ShouldNotBeNil)
+		})
+
+		Convey("Encode passwords", func() {
+			So(AdminPasswordPlain.Encode("pässwörd"), ShouldEqual, "pässwörd")
+			So(AdminPasswordRFC8187.Encode("€ rates"), ShouldEqual, "UTF-8''%E2%82%AC%20rates")
+			So(AdminPasswordBase64.Encode(" pw "), ShouldEqual, "base64:IHB3IA==")
+		})
+
+		Convey("Negotiate the encoding with the server", func() {
+			var infoRequests int
+			var infoAdmin string
+			var received []string
+			encodings := `["base64"]`
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/info":
+					infoRequests++
+					infoAdmin += r.Header.Get("X-Admin-Password")
+					w.Write([]byte(`{"version":"1.0","admin_password_encodings":` + encodings + `}`))
+				default:
+					received = append(received, r.Header.Get("X-Admin-Password"))
+					w.Write([]byte(`{"sessions":[],"count":0}`))
+				}
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			client.AdminPassword = "pässwörd"
+			_, err = client.ListSessions()
+			So(err, ShouldBeNil)
+			_, err = client.ListSessions()
+			So(err, ShouldBeNil)
+			So(infoRequests, ShouldEqual, 1)
+			So(infoAdmin, ShouldBeEmpty)
+			So(received, ShouldResemble, []string{"base64:cMOkc3N3w7ZyZA==", "base64:cMOkc3N3w7ZyZA=="})
+
+			encodings = `[]`
+			client, err = NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			client.AdminPassword = "pässwörd"
+			_, err = client.ListSessions()
+			So(err, ShouldBeNil)
+			So(received[2], ShouldEqual, "pässwörd")
+
+			client, err = NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			client.AdminPassword = "plain"
+			_, err = client.ListSessions()
+			So(err, ShouldBeNil)
+			So(infoRequests, ShouldEqual, 2)
+		})
+	})
+}
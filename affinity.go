@@ -0,0 +1,69 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// affinity replays the backend affinity token a load balancer returns in a
+// response header, so the requests of a client all reach the same replica
+type affinity struct {
+	mutex  sync.Mutex
+	header string
+	token  string
+}
+
+// capture remembers the affinity token of a response, if any
+func (a *affinity) capture(resp *http.Response) {
+	if a == nil || a.header == "" || resp == nil {
+		return
+	}
+	token := resp.Header.Get(a.header)
+	if token == "" {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if token != a.token {
+		logrus.Debugf("Affinity %s: %q", a.header, token)
+		a.token = token
+	}
+}
+
+// apply adds the affinity token to request headers once there is one
+func (a *affinity) apply(header http.Header) {
+	if a == nil || a.header == "" {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.token != "" {
+		header.Set(a.header, a.token)
+	}
+}
+
+// affinity returns the client's affinity token state, shared with its
+// session API clients
+func (c *Client) affinity() *affinity {
+	c.affinityOnce.Do(func() {
+		c.affinityState = &affinity{header: c.AffinityHeader}
+	})
+	return c.affinityState
+}
+
+// cookieJar returns CookieJar, or else a jar keeping cookies in memory for
+// the life of the client, so sticky-session cookies set by a load balancer
+// are replayed on the websocket upgrade and later API calls
+func (c *Client) cookieJar() http.CookieJar {
+	if c.CookieJar != nil {
+		return c.CookieJar
+	}
+	c.memoryJarOnce.Do(func() {
+		// cookiejar.New only fails with invalid options
+		c.memoryJar, _ = cookiejar.New(nil)
+	})
+	return c.memoryJar
+}
@@ -0,0 +1,52 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAffinity(t *testing.T) {
+	Convey("Testing load balancer affinity", t, func() {
+		var mu sync.Mutex
+		seen := map[string][2]string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie := ""
+			if c, err := r.Cookie("backend"); err == nil {
+				cookie = c.Value
+			}
+			mu.Lock()
+			seen[r.URL.Path] = [2]string{cookie, r.Header.Get("X-Backend-Id")}
+			mu.Unlock()
+			switch r.URL.Path {
+			case "/auth_token.js":
+				http.SetCookie(w, &http.Cookie{Name: "backend", Value: "replica-2", Path: "/"})
+				w.Header().Set("X-Backend-Id", "b2")
+				w.Write([]byte("var gotty_auth_token = 'token'"))
+			case "/ws":
+				serveTerminal(w, r)
+			default:
+				w.Write([]byte(`{"sessions":[],"count":0}`))
+			}
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		client.V2 = true
+		client.AffinityHeader = "X-Backend-Id"
+		So(client.Connect(), ShouldBeNil)
+		defer client.Close()
+		_, err = client.ListSessions()
+		So(err, ShouldBeNil)
+
+		mu.Lock()
+		defer mu.Unlock()
+		So(seen["/auth_token.js"], ShouldResemble, [2]string{"", ""})
+		So(seen["/ws"], ShouldResemble, [2]string{"replica-2", "b2"})
+		So(seen["/api/sessions"], ShouldResemble, [2]string{"replica-2", "b2"})
+	})
+}
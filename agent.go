@@ -0,0 +1,230 @@
+package gottyclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetDefaultAgentSocketPath returns the default agent socket path,
+// $GOTTY_CLIENT_AGENT_SOCK or ~/.gotty-client/agent.sock
+func GetDefaultAgentSocketPath() string {
+	if path := os.Getenv("GOTTY_CLIENT_AGENT_SOCK"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gotty-client", "agent.sock")
+}
+
+// CredentialKey returns the agent key of a user's password on a host
+func CredentialKey(host, user string) string {
+	return "basic:" + user + "@" + host
+}
+
+// agentRequest is one line of the agent protocol
+type agentRequest struct {
+	Op    string              `json:"op"`
+	Key   string              `json:"key"`
+	Value string              `json:"value,omitempty"`
+	Entry *registryCacheEntry `json:"entry,omitempty"`
+}
+
+// agentResponse answers an agentRequest
+type agentResponse struct {
+	Found bool                `json:"found,omitempty"`
+	Value string              `json:"value,omitempty"`
+	Entry *registryCacheEntry `json:"entry,omitempty"`
+	Error string              `json:"error,omitempty"`
+}
+
+// Agent keeps credentials and registry responses in memory for the CLI
+// invocations of one user, so passwords are typed once per login session
+type Agent struct {
+	mutex       sync.Mutex
+	credentials map[string]string
+	registry    map[string]*registryCacheEntry
+}
+
+// NewAgent returns an empty agent
+func NewAgent() *Agent {
+	return &Agent{
+		credentials: make(map[string]string),
+		registry:    make(map[string]*registryCacheEntry),
+	}
+}
+
+// ListenAgent creates the agent socket at path, readable by the current user
+// only. A stale socket left by an agent that is no longer running is removed.
+// The agent is not started where the user connecting cannot be checked.
+func ListenAgent(path string) (*net.UnixListener, error) {
+	if !agentPeerCredentials {
+		return nil, fmt.Errorf("the agent is not supported on %s, which cannot tell who connects to it", runtime.GOOS)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("an agent is already running on %s", path)
+	}
+	_ = os.Remove(path)
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// Serve answers requests on listener until it is closed
+func (a *Agent) Serve(listener *net.UnixListener) error {
+	for {
+		conn, err := listener.AcceptUnix()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkAgentPeer(conn); err != nil {
+			logrus.Warnf("Rejected agent connection: %v", err)
+			conn.Close()
+			continue
+		}
+		go a.serveConn(conn)
+	}
+}
+
+func (a *Agent) serveConn(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, 16*1024*1024)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req agentRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			_ = encoder.Encode(agentResponse{Error: err.Error()})
+			return
+		}
+		if err := encoder.Encode(a.handle(req)); err != nil {
+			return
+		}
+	}
+}
+
+func (a *Agent) handle(req agentRequest) agentResponse {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	switch req.Op {
+	case "get":
+		value, ok := a.credentials[req.Key]
+		return agentResponse{Found: ok, Value: value}
+	case "put":
+		a.credentials[req.Key] = req.Value
+	case "forget":
+		delete(a.credentials, req.Key)
+	case "registry-get":
+		entry, ok := a.registry[req.Key]
+		return agentResponse{Found: ok, Entry: entry}
+	case "registry-put":
+		if req.Entry == nil {
+			return agentResponse{Error: "missing registry entry"}
+		}
+		a.registry[req.Key] = req.Entry
+	default:
+		return agentResponse{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+	}
+	return agentResponse{Found: true}
+}
+
+// AgentClient talks to a running agent
+type AgentClient struct {
+	Path string
+}
+
+// ConnectAgent returns a client for the agent at path, or nil if path is
+// empty or no agent is running
+func ConnectAgent(path string) *AgentClient {
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	return &AgentClient{Path: path}
+}
+
+func (a *AgentClient) request(req agentRequest) (*agentResponse, error) {
+	conn, err := net.DialTimeout("unix", a.Path, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("agent: %s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// GetCredential returns the credential stored under key
+func (a *AgentClient) GetCredential(key string) (string, bool) {
+	resp, err := a.request(agentRequest{Op: "get", Key: key})
+	if err != nil {
+		logrus.Debugf("Agent lookup failed: %v", err)
+		return "", false
+	}
+	return resp.Value, resp.Found
+}
+
+// PutCredential stores a credential under key
+func (a *AgentClient) PutCredential(key, value string) error {
+	_, err := a.request(agentRequest{Op: "put", Key: key, Value: value})
+	return err
+}
+
+// ForgetCredential removes the credential stored under key, e.g. after it
+// was rejected by the server
+func (a *AgentClient) ForgetCredential(key string) error {
+	_, err := a.request(agentRequest{Op: "forget", Key: key})
+	return err
+}
+
+func (a *AgentClient) getRegistry(target string) *registryCacheEntry {
+	resp, err := a.request(agentRequest{Op: "registry-get", Key: target})
+	if err != nil || !resp.Found {
+		return nil
+	}
+	return resp.Entry
+}
+
+func (a *AgentClient) putRegistry(entry *registryCacheEntry) {
+	if _, err := a.request(agentRequest{Op: "registry-put", Key: entry.URL, Entry: entry}); err != nil {
+		logrus.Debugf("Failed to cache registry response in agent: %v", err)
+	}
+}
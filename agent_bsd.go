@@ -0,0 +1,38 @@
+//go:build darwin || freebsd
+// +build darwin freebsd
+
+package gottyclient
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// agentPeerCredentials tells whether checkAgentPeer can tell who connects
+const agentPeerCredentials = true
+
+// checkAgentPeer rejects agent connections from other users
+func checkAgentPeer(conn *net.UnixConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var cred *unix.Xucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	})
+	if err != nil {
+		return err
+	}
+	if credErr != nil {
+		return credErr
+	}
+	if int(cred.Uid) != os.Getuid() {
+		return fmt.Errorf("peer uid %d is not the agent owner", cred.Uid)
+	}
+	return nil
+}
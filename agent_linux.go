@@ -0,0 +1,35 @@
+package gottyclient
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// agentPeerCredentials tells whether checkAgentPeer can tell who connects
+const agentPeerCredentials = true
+
+// checkAgentPeer rejects agent connections from other users
+func checkAgentPeer(conn *net.UnixConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var cred *unix.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return err
+	}
+	if credErr != nil {
+		return credErr
+	}
+	if int(cred.Uid) != os.Getuid() {
+		return fmt.Errorf("peer uid %d (pid %d) is not the agent owner", cred.Uid, cred.Pid)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package gottyclient
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// agentPeerCredentials tells whether checkAgentPeer can tell who connects
+const agentPeerCredentials = false
+
+// checkAgentPeer rejects every connection where peer credentials are not
+// available; ListenAgent refuses to start there in the first place
+func checkAgentPeer(conn *net.UnixConn) error {
+	return fmt.Errorf("peer credentials are not available on %s", runtime.GOOS)
+}
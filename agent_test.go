@@ -0,0 +1,56 @@
+package gottyclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAgent(t *testing.T) {
+	Convey("Testing the credential agent", t, func() {
+		// Unix socket paths are short, so avoid the long t.TempDir path
+		dir, err := os.MkdirTemp("", "agent")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "agent.sock")
+
+		listener, err := ListenAgent(path)
+		So(err, ShouldBeNil)
+		done := make(chan error, 1)
+		go func() { done <- NewAgent().Serve(listener) }()
+
+		info, err := os.Stat(path)
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+
+		_, err = ListenAgent(path)
+		So(err, ShouldNotBeNil)
+
+		agent := ConnectAgent(path)
+		So(agent, ShouldNotBeNil)
+
+		key := CredentialKey("sdr.example.com:8080", "alice")
+		_, ok := agent.GetCredential(key)
+		So(ok, ShouldBeFalse)
+		So(agent.PutCredential(key, "secret"), ShouldBeNil)
+		password, ok := agent.GetCredential(key)
+		So(ok, ShouldBeTrue)
+		So(password, ShouldEqual, "secret")
+		So(agent.ForgetCredential(key), ShouldBeNil)
+		_, ok = agent.GetCredential(key)
+		So(ok, ShouldBeFalse)
+
+		entry := &registryCacheEntry{URL: "https://registry.example.com/instances", ETag: `"v1"`, Fetched: time.Now(), Body: []byte("{}")}
+		agent.putRegistry(entry)
+		cached := agent.getRegistry(entry.URL)
+		So(cached, ShouldNotBeNil)
+		So(cached.ETag, ShouldEqual, `"v1"`)
+		So(string(cached.Body), ShouldEqual, "{}")
+
+		listener.Close()
+		So(<-done, ShouldBeNil)
+	})
+}
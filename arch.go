@@ -1,3 +1,4 @@
+//go:build !windows
 // +build !windows
 
 package gottyclient
@@ -24,7 +25,7 @@ func syscallTIOCGWINSZ() ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("ioctl error: %v", err)
 	}
-	tws := winsize{Rows: ws.Row, Columns: ws.Col}
+	tws := WinSize{Rows: ws.Row, Columns: ws.Col}
 	b, err := json.Marshal(tws)
 	if err != nil {
 		return nil, fmt.Errorf("json.Marshal error: %v", err)
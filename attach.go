@@ -0,0 +1,72 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSessionDetachUnsupported is returned when the server has no API to detach
+// the clients of a session
+var ErrSessionDetachUnsupported = fmt.Errorf("server does not support detaching session clients")
+
+// AttachMode decides what to do when attaching to a session another client is
+// already attached to
+type AttachMode string
+
+const (
+	// AttachAsk prompts the user, or attaches shared without a terminal
+	AttachAsk AttachMode = ""
+	// AttachShared attaches alongside the other clients
+	AttachShared AttachMode = "shared"
+	// AttachSteal detaches the other clients first
+	AttachSteal AttachMode = "steal"
+	// AttachFail refuses to attach
+	AttachFail AttachMode = "fail"
+)
+
+// ParseAttachMode parses an --attach-mode value
+func ParseAttachMode(s string) (AttachMode, error) {
+	switch mode := AttachMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case AttachAsk, AttachShared, AttachSteal, AttachFail:
+		return mode, nil
+	}
+	return AttachAsk, fmt.Errorf("invalid attach mode %q (expected shared, steal or fail)", s)
+}
+
+// DetachSessionClients asks the server to detach all clients currently
+// attached to a session, leaving the session itself running
+func (s *SessionsClient) DetachSessionClients(sessionName string) (*SessionActionResponse, error) {
+	query := url.Values{}
+	query.Set("name", sessionName)
+	req, err := s.newAPIRequest("POST", "/api/sessions/detach", query)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Debugf("Detaching session clients: %q", req.URL.String())
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var actionResp SessionActionResponse
+		if err := json.Unmarshal(body, &actionResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %v", err)
+		}
+		return &actionResp, nil
+	case apiUnsupported(resp.StatusCode, body):
+		return nil, ErrSessionDetachUnsupported
+	default:
+		return nil, apiError("failed to detach session clients", resp.StatusCode, body)
+	}
+}
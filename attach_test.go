@@ -0,0 +1,59 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAttachMode(t *testing.T) {
+	Convey("Testing attach collision handling", t, func() {
+		Convey("ParseAttachMode", func() {
+			for input, expected := range map[string]AttachMode{"": AttachAsk, "shared": AttachShared, "Steal": AttachSteal, "fail": AttachFail} {
+				mode, err := ParseAttachMode(input)
+				So(err, ShouldBeNil)
+				So(mode, ShouldEqual, expected)
+			}
+			_, err := ParseAttachMode("kick")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("DetachSessionClients", func() {
+			var detached string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/sessions/detach" || r.Method != "POST" {
+					http.NotFound(w, r)
+					return
+				}
+				if r.URL.Query().Get("name") != "ft8" {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"success":false,"message":"session not found"}`))
+					return
+				}
+				detached = r.URL.Query().Get("name")
+				w.Write([]byte(`{"success":true,"message":"detached 1 client"}`))
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			resp, err := client.DetachSessionClients("ft8")
+			So(err, ShouldBeNil)
+			So(resp.Success, ShouldBeTrue)
+			So(detached, ShouldEqual, "ft8")
+
+			client, err = NewClient(server.URL + "/other/")
+			So(err, ShouldBeNil)
+			_, err = client.DetachSessionClients("ft8")
+			So(err, ShouldEqual, ErrSessionDetachUnsupported)
+
+			// A session that does not exist is not a missing API
+			client, err = NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			_, err = client.DetachSessionClients("wspr")
+			So(err, ShouldNotEqual, ErrSessionDetachUnsupported)
+			So(err.Error(), ShouldEqual, "failed to detach session clients: session not found")
+		})
+	})
+}
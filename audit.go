@@ -0,0 +1,147 @@
+package gottyclient
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEvent is one connection or admin action recorded in the audit log
+type AuditEvent struct {
+	Action   string
+	Host     string
+	User     string
+	Session  string
+	Duration time.Duration
+	Detail   string
+}
+
+// AuditLogger appends one line per connection and admin action, so shared
+// club machines keep a record of who did what. Unlike InputLogger it never
+// records what was typed.
+type AuditLogger struct {
+	mutex    sync.Mutex
+	w        io.WriteCloser
+	syslog   bool
+	Operator string
+}
+
+// GetDefaultAuditLogPath returns the default audit log path
+func GetDefaultAuditLogPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gotty-client", "audit.log")
+}
+
+// AuditLogFile returns the file written by the audit log named by an AuditLog
+// config value, or "" if it is disabled or goes to syslog
+func AuditLogFile(target string) string {
+	switch strings.ToLower(target) {
+	case "", "false", "no", "off", "0", "syslog":
+		return ""
+	case "true", "yes", "on", "1":
+		return GetDefaultAuditLogPath()
+	}
+	return target
+}
+
+// OpenAuditLog opens the audit log named by an AuditLog config value: "syslog",
+// "true" for the default path, or a file path. It returns nil for "false" or
+// an empty value.
+func OpenAuditLog(target string) (*AuditLogger, error) {
+	operator := "unknown"
+	if u, err := user.Current(); err == nil {
+		operator = u.Username
+	}
+
+	if strings.EqualFold(target, "syslog") {
+		w, err := openSyslog()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open syslog: %v", err)
+		}
+		return &AuditLogger{w: w, syslog: true, Operator: operator}, nil
+	}
+	target = AuditLogFile(target)
+	if target == "" {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	return &AuditLogger{w: file, Operator: operator}, nil
+}
+
+// Log appends an event
+func (l *AuditLogger) Log(event AuditEvent) {
+	if l == nil {
+		return
+	}
+
+	fields := []string{"action=" + event.Action, "operator=" + l.Operator}
+	if event.Host != "" {
+		fields = append(fields, "host="+event.Host)
+	}
+	if event.User != "" {
+		fields = append(fields, "user="+event.User)
+	}
+	if event.Session != "" {
+		fields = append(fields, "session="+event.Session)
+	}
+	if event.Duration != 0 {
+		fields = append(fields, "duration="+event.Duration.Round(time.Second).String())
+	}
+	if event.Detail != "" {
+		fields = append(fields, fmt.Sprintf("detail=%q", event.Detail))
+	}
+	line := strings.Join(fields, " ")
+	if !l.syslog {
+		// syslog adds its own timestamp
+		line = time.Now().Format(time.RFC3339) + " " + line
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, _ = fmt.Fprintln(l.w, line)
+}
+
+// Close closes the underlying log
+func (l *AuditLogger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.w.Close()
+}
+
+// auditEvent returns an event for action filled in with the client's host,
+// user and session
+func (c *Client) auditEvent(action string) AuditEvent {
+	event := AuditEvent{Action: action, Host: c.Host(), User: c.Credentials().User}
+	if query, err := GetURLQuery(c.URL); err == nil {
+		event.Session = query.Get("session")
+	}
+	return event
+}
+
+// Audit records an admin action in the client's audit log, if any
+func (c *Client) Audit(action, session, detail string) {
+	event := c.auditEvent(action)
+	if session != "" {
+		event.Session = session
+	}
+	event.Detail = detail
+	c.AuditLog.Log(event)
+}
@@ -0,0 +1,14 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package gottyclient
+
+import (
+	"fmt"
+	"io"
+)
+
+// openSyslog is not available on this platform
+func openSyslog() (io.WriteCloser, error) {
+	return nil, fmt.Errorf("syslog is not supported on this platform")
+}
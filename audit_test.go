@@ -0,0 +1,58 @@
+package gottyclient
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAuditLog(t *testing.T) {
+	Convey("Testing the audit log", t, func() {
+		Convey("Disabled values open nothing", func() {
+			for _, value := range []string{"", "false", "no"} {
+				logger, err := OpenAuditLog(value)
+				So(err, ShouldBeNil)
+				So(logger, ShouldBeNil)
+			}
+		})
+
+		Convey("Events are appended to the file", func() {
+			path := filepath.Join(t.TempDir(), "audit", "audit.log")
+			logger, err := OpenAuditLog(path)
+			So(err, ShouldBeNil)
+			logger.Operator = "alice"
+
+			client, err := NewClient("http://sdr.example.com:8080/terminal/?session=ft8")
+			So(err, ShouldBeNil)
+			client.User = "op"
+			client.AuditLog = logger
+
+			event := client.auditEvent("detach")
+			event.Duration = 90 * time.Second
+			logger.Log(event)
+			client.Audit("destroy", "old", "")
+			So(logger.Close(), ShouldBeNil)
+
+			data, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			So(len(lines), ShouldEqual, 2)
+			So(lines[0], ShouldEndWith, "action=detach operator=alice host=sdr.example.com:8080 user=op session=ft8 duration=1m30s")
+			So(lines[1], ShouldEndWith, "action=destroy operator=alice host=sdr.example.com:8080 user=op session=old")
+
+			info, err := os.Stat(path)
+			So(err, ShouldBeNil)
+			So(info.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+		})
+
+		Convey("A nil logger is a no-op", func() {
+			var logger *AuditLogger
+			logger.Log(AuditEvent{Action: "connect"})
+			So(logger.Close(), ShouldBeNil)
+		})
+	})
+}
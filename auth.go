@@ -0,0 +1,278 @@
+package gottyclient
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AuthProvider adds authentication to the HTTP requests and the websocket
+// upgrade of a client. Implementations are called before every request, so
+// they can refresh short-lived credentials such as OIDC tokens.
+type AuthProvider interface {
+	// ApplyHeaders authenticates a REST request
+	ApplyHeaders(req *http.Request)
+	// ApplyWSHeaders authenticates the websocket upgrade request
+	ApplyWSHeaders(header http.Header)
+}
+
+// BasicAuth authenticates with HTTP basic authentication
+type BasicAuth struct {
+	User     string
+	Password string
+}
+
+// ApplyHeaders implements AuthProvider
+func (a BasicAuth) ApplyHeaders(req *http.Request) { a.ApplyWSHeaders(req.Header) }
+
+// ApplyWSHeaders implements AuthProvider
+func (a BasicAuth) ApplyWSHeaders(header http.Header) {
+	if a.User == "" {
+		return
+	}
+	basicAuth := a.User + ":" + a.Password
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(basicAuth)))
+}
+
+// AdminPasswordAuth sends the ubersdr admin password in the X-Admin-Password header
+type AdminPasswordAuth string
+
+// ApplyHeaders implements AuthProvider
+func (a AdminPasswordAuth) ApplyHeaders(req *http.Request) { a.ApplyWSHeaders(req.Header) }
+
+// ApplyWSHeaders implements AuthProvider
+func (a AdminPasswordAuth) ApplyWSHeaders(header http.Header) {
+	if a != "" {
+		header.Add("X-Admin-Password", string(a))
+	}
+}
+
+// BearerToken authenticates with a static bearer token
+type BearerToken string
+
+// ApplyHeaders implements AuthProvider
+func (t BearerToken) ApplyHeaders(req *http.Request) { t.ApplyWSHeaders(req.Header) }
+
+// ApplyWSHeaders implements AuthProvider
+func (t BearerToken) ApplyWSHeaders(header http.Header) {
+	if t != "" {
+		header.Set("Authorization", "Bearer "+string(t))
+	}
+}
+
+// HeaderAuth sets a fixed set of headers, e.g. for API gateways with custom
+// key headers
+type HeaderAuth http.Header
+
+// ApplyHeaders implements AuthProvider
+func (h HeaderAuth) ApplyHeaders(req *http.Request) { h.ApplyWSHeaders(req.Header) }
+
+// ApplyWSHeaders implements AuthProvider
+func (h HeaderAuth) ApplyWSHeaders(header http.Header) {
+	for name, values := range h {
+		header.Del(name)
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+}
+
+// MultiAuth applies several providers in order
+type MultiAuth []AuthProvider
+
+// ApplyHeaders implements AuthProvider
+func (m MultiAuth) ApplyHeaders(req *http.Request) {
+	for _, provider := range m {
+		provider.ApplyHeaders(req)
+	}
+}
+
+// ApplyWSHeaders implements AuthProvider
+func (m MultiAuth) ApplyWSHeaders(header http.Header) {
+	for _, provider := range m {
+		provider.ApplyWSHeaders(header)
+	}
+}
+
+// urlCredentials returns the basic auth credentials embedded in target
+// (user:pass@host), if any
+func urlCredentials(target *url.URL) (BasicAuth, bool) {
+	if target == nil || target.User == nil {
+		return BasicAuth{}, false
+	}
+	password, _ := target.User.Password()
+	return BasicAuth{User: target.User.Username(), Password: password}, true
+}
+
+// Credentials returns the client's basic auth credentials: User and Password
+// when User is set, else those embedded in the URL. A URL password is still
+// used for User when Password is empty and the URL names the same user.
+func (c *Client) Credentials() BasicAuth {
+	target, _ := url.Parse(c.URL)
+	fromURL, ok := urlCredentials(target)
+	user, password, _ := c.credentialFields()
+	switch {
+	case user == "":
+		return fromURL
+	case password == "" && ok && fromURL.User == user:
+		return fromURL
+	}
+	return BasicAuth{User: user, Password: password}
+}
+
+// credentialFields returns User, Password and AdminPassword, which
+// repromptCredential changes while other goroutines may be sending requests
+func (c *Client) credentialFields() (user, password, adminPassword string) {
+	c.credentialMutex.RLock()
+	defer c.credentialMutex.RUnlock()
+	return c.User, c.Password, c.AdminPassword
+}
+
+// authProvider returns the providers for the client's credentials, applied
+// the same way to every request: the admin password header first (highest
+// priority for proxy authentication), then basic auth (see Credentials), the
+// one-time code if any, then Client.Auth if set, whose Authorization header
+// replaces the basic one
+func (c *Client) authProvider() AuthProvider {
+	_, _, adminPassword := c.credentialFields()
+	admin := AdminPasswordAuth(c.adminPasswordEncoding(adminPassword).Encode(adminPassword))
+	basic := c.Credentials()
+	if c.OTPHeader == OTPInPassword && basic.User != "" {
+		code, err := c.otpCode()
+		if err != nil {
+			logrus.Warnf("Failed to compute one-time code: %v", err)
+		}
+		basic.Password += code
+	}
+	providers := MultiAuth{admin, basic, otpAuth{client: c}}
+	if c.Auth != nil {
+		providers = append(providers, c.Auth)
+	}
+	return providers
+}
+
+// DefaultAuthAttempts is how many times Connect asks for a refused credential
+// when Client.AuthAttempts is not set
+const DefaultAuthAttempts = 3
+
+// AuthError is returned when the server refuses the client's credentials
+type AuthError struct {
+	StatusCode int
+	// Admin is set when the admin password, rather than the basic auth
+	// credentials, was refused
+	Admin bool
+	// User is the basic auth user, if any
+	User string
+	// Err is the underlying error, e.g. a HandshakeError
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	status := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	switch {
+	case e.Admin:
+		return fmt.Sprintf("admin password refused (%s)", status)
+	case e.User != "":
+		return fmt.Sprintf("user %q or its password refused (%s)", e.User, status)
+	}
+	return fmt.Sprintf("server requires authentication (%s)", status)
+}
+
+// Unwrap returns the underlying error
+func (e *AuthError) Unwrap() error { return e.Err }
+
+// AdminChallengeHeader marks the responses of servers refusing the admin
+// password, telling it apart from the basic auth credentials sent along
+const AdminChallengeHeader = "X-Admin-Password-Required"
+
+// authError classifies a 401 or 403 response. A basic auth challenge blames
+// the basic credentials. The admin password is blamed when the server says so
+// with AdminChallengeHeader, or when it was the only credential sent. When
+// neither can be told apart from the other, the error names no credential.
+func (c *Client) authError(status int, header http.Header, err error) *AuthError {
+	if status != http.StatusUnauthorized && status != http.StatusForbidden {
+		return nil
+	}
+	authErr := &AuthError{StatusCode: status, Err: err}
+	user := c.Credentials().User
+	_, _, adminPassword := c.credentialFields()
+	switch {
+	case strings.HasPrefix(strings.ToLower(header.Get("WWW-Authenticate")), "basic"):
+		authErr.User = user
+	case adminPassword == "":
+		authErr.User = user
+	case header.Get(AdminChallengeHeader) != "" || (user == "" && c.Auth == nil):
+		authErr.Admin = true
+	}
+	return authErr
+}
+
+// CredentialStore keeps passwords between runs, e.g. an AgentClient
+type CredentialStore interface {
+	PutCredential(key, value string) error
+	ForgetCredential(key string) error
+}
+
+// storeCredential hands the password to the CredentialStore once the server
+// accepted it
+func (c *Client) storeCredential() {
+	if c.CredentialStore == nil || c.credentialStored {
+		return
+	}
+	password := c.Credentials().Password
+	if password == "" {
+		return
+	}
+	if err := c.CredentialStore.PutCredential(c.CredentialKey, password); err != nil {
+		logrus.Debugf("Failed to store password: %v", err)
+		return
+	}
+	c.credentialStored = true
+}
+
+// forgetCredential removes a refused password from the CredentialStore, so
+// later runs ask for it again
+func (c *Client) forgetCredential(authErr *AuthError) {
+	if c.CredentialStore == nil || authErr.Admin {
+		return
+	}
+	if err := c.CredentialStore.ForgetCredential(c.CredentialKey); err != nil {
+		logrus.Debugf("Failed to forget password: %v", err)
+	}
+	c.credentialStored = false
+}
+
+// repromptCredential asks again for the credential refused with authErr,
+// reporting whether one was given and connecting is worth retrying
+func (c *Client) repromptCredential(authErr *AuthError) bool {
+	c.forgetCredential(authErr)
+	attempts := c.AuthAttempts
+	if attempts == 0 {
+		attempts = DefaultAuthAttempts
+	}
+	// The first attempt used the configured credentials; without a user
+	// there is no password to ask for
+	if c.CredentialPrompt == nil || c.authPrompts >= attempts-1 || (!authErr.Admin && authErr.User == "") {
+		return false
+	}
+	c.authPrompts++
+	resume := pauseProgress(c.Progress)
+	value, err := c.CredentialPrompt(authErr)
+	resume()
+	if err != nil {
+		return false
+	}
+	user := c.Credentials().User
+	c.credentialMutex.Lock()
+	defer c.credentialMutex.Unlock()
+	if authErr.Admin {
+		c.AdminPassword = value
+	} else {
+		c.User, c.Password = user, value
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package gottyclient
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// authTokens caches the auth tokens fetched by this process by token URL
+var authTokens = struct {
+	sync.Mutex
+	tokens map[string]string
+}{tokens: make(map[string]string)}
+
+// authTokenCacheEntry is an auth token cached on disk
+type authTokenCacheEntry struct {
+	URL     string    `json:"url"`
+	Token   string    `json:"token"`
+	Fetched time.Time `json:"fetched"`
+}
+
+func authTokenCachePath(dir, target string) string {
+	sum := sha1.Sum([]byte(target))
+	return filepath.Join(dir, "authtoken-"+hex.EncodeToString(sum[:])+".json")
+}
+
+// cachedAuthToken returns the auth token of the client's server, fetching it
+// unless cached, and whether it came from the cache
+func (c *Client) cachedAuthToken() (string, bool, error) {
+	key, err := c.authTokenKey()
+	if err != nil {
+		return "", false, err
+	}
+	if token, ok := loadAuthToken(c.CacheDir, key, c.AuthTokenCacheTTL); ok {
+		logrus.Debugf("Using cached auth token for %q", key)
+		return token, true, nil
+	}
+
+	token, err := c.GetAuthToken()
+	if err != nil {
+		return "", false, err
+	}
+	saveAuthToken(c.CacheDir, key, token, c.AuthTokenCacheTTL)
+	return token, false, nil
+}
+
+// forgetAuthToken drops the cached auth token of the client's server
+func (c *Client) forgetAuthToken() {
+	key, err := c.authTokenKey()
+	if err != nil {
+		return
+	}
+	authTokens.Lock()
+	delete(authTokens.tokens, key)
+	authTokens.Unlock()
+	if c.CacheDir != "" {
+		os.Remove(authTokenCachePath(c.CacheDir, key))
+	}
+}
+
+// authTokenKey identifies the server's auth token for the client's user,
+// e.g. "https://op@sdr.example.com/auth_token.js", without the password
+func (c *Client) authTokenKey() (string, error) {
+	target, _, err := getAuthTokenURL(c.URL, c.AuthTokenPath)
+	if err != nil {
+		return "", err
+	}
+	target.User = nil
+	if user := c.Credentials().User; user != "" {
+		target.User = url.User(user)
+	}
+	return target.String(), nil
+}
+
+// loadAuthToken returns the token cached in memory, or in dir if younger than ttl
+func loadAuthToken(dir, key string, ttl time.Duration) (string, bool) {
+	authTokens.Lock()
+	token, ok := authTokens.tokens[key]
+	authTokens.Unlock()
+	if ok || ttl <= 0 || dir == "" {
+		return token, ok
+	}
+
+	data, err := ioutil.ReadFile(authTokenCachePath(dir, key))
+	if err != nil {
+		return "", false
+	}
+	var entry authTokenCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != key || time.Since(entry.Fetched) > ttl {
+		return "", false
+	}
+	authTokens.Lock()
+	authTokens.tokens[key] = entry.Token
+	authTokens.Unlock()
+	return entry.Token, true
+}
+
+// saveAuthToken caches a token in memory, and in dir if ttl is positive
+func saveAuthToken(dir, key, token string, ttl time.Duration) {
+	authTokens.Lock()
+	authTokens.tokens[key] = token
+	authTokens.Unlock()
+	if ttl <= 0 || dir == "" {
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		logrus.Debugf("Failed to create cache directory: %v", err)
+		return
+	}
+	data, err := json.Marshal(authTokenCacheEntry{URL: key, Token: token, Fetched: time.Now()})
+	if err != nil {
+		return
+	}
+	if err := ioutil.WriteFile(authTokenCachePath(dir, key), data, 0600); err != nil {
+		logrus.Debugf("Failed to write auth token cache: %v", err)
+	}
+}
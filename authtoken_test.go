@@ -0,0 +1,100 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAuthTokenCache(t *testing.T) {
+	Convey("Testing auth token caching", t, func() {
+		var fetches, rejects int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/auth_token.js":
+				atomic.AddInt32(&fetches, 1)
+				w.Write([]byte("var gotty_auth_token = 'token'"))
+			case "/ws":
+				if atomic.AddInt32(&rejects, -1) >= 0 {
+					http.Error(w, "stale token", http.StatusUnauthorized)
+					return
+				}
+				serveTerminal(w, r)
+			}
+		}))
+		defer server.Close()
+
+		connect := func() error {
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			client.V2 = true
+			if err := client.Connect(); err != nil {
+				return err
+			}
+			return client.Close()
+		}
+
+		Convey("Later connections reuse the token", func() {
+			So(connect(), ShouldBeNil)
+			So(connect(), ShouldBeNil)
+			So(atomic.LoadInt32(&fetches), ShouldEqual, 1)
+
+			Convey("A token refused with 401 is fetched again", func() {
+				atomic.StoreInt32(&rejects, 1)
+				So(connect(), ShouldBeNil)
+				So(atomic.LoadInt32(&fetches), ShouldEqual, 2)
+			})
+
+			Convey("A 401 for a fresh token is returned", func() {
+				atomic.StoreInt32(&rejects, 2)
+				err := connect()
+				So(err, ShouldHaveSameTypeAs, &AuthError{})
+				So(err.(*AuthError).Err, ShouldHaveSameTypeAs, &HandshakeError{})
+				So(err.Error(), ShouldContainSubstring, "401")
+			})
+		})
+
+		Convey("Each user has its own token", func() {
+			key := func(user, password string) string {
+				client := &Client{URL: "https://sdr.example.com/", User: user, Password: password}
+				key, err := client.authTokenKey()
+				So(err, ShouldBeNil)
+				return key
+			}
+			So(key("", ""), ShouldEqual, "https://sdr.example.com/auth_token.js")
+			So(key("alice", "secret"), ShouldEqual, "https://alice@sdr.example.com/auth_token.js")
+			So(key("bob", "secret"), ShouldNotEqual, key("alice", "secret"))
+			So(key("alice", "other"), ShouldEqual, key("alice", "secret"))
+		})
+
+		Convey("Tokens are kept on disk with a TTL", func() {
+			dir := t.TempDir()
+			saveAuthToken(dir, "https://sdr.example.com/auth_token.js", "secret", time.Minute)
+			authTokens.Lock()
+			delete(authTokens.tokens, "https://sdr.example.com/auth_token.js")
+			authTokens.Unlock()
+			token, ok := loadAuthToken(dir, "https://sdr.example.com/auth_token.js", time.Minute)
+			So(ok, ShouldBeTrue)
+			So(token, ShouldEqual, "secret")
+
+			authTokens.Lock()
+			delete(authTokens.tokens, "https://sdr.example.com/auth_token.js")
+			authTokens.Unlock()
+			_, ok = loadAuthToken(dir, "https://sdr.example.com/auth_token.js", time.Nanosecond)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("An empty cache directory keeps tokens in memory", func() {
+			saveAuthToken("", "https://sdr.example.com/auth_token.js", "secret", time.Minute)
+			authTokens.Lock()
+			delete(authTokens.tokens, "https://sdr.example.com/auth_token.js")
+			authTokens.Unlock()
+			_, ok := loadAuthToken("", "https://sdr.example.com/auth_token.js", time.Minute)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
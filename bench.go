@@ -0,0 +1,182 @@
+package gottyclient
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// benchFrameTimeout bounds the wait for output while benchmarking
+const benchFrameTimeout = 5 * time.Second
+
+var errBenchTimeout = errors.New("timed out waiting for output")
+
+// BenchOptions configures Client.Bench
+type BenchOptions struct {
+	// Duration of the throughput measurement
+	Duration time.Duration
+	// Samples is the number of echo round trips timed
+	Samples int
+	// Command is typed to produce sustained output and interrupted with ^C
+	// once Duration has passed
+	Command string
+}
+
+// BenchResult holds the measurements taken by Client.Bench
+type BenchResult struct {
+	// Bytes of output received while Command ran, over Elapsed
+	Bytes   int64
+	Elapsed time.Duration
+	// FrameSizes are the decoded sizes of the output frames received
+	FrameSizes []int
+	// Latencies are the echo round trips of single keystrokes
+	Latencies []time.Duration
+}
+
+// Throughput returns the output rate in bytes per second
+func (r *BenchResult) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Elapsed.Seconds()
+}
+
+// LatencyPercentile returns the p-th percentile (0-100) of the echo latencies
+func (r *BenchResult) LatencyPercentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), r.Latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// FrameSizeStats returns the smallest, average and largest frame size
+func (r *BenchResult) FrameSizeStats() (min, avg, max int) {
+	if len(r.FrameSizes) == 0 {
+		return 0, 0, 0
+	}
+	min = r.FrameSizes[0]
+	total := 0
+	for _, size := range r.FrameSizes {
+		if size < min {
+			min = size
+		}
+		if size > max {
+			max = size
+		}
+		total += size
+	}
+	return min, total / len(r.FrameSizes), max
+}
+
+// Bench measures echo latency and sustained output throughput on a
+// connected client. It reads the connection itself, so it must not run
+// alongside Loop; close the client afterwards.
+func (c *Client) Bench(opts BenchOptions) (*BenchResult, error) {
+	if !c.IsConnected() {
+		return nil, errors.New("not connected")
+	}
+	frames := make(chan []byte, 1024)
+	readErr := make(chan error, 1)
+	go func(transport Transport) {
+		defer close(frames)
+		for {
+			data, err := c.readFrame(transport)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			message, err := ParseServerMessage(c.V2, data)
+			if err != nil {
+				continue
+			}
+			if output, ok := message.(OutputMessage); ok {
+				select {
+				case frames <- output.Data:
+				case <-c.closed:
+					return
+				}
+			}
+		}
+	}(c.transport())
+
+	// next waits for the next output frame
+	next := func(timeout time.Duration) ([]byte, error) {
+		select {
+		case frame, ok := <-frames:
+			if !ok {
+				return nil, fmt.Errorf("connection closed: %v", <-readErr)
+			}
+			return frame, nil
+		case <-time.After(timeout):
+			return nil, errBenchTimeout
+		}
+	}
+	// drain discards output until none arrived for quiet
+	drain := func(quiet time.Duration) error {
+		for {
+			if _, err := next(quiet); err == errBenchTimeout {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	result := &BenchResult{}
+	// Skip the prompt and banner
+	if err := drain(500 * time.Millisecond); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < opts.Samples; i++ {
+		start := time.Now()
+		if err := c.sendInput([]byte("x")); err != nil {
+			return nil, err
+		}
+		if _, err := next(benchFrameTimeout); err != nil {
+			return nil, fmt.Errorf("echo sample %d: %v", i+1, err)
+		}
+		result.Latencies = append(result.Latencies, time.Since(start))
+		if err := drain(20 * time.Millisecond); err != nil {
+			return nil, err
+		}
+	}
+	// Erase the typed line before running the command
+	if err := c.sendInput([]byte{0x15}); err != nil {
+		return nil, err
+	}
+	if err := drain(200 * time.Millisecond); err != nil {
+		return nil, err
+	}
+
+	if opts.Duration > 0 && opts.Command != "" {
+		if err := c.sendInput([]byte(opts.Command + "\r")); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		for deadline := start.Add(opts.Duration); time.Now().Before(deadline); {
+			frame, err := next(benchFrameTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("throughput: %v", err)
+			}
+			result.Bytes += int64(len(frame))
+			result.FrameSizes = append(result.FrameSizes, len(frame))
+		}
+		result.Elapsed = time.Since(start)
+		if err := c.sendInput([]byte{0x03}); err != nil {
+			return nil, err
+		}
+		if err := drain(500 * time.Millisecond); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package gottyclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moul/gotty-client/internal/mockserver"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBench(t *testing.T) {
+	Convey("Testing Bench against the mock server", t, func() {
+		server := mockserver.New()
+		defer server.Close()
+
+		client, err := NewClient(server.URL)
+		So(err, ShouldBeNil)
+		client.V2 = true
+		So(client.Connect(), ShouldBeNil)
+		defer client.Close()
+
+		result, err := client.Bench(BenchOptions{Duration: 200 * time.Millisecond, Samples: 5, Command: "yes"})
+		So(err, ShouldBeNil)
+		So(len(result.Latencies), ShouldEqual, 5)
+		So(result.LatencyPercentile(50), ShouldBeGreaterThan, 0)
+		So(result.LatencyPercentile(99), ShouldBeGreaterThanOrEqualTo, result.LatencyPercentile(50))
+		So(result.Bytes, ShouldBeGreaterThan, 0)
+		So(result.Throughput(), ShouldBeGreaterThan, 0)
+		_, _, max := result.FrameSizeStats()
+		So(max, ShouldEqual, mockserver.FrameSize/len(mockserver.FloodLine)*len(mockserver.FloodLine))
+	})
+
+	Convey("Testing BenchResult statistics", t, func() {
+		result := &BenchResult{
+			Latencies:  []time.Duration{4, 1, 3, 2, 10},
+			FrameSizes: []int{10, 2, 30},
+			Bytes:      1000,
+			Elapsed:    2 * time.Second,
+		}
+		So(result.LatencyPercentile(50), ShouldEqual, 3)
+		So(result.LatencyPercentile(90), ShouldEqual, 10)
+		So(result.LatencyPercentile(0), ShouldEqual, 1)
+		min, avg, max := result.FrameSizeStats()
+		So([]int{min, avg, max}, ShouldResemble, []int{2, 14, 30})
+		So(result.Throughput(), ShouldEqual, 500)
+	})
+}
+
+// discardTransport drops written messages
+type discardTransport struct {
+	fakeTransport
+}
+
+func (t *discardTransport) Write(data []byte) error { return nil }
+
+func outputFrame(size int) []byte {
+	data := bytes.Repeat([]byte("y\r\n"), size/3)
+	return append([]byte{Output}, base64.StdEncoding.EncodeToString(data)...)
+}
+
+func BenchmarkParseServerMessage(b *testing.B) {
+	for _, size := range []int{64, 4096, 65536} {
+		frame := outputFrame(size)
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(len(frame)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseServerMessage(true, frame); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHandleOutput(b *testing.B) {
+	client, err := NewClient("http://localhost/")
+	if err != nil {
+		b.Fatal(err)
+	}
+	client.Output = ioutil.Discard
+	client.NormalizeOutput = true
+	message, err := ParseServerMessage(true, outputFrame(4096))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(4096)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		client.handleMessage(message)
+	}
+}
+
+func BenchmarkSendInput(b *testing.B) {
+	client, err := NewClient("http://localhost/")
+	if err != nil {
+		b.Fatal(err)
+	}
+	client.Transport = &discardTransport{}
+	client.V2 = true
+	client.message = newMessageType(true)
+	input := []byte("ls -la\r")
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := client.sendInput(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInputTranslation(b *testing.B) {
+	keymap, err := ParseKeymap(strings.NewReader("backspace delete\nf1 \\x1b[[A\n"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	input := []byte("echo hello\x7f\x1bOP\r")
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		CRLFSendCRLF.Translate(keymap.Apply(input))
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// genDocsAction writes a man page and markdown documentation generated from
+// the CLI definition, for packaging
+func genDocsAction(c *cli.Context) error {
+	dir := c.String("dir")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	app := c.App
+
+	man, err := app.ToMan()
+	if err != nil {
+		return fmt.Errorf("failed to generate man page: %v", err)
+	}
+	// uberterm is a user command, section 1
+	man = strings.Replace(man, app.Name+"(8)", app.Name+"(1)", 1)
+	if err := writeDoc(filepath.Join(dir, app.Name+".1"), man); err != nil {
+		return err
+	}
+
+	markdown, err := app.ToMarkdown()
+	if err != nil {
+		return fmt.Errorf("failed to generate markdown: %v", err)
+	}
+	markdown = strings.Replace(markdown, app.Name+"(8)", app.Name+"(1)", 1)
+	if err := writeDoc(filepath.Join(dir, app.Name+".md"), markdown); err != nil {
+		return err
+	}
+
+	return writeCommandDocs(dir, app.Name, app.Commands)
+}
+
+// writeCommandDocs writes one markdown file per command and subcommand
+func writeCommandDocs(dir, parent string, commands []cli.Command) error {
+	for _, command := range commands {
+		// The help command is added by cli itself
+		if command.Hidden || command.Name == "help" {
+			continue
+		}
+		fullName := parent + " " + command.Name
+		path := filepath.Join(dir, strings.Replace(fullName, " ", "-", -1)+".md")
+		if err := writeDoc(path, commandMarkdown(fullName, command)); err != nil {
+			return err
+		}
+		if err := writeCommandDocs(dir, fullName, command.Subcommands); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commandMarkdown renders the help of a command as markdown
+func commandMarkdown(fullName string, command cli.Command) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n\n", fullName)
+	if command.Usage != "" {
+		fmt.Fprintf(&buf, "%s\n\n", command.Usage)
+	}
+	if command.Description != "" {
+		fmt.Fprintf(&buf, "%s\n\n", command.Description)
+	}
+
+	fmt.Fprintf(&buf, "## Usage\n\n```\n%s", fullName)
+	if len(command.Flags) > 0 {
+		buf.WriteString(" [OPTIONS]")
+	}
+	if len(command.Subcommands) > 0 {
+		buf.WriteString(" [COMMAND]")
+	}
+	if command.ArgsUsage != "" {
+		buf.WriteString(" " + command.ArgsUsage)
+	}
+	buf.WriteString("\n```\n")
+
+	if len(command.Aliases) > 0 {
+		fmt.Fprintf(&buf, "\n**Aliases:** `%s`\n", strings.Join(command.Aliases, "`, `"))
+	}
+
+	if len(command.Subcommands) > 0 {
+		buf.WriteString("\n## Commands\n\n")
+		for _, sub := range command.Subcommands {
+			if sub.Hidden {
+				continue
+			}
+			fmt.Fprintf(&buf, "- [`%s`](%s.md) - %s\n", sub.Name, strings.Replace(fullName+" "+sub.Name, " ", "-", -1), sub.Usage)
+		}
+	}
+
+	if len(command.Flags) > 0 {
+		buf.WriteString("\n## Options\n\n")
+		for _, flag := range command.Flags {
+			// Same text as --help: "--name value  usage (default: x) [$ENV]"
+			parts := strings.SplitN(cli.FlagStringer(flag), "\t", 2)
+			if len(parts) == 2 {
+				fmt.Fprintf(&buf, "- `%s` - %s\n", parts[0], parts[1])
+			} else {
+				fmt.Fprintf(&buf, "- `%s`\n", parts[0])
+			}
+		}
+	}
+	return buf.String()
+}
+
+func writeDoc(path, content string) error {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	fmt.Printf("Wrote %s\n", path)
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// runMainEnv makes the test binary run main instead of the tests, so the
+// command line can be tested as a whole
+const runMainEnv = "UBERTERM_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// uberterm runs the command line with home as HOME and no GOTTY_CLIENT_*
+// settings, returning its output and whether it succeeded
+func uberterm(home string, args ...string) (string, bool) {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = []string{runMainEnv + "=1", "HOME=" + home}
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "GOTTY_CLIENT_") && !strings.HasPrefix(env, "HOME=") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	out, err := cmd.CombinedOutput()
+	return string(out), err == nil
+}
+
+func TestStatelessCommandLine(t *testing.T) {
+	Convey("Testing --stateless from the command line", t, func() {
+		home := t.TempDir()
+		So(os.Chmod(home, 0500), ShouldBeNil)
+		defer os.Chmod(home, 0700)
+
+		for _, args := range [][]string{
+			{"--stateless", "schedule", "run", "--once"},
+			{"--stateless", "schedule", "run", "--once", "--record-dir", home + "/recordings"},
+			{"--stateless", "schedule", "add", "--exec", "date", "--record", "12:00", "http://127.0.0.1:1/"},
+			{"--stateless", "schedule", "remove", "1"},
+			{"--stateless", "agent", "--socket", home + "/agent.sock"},
+		} {
+			out, ok := uberterm(home, args...)
+			So(ok, ShouldBeFalse)
+			So(out, ShouldContainSubstring, "not available in stateless mode")
+		}
+
+		// Nothing may be written, even where the permissions allow it,
+		// e.g. when running as root
+		entries, err := ioutil.ReadDir(home)
+		So(err, ShouldBeNil)
+		So(entries, ShouldBeEmpty)
+	})
+}
+
+func TestWaitCommandLine(t *testing.T) {
+	Convey("Testing --wait from the command line", t, func() {
+		Convey("Mistakes in the settings are not waited on", func() {
+			start := time.Now()
+			out, ok := uberterm(t.TempDir(), "--stateless", "--wait", "--wait-timeout", "1m", "--freq", "abc", "http://127.0.0.1:1/")
+			So(ok, ShouldBeFalse)
+			So(out, ShouldNotContainSubstring, "Not connected yet")
+			So(time.Since(start), ShouldBeLessThan, 10*time.Second)
+		})
+	})
+}
+
+func TestWindowLookupCommandLine(t *testing.T) {
+	Convey("Testing looking a session up by window from the command line", t, func() {
+		Convey("Settings the lookup cannot use are errors, not ignored", func() {
+			home := t.TempDir()
+			out, ok := uberterm(home, "--window", "ft8", "--known-hosts", home, "http://127.0.0.1:1/")
+			So(ok, ShouldBeFalse)
+			So(out, ShouldContainSubstring, "failed to read known hosts")
+			So(out, ShouldNotContainSubstring, "Failed to look up session")
+		})
+	})
+}
+
+func TestHandoverCommandLine(t *testing.T) {
+	Convey("Testing session handover from the command line", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"success":true,"session":"ft8"}`))
+		}))
+		defer server.Close()
+
+		out, ok := uberterm(t.TempDir(), "--stateless", "sessions", "handover", "--to", "bob", "ft8", strings.Replace(server.URL, "://", "://alice:pw@", 1)+"/?key=secret")
+		So(ok, ShouldBeTrue)
+		So(out, ShouldContainSubstring, "bob can attach with:\n    uberterm --session ft8 "+server.URL+"/\n")
+		So(out, ShouldNotContainSubstring, "secret")
+		So(out, ShouldNotContainSubstring, "alice")
+	})
+}
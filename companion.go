@@ -0,0 +1,56 @@
+package gottyclient
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CompanionStopTimeout is how long a companion command is given to exit
+// after being asked to, before it is killed
+const CompanionStopTimeout = 2 * time.Second
+
+// startCompanion starts CompanionCommand in the background with the system
+// shell and the environment of the local commands, returning the function
+// stopping it. Its output is discarded, so it cannot garble the terminal.
+func (c *Client) startCompanion() (func(), error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", c.CompanionCommand)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", c.CompanionCommand)
+	}
+	cmd.Env = append(os.Environ(), c.hookEnv("companion")...)
+	// The shell's children, e.g. the player, are stopped with it
+	setProcessGroup(cmd)
+	logrus.Debugf("Starting companion command: %q", c.CompanionCommand)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		logrus.Debugf("Companion command exited: %v", err)
+		close(exited)
+	}()
+	return func() {
+		select {
+		case <-exited:
+			return
+		default:
+		}
+		if err := stopProcessGroup(cmd.Process); err != nil {
+			logrus.Debugf("Failed to stop companion command: %v", err)
+		}
+		select {
+		case <-exited:
+		case <-time.After(CompanionStopTimeout):
+			killProcessGroup(cmd.Process)
+			<-exited
+		}
+	}, nil
+}
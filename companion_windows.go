@@ -0,0 +1,23 @@
+package gottyclient
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup starts cmd in a process group of its own
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// stopProcessGroup ends process and the processes it started
+func stopProcessGroup(process *os.Process) error {
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(process.Pid)).Run()
+}
+
+// killProcessGroup forcibly ends process and the processes it started
+func killProcessGroup(process *os.Process) {
+	_ = exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(process.Pid)).Run()
+}
@@ -0,0 +1,73 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Run with -race: the session API and reconnects are used while the loops
+// read and write the connection
+func TestConcurrentUse(t *testing.T) {
+	Convey("Testing concurrent use of a connected client", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasSuffix(r.URL.Path, "auth_token.js"):
+				w.Write([]byte("var gotty_auth_token = 'token'"))
+			case strings.HasSuffix(r.URL.Path, "/api/sessions"):
+				w.Write([]byte(`{"sessions":[{"name":"ft8"}],"count":1}`))
+			case strings.HasSuffix(r.URL.Path, "/api/sessions/destroy"):
+				w.Write([]byte(`{"success":true,"session":"ft8"}`))
+			default:
+				serveTerminal(w, r)
+			}
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		client.V2 = true
+		So(client.Connect(), ShouldBeNil)
+		defer client.Close()
+
+		errs := make(chan error, 100)
+		wg := &sync.WaitGroup{}
+		run := func(f func() error) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 5; i++ {
+					if err := f(); err != nil {
+						errs <- err
+					}
+				}
+			}()
+		}
+		run(func() error { _, err := client.ListSessions(); return err })
+		run(func() error { _, err := client.DestroySession("ft8"); return err })
+		// Writes fail while the transport is being replaced, which is fine here
+		run(func() error { _ = client.sendInput([]byte("ls\r")); return nil })
+		run(func() error { client.ping(); client.touchRead(); return nil })
+		run(func() error {
+			if !client.IsConnected() {
+				return nil
+			}
+			// Reconnecting replaces the transport under the other goroutines
+			_ = client.transport().Close()
+			return client.Connect()
+		})
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			So(err, ShouldBeNil)
+		}
+		So(client.IsConnected(), ShouldBeTrue)
+		So(client.Close(), ShouldBeNil)
+		So(client.IsConnected(), ShouldBeFalse)
+	})
+}
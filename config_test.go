@@ -0,0 +1,266 @@
+package gottyclient
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHostPrecedence(t *testing.T) {
+	Convey("Testing host block precedence", t, func() {
+		path := filepath.Join(t.TempDir(), "config")
+		So(ioutil.WriteFile(path, []byte(`Host sdr.lab.internal
+    URL http://sdr:8080
+
+Host *.lab.internal
+    User lab
+
+Host *
+    User anyone
+    PathSuffix /t/
+
+Host *.internal
+    User internal
+    SkipTLSVerify true
+
+Defaults
+    User nobody
+    AdminPassword secret
+    PathSuffix /terminal/
+`), 0600), ShouldBeNil)
+		config, err := LoadConfigFromPath(path)
+		So(err, ShouldBeNil)
+
+		Convey("Every matching block applies, the most specific last", func() {
+			// Map order must not matter, so look several times
+			for i := 0; i < 20; i++ {
+				host := config.GetHostConfig("sdr.lab.internal")
+				So(host.URL, ShouldEqual, "http://sdr:8080")
+				So(host.User, ShouldEqual, "lab")
+				So(host.SkipTLSVerify, ShouldBeTrue)
+				So(host.PathSuffix, ShouldEqual, "/t/")
+				So(host.AdminPassword, ShouldEqual, "secret")
+
+				So(config.GetHostConfig("db.internal").User, ShouldEqual, "internal")
+			}
+			So(config.GetHostConfig("example.org").User, ShouldEqual, "anyone")
+		})
+
+		Convey("Defaults apply without Host blocks", func() {
+			config.Hosts = append(config.Hosts[:2], config.Hosts[3])
+			So(config.GetHostConfig("example.org"), ShouldBeNil)
+			defaults := config.DefaultHostConfig()
+			So(defaults.User, ShouldEqual, "nobody")
+			So(defaults.PathSuffix, ShouldEqual, "/terminal/")
+
+			url, host, err := config.Resolve("sdr.lab.internal")
+			So(err, ShouldBeNil)
+			So(url, ShouldEqual, "http://sdr:8080")
+			So(host.AdminPassword, ShouldEqual, "secret")
+		})
+
+		Convey("Patterns of the same length are taken in file order", func() {
+			config := &Config{Hosts: []*HostConfig{
+				{Host: "*.lab", User: "first"},
+				{Host: "sdr.*", User: "second"},
+			}}
+			So(config.GetHostConfig("sdr.lab").User, ShouldEqual, "first")
+
+			config.SetHost(&HostConfig{Host: "*.lab", User: "replaced"})
+			So(config.Hosts, ShouldHaveLength, 2)
+			So(config.GetHostConfig("sdr.lab").User, ShouldEqual, "replaced")
+		})
+
+		Convey("The file is rewritten in order with its Defaults block", func() {
+			So(WriteConfig(path, config), ShouldBeNil)
+			first, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			config, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			So(config.Defaults.AdminPassword, ShouldEqual, "secret")
+			So(config.Hosts, ShouldHaveLength, 4)
+			So(config.Hosts[0].Host, ShouldEqual, "sdr.lab.internal")
+			So(config.Hosts[3].Host, ShouldEqual, "*.internal")
+
+			So(WriteConfig(path, config), ShouldBeNil)
+			second, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(second), ShouldEqual, string(first))
+		})
+
+		Convey("Bookmarks add up across blocks and survive rewriting", func() {
+			So(ioutil.WriteFile(path, []byte(`Host m9psy
+    Callsign M9PSY
+    Bookmark ft8-40m 7.074MHz USB FT8 on 40m
+    Bookmark wwv 10MHz AM
+
+Host *
+    Bookmark wwv 15MHz AM
+    Bookmark ft8-20m 14074kHz usb
+`), 0600), ShouldBeNil)
+			config, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			host := config.GetHostConfig("m9psy")
+			So(host.Bookmarks, ShouldHaveLength, 3)
+			So(host.Bookmark("wwv").Freq, ShouldEqual, 10000000)
+			So(host.Bookmark("ft8-20m").Mode, ShouldEqual, "usb")
+			So(host.Bookmark("ft8-40m").Label, ShouldEqual, "FT8 on 40m")
+			So(host.Bookmark("ft8-80m"), ShouldBeNil)
+
+			So(WriteConfig(path, config), ShouldBeNil)
+			rewritten, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			So(rewritten.Host("m9psy").Bookmarks, ShouldResemble, config.Host("m9psy").Bookmarks)
+
+			So(ioutil.WriteFile(path, []byte("Host a\n    Bookmark x 1MHz\n    Bookmark x 2MHz\n"), 0600), ShouldBeNil)
+			_, err = LoadConfigFromPath(path)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "line 3: duplicate Bookmark x")
+		})
+
+		Convey("TCPNoDelay is kept as written and a specific block undoes Defaults", func() {
+			So(ioutil.WriteFile(path, []byte(`Host lab
+    URL http://lab:8080
+    TCPNoDelay yes
+
+Host club
+    URL http://club:8080
+
+Defaults
+    TCPNoDelay no
+`), 0600), ShouldBeNil)
+			config, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+
+			client := &Client{}
+			config.GetHostConfig("lab").ApplyToClient(client)
+			So(client.TCPNagle, ShouldBeFalse)
+			config.GetHostConfig("club").ApplyToClient(client)
+			So(client.TCPNagle, ShouldBeTrue)
+
+			client = &Client{}
+			(&HostConfig{}).ApplyToClient(client)
+			So(client.TCPNagle, ShouldBeFalse)
+
+			So(WriteConfig(path, config), ShouldBeNil)
+			data, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, "TCPNoDelay true")
+			So(string(data), ShouldContainSubstring, "TCPNoDelay false")
+		})
+
+		Convey("A specific block's no overrides an inherited yes", func() {
+			So(ioutil.WriteFile(path, []byte(`Host lab
+    URL http://lab:8080
+    SkipTLSVerify no
+    V2 no
+    CookieJar no
+    UseProxyFromEnv no
+    NormalizeOutput no
+    Tips yes
+
+Host club
+    URL http://club:8080
+
+Host *
+    SkipTLSVerify yes
+    V2 yes
+    CookieJar yes
+
+Defaults
+    UseProxyFromEnv yes
+    NormalizeOutput yes
+    Tips no
+`), 0600), ShouldBeNil)
+			config, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+
+			club := config.GetHostConfig("club")
+			So(club.SkipTLSVerify, ShouldBeTrue)
+			So(club.V2, ShouldBeTrue)
+			So(club.CookieJar, ShouldBeTrue)
+			So(club.UseProxyFromEnv, ShouldBeTrue)
+			So(club.NormalizeOutput, ShouldBeTrue)
+			So(club.NoTips, ShouldBeTrue)
+
+			lab := config.GetHostConfig("lab")
+			So(lab.SkipTLSVerify, ShouldBeFalse)
+			So(lab.V2, ShouldBeFalse)
+			So(lab.CookieJar, ShouldBeFalse)
+			So(lab.UseProxyFromEnv, ShouldBeFalse)
+			So(lab.NormalizeOutput, ShouldBeFalse)
+			So(lab.NoTips, ShouldBeFalse)
+
+			client := &Client{}
+			club.ApplyToClient(client)
+			lab.ApplyToClient(client)
+			So(client.SkipTLSVerify, ShouldBeFalse)
+			So(client.V2, ShouldBeFalse)
+			So(client.UseProxyFromEnv, ShouldBeFalse)
+
+			So(WriteConfig(path, config), ShouldBeNil)
+			config, err = LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			So(config.GetHostConfig("lab").SkipTLSVerify, ShouldBeFalse)
+			So(config.GetHostConfig("lab").NoTips, ShouldBeFalse)
+			So(config.GetHostConfig("club").SkipTLSVerify, ShouldBeTrue)
+		})
+
+		Convey("Duplicate blocks are refused", func() {
+			So(ioutil.WriteFile(path, []byte("Host a\n    URL http://a\n\nHost b\n\nHost a\n    URL http://other\n"), 0600), ShouldBeNil)
+			_, err := LoadConfigFromPath(path)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "line 6: duplicate Host a, first defined on line 1")
+
+			So(ioutil.WriteFile(path, []byte("Host a\nProfile x\nHost a\n"), 0600), ShouldBeNil)
+			_, err = LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func TestConfigPath(t *testing.T) {
+	Convey("Testing the config file path", t, func() {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "custom", "config")
+
+		oldEnv, hadEnv := os.LookupEnv(ConfigEnv)
+		os.Setenv(ConfigEnv, path)
+		defer func() {
+			if hadEnv {
+				os.Setenv(ConfigEnv, oldEnv)
+			} else {
+				os.Unsetenv(ConfigEnv)
+			}
+		}()
+		So(GetDefaultConfigPath(), ShouldEqual, path)
+
+		Convey("Creation and saves use the chosen file", func() {
+			exists, err := EnsureConfigExistsAtPath(path, false)
+			So(err, ShouldBeNil)
+			So(exists, ShouldBeFalse)
+			_, err = os.Stat(filepath.Dir(path))
+			So(os.IsNotExist(err), ShouldBeTrue)
+
+			So(EnsureConfigExists(), ShouldBeNil)
+			_, err = os.Stat(path)
+			So(err, ShouldBeNil)
+			exists, err = EnsureConfigExistsAtPath(path, false)
+			So(err, ShouldBeNil)
+			So(exists, ShouldBeTrue)
+
+			other := filepath.Join(dir, "other")
+			So(SaveHostConfigToPath(other, "lab", &HostConfig{URL: "http://lab:8080"}), ShouldBeNil)
+			config, err := LoadConfigFromPath(other)
+			So(err, ShouldBeNil)
+			So(config.Host("lab").URL, ShouldEqual, "http://lab:8080")
+
+			config, err = LoadConfig()
+			So(err, ShouldBeNil)
+			So(config.Host("lab"), ShouldBeNil)
+		})
+	})
+}
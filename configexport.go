@@ -0,0 +1,290 @@
+package gottyclient
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// EncryptedSecretPrefix marks the config values encrypted by EncryptSecrets
+const EncryptedSecretPrefix = "enc:"
+
+const (
+	secretSaltSize  = 16
+	secretNonceSize = 24
+)
+
+// IsEncryptedSecret reports whether a config value was encrypted by
+// EncryptSecrets
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, EncryptedSecretPrefix)
+}
+
+// hostConfigs returns the Defaults and Host blocks of the configuration and
+// of its Profile sections
+func (c *Config) hostConfigs() []*HostConfig {
+	var hosts []*HostConfig
+	if c.Defaults != nil {
+		hosts = append(hosts, c.Defaults)
+	}
+	hosts = append(hosts, c.Hosts...)
+	for _, profile := range c.Profiles {
+		hosts = append(hosts, profile.hostConfigs()...)
+	}
+	return hosts
+}
+
+// secretFields returns the settings of a host block holding secrets: its
+// passwords and TOTP secret, and its URLs and proxy when they carry a
+// password
+func (hc *HostConfig) secretFields() []*string {
+	fields := []*string{&hc.Password, &hc.AdminPassword, &hc.TOTPSecret}
+	for _, field := range []*string{&hc.URL, &hc.AlternateURL, &hc.Proxy} {
+		if _, ok := urlListPassword(*field); ok || IsEncryptedSecret(*field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// secret is a config value holding a secret, with the host block or
+// registry it belongs to
+type secret struct {
+	owner string
+	value *string
+}
+
+// secrets returns the values holding secrets in the configuration and its
+// profiles: those of the host blocks, and registry URLs carrying a password
+func (c *Config) secrets() []secret {
+	var secrets []secret
+	for _, host := range c.hostConfigs() {
+		owner := "host " + host.Host
+		if host.Host == "" {
+			owner = "the Defaults block"
+		}
+		for _, field := range host.secretFields() {
+			secrets = append(secrets, secret{owner, field})
+		}
+	}
+	for _, config := range append([]*Config{c}, c.profileConfigs()...) {
+		for i := range config.Registries {
+			registry := &config.Registries[i]
+			if _, ok := urlPassword(registry.URL); ok || IsEncryptedSecret(registry.URL) {
+				secrets = append(secrets, secret{"registry " + registry.Name, &registry.URL})
+			}
+		}
+	}
+	return secrets
+}
+
+// profileConfigs returns the Profile sections of the configuration
+func (c *Config) profileConfigs() []*Config {
+	var profiles []*Config
+	for _, profile := range c.Profiles {
+		profiles = append(profiles, profile)
+		profiles = append(profiles, profile.profileConfigs()...)
+	}
+	return profiles
+}
+
+// urlPassword returns the URL without its password, and whether it had one
+func urlPassword(raw string) (string, bool) {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.User == nil {
+		return raw, false
+	}
+	if _, ok := parsed.User.Password(); !ok {
+		return raw, false
+	}
+	parsed.User = url.User(parsed.User.Username())
+	return parsed.String(), true
+}
+
+// urlListPassword is urlPassword for a comma-separated list of URLs, such as
+// AlternateURL
+func urlListPassword(raw string) (string, bool) {
+	urls := strings.Split(raw, ",")
+	found := false
+	for i, u := range urls {
+		stripped, ok := urlPassword(strings.TrimSpace(u))
+		if ok {
+			urls[i], found = stripped, true
+		}
+	}
+	if !found {
+		return raw, false
+	}
+	return strings.Join(urls, ","), true
+}
+
+// RedactSecrets removes the passwords, admin passwords and TOTP secrets of
+// every host, and the passwords in their URLs and proxies and in registry
+// URLs, e.g. to share a host list
+func RedactSecrets(config *Config) {
+	for _, host := range config.hostConfigs() {
+		host.Password, host.AdminPassword, host.TOTPSecret = "", "", ""
+		host.URL, _ = urlPassword(host.URL)
+		host.AlternateURL, _ = urlListPassword(host.AlternateURL)
+		host.Proxy, _ = urlPassword(host.Proxy)
+	}
+	for _, config := range append([]*Config{config}, config.profileConfigs()...) {
+		for i := range config.Registries {
+			config.Registries[i].URL, _ = urlPassword(config.Registries[i].URL)
+		}
+	}
+}
+
+// secretKey derives the key encrypting secrets from a passphrase and salt
+func secretKey(passphrase string, salt []byte) (*[32]byte, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	var key [32]byte
+	copy(key[:], derived)
+	return &key, nil
+}
+
+// EncryptSecrets encrypts the secrets RedactSecrets would remove with a key
+// derived from passphrase, so that a host list can be moved to another
+// machine without exposing them; DecryptSecrets reverses it
+func EncryptSecrets(config *Config, passphrase string) error {
+	salt := make([]byte, secretSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := secretKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range config.secrets() {
+		field := secret.value
+		if *field == "" || IsEncryptedSecret(*field) {
+			continue
+		}
+		var nonce [secretNonceSize]byte
+		if _, err := rand.Read(nonce[:]); err != nil {
+			return err
+		}
+		sealed := append(append([]byte{}, salt...), nonce[:]...)
+		sealed = secretbox.Seal(sealed, []byte(*field), &nonce, key)
+		*field = EncryptedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed)
+	}
+	return nil
+}
+
+// DecryptSecrets decrypts the values encrypted by EncryptSecrets
+func DecryptSecrets(config *Config, passphrase string) error {
+	keys := make(map[string]*[32]byte)
+	for _, secret := range config.secrets() {
+		field := secret.value
+		if !IsEncryptedSecret(*field) {
+			continue
+		}
+		sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(*field, EncryptedSecretPrefix))
+		if err != nil || len(sealed) < secretSaltSize+secretNonceSize+secretbox.Overhead {
+			return fmt.Errorf("%s: malformed encrypted value", secret.owner)
+		}
+		salt := string(sealed[:secretSaltSize])
+		key, ok := keys[salt]
+		if !ok {
+			if key, err = secretKey(passphrase, sealed[:secretSaltSize]); err != nil {
+				return err
+			}
+			keys[salt] = key
+		}
+		var nonce [secretNonceSize]byte
+		copy(nonce[:], sealed[secretSaltSize:])
+		plain, ok := secretbox.Open(nil, sealed[secretSaltSize+secretNonceSize:], &nonce, key)
+		if !ok {
+			return fmt.Errorf("failed to decrypt the secrets of %s: wrong passphrase?", secret.owner)
+		}
+		*field = string(plain)
+	}
+	return nil
+}
+
+// HasSecrets reports whether the configuration holds secrets in clear
+func HasSecrets(config *Config) bool {
+	for _, secret := range config.secrets() {
+		if *secret.value != "" && !IsEncryptedSecret(*secret.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasEncryptedSecrets reports whether the configuration holds values
+// encrypted by EncryptSecrets
+func HasEncryptedSecrets(config *Config) bool {
+	for _, secret := range config.secrets() {
+		if IsEncryptedSecret(*secret.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportConfig writes the configuration in the config file format, e.g. to
+// share it or move it to another machine
+func ExportConfig(w io.Writer, config *Config) error {
+	writer := bufio.NewWriter(w)
+	if err := writeConfig(writer, "", config); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// ImportConfig adds the hosts, registries and profiles of src to dst, those
+// of src replacing those of dst with the same name, or with replace makes dst
+// a copy of src. A merged Defaults block is applied over dst's.
+func ImportConfig(dst, src *Config, replace bool) {
+	if replace {
+		path := dst.path
+		*dst = *src
+		dst.path = path
+		return
+	}
+
+	for _, host := range src.Hosts {
+		dst.SetHost(host)
+	}
+	if src.Defaults != nil {
+		dst.Defaults = MergeHostConfigs(dst.Defaults, src.Defaults)
+	}
+	for _, registry := range src.Registries {
+		known := false
+		for i, existing := range dst.Registries {
+			if existing.Name == registry.Name || existing.URL == registry.URL {
+				dst.Registries[i] = registry
+				known = true
+				break
+			}
+		}
+		if !known {
+			dst.Registries = append(dst.Registries, registry)
+		}
+	}
+	for name, profile := range src.Profiles {
+		if dst.Profiles == nil {
+			dst.Profiles = make(map[string]*Config)
+		}
+		if existing := dst.Profiles[name]; existing != nil {
+			ImportConfig(existing, profile, false)
+		} else {
+			dst.Profiles[name] = profile
+		}
+	}
+	if dst.DefaultProfile == "" {
+		dst.DefaultProfile = src.DefaultProfile
+	}
+}
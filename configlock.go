@@ -0,0 +1,104 @@
+package gottyclient
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// configLockTimeout is how long a config file update waits for another
+// process updating the same file
+var configLockTimeout = 10 * time.Second
+
+// errLocked is returned by tryLockFile when another process holds the lock
+var errLocked = fmt.Errorf("locked")
+
+// lockConfig takes the advisory lock of the config file at path, waiting up
+// to configLockTimeout, and returns the function releasing it. The lock is a
+// separate file, path + ".lock", as the config file itself is replaced on
+// every write.
+func lockConfig(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %v", err)
+	}
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config lock: %v", err)
+	}
+
+	deadline := time.Now().Add(configLockTimeout)
+	for {
+		err := tryLockFile(file)
+		if err == nil {
+			break
+		}
+		if err != errLocked || time.Now().After(deadline) {
+			file.Close()
+			if err == errLocked {
+				return nil, fmt.Errorf("config file %s is being updated by another process", path)
+			}
+			return nil, fmt.Errorf("failed to lock config file: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
+
+// writeFileAtomic writes a file through write and renames it over path, so
+// readers see either the old or the new file, never a partial one. A
+// symbolic link at path is kept and its target replaced.
+func writeFileAtomic(path string, perm os.FileMode, write func(*bufio.Writer) error) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	err = write(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// UpdateConfig loads the config file at path, lets update change it and
+// writes it back, holding the file's lock so that concurrent updates, e.g.
+// two --save runs, are applied one after the other
+func UpdateConfig(path string, update func(*Config) error) error {
+	unlock, err := lockConfig(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := LoadConfigFromPath(path)
+	if err != nil {
+		return fmt.Errorf("failed to load existing config: %v", err)
+	}
+	if err := update(config); err != nil {
+		return err
+	}
+	return WriteConfig(path, config)
+}
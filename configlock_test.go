@@ -0,0 +1,91 @@
+package gottyclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfigLocking(t *testing.T) {
+	Convey("Testing concurrent config updates", t, func() {
+		path := filepath.Join(t.TempDir(), "config")
+		So(ioutil.WriteFile(path, []byte("Host base\n    URL http://base:8080\n"), 0600), ShouldBeNil)
+
+		Convey("Concurrent writers do not lose each other's hosts", func() {
+			const writers = 20
+			var wg sync.WaitGroup
+			errs := make(chan error, writers)
+			for i := 0; i < writers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs <- UpdateConfig(path, func(config *Config) error {
+						config.SetHost(&HostConfig{Host: fmt.Sprintf("host%d", i), URL: fmt.Sprintf("http://host%d:8080", i)})
+						return nil
+					})
+				}(i)
+			}
+
+			// Readers only ever see whole files
+			stop := make(chan struct{})
+			var readErrs []error
+			var readers sync.WaitGroup
+			readers.Add(1)
+			go func() {
+				defer readers.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					config, err := LoadConfigFromPath(path)
+					if err == nil && config.Host("base") == nil {
+						err = fmt.Errorf("partial config with %d hosts", len(config.Hosts))
+					}
+					if err != nil {
+						readErrs = append(readErrs, err)
+					}
+				}
+			}()
+
+			wg.Wait()
+			close(stop)
+			readers.Wait()
+			close(errs)
+			for err := range errs {
+				So(err, ShouldBeNil)
+			}
+			So(readErrs, ShouldBeEmpty)
+
+			config, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			So(config.Hosts, ShouldHaveLength, writers+1)
+
+			matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), ".config.tmp*"))
+			So(err, ShouldBeNil)
+			So(matches, ShouldBeEmpty)
+		})
+
+		Convey("Updates give up when the lock is held too long", func() {
+			oldTimeout := configLockTimeout
+			configLockTimeout = 50 * time.Millisecond
+			defer func() { configLockTimeout = oldTimeout }()
+
+			unlock, err := lockConfig(path)
+			So(err, ShouldBeNil)
+			err = UpdateConfig(path, func(*Config) error { return nil })
+			So(err, ShouldNotBeNil)
+			So(strings.Contains(err.Error(), "another process"), ShouldBeTrue)
+
+			unlock()
+			So(SetDefaultProfile(path, ""), ShouldBeNil)
+		})
+	})
+}
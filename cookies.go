@@ -0,0 +1,133 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetDefaultCookiePath returns the cookie jar file for a host
+func GetDefaultCookiePath(host string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(host)
+	return filepath.Join(home, ".gotty-client", "cookies", name+".json")
+}
+
+// PersistentJar is an http.CookieJar that keeps cookies on disk between runs,
+// so cookie-based SSO in front of a server only has to be completed once.
+// It is shared between the auth-token fetch, the session API and the
+// websocket upgrade request.
+type PersistentJar struct {
+	mutex   sync.Mutex
+	path    string
+	jar     *cookiejar.Jar
+	cookies map[string][]*http.Cookie
+}
+
+// NewPersistentJar loads the cookie jar stored at path, or starts an empty
+// one; an empty path keeps it in memory only
+func NewPersistentJar(path string) (*PersistentJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	pj := &PersistentJar{path: path, jar: jar, cookies: make(map[string][]*http.Cookie)}
+	if path == "" {
+		return pj, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pj, nil
+		}
+		return nil, fmt.Errorf("failed to read cookie jar: %v", err)
+	}
+	if err := json.Unmarshal(data, &pj.cookies); err != nil {
+		return nil, fmt.Errorf("failed to decode cookie jar: %v", err)
+	}
+
+	now := time.Now()
+	for rawURL, cookies := range pj.cookies {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			delete(pj.cookies, rawURL)
+			continue
+		}
+		valid := cookies[:0]
+		for _, cookie := range cookies {
+			if cookie.Expires.IsZero() || cookie.Expires.After(now) {
+				valid = append(valid, cookie)
+			}
+		}
+		pj.cookies[rawURL] = valid
+		jar.SetCookies(u, valid)
+	}
+	return pj, nil
+}
+
+// SetCookies implements http.CookieJar and saves the jar to disk
+func (pj *PersistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	pj.jar.SetCookies(u, cookies)
+
+	pj.mutex.Lock()
+	defer pj.mutex.Unlock()
+
+	key := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+	merged := pj.cookies[key]
+	for _, cookie := range cookies {
+		// Drop any previous cookie with the same identity
+		kept := merged[:0]
+		for _, existing := range merged {
+			if existing.Name != cookie.Name || existing.Path != cookie.Path || existing.Domain != cookie.Domain {
+				kept = append(kept, existing)
+			}
+		}
+		merged = kept
+
+		if cookie.MaxAge < 0 {
+			continue // deleted by the server
+		}
+		if cookie.MaxAge > 0 && cookie.Expires.IsZero() {
+			cookie.Expires = time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		merged = append(merged, cookie)
+	}
+	pj.cookies[key] = merged
+
+	if err := pj.save(); err != nil {
+		logrus.Warnf("Failed to save cookie jar: %v", err)
+	}
+}
+
+// Cookies implements http.CookieJar
+func (pj *PersistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return pj.jar.Cookies(u)
+}
+
+func (pj *PersistentJar) save() error {
+	if pj.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(pj.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(pj.cookies, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(pj.path, data, 0600)
+}
@@ -0,0 +1,32 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPersistentJar(t *testing.T) {
+	Convey("Testing PersistentJar", t, func() {
+		path := filepath.Join(t.TempDir(), "cookies", "example.com.json")
+		u, _ := url.Parse("https://example.com/terminal/")
+
+		jar, err := NewPersistentJar(path)
+		So(err, ShouldBeNil)
+		jar.SetCookies(u, []*http.Cookie{
+			{Name: "sso", Value: "token", Path: "/", MaxAge: 3600},
+			{Name: "gone", Value: "x", Path: "/"},
+		})
+		jar.SetCookies(u, []*http.Cookie{{Name: "gone", Path: "/", MaxAge: -1}})
+
+		reloaded, err := NewPersistentJar(path)
+		So(err, ShouldBeNil)
+		cookies := reloaded.Cookies(u)
+		So(len(cookies), ShouldEqual, 1)
+		So(cookies[0].Name, ShouldEqual, "sso")
+		So(cookies[0].Value, ShouldEqual, "token")
+	})
+}
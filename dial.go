@@ -0,0 +1,256 @@
+package gottyclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ParseAddressFamily parses an SSH-style AddressFamily value (any, inet,
+// inet6) into an IP version: 0 for any, 4 or 6
+func ParseAddressFamily(family string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(family)) {
+	case "", "any":
+		return 0, nil
+	case "inet", "inet4", "ipv4", "4":
+		return 4, nil
+	case "inet6", "ipv6", "6":
+		return 6, nil
+	}
+	return 0, fmt.Errorf("invalid address family %q, expected any, inet or inet6", family)
+}
+
+// isDoHResolver reports whether a Resolver setting is a DNS-over-HTTPS URL
+func isDoHResolver(resolver string) bool {
+	return strings.HasPrefix(resolver, "https://") || strings.HasPrefix(resolver, "http://")
+}
+
+// customDial reports whether connections need dialContext instead of the default dialer
+func (c *Client) customDial() bool {
+	return c.IPVersion != 0 || c.Resolver != "" || c.JumpHost != "" ||
+		c.TCPKeepAlive != 0 || c.TCPUserTimeout != 0 || c.TCPNagle
+}
+
+// netDialer returns a dialer applying the TCP tuning settings
+func (c *Client) netDialer() *net.Dialer {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if c.TCPKeepAlive != 0 {
+		dialer.KeepAlive = c.TCPKeepAlive
+	}
+	if c.TCPUserTimeout > 0 {
+		timeout := c.TCPUserTimeout
+		dialer.Control = func(network, address string, conn syscall.RawConn) error {
+			return setUserTimeout(conn, timeout)
+		}
+	}
+	return dialer
+}
+
+// tcpDial dials addr and applies the Nagle setting to the connection
+func (c *Client) tcpDial(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		// Go disables Nagle by default, which suits interactive typing
+		_ = tcpConn.SetNoDelay(!c.TCPNagle)
+	}
+	return conn, nil
+}
+
+// dialContext opens the TCP connections used by both the HTTP transport and
+// the websocket dialer, honoring the IP version, resolver and jump host settings
+func (c *Client) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	switch c.IPVersion {
+	case 4:
+		network = "tcp4"
+	case 6:
+		network = "tcp6"
+	}
+	if c.JumpHost != "" {
+		return c.dialJump(ctx, network, addr)
+	}
+
+	dialer := c.netDialer()
+	if c.Resolver == "" {
+		return c.tcpDial(ctx, dialer, network, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return c.tcpDial(ctx, dialer, network, addr)
+	}
+
+	var ips []string
+	if isDoHResolver(c.Resolver) {
+		ips, err = c.lookupDoH(ctx, host)
+	} else {
+		ips, err = c.dnsResolver().LookupHost(ctx, host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", host, err)
+	}
+
+	var addrs []string
+	for _, ip := range ips {
+		if c.ipAllowed(ip) {
+			addrs = append(addrs, net.JoinHostPort(ip, port))
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no suitable address found for %s", host)
+	}
+	return c.raceDial(ctx, dialer, network, addrs)
+}
+
+// ipAllowed reports whether an address matches the client's IP version
+func (c *Client) ipAllowed(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	switch c.IPVersion {
+	case 4:
+		return parsed.To4() != nil
+	case 6:
+		return parsed.To4() == nil
+	}
+	return true
+}
+
+// dnsResolver returns a resolver querying the DNS server in c.Resolver
+func (c *Client) dnsResolver() *net.Resolver {
+	server := c.Resolver
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// dohResponse is the JSON form of a DNS-over-HTTPS answer
+type dohResponse struct {
+	Status int `json:"Status"`
+	Answer []struct {
+		Type int    `json:"type"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// lookupDoH resolves host with the DNS-over-HTTPS JSON API (as served by
+// Cloudflare and Google) at c.Resolver, IPv4 addresses first
+func (c *Client) lookupDoH(ctx context.Context, host string) ([]string, error) {
+	var types []string
+	switch c.IPVersion {
+	case 4:
+		types = []string{"A"}
+	case 6:
+		types = []string{"AAAA"}
+	default:
+		types = []string{"A", "AAAA"}
+	}
+
+	var ips []string
+	for _, qtype := range types {
+		target, err := url.Parse(c.Resolver)
+		if err != nil {
+			return nil, err
+		}
+		query := target.Query()
+		query.Set("name", host)
+		query.Set("type", qtype)
+		target.RawQuery = query.Encode()
+
+		req, err := http.NewRequest("GET", target.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/dns-json")
+		identify(req.Header, "", "")
+		resp, err := c.dohClient().Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("DNS-over-HTTPS query failed: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		}
+		var answer dohResponse
+		err = json.NewDecoder(resp.Body).Decode(&answer)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid DNS-over-HTTPS response: %v", err)
+		}
+		// 3 is NXDOMAIN, which only means there is no such host
+		if answer.Status != 0 && answer.Status != 3 {
+			return nil, fmt.Errorf("DNS-over-HTTPS query for %s %s failed: %s", host, qtype, dnsRcode(answer.Status))
+		}
+
+		for _, record := range answer.Answer {
+			// 1 is A, 28 is AAAA; CNAMEs are followed by the server
+			if record.Type == 1 || record.Type == 28 {
+				ips = append(ips, record.Data)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no such host")
+	}
+	return ips, nil
+}
+
+// dohTimeout bounds DNS-over-HTTPS queries when Client.RequestTimeout is unset
+const dohTimeout = 10 * time.Second
+
+// dohClient returns the client's HTTP client for DNS-over-HTTPS queries. It
+// keeps the proxy and TLS settings but dials directly, since resolving the
+// resolver's own name through it would never end, and verifies the
+// resolver's certificate against its own name rather than ServerName.
+func (c *Client) dohClient() *http.Client {
+	client := c.httpClient()
+	client.Transport = c.sharedTransport(&c.dohTransports, func(host string) *http.Transport {
+		tr := c.httpTransport(host)
+		tr.DialContext = c.netDialer().DialContext
+		if tr.TLSClientConfig != nil {
+			tr.TLSClientConfig.ServerName = ""
+		}
+		return tr
+	})
+	client.Jar = nil
+	if client.Timeout == 0 {
+		client.Timeout = dohTimeout
+	}
+	return client
+}
+
+// dnsRcode names a DNS response code
+func dnsRcode(rcode int) string {
+	switch rcode {
+	case 1:
+		return "FORMERR"
+	case 2:
+		return "SERVFAIL"
+	case 3:
+		return "NXDOMAIN"
+	case 4:
+		return "NOTIMP"
+	case 5:
+		return "REFUSED"
+	}
+	return fmt.Sprintf("rcode %d", rcode)
+}
@@ -0,0 +1,101 @@
+package gottyclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseAddressFamily(t *testing.T) {
+	Convey("Testing ParseAddressFamily", t, func() {
+		for input, expected := range map[string]int{"": 0, "any": 0, "inet": 4, "inet6": 6} {
+			version, err := ParseAddressFamily(input)
+			So(err, ShouldBeNil)
+			So(version, ShouldEqual, expected)
+		}
+		_, err := ParseAddressFamily("ipx")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestDoHResolver(t *testing.T) {
+	Convey("Testing DNS-over-HTTPS resolution", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("var gotty_auth_token = 'token'"))
+		}))
+		defer server.Close()
+		_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+		var queries []string
+		doh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			queries = append(queries, r.URL.Query().Get("name")+"/"+r.URL.Query().Get("type"))
+			if r.URL.Query().Get("type") == "A" {
+				fmt.Fprint(w, `{"Status":0,"Answer":[{"type":1,"data":"127.0.0.1"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"Status":0}`)
+		}))
+		defer doh.Close()
+
+		client, err := NewClient("http://sdr.invalid:" + port + "/")
+		So(err, ShouldBeNil)
+		client.RetryPolicy = NoRetry
+		client.Resolver = doh.URL + "/dns-query"
+		client.IPVersion = 4
+
+		token, err := client.GetAuthToken()
+		So(err, ShouldBeNil)
+		So(token, ShouldEqual, "token")
+		So(queries, ShouldResemble, []string{"sdr.invalid/A"})
+
+		Convey("Failed queries are errors", func() {
+			failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"Status":2,"Answer":[{"type":1,"data":"127.0.0.1"}]}`)
+			}))
+			defer failing.Close()
+			client.Resolver = failing.URL + "/dns-query"
+
+			_, err := client.lookupDoH(context.Background(), "sdr.invalid")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "SERVFAIL")
+		})
+
+		Convey("Queries go through the client's proxy", func() {
+			var proxied string
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proxied = r.URL.String()
+				fmt.Fprint(w, `{"Status":0,"Answer":[{"type":1,"data":"127.0.0.2"}]}`)
+			}))
+			defer proxy.Close()
+			client.ProxyURL = proxy.URL
+			client.Resolver = "http://dns.invalid/dns-query"
+
+			ips, err := client.lookupDoH(context.Background(), "sdr.invalid")
+			So(err, ShouldBeNil)
+			So(ips, ShouldResemble, []string{"127.0.0.2"})
+			So(proxied, ShouldStartWith, "http://dns.invalid/dns-query?")
+		})
+	})
+}
+
+func TestTCPTuning(t *testing.T) {
+	Convey("Testing TCP tuning options", t, func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer listener.Close()
+
+		client := &Client{TCPKeepAlive: 5 * time.Second, TCPUserTimeout: 20 * time.Second, TCPNagle: true}
+		So(client.customDial(), ShouldBeTrue)
+		So(client.netDialer().KeepAlive, ShouldEqual, 5*time.Second)
+
+		conn, err := client.dialContext(context.Background(), "tcp", listener.Addr().String())
+		So(err, ShouldBeNil)
+		conn.Close()
+	})
+}
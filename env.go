@@ -0,0 +1,46 @@
+package gottyclient
+
+import (
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ParseEnvNames parses a comma or space separated list of environment
+// variable names, as given to --send-env and SendEnv
+func ParseEnvNames(s string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if strings.Contains(name, "=") {
+			logrus.Warnf("Ignoring invalid environment variable name %q", name)
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// addEnvArguments adds an env=NAME=value argument for each variable of
+// SendEnv set locally, and for the Terminal hint. Servers that support it
+// export them in the session so remote programs get the right terminfo and
+// locale; others ignore them.
+func (c *Client) addEnvArguments(query url.Values) {
+	hinted := map[string]string{"TERM": c.Terminal.Term, "COLORTERM": c.Terminal.ColorTerm}
+	for _, name := range []string{"TERM", "COLORTERM"} {
+		if hinted[name] != "" {
+			query.Add("env", name+"="+hinted[name])
+			logrus.Debugf("Advertising %s=%q", name, hinted[name])
+		}
+	}
+
+	for _, name := range c.SendEnv {
+		value, ok := os.LookupEnv(name)
+		if !ok || hinted[name] != "" {
+			continue
+		}
+		query.Add("env", name+"="+value)
+		logrus.Debugf("Forwarding environment variable %s=%q", name, value)
+	}
+}
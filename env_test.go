@@ -0,0 +1,40 @@
+package gottyclient
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSendEnv(t *testing.T) {
+	Convey("Testing environment forwarding", t, func() {
+		Convey("ParseEnvNames splits on commas and spaces", func() {
+			So(ParseEnvNames("TERM,LANG COLORTERM"), ShouldResemble, []string{"TERM", "LANG", "COLORTERM"})
+			So(ParseEnvNames(" TERM, ,A=B"), ShouldResemble, []string{"TERM"})
+			So(ParseEnvNames(""), ShouldBeNil)
+		})
+		Convey("Only variables set locally are forwarded", func() {
+			t.Setenv("GOTTY_CLIENT_TEST_TERM", "xterm-256color")
+			os.Unsetenv("GOTTY_CLIENT_TEST_UNSET")
+
+			client := &Client{SendEnv: []string{"GOTTY_CLIENT_TEST_TERM", "GOTTY_CLIENT_TEST_UNSET"}}
+			query := url.Values{"session": {"main"}}
+			client.addEnvArguments(query)
+			So(query["env"], ShouldResemble, []string{"GOTTY_CLIENT_TEST_TERM=xterm-256color"})
+			So(query.Get("session"), ShouldEqual, "main")
+		})
+		Convey("SendEnv is read from the config", func() {
+			path := filepath.Join(t.TempDir(), "config")
+			So(os.WriteFile(path, []byte("Host sdr\n    SendEnv TERM,LANG\n"), 0600), ShouldBeNil)
+
+			config, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			client := &Client{}
+			config.GetHostConfig("sdr").ApplyToClient(client)
+			So(client.SendEnv, ShouldResemble, []string{"TERM", "LANG"})
+		})
+	})
+}
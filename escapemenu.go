@@ -0,0 +1,100 @@
+package gottyclient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// escapeMenuItem is a single action offered by the in-session escape menu
+type escapeMenuItem struct {
+	key    byte
+	label  string
+	action func(c *Client)
+}
+
+// escapeMenuItems returns the actions available for the current client settings
+func (c *Client) escapeMenuItems() []escapeMenuItem {
+	items := []escapeMenuItem{}
+	if c.controlEnabled() {
+		items = append(items,
+			escapeMenuItem{key: 'c', label: "request control", action: (*Client).requestControl},
+			escapeMenuItem{key: 'r', label: "release control", action: (*Client).releaseControl},
+		)
+	}
+	if c.Screen != nil && c.SnapshotPath != "" {
+		items = append(items, escapeMenuItem{key: 's', label: "save screen snapshot", action: (*Client).saveSnapshot})
+	}
+	if c.inputHistory != nil && len(c.inputHistory.Lines()) > 0 {
+		items = append(items, escapeMenuItem{key: 'h', label: "input history", action: (*Client).showHistoryMenu})
+	}
+	return items
+}
+
+// historyMenuSize is the number of recent lines the history menu offers
+const historyMenuSize = 9
+
+// showHistoryMenu lists the latest lines of the input history, numbered from
+// the most recent, for the next key to pick one
+func (c *Client) showHistoryMenu() {
+	lines := c.inputHistory.Lines()
+	if len(lines) > historyMenuSize {
+		lines = lines[len(lines)-historyMenuSize:]
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		c.statusf("%d: %s", len(lines)-i, lines[i])
+	}
+	c.statusf("1-%d: recall, any other key: cancel", len(lines))
+	c.menuPending = func(c *Client, key byte) {
+		index := int(key - '1')
+		if index < 0 || index >= len(lines) {
+			c.statusf("menu closed")
+			return
+		}
+		c.recallInput(lines[len(lines)-1-index])
+	}
+}
+
+// recallInput puts a line from the history into the line being edited, or
+// types it without Enter outside line mode
+func (c *Client) recallInput(line string) {
+	if c.lineEditor != nil {
+		c.lineEditor.SetLine(line)
+		return
+	}
+	if err := c.sendInput([]byte(line)); err != nil {
+		c.statusf("failed to send %q: %v", line, err)
+	}
+}
+
+// showEscapeMenu prints the escape menu choices. It returns false, printing
+// nothing, when no action is currently available, e.g. once the write lock
+// turned out to be unsupported, so the menu keys can be sent as input.
+func (c *Client) showEscapeMenu() bool {
+	items := c.escapeMenuItems()
+	if len(items) == 0 {
+		return false
+	}
+	choices := []string{}
+	for _, item := range items {
+		choices = append(choices, fmt.Sprintf("%c: %s", item.key, item.label))
+	}
+	choices = append(choices, "any other key: cancel")
+	c.statusf("%s", strings.Join(choices, ", "))
+	return true
+}
+
+// handleEscapeMenuKey runs the escape menu action bound to key, if any
+func (c *Client) handleEscapeMenuKey(key byte) {
+	if pending := c.menuPending; pending != nil {
+		c.menuPending = nil
+		pending(c, key)
+		return
+	}
+	for _, item := range c.escapeMenuItems() {
+		if item.key == key {
+			item.action(c)
+			return
+		}
+	}
+	c.statusf("menu closed")
+}
@@ -0,0 +1,194 @@
+package gottyclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrEventsUnsupported is returned when the server has no event stream
+var ErrEventsUnsupported = fmt.Errorf("server does not stream events")
+
+// ServerEventBuffer is the number of events queued by StreamEvents for a
+// slow reader; the stream is not read while the queue is full.
+const ServerEventBuffer = 64
+
+// ServerEvent is a lifecycle event sent by the server's event stream, e.g.
+// "session.created", "session.attached" or "instance.online"
+type ServerEvent struct {
+	ID       string    `json:"id,omitempty"`
+	Type     string    `json:"type"`
+	Session  string    `json:"session,omitempty"`
+	Window   string    `json:"window,omitempty"`
+	Instance string    `json:"instance,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// SessionEvent returns the session change a "session.*" event reports
+func (e ServerEvent) SessionEvent() (SessionEvent, bool) {
+	if !strings.HasPrefix(e.Type, "session.") || e.Session == "" {
+		return SessionEvent{}, false
+	}
+	return SessionEvent{
+		Type:    SessionEventType(strings.TrimPrefix(e.Type, "session.")),
+		Session: e.Session,
+		Window:  e.Window,
+		Time:    e.Time,
+	}, true
+}
+
+// StreamEvents subscribes to the server's event stream, a server-sent events
+// endpoint at /api/events. ErrEventsUnsupported is returned for servers
+// without one. Once connected, the stream is re-established after errors,
+// resuming after the last event received, until ctx is done; the channel is
+// closed then.
+func (s *SessionsClient) StreamEvents(ctx context.Context) (<-chan ServerEvent, error) {
+	resp, err := s.openEventStream(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan ServerEvent, ServerEventBuffer)
+	go func() {
+		defer close(events)
+		policy := s.RetryPolicy
+		if policy == nil || policy.Backoff <= 0 {
+			policy = DefaultRetryPolicy()
+		}
+		lastID := ""
+		backoff := policy.Backoff
+		for {
+			received, err := readEventStream(ctx, resp, events, &lastID, s.progress)
+			resp.Body.Close()
+			if ctx.Err() != nil {
+				return
+			}
+			if received {
+				backoff = policy.Backoff
+			}
+			for {
+				logrus.Warnf("Event stream interrupted: %v, reconnecting in %v", err, backoff)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				if backoff *= 2; policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+					backoff = policy.MaxBackoff
+				}
+				if resp, err = s.openEventStream(ctx, lastID); err == nil {
+					break
+				}
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// StreamEvents subscribes to the server's session and instance lifecycle events
+func (c *Client) StreamEvents(ctx context.Context) (<-chan ServerEvent, error) {
+	return c.Sessions().StreamEvents(ctx)
+}
+
+// openEventStream connects to the event stream, resuming after lastID
+func (s *SessionsClient) openEventStream(ctx context.Context, lastID string) (*http.Response, error) {
+	req, err := s.newAPIRequest("GET", "/api/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	logrus.Debugf("Opening event stream: %q", req.URL.String())
+	resp, err := s.stream(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"):
+		return resp, nil
+	case resp.StatusCode == http.StatusOK, resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusMethodNotAllowed:
+		resp.Body.Close()
+		return nil, ErrEventsUnsupported
+	default:
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to open event stream: %d %s - %s", resp.StatusCode, http.StatusText(resp.StatusCode), string(body))
+	}
+}
+
+// readEventStream decodes server-sent events from resp until it ends,
+// recording the last event ID and calling progress on each line. It reports
+// whether any event was received.
+func readEventStream(ctx context.Context, resp *http.Response, events chan<- ServerEvent, lastID *string, progress func()) (bool, error) {
+	received := false
+	var name, id string
+	var data []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		progress()
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				var event ServerEvent
+				if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err != nil {
+					logrus.Warnf("Ignoring malformed server event: %v", err)
+				} else {
+					if event.Type == "" {
+						event.Type = name
+					}
+					if event.ID == "" {
+						event.ID = id
+					}
+					if event.Time.IsZero() {
+						event.Time = time.Now()
+					}
+					select {
+					case events <- event:
+						received = true
+					case <-ctx.Done():
+						return received, ctx.Err()
+					}
+				}
+			}
+			if id != "" {
+				*lastID = id
+			}
+			name, id, data = "", "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			id = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return received, err
+	}
+	return received, fmt.Errorf("stream closed by server")
+}
@@ -0,0 +1,109 @@
+package gottyclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStreamEvents(t *testing.T) {
+	Convey("Testing the server event stream", t, func() {
+		lastIDs := make(chan string, 10)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/events" {
+				http.NotFound(w, r)
+				return
+			}
+			lastIDs <- r.Header.Get("Last-Event-ID")
+			w.Header().Set("Content-Type", "text/event-stream")
+			if r.Header.Get("Last-Event-ID") == "" {
+				fmt.Fprint(w, ": hello\n\n")
+				fmt.Fprint(w, "id: 1\nevent: session.created\ndata: {\"session\":\"ft8\",\"window\":\"wsjtx\"}\n\n")
+				fmt.Fprint(w, "id: 2\ndata: {\"type\":\"instance.online\",\n")
+				fmt.Fprint(w, "data: \"instance\":\"M9PSY\"}\n\n")
+				return
+			}
+			fmt.Fprint(w, "id: 3\ndata: {\"type\":\"session.attached\",\"session\":\"ft8\"}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		sessions, err := NewSessionsClient(server.URL, nil, nil)
+		So(err, ShouldBeNil)
+		sessions.RetryPolicy = &RetryPolicy{Attempts: 1, Backoff: 10 * time.Millisecond}
+		var lines int32
+		sessions.Progress = func() { atomic.AddInt32(&lines, 1) }
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events, err := sessions.StreamEvents(ctx)
+		So(err, ShouldBeNil)
+
+		created := <-events
+		So(created.Type, ShouldEqual, "session.created")
+		So(created.ID, ShouldEqual, "1")
+		So(created.Time.IsZero(), ShouldBeFalse)
+		sessionEvent, ok := created.SessionEvent()
+		So(ok, ShouldBeTrue)
+		So(sessionEvent.Type, ShouldEqual, SessionCreated)
+		So(sessionEvent.Window, ShouldEqual, "wsjtx")
+
+		online := <-events
+		So(online.Type, ShouldEqual, "instance.online")
+		So(online.Instance, ShouldEqual, "M9PSY")
+		_, ok = online.SessionEvent()
+		So(ok, ShouldBeFalse)
+		// Keepalive comments count as progress too
+		So(atomic.LoadInt32(&lines), ShouldBeGreaterThanOrEqualTo, 8)
+
+		Convey("The stream resumes after the last event", func() {
+			attached := <-events
+			So(attached.Type, ShouldEqual, "session.attached")
+			So(<-lastIDs, ShouldEqual, "")
+			So(<-lastIDs, ShouldEqual, "2")
+
+			cancel()
+			for range events {
+			}
+		})
+
+		Convey("Servers without a stream are reported", func() {
+			other, err := NewSessionsClient(server.URL+"/other/", nil, nil)
+			So(err, ShouldBeNil)
+			_, err = other.StreamEvents(ctx)
+			So(err, ShouldEqual, ErrEventsUnsupported)
+		})
+
+		Convey("The stream identifies the client and keeps its affinity", func() {
+			headers := make(chan http.Header, 1)
+			tagged := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				headers <- r.Header
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			}))
+			defer tagged.Close()
+
+			client, err := NewClient(tagged.URL)
+			So(err, ShouldBeNil)
+			client.UserAgent = "logger/1.0"
+			client.AffinityHeader = "X-Backend"
+			client.affinity().token = "node-2"
+			streamCtx, stopStream := context.WithCancel(context.Background())
+			_, err = client.StreamEvents(streamCtx)
+			So(err, ShouldBeNil)
+			header := <-headers
+			stopStream()
+			So(header.Get("User-Agent"), ShouldEqual, "logger/1.0")
+			So(header.Get(InstanceHeader), ShouldEqual, processInstanceID)
+			So(header.Get("X-Backend"), ShouldEqual, "node-2")
+		})
+	})
+}
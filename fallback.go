@@ -0,0 +1,183 @@
+package gottyclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrFallbackUnsupported is returned when the server does not list the HTTP
+// fallback transport in its info, or has no endpoint for it
+var ErrFallbackUnsupported = fmt.Errorf("server does not support the HTTP fallback transport")
+
+// SSETransportVersion is how servers list the protocol of SSETransport in
+// ServerInfo.Transports
+const SSETransportVersion = "sse/1"
+
+// SSETransport runs the GoTTY message protocol over plain HTTP for networks
+// that block websocket upgrades. Server messages arrive as server-sent
+// events from GET <url>/sse?session=ID, one message per event, and client
+// messages are sent as POST <url>/sse?session=ID bodies.
+type SSETransport struct {
+	Client *http.Client
+
+	target string
+	header http.Header
+	stream io.ReadCloser
+	reader *bufio.Reader
+}
+
+// checkFallback makes sure the server speaks the protocol of SSETransport
+// before falling back to it: the /sse endpoint has no version of its own, so
+// only servers listing SSETransportVersion in their info are trusted with it
+func (c *Client) checkFallback() error {
+	info, err := c.ServerInfo()
+	if err == ErrServerInfoUnsupported {
+		return ErrFallbackUnsupported
+	}
+	if err != nil {
+		return err
+	}
+	for _, transport := range info.Transports {
+		if strings.EqualFold(transport, SSETransportVersion) {
+			return nil
+		}
+	}
+	return ErrFallbackUnsupported
+}
+
+// sseURL maps the websocket URL to the fallback endpoint
+func sseURL(wsTarget string, session string) (string, error) {
+	target, err := url.Parse(wsTarget)
+	if err != nil {
+		return "", err
+	}
+	if target.Scheme == "wss" {
+		target.Scheme = "https"
+	} else {
+		target.Scheme = "http"
+	}
+	target.Path = strings.TrimSuffix(target.Path, "ws") + "sse"
+	query := target.Query()
+	query.Set("session", session)
+	target.RawQuery = query.Encode()
+	return target.String(), nil
+}
+
+func (t *SSETransport) newRequest(method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, t.target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range t.header {
+		if name == "Host" {
+			req.Host = values[0]
+			continue
+		}
+		req.Header[name] = values
+	}
+	return req, nil
+}
+
+// Dial implements Transport. The network options are already part of Client.
+func (t *SSETransport) Dial(ctx context.Context, target string, header http.Header, opts TransportOptions) error {
+	if t.Client == nil {
+		t.Client = http.DefaultClient
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	sseTarget, err := sseURL(target, hex.EncodeToString(id))
+	if err != nil {
+		return err
+	}
+	t.target = sseTarget
+	t.header = header
+
+	req, err := t.newRequest("GET", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := t.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed:
+		resp.Body.Close()
+		return ErrFallbackUnsupported
+	case resp.StatusCode != 200:
+		resp.Body.Close()
+		return fmt.Errorf("fallback transport: unexpected status %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	t.stream = resp.Body
+	t.reader = bufio.NewReader(resp.Body)
+	return nil
+}
+
+// Read implements Transport, returning the data of the next event
+func (t *SSETransport) Read() ([]byte, error) {
+	if t.reader == nil {
+		return nil, ErrNotDialed
+	}
+	var data [][]byte
+	for {
+		line, err := t.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+
+		switch {
+		case len(line) == 0:
+			if len(data) > 0 {
+				return bytes.Join(data, []byte("\n")), nil
+			}
+		case bytes.HasPrefix(line, []byte("data:")):
+			data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" ")))
+		}
+		// Comments, event names and ids are ignored
+	}
+}
+
+// Write implements Transport
+func (t *SSETransport) Write(data []byte) error {
+	if t.target == "" {
+		return ErrNotDialed
+	}
+	req, err := t.newRequest("POST", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("fallback transport: unexpected status %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
+
+// Close implements Transport
+func (t *SSETransport) Close() error {
+	if t.stream == nil {
+		return nil
+	}
+	return t.stream.Close()
+}
@@ -1,16 +1,17 @@
 package gottyclient
 
 import (
-	"crypto/tls"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -20,6 +21,7 @@ import (
 	"github.com/creack/goselect"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
 )
 
 // SanitizeSessionName sanitizes a session/window name to only contain lowercase alphanumeric characters and hyphens
@@ -97,20 +99,20 @@ type gottyMessageType struct {
 
 // GetAuthTokenURL transforms a GoTTY http URL to its AuthToken file URL
 func GetAuthTokenURL(httpURL string) (*url.URL, *http.Header, error) {
+	return getAuthTokenURL(httpURL, "")
+}
+
+// getAuthTokenURL returns the AuthToken file URL, at path if set
+func getAuthTokenURL(httpURL, path string) (*url.URL, *http.Header, error) {
 	header := http.Header{}
-	target, err := url.Parse(httpURL)
+	target, err := terminalEndpoint(httpURL, "auth_token.js", path)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	target.Path = strings.TrimLeft(target.Path+"auth_token.js", "/")
-
-	user, err := url.PathUnescape(target.User.String())
-	if err != nil {
-		user = target.User.String()
-	}
-	if target.User != nil {
-		header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user)))
+	// Credentials are sent in a header rather than in the URL
+	if credentials, ok := urlCredentials(target); ok {
+		credentials.ApplyWSHeaders(header)
 		target.User = nil
 	}
 
@@ -126,10 +128,26 @@ func GetURLQuery(rawURL string) (url.Values, error) {
 	return target.Query(), nil
 }
 
+// StripURLCredentials returns rawURL without the user:pass it may embed, for
+// showing or passing it on. Unparsable URLs are returned unchanged.
+func StripURLCredentials(rawURL string) string {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.User == nil {
+		return rawURL
+	}
+	target.User = nil
+	return target.String()
+}
+
 // GetWebsocketURL transforms a GoTTY http URL to its WebSocket URL
 func GetWebsocketURL(httpURL string) (*url.URL, *http.Header, error) {
+	return getWebsocketURL(httpURL, "")
+}
+
+// getWebsocketURL returns the WebSocket URL, at path if set
+func getWebsocketURL(httpURL, path string) (*url.URL, *http.Header, error) {
 	header := http.Header{}
-	target, err := url.Parse(httpURL)
+	target, err := terminalEndpoint(httpURL, "ws", path)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -140,86 +158,346 @@ func GetWebsocketURL(httpURL string) (*url.URL, *http.Header, error) {
 		target.Scheme = "ws"
 	}
 
-	target.Path = strings.TrimLeft(target.Path+"ws", "/")
-
-	user, err := url.PathUnescape(target.User.String())
-	if err != nil {
-		user = target.User.String()
-	}
-	if target.User != nil {
-		header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user)))
+	// Credentials are sent in a header rather than in the URL
+	if credentials, ok := urlCredentials(target); ok {
+		credentials.ApplyWSHeaders(header)
 		target.User = nil
 	}
 
 	return target, &header, nil
 }
 
+// Client is a GoTTY terminal client. Its exported fields are settings, set
+// before Connect. Once connected, its methods are safe for concurrent use,
+// e.g. ListSessions or DestroySession from another goroutine while Loop
+// runs; use IsConnected rather than reading Connected, Conn and Transport,
+// which Connect replaces when reconnecting.
 type Client struct {
-	Dialer          *websocket.Dialer
-	Conn            *websocket.Conn
-	URL             string
-	WriteMutex      *sync.Mutex
-	Output          io.Writer
-	poison          chan bool
-	SkipTLSVerify   bool
-	UseProxyFromEnv bool
-	Connected       bool
-	EscapeKeys      []byte
-	V2              bool
-	message         *gottyMessageType
-	WSOrigin        string
-	User            string
-	Password        string
-	AdminPassword   string
-	PathSuffix      string
+	Dialer            *websocket.Dialer
+	Conn              *websocket.Conn
+	Transport         Transport
+	AllowFallback     bool
+	ReadTimeout       time.Duration
+	lastRead          time.Time
+	lastInput         time.Time
+	activity          Activity
+	idleHooks         []*idleHook
+	rtt               time.Duration
+	statsMutex        sync.Mutex
+	loopErr           error
+	Reconnect         bool
+	// ReconnectDelay is the first wait before reconnecting, doubled on each
+	// failed attempt up to 30s; it defaults to one second
+	ReconnectDelay    time.Duration
+	NoInputBuffer     bool
+	reconnection      reconnectState
+	// RestartOnExit recreates the session up to this many times when the
+	// server ends it, e.g. because its program exited, or the connection is
+	// lost without Reconnect, and reattaches; the first restart waits
+	// RestartDelay, one second by default, doubled on each restart up to 30s
+	RestartOnExit     int
+	RestartDelay      time.Duration
+	restart           restartState
+	ownTransport      bool
+	// handshakeRead delivers the first frame, read by Connect while
+	// waiting for the server to be ready
+	handshakeRead     chan frame
+	// ReadyTimeout bounds how long Connect waits for the server's first
+	// frame after authenticating; it defaults to DefaultReadyTimeout
+	ReadyTimeout      time.Duration
+	// InitialWinSize is the size the remote terminal is created at, sent
+	// with the auth message; nil uses the size of the local terminal
+	InitialWinSize    *WinSize
+	pingOnce          sync.Once
+	// stateMutex guards Transport, Conn, Connected, message and state, which are
+	// replaced by Connect; writers hold WriteMutex too
+	stateMutex        sync.RWMutex
+	state             ConnectionState
+	closeOnce         sync.Once
+	closed            chan struct{}
+	URL               string
+	// AlternateURLs are other scheme://host[:port] of the same server, e.g.
+	// its LAN address. Connect races them against URL, keeping URL's path
+	// and parameters, and continues with the first to answer, reconnections
+	// included.
+	AlternateURLs     []string
+	// raceURLs are the candidates raced, fixed on the first race as URL
+	// becomes the winner
+	raceURLs          []string
+	// raced is set once a race was won: URL then stays the winner, as the
+	// loops reading it may be running
+	raced             bool
+	// httpTransports and dohTransports carry the HTTP requests, made on
+	// first use and kept so that their idle connections are reused
+	transportMutex    sync.Mutex
+	httpTransports    *hostTransport
+	dohTransports     *hostTransport
+	WriteMutex        *sync.Mutex
+	Output            io.Writer
+	// Stdin, if set, is read by Loop instead of os.Stdin; like *os.File,
+	// it must have an Fd method
+	Stdin             io.ReadCloser
+	poison            chan bool
+	SkipTLSVerify     bool
+	TLSMinVersion     uint16
+	TLSMaxVersion     uint16
+	TLSCipherSuites   []uint16
+	KnownHosts        *KnownHosts
+	ServerName        string
+	HostHeader        string
+	IPVersion         int
+	Resolver          string
+	JumpHost          string
+	TCPKeepAlive      time.Duration
+	TCPUserTimeout    time.Duration
+	TCPNagle          bool
+	ProxyURL          string
+	ProxyHeaders      http.Header
+	jump              *ssh.Client
+	jumpMutex         sync.Mutex
+	ConfirmHost       func(host, fingerprint string, err error) bool
+	confirmMutex      sync.Mutex
+	UseProxyFromEnv   bool
+	// Connected is set while connected.
+	//
+	// Deprecated: use State, which tells the connection's progress too
+	Connected         bool
+	EscapeKeys        []byte
+	V2                bool
+	message           *gottyMessageType
+	WSOrigin          string
+	User              string
+	Password          string
+	AdminPassword     string
+	// AdminPasswordEncoding is how AdminPassword is sent, negotiated with
+	// the server when needed by default
+	AdminPasswordEncoding AdminPasswordEncoding
+	adminEncodingOnce     sync.Once
+	adminEncoding         AdminPasswordEncoding
+	// TOTPSecret (base32) or TOTPCommand, whose output is the code, give
+	// the one-time code sent in OTPHeader with every request for servers
+	// behind two-factor authentication. When a response carries
+	// OTPChallengeHeader, or has status OTPChallengeStatus if set, the code
+	// is asked for with OTPPrompt instead, and used for one connection.
+	TOTPSecret        string
+	TOTPCommand       string
+	OTPHeader         string
+	OTPChallengeStatus int
+	OTPPrompt         func() (string, error)
+	// otpMutex guards the typed code and the cached TOTPCommand code, which
+	// requests on other goroutines read
+	otpMutex          sync.Mutex
+	otpPrompted       string
+	otpCommandCode    string
+	otpCommandStep    int64
+	otpPrompts        int
+	// CredentialPrompt, if set, is asked for the credential refused with a
+	// 401 or 403, in up to AuthAttempts attempts (DefaultAuthAttempts)
+	CredentialPrompt  func(*AuthError) (string, error)
+	// credentialMutex guards User, Password and AdminPassword against
+	// repromptCredential, see credentialFields
+	credentialMutex   sync.RWMutex
+	AuthAttempts      int
+	authPrompts       int
+	// CredentialStore, if set, keeps the password under CredentialKey once
+	// the server accepts it, and forgets it when it is refused
+	CredentialStore   CredentialStore
+	CredentialKey     string
+	credentialStored  bool
+	// Auth adds further credentials, e.g. refreshed OIDC tokens, after the
+	// User/Password and AdminPassword ones
+	Auth              AuthProvider
+	PathSuffix        string
+	// WSPath and AuthTokenPath override where the websocket and the auth
+	// token are found: relative to the terminal URL, or absolute
+	WSPath            string
+	AuthTokenPath     string
+	// SendEnv names local environment variables forwarded to the session
+	SendEnv           []string
+	// Terminal is advertised to the session as TERM and COLORTERM
+	Terminal          TerminalHint
+	// InputRate limits typed input to this many bytes per second, with
+	// bursts of InputBurst; pastes over the limit are confirmed first, then
+	// sent at that rate
+	InputRate         int
+	InputBurst        int
+	inputLimiter      *inputLimiter
+	// Keymap rewrites key sequences of typed input
+	Keymap            *Keymap
+	// InputCRLF translates the line endings of typed input
+	InputCRLF         CRLFMode
+	// NormalizeOutput turns bare LFs in the output into CR LF
+	NormalizeOutput   bool
+	// LineMode edits typed input locally a line at a time, see LineEditor
+	LineMode          bool
+	lineEditor        *LineEditor
+	// HistoryPath keeps the lines entered in line mode in a file, to be
+	// recalled in later sessions and from the escape menu, in plain text, so
+	// only lines the server echoes are kept; empty keeps them in memory
+	HistoryPath       string
+	inputHistory      *InputHistory
+	// menuPending, if set, takes the key after an escape menu action
+	menuPending       func(c *Client, key byte)
+	// Stdio makes Loop a plain byte pipe between Stdin and Output, e.g. for
+	// a ProxyCommand or a PTYBridge: the terminal is neither put in raw mode
+	// nor resized, input is sent unchanged, without escape keys, escape menu,
+	// keymap, line mode, line ending translation or rate limit, no window title is
+	// written and status lines go to standard error. At the end of input,
+	// Ctrl-D is sent once and output is relayed until the session ends.
+	Stdio             bool
+	outputNormalizer  outputNormalizer
+	subscriptions     subscriptions
+	// Screen, if set, follows the output to model the terminal screen;
+	// the escape menu can save it to SnapshotPath
+	Screen            *Screen
+	SnapshotPath      string
+	// LocalCommandPre and LocalCommandPost are shell commands Loop runs
+	// before connecting and after disconnecting, with the target described
+	// by UBERTERM_* variables plus HookEnv
+	LocalCommandPre   string
+	LocalCommandPost  string
+	HookEnv           []string
+	// CompanionCommand, e.g. an audio player for the receiver, is a shell
+	// command Loop runs in the background once connected, with the same
+	// environment, and stops when it returns
+	CompanionCommand  string
+	// KeepaliveInput, if set, sends KeepaliveData as input after that long
+	// without typing, e.g. an empty Input message by default
+	KeepaliveInput    time.Duration
+	KeepaliveData     []byte
+	// Instance is the registry entry the URL was resolved from, if any.
+	// Loop re-checks it every RegistryCheck and warns when it goes
+	// offline, moves or last reported longer than MaxReportAge ago.
+	Instance          *Instance
+	RegistryCheck     time.Duration
+	MaxReportAge      time.Duration
+	// ActivityPath, if set, is where Loop writes the session's activity
+	// every IdleReportInterval for sessions list --show-idle, removing it
+	// on return
+	ActivityPath      string
+	InputLog          *InputLogger
+	AuditLog          *AuditLogger
+	detached          bool
+	RetryPolicy       *RetryPolicy
+	// RequestTimeout bounds each REST call and websocket handshake attempt;
+	// 0 means no limit. Timed out attempts are retried.
+	RequestTimeout    time.Duration
+	// AuthTokenCacheTTL is how long a fetched auth token is kept on disk, in
+	// CacheDir, for later invocations; 0 keeps tokens in memory for the
+	// life of the process only. Either way a token refused by the
+	// websocket handshake is dropped and fetched again.
+	AuthTokenCacheTTL time.Duration
+	// CacheDir defaults to GetDefaultCacheDir(); empty keeps the auth token
+	// cache off the filesystem
+	CacheDir          string
+	// InputBufferSize bounds the keystrokes kept while reconnecting,
+	// DefaultInputBufferSize if 0
+	InputBufferSize   int
+	// PageSize is the number of items asked for per page of paginated
+	// APIs, DefaultPageSize if 0
+	PageSize          int
+	// Registry looks Instance up again every RegistryCheck;
+	// NewRegistryClient() if nil
+	Registry          *RegistryClient
+	// Progress, if set, is told about slow operations and their retries,
+	// except while reconnecting from within Loop
+	Progress          ProgressReporter
+	// CookieJar keeps cookies, e.g. a PersistentJar; without one they are
+	// kept in memory for the life of the client
+	CookieJar         http.CookieJar
+	memoryJar         http.CookieJar
+	memoryJarOnce     sync.Once
+	// AffinityHeader names a response header carrying a load balancer's
+	// affinity token, replayed on the later requests and websocket upgrade
+	AffinityHeader    string
+	// UserAgent is sent with every request, DefaultUserAgent() if empty,
+	// along with InstanceID in InstanceHeader unless empty
+	UserAgent         string
+	InstanceID        string
+	affinityState     *affinity
+	affinityOnce      sync.Once
+	NoFollowRedirects bool
+	MaxRedirects      int
+	MenuKeys          []byte
+	WriteLock         bool
+	Operator          string
+	control           *controlState
 }
 
 type querySingleType struct {
 	AuthToken string `json:"AuthToken"`
 	Arguments string `json:"Arguments"`
+	// Columns and Rows ask servers supporting it to create the terminal at
+	// this size; others ignore them and wait for the first resize
+	Columns uint16 `json:"Columns,omitempty"`
+	Rows    uint16 `json:"Rows,omitempty"`
 }
 
 func (c *Client) write(data []byte) error {
 	c.WriteMutex.Lock()
 	defer c.WriteMutex.Unlock()
-	return c.Conn.WriteMessage(websocket.TextMessage, data)
+	return c.Transport.Write(data)
+}
+
+// sendInput sends user input to the server and records it in the input log.
+// Input is silently dropped while another client holds the session write lock,
+// and queued while reconnecting.
+func (c *Client) sendInput(data []byte) error {
+	if !c.HasControl() {
+		c.notifyReadOnly()
+		return nil
+	}
+	if c.queueInput(data) {
+		return nil
+	}
+	if err := c.write(append([]byte{c.messages().input}, data...)); err != nil {
+		if !c.Reconnect {
+			return err
+		}
+		// The read loop notices the broken connection and reconnects
+		c.beginReconnect()
+		c.queueInput(data)
+		return nil
+	}
+	c.InputLog.Log(data)
+	c.touchInput()
+	c.touchTyped()
+	return nil
 }
 
 // GetAuthToken retrieves an Auth Token from dynamic auth_token.js file
 func (c *Client) GetAuthToken() (string, error) {
-	target, header, err := GetAuthTokenURL(c.URL)
+	target, _, err := getAuthTokenURL(c.URL, c.AuthTokenPath)
 	if err != nil {
 		return "", err
 	}
-	
-	// Add admin password header first (highest priority for proxy authentication)
-	if c.AdminPassword != "" {
-		header.Add("X-Admin-Password", c.AdminPassword)
-	}
-	
-	// Add basic auth if user is specified
-	if c.User != "" {
-		basicAuth := c.User + ":" + c.Password
-		header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(basicAuth)))
+	stop := c.startProgress("Fetching auth token from " + target.Host)
+	defer stop()
+	return c.fetchAuthToken(context.Background(), c.URL)
+}
+
+// fetchAuthToken retrieves the auth token of the terminal at base, which
+// may differ from c.URL when racing AlternateURLs
+func (c *Client) fetchAuthToken(ctx context.Context, base string) (string, error) {
+	target, header, err := getAuthTokenURL(base, c.AuthTokenPath)
+	if err != nil {
+		return "", err
 	}
 
-	logrus.Debugf("Fetching auth token auth-token: %q", target.String())
-	logrus.Debugf("Request headers: %v", header)
+
 	req, err := http.NewRequest("GET", target.String(), nil)
 	if err != nil {
 		return "", err
 	}
 	req.Header = *header
-	tr := &http.Transport{}
-	if c.SkipTLSVerify {
-		conf := &tls.Config{InsecureSkipVerify: true}
-		tr.TLSClientConfig = conf
-	}
-	if c.UseProxyFromEnv {
-		tr.Proxy = http.ProxyFromEnvironment
-	}
-	client := &http.Client{Transport: tr}
-	resp, err := client.Do(req)
+	c.authProvider().ApplyHeaders(req)
+	// Once the server is reached, Connect moves on to authenticating
+	req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { c.advanceState(StateResolving, StateAuthenticating) },
+	}))
+	logrus.Debugf("Fetching auth token auth-token: %q", target.String())
+	logrus.Debugf("Request headers: %v", req.Header)
+	resp, err := c.do(req)
 	if err != nil {
 		return "", err
 	}
@@ -227,7 +505,17 @@ func (c *Client) GetAuthToken() (string, error) {
 	switch resp.StatusCode {
 	case 200:
 		// Everything is OK
+	case 301, 302, 303, 307, 308:
+		resp.Body.Close()
+		return "", fmt.Errorf("server redirected to %q and redirects are disabled", resp.Header.Get("Location"))
 	default:
+		resp.Body.Close()
+		if c.isOTPChallenge(resp.StatusCode, resp.Header) {
+			return "", ErrOTPRequired
+		}
+		if authErr := c.authError(resp.StatusCode, resp.Header, nil); authErr != nil {
+			return "", authErr
+		}
 		return "", fmt.Errorf("unknown status code: %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
 	}
 
@@ -250,62 +538,170 @@ func (c *Client) GetAuthToken() (string, error) {
 	return authToken, nil
 }
 
-// Connect tries to dial a websocket server
+// Connect tries to dial a websocket server, going through StateResolving,
+// StateAuthenticating and StateDialing to StateConnected. On failure the
+// client returns to StateDisconnected, or StateReconnecting.
 func (c *Client) Connect() error {
-	// Retrieve AuthToken
-	authToken, err := c.GetAuthToken()
+	previous := c.State()
+	c.setState(StateResolving)
+	err := c.connect()
+	if err != nil {
+		if previous != StateReconnecting {
+			previous = StateDisconnected
+		}
+		c.setState(previous)
+	}
+	return err
+}
+
+// closeContext returns a context cancelled once the client is closed, for
+// dials to give up on; cancel releases it
+func (c *Client) closeContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-c.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// connect does the work of Connect
+func (c *Client) connect() error {
+	ctx, cancel := c.closeContext()
+	defer cancel()
+
+	// Pick the first of the alternate URLs to answer, whose connection is
+	// used unless the connection fails before dialing
+	var raced *WebsocketTransport
+	if len(c.AlternateURLs) > 0 {
+		var err error
+		if raced, err = c.raceAlternates(ctx); err != nil {
+			return err
+		}
+		defer func() {
+			if raced != nil {
+				_ = raced.Close()
+			}
+		}()
+	}
+
+	// Retrieve AuthToken, reusing the one fetched by an earlier connection
+	authToken, cachedToken, err := c.cachedAuthToken()
+	if err == ErrOTPRequired && c.promptOTP() {
+		return c.connect()
+	}
+	if authErr, ok := err.(*AuthError); ok && c.repromptCredential(authErr) {
+		return c.connect()
+	}
 	if err != nil {
 		return err
 	}
+	// A cached token skips the request reporting StateAuthenticating
+	c.advanceState(StateResolving, StateAuthenticating)
 	logrus.Debugf("Auth-token: %q", authToken)
 
 	// Open WebSocket connection
-	target, header, err := GetWebsocketURL(c.URL)
+	target, header, opts, err := c.websocketRequest()
 	if err != nil {
 		return err
 	}
-	
-	// Add admin password header first (highest priority for proxy authentication)
-	if c.AdminPassword != "" {
-		header.Add("X-Admin-Password", c.AdminPassword)
-	}
-	
-	// Add basic auth if user is specified
-	if c.User != "" {
-		basicAuth := c.User + ":" + c.Password
-		header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(basicAuth)))
+	// Transports created here are replaced by fresh ones when reconnecting
+	c.stateMutex.RLock()
+	transport := c.Transport
+	defaultTransport := transport == nil || c.ownTransport
+	c.stateMutex.RUnlock()
+	if defaultTransport {
+		transport = &WebsocketTransport{Dialer: c.Dialer, OnPong: c.handlePong}
+	}
+	c.setState(StateDialing)
+	if defaultTransport && raced != nil {
+		transport, raced = raced, nil
+	} else {
+		stop := c.startProgress("Connecting to " + target.Host)
+		err = transport.Dial(ctx, target.String(), *header, opts)
+		stop()
 	}
-	
-	if c.WSOrigin != "" {
-		header.Add("Origin", c.WSOrigin)
+	if err != nil && c.confirmHost(err) {
+		return c.connect()
 	}
-	logrus.Debugf("Connecting to websocket: %q", target.String())
-	logrus.Debugf("WebSocket headers: %v", header)
-	if c.SkipTLSVerify {
-		c.Dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-	if c.UseProxyFromEnv {
-		c.Dialer.Proxy = http.ProxyFromEnvironment
+	if handshakeErr, ok := err.(*HandshakeError); ok && c.isOTPChallenge(handshakeErr.StatusCode, handshakeErr.Header) {
+		if c.promptOTP() {
+			return c.connect()
+		}
+		return ErrOTPRequired
+	}
+	if handshakeErr, ok := err.(*HandshakeError); ok && cachedToken && handshakeErr.StatusCode == http.StatusUnauthorized {
+		logrus.Debugf("Cached auth token refused, fetching a new one")
+		c.forgetAuthToken()
+		return c.connect()
+	}
+	// Proxies refusing websockets answer 403 too, so that is left to the
+	// fallback when there is one
+	if handshakeErr, ok := err.(*HandshakeError); ok && !(handshakeErr.StatusCode == http.StatusForbidden && defaultTransport && c.AllowFallback) {
+		if authErr := c.authError(handshakeErr.StatusCode, handshakeErr.Header, handshakeErr); authErr != nil {
+			if c.repromptCredential(authErr) {
+				return c.connect()
+			}
+			return authErr
+		}
 	}
-	conn, _, err := c.Dialer.Dial(target.String(), *header)
 	if err != nil {
-		return err
+		if !defaultTransport || !c.AllowFallback {
+			return err
+		}
+		if fallbackErr := c.checkFallback(); fallbackErr != nil {
+			return fmt.Errorf("%v (fallback: %v)", err, fallbackErr)
+		}
+		logrus.Warnf("Websocket connection failed (%v), falling back to HTTP streaming", err)
+		// The event stream stays open for the whole session, so only the
+		// handshake may be bounded by RequestTimeout
+		streamClient := c.httpClient()
+		streamClient.Timeout = 0
+		transport = &SSETransport{Client: streamClient}
+		stop := c.startProgress("Connecting to " + target.Host + " over HTTP streaming")
+		fallbackErr := transport.Dial(ctx, target.String(), *header, opts)
+		stop()
+		if fallbackErr != nil {
+			return fmt.Errorf("%v (fallback: %v)", err, fallbackErr)
+		}
 	}
-	c.Conn = conn
-	c.Connected = true
-
+	c.otpPrompts, c.authPrompts = 0, 0
+	c.storeCredential()
+	c.forgetOTP(false)
 	// Initialize message types for gotty BEFORE sending any messages
-	c.initMessageType()
+	message := newMessageType(c.V2)
+	c.WriteMutex.Lock()
+	c.stateMutex.Lock()
+	c.Transport = transport
+	c.ownTransport = defaultTransport
+	if ws, ok := transport.(*WebsocketTransport); ok {
+		c.Conn = ws.Conn
+	}
+	c.Connected = true
+	c.message = message
+	c.stateMutex.Unlock()
+	c.WriteMutex.Unlock()
+	c.InputLog.Start(target.String())
 
 	// Pass arguments and auth-token
 	query, err := GetURLQuery(c.URL)
 	if err != nil {
 		return err
 	}
+	c.addEnvArguments(query)
 	querySingle := querySingleType{
 		Arguments: "?" + query.Encode(),
 		AuthToken: authToken,
 	}
+	if size := c.initialWinSize(); size != nil {
+		querySingle.Columns, querySingle.Rows = size.Columns, size.Rows
+		if c.Screen != nil {
+			c.Screen.Resize(int(size.Rows), int(size.Columns))
+		}
+	}
 	queryJSON, err := json.Marshal(querySingle)
 	if err != nil {
 		logrus.Errorf("Failed to parse init message %v", err)
@@ -318,18 +714,59 @@ func (c *Client) Connect() error {
 		return err
 	}
 
-	// Small delay to ensure server processes auth message before we start sending other messages
-	time.Sleep(100 * time.Millisecond)
+	// Nothing else may be sent before the server has set the session up
+	c.awaitReady(transport)
 
-	go c.pingLoop()
+	c.pingOnce.Do(func() { go c.pingLoop(c.closed) })
+	c.setState(StateConnected)
 
 	return nil
 }
 
-// initMessageType initialize message types for gotty
-func (c *Client) initMessageType() {
-	if c.V2 {
-		c.message = &gottyMessageType{
+// websocketRequest returns the websocket URL of the terminal, the headers of
+// the upgrade request and the options dialing it
+func (c *Client) websocketRequest() (*url.URL, *http.Header, TransportOptions, error) {
+	return c.websocketRequestTo(c.URL)
+}
+
+// websocketRequestTo is websocketRequest for the terminal at rawURL, e.g.
+// a candidate of a race
+func (c *Client) websocketRequestTo(rawURL string) (*url.URL, *http.Header, TransportOptions, error) {
+	target, header, err := getWebsocketURL(rawURL, c.WSPath)
+	if err != nil {
+		return nil, nil, TransportOptions{}, err
+	}
+
+	c.authProvider().ApplyWSHeaders(*header)
+	if c.WSOrigin != "" {
+		header.Add("Origin", c.WSOrigin)
+	}
+	if c.HostHeader != "" {
+		// The websocket dialer uses this as the request Host
+		header.Set("Host", c.HostHeader)
+	}
+	c.affinity().apply(*header)
+	identify(*header, c.UserAgent, c.InstanceID)
+	logrus.Debugf("Connecting to websocket: %q", target.String())
+	logrus.Debugf("WebSocket headers: %v", header)
+	opts := TransportOptions{TLSConfig: c.tlsConfig(target.Host), Jar: c.cookieJar(), HandshakeTimeout: c.RequestTimeout}
+	if c.UseProxyFromEnv {
+		opts.Proxy = http.ProxyFromEnvironment
+	}
+	if c.ProxyURL != "" {
+		// Tunnel through the explicit proxy ourselves so custom CONNECT headers are sent
+		opts.Proxy = nil
+		opts.NetDialContext = c.dialProxy
+	} else if c.customDial() {
+		opts.NetDialContext = c.dialContext
+	}
+	return target, header, opts, nil
+}
+
+// newMessageType returns the message types of a gotty protocol version
+func newMessageType(v2 bool) *gottyMessageType {
+	if v2 {
+		return &gottyMessageType{
 			output:         Output,
 			pong:           Pong,
 			setWindowTitle: SetWindowTitle,
@@ -339,34 +776,98 @@ func (c *Client) initMessageType() {
 			ping:           Ping,
 			resizeTerminal: ResizeTerminal,
 		}
-	} else {
-		c.message = &gottyMessageType{
-			output:         OutputV1,
-			pong:           PongV1,
-			setWindowTitle: SetWindowTitleV1,
-			setPreferences: SetPreferencesV1,
-			setReconnect:   SetReconnectV1,
-			input:          InputV1,
-			ping:           PingV1,
-			resizeTerminal: ResizeTerminalV1,
-		}
 	}
+	return &gottyMessageType{
+		output:         OutputV1,
+		pong:           PongV1,
+		setWindowTitle: SetWindowTitleV1,
+		setPreferences: SetPreferencesV1,
+		setReconnect:   SetReconnectV1,
+		input:          InputV1,
+		ping:           PingV1,
+		resizeTerminal: ResizeTerminalV1,
+	}
+}
+
+// messages returns the message types of the current connection
+func (c *Client) messages() *gottyMessageType {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+	return c.message
 }
 
-func (c *Client) pingLoop() {
+// transport returns the current transport
+func (c *Client) transport() Transport {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+	return c.Transport
+}
+
+// IsConnected reports whether Connect succeeded and the client is not closed
+func (c *Client) IsConnected() bool {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+	return c.Connected
+}
+
+
+
+// pingLoop pings the server every 30 seconds until the client is closed
+func (c *Client) pingLoop(closed <-chan struct{}) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
 	for {
-		logrus.Debugf("Sending ping")
-		err := c.write([]byte{c.message.ping})
-		if err != nil {
-			logrus.Warnf("c.write: %v", err)
+		select {
+		case <-closed:
+			logrus.Debug("pingLoop exiting")
+			return
+		default:
+		}
+		if !c.isReconnecting() {
+			c.ping()
+		}
+		select {
+		case <-closed:
+			logrus.Debug("pingLoop exiting")
+			return
+		case <-ticker.C:
 		}
-		time.Sleep(30 * time.Second)
 	}
 }
 
-// Close will nicely close the dialer
+func (c *Client) ping() {
+	logrus.Debugf("Sending ping")
+	err := c.write([]byte{c.messages().ping})
+	if err != nil {
+		logrus.Warnf("c.write: %v", err)
+	}
+	if p, ok := c.transport().(pinger); ok {
+		if err := p.Ping(); err != nil {
+			logrus.Debugf("Ping control frame: %v", err)
+		}
+	}
+}
+
+// Close closes the connection and stops the goroutines started by Connect.
+// It is safe to call more than once; a closed client cannot be reused.
 func (c *Client) Close() error {
-	return c.Conn.Close()
+	var err error
+	c.closeOnce.Do(func() {
+		defer c.closeJump()
+		defer c.closeIdleConnections()
+		if c.closed != nil {
+			close(c.closed)
+		}
+		c.stateMutex.Lock()
+		c.Connected = false
+		transport := c.Transport
+		c.stateMutex.Unlock()
+		c.setState(StateClosed)
+		if transport != nil {
+			err = transport.Close()
+		}
+	})
+	return err
 }
 
 // ExitLoop will kill all goroutines launched by c.Loop()
@@ -376,31 +877,128 @@ func (c *Client) ExitLoop() {
 	openPoison(fname, c.poison)
 }
 
-// Loop will look indefinitely for new messages
+// LoopContext is Loop, also ending when ctx is cancelled
+func (c *Client) LoopContext(ctx context.Context) error {
+	defer c.exitLoopOnCancel(ctx)()
+	return c.Loop()
+}
+
+// exitLoopOnCancel calls ExitLoop once ctx is cancelled, until the returned
+// function is called
+func (c *Client) exitLoopOnCancel(ctx context.Context) func() {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.ExitLoop()
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
+
+// Loop will look indefinitely for new messages. All goroutines it starts
+// have ended when it returns, except the transport reader which ends once
+// the client is closed.
 func (c *Client) Loop() error {
+	if c.LocalCommandPre != "" {
+		if err := c.RunLocalCommand("pre", c.LocalCommandPre); err != nil {
+			return fmt.Errorf("local pre-connect command failed: %v", err)
+		}
+	}
+	if c.LocalCommandPost != "" {
+		defer func() {
+			if err := c.RunLocalCommand("post", c.LocalCommandPost); err != nil {
+				logrus.Warnf("Local post-disconnect command failed: %v", err)
+			}
+		}()
+	}
 
-	if !c.Connected {
+	if !c.IsConnected() {
 		err := c.Connect()
 		if err != nil {
 			return err
 		}
 	}
-	term, err := console.ConsoleFromFile(os.Stdout)
-	if err != nil {
-		return fmt.Errorf("os.Stdout is not a valid terminal")
+	if c.CompanionCommand != "" {
+		stop, err := c.startCompanion()
+		if err != nil {
+			logrus.Warnf("Failed to start companion command: %v", err)
+		} else {
+			defer stop()
+		}
 	}
-	err = term.SetRaw()
-	if err != nil {
-		return fmt.Errorf("error setting raw terminal: %v", err)
+	if !c.Stdio {
+		term, err := console.ConsoleFromFile(os.Stdout)
+		if err != nil {
+			return fmt.Errorf("os.Stdout is not a valid terminal")
+		}
+		err = term.SetRaw()
+		if err != nil {
+			return fmt.Errorf("error setting raw terminal: %v", err)
+		}
+		defer func() {
+			_ = term.Reset()
+		}()
 	}
+
+	started := time.Now()
+	c.statsMutex.Lock()
+	c.activity = Activity{Attached: started}
+	c.statsMutex.Unlock()
+	c.AuditLog.Log(c.auditEvent("connect"))
 	defer func() {
-		_ = term.Reset()
+		event := c.auditEvent("disconnect")
+		if c.detached {
+			event.Action = "detach"
+		}
+		event.Duration = time.Since(started)
+		c.AuditLog.Log(event)
 	}()
 
 	wg := &sync.WaitGroup{}
 
-	wg.Add(1)
-	go c.termsizeLoop(wg)
+	if c.RestartOnExit > 0 {
+		c.prepareRestart()
+	}
+
+	if c.inputHistory == nil && !c.Stdio {
+		history, err := LoadInputHistory(c.HistoryPath)
+		if err != nil {
+			logrus.Warnf("%v", err)
+			history, _ = LoadInputHistory("")
+		}
+		c.inputHistory = history
+	}
+	if c.LineMode && !c.Stdio && c.lineEditor == nil {
+		c.lineEditor = NewLineEditor(c.Output, c.inputHistory)
+	}
+
+	c.startControl()
+	if c.WriteLock {
+		wg.Add(1)
+		go c.controlLoop(wg)
+	}
+
+	if c.KeepaliveInput > 0 {
+		wg.Add(1)
+		go c.keepaliveLoop(wg)
+	}
+
+	if c.Instance != nil && c.RegistryCheck > 0 {
+		wg.Add(1)
+		go c.registryLoop(wg)
+	}
+
+	if c.ActivityPath != "" || len(c.idleHooks) > 0 {
+		wg.Add(1)
+		go c.idleLoop(wg)
+	}
+
+	if !c.Stdio {
+		wg.Add(1)
+		go c.termsizeLoop(wg)
+	}
 
 	wg.Add(1)
 	go c.readLoop(wg)
@@ -412,12 +1010,7 @@ func (c *Client) Loop() error {
 	wg.Wait()
 
 	logrus.Debug("Client.Loop() exiting")
-	return nil
-}
-
-type winsize struct {
-	Rows    uint16 `json:"rows"`
-	Columns uint16 `json:"columns"`
+	return c.loopErr
 }
 
 type poisonReason int
@@ -464,16 +1057,13 @@ func (c *Client) termsizeLoop(wg *sync.WaitGroup) poisonReason {
 	notifySignalSIGWINCH(ch)
 	defer resetSignalSIGWINCH()
 
-	// Delay first resize to ensure auth message is processed first and terminal is ready
-	// Match the web interface delay (100ms) plus extra time for terminal setup
-	time.Sleep(200 * time.Millisecond)
-	
-	// Send initial resize
-	if b, err := syscallTIOCGWINSZ(); err != nil {
+	// Send initial resize; Connect has waited for the server to be ready
+	if b, err := c.winSizePayload(); err != nil {
 		// Suppress warning on first attempt - terminal might not be fully ready
 		logrus.Debugf("Initial terminal size query failed (expected): %v", err)
 	} else {
-		if err = c.write(append([]byte{c.message.resizeTerminal}, b...)); err != nil {
+		c.resizeScreen(b)
+		if err = c.write(append([]byte{c.messages().resizeTerminal}, b...)); err != nil {
 			logrus.Warnf("ws.WriteMessage failed: %v", err)
 		}
 	}
@@ -485,10 +1075,15 @@ func (c *Client) termsizeLoop(wg *sync.WaitGroup) poisonReason {
 			/* Somebody poisoned the well; die */
 			return die(fname, c.poison)
 		case <-ch:
+			if c.isReconnecting() {
+				// The size is sent again once reconnected
+				continue
+			}
 			if b, err := syscallTIOCGWINSZ(); err != nil {
 				logrus.Warn(err)
 			} else {
-				if err = c.write(append([]byte{c.message.resizeTerminal}, b...)); err != nil {
+				c.resizeScreen(b)
+				if err = c.write(append([]byte{c.messages().resizeTerminal}, b...)); err != nil {
 					logrus.Warnf("ws.WriteMessage failed: %v", err)
 				}
 			}
@@ -508,10 +1103,33 @@ func (c *Client) writeLoop(wg *sync.WaitGroup) poisonReason {
 
 	rdfs := &goselect.FDSet{}
 	reader := io.ReadCloser(os.Stdin)
+	if c.Stdin != nil {
+		reader = c.Stdin
+	}
 
-	pr := NewEscapeProxy(reader, c.EscapeKeys)
+	pr := io.Reader(reader)
+	if !c.Stdio {
+		pr = NewEscapeProxy(reader, c.EscapeKeys)
+		if len(c.MenuKeys) > 0 {
+			pr = NewMenuProxy(pr, c.MenuKeys)
+		}
+	}
 	defer reader.Close()
 
+	menuOpen := false
+
+	// send passes typed input through the keymap, line editor and newline
+	// translation
+	send := func(data []byte) error {
+		data = c.Keymap.Apply(data)
+		if c.lineEditor != nil {
+			if data = c.lineEditor.Feed(data); len(data) == 0 {
+				return nil
+			}
+		}
+		return c.sendInput(c.InputCRLF.Translate(data))
+	}
+
 	for {
 		select {
 		case <-c.poison:
@@ -520,27 +1138,58 @@ func (c *Client) writeLoop(wg *sync.WaitGroup) poisonReason {
 		default:
 		}
 
+		// A confirmed paste trickles out at the input rate
+		if data := c.pacedInput(time.Now()); len(data) > 0 {
+			if err := send(data); err != nil {
+				return openPoison(fname, c.poison)
+			}
+		}
+		// An Escape typed alone is not followed by the rest of a key
+		if data := c.lineEditor.Expire(time.Now()); len(data) > 0 {
+			if err := c.sendInput(c.InputCRLF.Translate(data)); err != nil {
+				return openPoison(fname, c.poison)
+			}
+		}
+
 		rdfs.Zero()
-		rdfs.Set(reader.(exposeFd).Fd())
-		err := goselect.RetrySelect(1, rdfs, nil, nil, 50*time.Millisecond, 3, 50*time.Millisecond)
+		fd := reader.(exposeFd).Fd()
+		rdfs.Set(fd)
+		// The input may be another descriptor than standard input's
+		err := goselect.RetrySelect(int(fd)+1, rdfs, nil, nil, 50*time.Millisecond, 3, 50*time.Millisecond)
 		if err != nil && err != syscall.EINTR {
 			logrus.Debugf(err.Error())
 			return openPoison(fname, c.poison)
 		}
-		if rdfs.IsSet(reader.(exposeFd).Fd()) {
+		if rdfs.IsSet(fd) {
 			size, err := pr.Read(buff)
 
 			if err != nil {
+				if _, ok := err.(MenuEscapeError); ok {
+					if c.showEscapeMenu() {
+						menuOpen = true
+					} else if err = c.sendInput(c.MenuKeys); err != nil {
+						return openPoison(fname, c.poison)
+					}
+					continue
+				}
+				if _, ok := err.(EscapeError); ok {
+					c.detached = true
+				}
 				if err == io.EOF {
 					// Send EOF to GoTTY
 
 					// Send 'Input' marker, as defined in GoTTY::client_context.go,
 					// followed by EOT (a translation of Ctrl-D for terminals)
-					err = c.write(append([]byte{c.message.input}, byte(4)))
+					err = c.sendInput([]byte{4})
 
 					if err != nil {
 						return openPoison(fname, c.poison)
 					}
+					if c.Stdio {
+						// Nothing more to send; relay the output until the end
+						<-c.poison
+						return die(fname, c.poison)
+					}
 					continue
 				} else {
 					return openPoison(fname, c.poison)
@@ -552,8 +1201,21 @@ func (c *Client) writeLoop(wg *sync.WaitGroup) poisonReason {
 			}
 
 			data := buff[:size]
-			err = c.write(append([]byte{c.message.input}, data...))
-			if err != nil {
+			if c.Stdio {
+				if err = c.sendInput(data); err != nil {
+					return openPoison(fname, c.poison)
+				}
+				continue
+			}
+			if menuOpen {
+				c.handleEscapeMenuKey(data[0])
+				menuOpen = c.menuPending != nil
+				continue
+			}
+			if data = c.limitInput(data, time.Now()); len(data) == 0 {
+				continue
+			}
+			if err = send(data); err != nil {
 				return openPoison(fname, c.poison)
 			}
 		}
@@ -569,56 +1231,112 @@ func (c *Client) readLoop(wg *sync.WaitGroup) poisonReason {
 		Data []byte
 		Err  error
 	}
-	msgChan := make(chan MessageNonBlocking)
+	// A single reader feeds msgChan so the watchdog can wake the loop
+	// without starting concurrent reads. A new reader is started for each
+	// connection; done stops the previous one.
+	var msgChan chan MessageNonBlocking
+	var done chan struct{}
+	startReader := func() {
+		if done != nil {
+			close(done)
+		}
+		msgChan, done = make(chan MessageNonBlocking), make(chan struct{})
+		go func(transport Transport, msgChan chan MessageNonBlocking, done chan struct{}) {
+			for {
+				data, err := c.readFrame(transport)
+				select {
+				case msgChan <- MessageNonBlocking{Data: data, Err: err}:
+				case <-done:
+					return
+				case <-c.poison:
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}(c.transport(), msgChan, done)
+		c.touchRead()
+	}
+	startReader()
+	defer func() { close(done) }()
 
-	for {
-		go func() {
-			_, data, err := c.Conn.ReadMessage()
-			msgChan <- MessageNonBlocking{Data: data, Err: err}
-		}()
+	var watchdog <-chan time.Time
+	if timeout := c.readTimeout(); timeout > 0 {
+		ticker := time.NewTicker(timeout / 3)
+		defer ticker.Stop()
+		watchdog = ticker.C
+	}
 
+	for {
 		select {
 		case <-c.poison:
 			/* Somebody poisoned the well; die */
 			return die(fname, c.poison)
-		case msg := <-msgChan:
-			if msg.Err != nil {
-
-				if _, ok := msg.Err.(*websocket.CloseError); !ok {
-					logrus.Warnf("c.Conn.ReadMessage: %v", msg.Err)
+		case <-watchdog:
+			if c.readStale() {
+				if c.Reconnect && c.reconnectLoop() {
+					startReader()
+					continue
 				}
+				c.loopErr = ErrConnectionStale
 				return openPoison(fname, c.poison)
 			}
-			if len(msg.Data) == 0 {
-
-				logrus.Warnf("An error has occurred")
-				return openPoison(fname, c.poison)
-			}
-			switch msg.Data[0] {
-			case c.message.output: // data
-				buf, err := base64.StdEncoding.DecodeString(string(msg.Data[1:]))
-				if err != nil {
-					logrus.Warnf("Invalid base64 content: %q", msg.Data[1:])
-					break
+		case msg := <-msgChan:
+			if msg.Err != nil {
+				if c.shouldReconnect(msg.Err) && c.reconnectLoop() {
+					startReader()
+					continue
 				}
-				_, _ = c.Output.Write(buf)
-			case c.message.pong: // pong
-			case c.message.setWindowTitle: // new title
-				newTitle := string(msg.Data[1:])
-				_, _ = fmt.Fprintf(c.Output, "\033]0;%s\007", newTitle)
-			case c.message.setPreferences: // json prefs
-				logrus.Debugf("Received preferences: %s", string(msg.Data[1:]))
-			case c.message.setReconnect: // autoreconnect
-				var reconnectTimeout int
-				if err := json.Unmarshal(msg.Data[1:], &reconnectTimeout); err == nil {
-					logrus.Debugf("Server reconnect timeout: %d seconds", reconnectTimeout)
-				} else {
-					logrus.Debugf("Received reconnect message: %s", string(msg.Data[1:]))
+				if c.RestartOnExit > 0 && c.restartLoop() {
+					startReader()
+					continue
+				}
+				if isTimeout(msg.Err) {
+					c.loopErr = ErrConnectionStale
+				} else if _, ok := msg.Err.(*websocket.CloseError); !ok && msg.Err != io.EOF {
+					logrus.Warnf("c.Transport.Read: %v", msg.Err)
 				}
-			default:
-				logrus.Warnf("Unhandled protocol message: %s", string(msg.Data))
+				return openPoison(fname, c.poison)
+			}
+			c.touchRead()
+			message, err := ParseServerMessage(c.V2, msg.Data)
+			if err != nil {
+				// Frames mangled by a proxy are dropped rather than printed
+				logrus.Debugf("Dropping frame: %v", err)
+				continue
 			}
+			c.handleMessage(message)
+		}
+	}
+}
+
+// handleMessage acts on a message received from the server
+func (c *Client) handleMessage(message Message) {
+	switch message := message.(type) {
+	case OutputMessage:
+		buf := message.Data
+		if c.NormalizeOutput {
+			buf = c.outputNormalizer.normalize(buf)
+		}
+		c.lineEditor.Heard(buf)
+		c.lineEditor.Interrupt(func() {
+			_, _ = c.Output.Write(buf)
+		})
+		c.touchOutput()
+		if c.Screen != nil {
+			_, _ = c.Screen.Write(buf)
 		}
+		c.publish(OutputEvent{Type: EventOutput, Data: buf})
+	case SetWindowTitleMessage:
+		if !c.Stdio {
+			_, _ = fmt.Fprintf(c.Output, "\033]0;%s\007", message.Title)
+		}
+		c.publish(OutputEvent{Type: EventTitle, Title: message.Title})
+	case SetPreferencesMessage:
+		logrus.Debugf("Received preferences: %s", string(message.Preferences))
+	case SetReconnectMessage:
+		logrus.Debugf("Server reconnect timeout: %d seconds", message.Timeout)
 	}
 }
 
@@ -648,13 +1366,34 @@ func NewClient(inputURL string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newClient(parsedURL), nil
+}
+
+// NewRawClient returns a GoTTY client object for exactly rawURL, which must
+// be an http or https URL, without the scheme guessing and normalization of
+// NewClient
+func NewRawClient(rawURL string) (*Client, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("raw URL %q must start with http:// or https://", rawURL)
+	}
+	return newClient(rawURL), nil
+}
+
+func newClient(target string) *Client {
 	return &Client{
 		Dialer:     &websocket.Dialer{},
-		URL:        parsedURL,
+		URL:        target,
 		WriteMutex: &sync.Mutex{},
 		Output:     os.Stdout,
 		poison:     make(chan bool),
-	}, nil
+		closed:     make(chan struct{}),
+		InstanceID: processInstanceID,
+		CacheDir:   GetDefaultCacheDir(),
+	}
 }
 
 // SessionInfo represents information about a tmux session
@@ -665,12 +1404,88 @@ type SessionInfo struct {
 	Windows    int    `json:"windows"`
 	Attached   bool   `json:"attached"`
 	LastActive string `json:"last_active"`
+	HandoverTo string            `json:"handover_to,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+
+	// CreatedAt and LastActiveAt are Created and LastActive parsed from the
+	// server's format; they are zero if the server sent an unknown format.
+	CreatedAt    time.Time `json:"-"`
+	LastActiveAt time.Time `json:"-"`
+}
+
+// UnmarshalJSON decodes a session and parses its timestamps
+func (s *SessionInfo) UnmarshalJSON(data []byte) error {
+	type rawSessionInfo SessionInfo
+	var raw rawSessionInfo
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = SessionInfo(raw)
+	s.CreatedAt, _ = parseSessionTime(s.Created)
+	s.LastActiveAt, _ = parseSessionTime(s.LastActive)
+	return nil
 }
 
 // SessionListResponse represents the response for listing sessions
 type SessionListResponse struct {
 	Sessions []SessionInfo `json:"sessions"`
 	Count    int           `json:"count"`
+	Pagination
+}
+
+// Pagination holds the optional paging fields of list responses. Servers that
+// do not paginate leave them zero.
+type Pagination struct {
+	Page       int `json:"page,omitempty"`
+	TotalPages int `json:"total_pages,omitempty"`
+	Total      int `json:"total,omitempty"`
+}
+
+// hasNextPage reports whether another page follows the current one
+func (p Pagination) hasNextPage() bool {
+	return p.Page > 0 && p.Page < p.TotalPages
+}
+
+// DefaultPageSize is the number of items requested per page from paginated
+// APIs by clients without a PageSize
+const DefaultPageSize = 100
+
+// pageQuery returns the paging query parameters for a page of size items,
+// DefaultPageSize if 0. Servers compute the offset of a page from its size,
+// so the size stays the same on every page, only shrunk to limit (0 means no
+// limit) when a single page holds it; callers truncate the extra items of the
+// last page.
+func pageQuery(page, size, limit int) url.Values {
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	if limit > 0 && limit < size {
+		size = limit
+	}
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(size))
+	return query
+}
+
+// Find returns the session with the given name, or nil if there is none
+func (r *SessionListResponse) Find(name string) *SessionInfo {
+	for i := range r.Sessions {
+		if r.Sessions[i].Name == name {
+			return &r.Sessions[i]
+		}
+	}
+	return nil
+}
+
+// FindByWindow returns the first session with the given window name, or nil if there is none
+func (r *SessionListResponse) FindByWindow(windowName string) *SessionInfo {
+	for i := range r.Sessions {
+		if r.Sessions[i].WindowName == windowName {
+			return &r.Sessions[i]
+		}
+	}
+	return nil
 }
 
 // SessionActionResponse represents the response for session actions
@@ -682,6 +1497,8 @@ type SessionActionResponse struct {
 
 // Instance represents a UberSDR instance
 type Instance struct {
+	// Source names the registry listing the instance
+	Source                string   `json:"source,omitempty"`
 	ID                    string   `json:"id"`
 	Callsign              string   `json:"callsign"`
 	Name                  string   `json:"name"`
@@ -710,43 +1527,103 @@ type Instance struct {
 	RotatorConnected      bool     `json:"rotator_connected,omitempty"`
 	RotatorAzimuth        int      `json:"rotator_azimuth"`
 	LastReportAgeSeconds  int      `json:"last_report_age_seconds"`
+	// The operator's contact details and description of the receiver, for
+	// registries publishing them
+	OperatorName          string   `json:"operator_name,omitempty"`
+	OperatorEmail         string   `json:"operator_email,omitempty"`
+	QRZURL                string   `json:"qrz_url,omitempty"`
+	Description           string   `json:"description,omitempty"`
+	// RawExtra holds the fields of the registry entry this client does not know
+	RawExtra map[string]json.RawMessage `json:"-"`
 }
 
 // InstanceListResponse represents the response from the instances API
 type InstanceListResponse struct {
-	Count     int        `json:"count"`
-	Instances []Instance `json:"instances"`
+	// SchemaVersion is the registry schema the response follows, 0 if unversioned
+	SchemaVersion int        `json:"schema_version,omitempty"`
+	Count         int        `json:"count"`
+	Instances     []Instance `json:"instances"`
+	Pagination
 }
 
-// ListInstances retrieves the list of available UberSDR instances
+// InstancesURL is the instance registry API endpoint
+const InstancesURL = "https://instances.ubersdr.org/api/instances"
+
+// ListInstances retrieves the list of available UberSDR instances from the
+// public registry
 func ListInstances() (*InstanceListResponse, error) {
-	url := "https://instances.ubersdr.org/api/instances"
-	
-	logrus.Debugf("Fetching instances list: %q", url)
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return NewRegistryClient().ListInstances()
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to list instances: %d %s - %s", resp.StatusCode, http.StatusText(resp.StatusCode), string(body))
-	}
+// ListInstancesWithLimit retrieves at most limit UberSDR instances (0 means
+// all) from the public registry
+func ListInstancesWithLimit(limit int) (*InstanceListResponse, error) {
+	return NewRegistryClient().ListInstancesWithLimit(limit)
+}
+
+// ListInstances retrieves the list of available UberSDR instances
+func (r *RegistryClient) ListInstances() (*InstanceListResponse, error) {
+	return r.ListInstancesWithLimit(0)
+}
+
+// ListInstancesWithLimit retrieves at most limit UberSDR instances (0 means
+// all), following the registries' pages if they paginate
+func (r *RegistryClient) ListInstancesWithLimit(limit int) (*InstanceListResponse, error) {
+	return r.listInstances(limit, false)
+}
 
-	var instanceList InstanceListResponse
-	if err := json.NewDecoder(resp.Body).Decode(&instanceList); err != nil {
-		return nil, fmt.Errorf("failed to decode instance list: %v", err)
+// listRegistry lists the instances of one registry, tagged with its name,
+// without reporting progress if quiet
+func (r *RegistryClient) listRegistry(registry Registry, limit int, quiet bool) (*InstanceListResponse, error) {
+	result := &InstanceListResponse{Instances: []Instance{}}
+	for page := 1; ; page++ {
+		target := versionedRegistryURL(registry.URL + "?" + pageQuery(page, 0, limit).Encode())
+
+		logrus.Debugf("Fetching instances list: %q", target)
+		body, err := r.fetchRegistry(target, quiet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list instances: %v", err)
+		}
+
+		var instanceList InstanceListResponse
+		if err := json.Unmarshal(body, &instanceList); err != nil {
+			return nil, fmt.Errorf("failed to decode instance list: %v", err)
+		}
+		result.SchemaVersion = instanceList.SchemaVersion
+		if page == 1 && instanceList.SchemaVersion > RegistrySchemaVersion {
+			logrus.Debugf("Registry %s uses schema version %d, newer than %d; unknown fields are kept in RawExtra", registry.Name, instanceList.SchemaVersion, RegistrySchemaVersion)
+		}
+
+		for _, instance := range instanceList.Instances {
+			instance.Source = registry.Name
+			result.Instances = append(result.Instances, instance)
+		}
+		result.Total = instanceList.Total
+		if limit > 0 && len(result.Instances) >= limit {
+			result.Instances = result.Instances[:limit]
+			break
+		}
+		if !instanceList.hasNextPage() || len(instanceList.Instances) == 0 {
+			break
+		}
 	}
 
-	return &instanceList, nil
+	result.Count = len(result.Instances)
+	return result, nil
 }
 
-// FindInstanceByCallsign finds an instance by its callsign
+// FindInstanceByCallsign finds an instance of the public registry by its
+// callsign
 func FindInstanceByCallsign(callsign string) (*Instance, error) {
-	instances, err := ListInstances()
+	return NewRegistryClient().FindInstanceByCallsign(callsign)
+}
+
+// FindInstanceByCallsign finds an instance by its callsign. Its errors are
+// LookupErrors.
+func (r *RegistryClient) FindInstanceByCallsign(callsign string) (*Instance, error) {
+	instances, err := r.listInstances(0, false)
 	if err != nil {
-		return nil, err
+		return nil, &LookupError{Err: err}
 	}
 
 	callsign = strings.ToUpper(callsign)
@@ -756,110 +1633,120 @@ func FindInstanceByCallsign(callsign string) (*Instance, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("no instance found with callsign: %s", callsign)
+	return nil, &LookupError{Err: fmt.Errorf("no instance found with callsign: %s", callsign)}
 }
 
-// ListSessions retrieves the list of available tmux sessions
-func (c *Client) ListSessions() (*SessionListResponse, error) {
-	target, err := url.Parse(c.URL)
-	if err != nil {
-		return nil, err
-	}
-
-	// Build the sessions API URL
-	target.Path = strings.TrimRight(target.Path, "/") + "/api/sessions"
+// httpClient returns an HTTP client honoring the TLS and proxy settings
+func (c *Client) httpClient() *http.Client {
+	return &http.Client{Transport: c.sharedTransport(&c.httpTransports, c.httpTransport), Jar: c.cookieJar(), CheckRedirect: c.checkRedirect, Timeout: c.RequestTimeout}
+}
 
-	logrus.Debugf("Fetching sessions list: %q", target.String())
-	req, err := http.NewRequest("GET", target.String(), nil)
-	if err != nil {
-		return nil, err
+// sharedTransport returns *transports, making it with newTransport on first
+// use
+func (c *Client) sharedTransport(transports **hostTransport, newTransport func(host string) *http.Transport) *hostTransport {
+	c.transportMutex.Lock()
+	defer c.transportMutex.Unlock()
+	if *transports == nil {
+		*transports = &hostTransport{newTransport: newTransport}
 	}
+	return *transports
+}
 
-	// Add authentication headers
-	// Add admin password header first (highest priority for proxy authentication)
-	if c.AdminPassword != "" {
-		req.Header.Add("X-Admin-Password", c.AdminPassword)
-	}
-	
-	// Add basic auth if user is specified
-	if c.User != "" {
-		basicAuth := c.User + ":" + c.Password
-		req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(basicAuth)))
+// closeIdleConnections closes the idle keep-alive connections of the HTTP
+// requests
+func (c *Client) closeIdleConnections() {
+	c.transportMutex.Lock()
+	defer c.transportMutex.Unlock()
+	for _, transports := range []*hostTransport{c.httpTransports, c.dohTransports} {
+		if transports != nil {
+			transports.CloseIdleConnections()
+		}
 	}
+}
+
+// idleConnTimeout closes keep-alive connections left unused this long
+const idleConnTimeout = 90 * time.Second
 
-	// Setup HTTP client
-	tr := &http.Transport{}
-	if c.SkipTLSVerify {
-		conf := &tls.Config{InsecureSkipVerify: true}
-		tr.TLSClientConfig = conf
+// httpTransport returns the HTTP transport of requests to host
+func (c *Client) httpTransport(host string) *http.Transport {
+	tr := &http.Transport{TLSClientConfig: c.tlsConfig(host), ForceAttemptHTTP2: true, IdleConnTimeout: idleConnTimeout}
+	if c.customDial() {
+		tr.DialContext = c.dialContext
 	}
 	if c.UseProxyFromEnv {
 		tr.Proxy = http.ProxyFromEnvironment
 	}
-	client := &http.Client{Transport: tr}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	if c.ProxyURL != "" {
+		if proxyURL, err := ParseProxyURL(c.ProxyURL); err == nil {
+			tr.Proxy = http.ProxyURL(proxyURL)
+			tr.ProxyConnectHeader = c.proxyConnectHeader(proxyURL)
+		} else {
+			logrus.Warnf("Ignoring proxy: %v", err)
+		}
 	}
-	defer resp.Body.Close()
+	return tr
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to list sessions: %d %s - %s", resp.StatusCode, http.StatusText(resp.StatusCode), string(body))
-	}
+// ListSessions retrieves the list of available tmux sessions
+func (s *SessionsClient) ListSessions() (*SessionListResponse, error) {
+	return s.ListSessionsWithLimit(0)
+}
+
+// ListSessionsWithLimit retrieves at most limit tmux sessions (0 means all),
+// following the server's pages if it paginates
+func (s *SessionsClient) ListSessionsWithLimit(limit int) (*SessionListResponse, error) {
+	result := &SessionListResponse{Sessions: []SessionInfo{}}
+	for page := 1; ; page++ {
+		req, err := s.newAPIRequest("GET", "/api/sessions", pageQuery(page, s.PageSize, limit))
+		if err != nil {
+			return nil, err
+		}
+
+		logrus.Debugf("Fetching sessions list: %q", req.URL.String())
+		resp, err := s.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != 200 {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to list sessions: %d %s - %s", resp.StatusCode, http.StatusText(resp.StatusCode), string(body))
+		}
+
+		var sessionList SessionListResponse
+		err = json.NewDecoder(resp.Body).Decode(&sessionList)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode session list: %v", err)
+		}
 
-	var sessionList SessionListResponse
-	if err := json.NewDecoder(resp.Body).Decode(&sessionList); err != nil {
-		return nil, fmt.Errorf("failed to decode session list: %v", err)
+		result.Sessions = append(result.Sessions, sessionList.Sessions...)
+		result.Total = sessionList.Total
+		if limit > 0 && len(result.Sessions) >= limit {
+			result.Sessions = result.Sessions[:limit]
+			break
+		}
+		if !sessionList.hasNextPage() || len(sessionList.Sessions) == 0 {
+			break
+		}
 	}
 
-	return &sessionList, nil
+	result.Count = len(result.Sessions)
+	return result, nil
 }
 
 // DestroySession destroys a tmux session by name
-func (c *Client) DestroySession(sessionName string) (*SessionActionResponse, error) {
-	target, err := url.Parse(c.URL)
-	if err != nil {
-		return nil, err
-	}
-
-	// Build the destroy API URL
-	target.Path = strings.TrimRight(target.Path, "/") + "/api/sessions/destroy"
-	query := target.Query()
+func (s *SessionsClient) DestroySession(sessionName string) (*SessionActionResponse, error) {
+	query := url.Values{}
 	query.Set("name", sessionName)
-	target.RawQuery = query.Encode()
-
-	logrus.Debugf("Destroying session: %q", target.String())
-	req, err := http.NewRequest("DELETE", target.String(), nil)
+	req, err := s.newAPIRequest("DELETE", "/api/sessions/destroy", query)
 	if err != nil {
 		return nil, err
 	}
 
-	// Add authentication headers
-	// Add admin password header first (highest priority for proxy authentication)
-	if c.AdminPassword != "" {
-		req.Header.Add("X-Admin-Password", c.AdminPassword)
-	}
-	
-	// Add basic auth if user is specified
-	if c.User != "" {
-		basicAuth := c.User + ":" + c.Password
-		req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(basicAuth)))
-	}
-
-	// Setup HTTP client
-	tr := &http.Transport{}
-	if c.SkipTLSVerify {
-		conf := &tls.Config{InsecureSkipVerify: true}
-		tr.TLSClientConfig = conf
-	}
-	if c.UseProxyFromEnv {
-		tr.Proxy = http.ProxyFromEnvironment
-	}
-	client := &http.Client{Transport: tr}
-
-	resp, err := client.Do(req)
+	logrus.Debugf("Destroying session: %q", req.URL.String())
+	resp, err := s.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -876,3 +1763,40 @@ func (c *Client) DestroySession(sessionName string) (*SessionActionResponse, err
 
 	return &actionResp, nil
 }
+
+// HandoverSession marks a session as handed over to another operator and asks
+// the server to detach the clients currently attached to it
+func (s *SessionsClient) HandoverSession(sessionName, to string) (*SessionActionResponse, error) {
+	query := url.Values{}
+	query.Set("name", sessionName)
+	query.Set("to", to)
+	query.Set("from", LocalOperator())
+	query.Set("detach", "true")
+	req, err := s.newAPIRequest("POST", "/api/sessions/handover", query)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Debugf("Handing over session: %q", req.URL.String())
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var actionResp SessionActionResponse
+		if err := json.Unmarshal(body, &actionResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %v", err)
+		}
+		return &actionResp, nil
+	case apiUnsupported(resp.StatusCode, body):
+		return nil, ErrHandoverUnsupported
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("session %s not found", sessionName)
+	default:
+		return nil, apiError("failed to hand over session", resp.StatusCode, body)
+	}
+}
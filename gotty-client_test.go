@@ -1,7 +1,15 @@
 package gottyclient
 
 import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -37,5 +45,119 @@ func TestParseURL(t *testing.T) {
 			So(err, ShouldBeNil)
 			So(output, ShouldEqual, expected)
 		})
+		Convey("Raw URLs are kept as given", func() {
+			input := "https://test.com/proxy/terminal/x/terminal/?token=a%2Fb&x"
+			client, err := NewRawClient(input)
+			So(err, ShouldBeNil)
+			So(client.URL, ShouldEqual, input)
+
+			_, err = NewRawClient("test.com:8888/terminal/")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestListSessionsPagination(t *testing.T) {
+	Convey("Testing ListSessions pagination", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			page := r.URL.Query().Get("page")
+			fmt.Fprintf(w, `{"sessions":[{"name":"s%s-1"},{"name":"s%s-2"}],"count":2,"page":%s,"total_pages":3,"total":6}`, page, page, page)
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/terminal/")
+		So(err, ShouldBeNil)
+
+		Convey("All pages are fetched", func() {
+			list, err := client.ListSessions()
+			So(err, ShouldBeNil)
+			So(list.Count, ShouldEqual, 6)
+			So(list.Total, ShouldEqual, 6)
+			So(list.Sessions[5].Name, ShouldEqual, "s3-2")
+		})
+		Convey("Limit stops early", func() {
+			list, err := client.ListSessionsWithLimit(3)
+			So(err, ShouldBeNil)
+			So(list.Count, ShouldEqual, 3)
+			So(list.Sessions[2].Name, ShouldEqual, "s2-1")
+		})
+	})
+
+	Convey("Testing the page size stays constant up to the limit", t, func() {
+		const total = 250
+		var sizes []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			size, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			sizes = append(sizes, r.URL.Query().Get("limit"))
+			names := []string{}
+			for i := (page - 1) * size; i < page*size && i < total; i++ {
+				names = append(names, fmt.Sprintf(`{"name":"s%d"}`, i))
+			}
+			fmt.Fprintf(w, `{"sessions":[%s],"page":%d,"total_pages":%d,"total":%d}`, strings.Join(names, ","), page, (total+size-1)/size, total)
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/terminal/")
+		So(err, ShouldBeNil)
+
+		list, err := client.ListSessionsWithLimit(150)
+		So(err, ShouldBeNil)
+		So(list.Count, ShouldEqual, 150)
+		So(sizes, ShouldResemble, []string{"100", "100"})
+		for i, session := range list.Sessions {
+			So(session.Name, ShouldEqual, fmt.Sprintf("s%d", i))
+		}
+
+		sizes = nil
+		list, err = client.ListSessionsWithLimit(30)
+		So(err, ShouldBeNil)
+		So(list.Count, ShouldEqual, 30)
+		So(sizes, ShouldResemble, []string{"30"})
+	})
+}
+
+func TestHTTPConnectionReuse(t *testing.T) {
+	Convey("Testing that API requests share their connections", t, func() {
+		var mutex sync.Mutex
+		opened, closed := 0, 0
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"sessions":[],"count":0}`))
+		}))
+		server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch state {
+			case http.StateNew:
+				opened++
+			case http.StateClosed:
+				closed++
+			}
+		}
+		server.Start()
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		for i := 0; i < 3; i++ {
+			_, err := client.ListSessions()
+			So(err, ShouldBeNil)
+		}
+		mutex.Lock()
+		So(opened, ShouldEqual, 1)
+		mutex.Unlock()
+
+		So(client.Close(), ShouldBeNil)
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			mutex.Lock()
+			done := closed == 1
+			mutex.Unlock()
+			if done || time.Now().After(deadline) {
+				So(done, ShouldBeTrue)
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
 	})
 }
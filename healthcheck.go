@@ -0,0 +1,43 @@
+package gottyclient
+
+// HealthCheck checks that the server accepts the client's credentials and
+// upgrades the terminal's websocket, then disconnects before the terminal is
+// started, so no session is created. Nothing is prompted for, and the auth
+// token is always fetched anew.
+func (c *Client) HealthCheck() error {
+	ctx, cancel := c.closeContext()
+	defer cancel()
+	// The race only picks the URL: the check dials it once the auth token
+	// was fetched
+	if len(c.AlternateURLs) > 0 {
+		raced, err := c.raceAlternates(ctx)
+		if err != nil {
+			return err
+		}
+		if raced != nil {
+			_ = raced.Close()
+		}
+	}
+	if _, err := c.GetAuthToken(); err != nil {
+		return err
+	}
+
+	target, header, opts, err := c.websocketRequest()
+	if err != nil {
+		return err
+	}
+	transport := &WebsocketTransport{Dialer: c.Dialer}
+	err = transport.Dial(ctx, target.String(), *header, opts)
+	if handshakeErr, ok := err.(*HandshakeError); ok {
+		if c.isOTPChallenge(handshakeErr.StatusCode, handshakeErr.Header) {
+			return ErrOTPRequired
+		}
+		if authErr := c.authError(handshakeErr.StatusCode, handshakeErr.Header, handshakeErr); authErr != nil {
+			return authErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return transport.Close()
+}
@@ -0,0 +1,72 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHealthCheck(t *testing.T) {
+	Convey("Testing health checks", t, func() {
+		var upgrades, sessions int32
+		upgrader := websocket.Upgrader{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, password, _ := r.BasicAuth(); user != "alice" || password != "pw" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="sdr"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			switch r.URL.Path {
+			case "/auth_token.js":
+				w.Write([]byte("var gotty_auth_token = 'token'"))
+			case "/ws":
+				atomic.AddInt32(&upgrades, 1)
+				conn, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				// The first message would start the terminal
+				if _, _, err := conn.ReadMessage(); err == nil {
+					atomic.AddInt32(&sessions, 1)
+				}
+			}
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		client.RetryPolicy = NoRetry
+		client.User = "alice"
+
+		Convey("Valid credentials pass without starting a terminal", func() {
+			client.Password = "pw"
+			So(client.HealthCheck(), ShouldBeNil)
+			So(client.HealthCheck(), ShouldBeNil)
+			So(atomic.LoadInt32(&upgrades), ShouldEqual, 2)
+			So(atomic.LoadInt32(&sessions), ShouldEqual, 0)
+		})
+
+		Convey("Wrong credentials fail without prompting", func() {
+			client.Password = "wrong"
+			prompted := false
+			client.CredentialPrompt = func(*AuthError) (string, error) {
+				prompted = true
+				return "pw", nil
+			}
+			err := client.HealthCheck()
+			So(err, ShouldHaveSameTypeAs, &AuthError{})
+			So(prompted, ShouldBeFalse)
+			So(atomic.LoadInt32(&upgrades), ShouldEqual, 0)
+		})
+
+		Convey("Unreachable servers fail", func() {
+			server.Close()
+			So(client.HealthCheck(), ShouldNotBeNil)
+		})
+	})
+}
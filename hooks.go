@@ -0,0 +1,59 @@
+package gottyclient
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// hookEnv returns the environment describing the connection to the local
+// commands run around it. Credentials embedded in the URL are left out, as
+// the environment of a process is readable by others.
+func (c *Client) hookEnv(event string) []string {
+	env := []string{
+		"UBERTERM_EVENT=" + event,
+		"UBERTERM_URL=" + StripURLCredentials(c.URL),
+		"UBERTERM_HOST=" + c.Host(),
+		"UBERTERM_USER=" + c.Credentials().User,
+	}
+	if target, err := url.Parse(c.URL); err == nil {
+		origin := target.Scheme + "://" + target.Host
+		env = append(env, "UBERTERM_ORIGIN="+origin)
+		env = append(env, "UBERTERM_SESSION="+target.Query().Get("session"))
+		env = append(env, "UBERTERM_FREQ="+target.Query().Get("freq"))
+		env = append(env, "UBERTERM_MODE="+target.Query().Get("mode"))
+		// The web interface, e.g. for audio, of a registry instance, or the origin
+		publicURL := origin
+		if c.Instance != nil && c.Instance.PublicURL != "" {
+			publicURL = strings.TrimSuffix(StripURLCredentials(c.Instance.PublicURL), "/")
+		}
+		env = append(env, "UBERTERM_PUBLIC_URL="+publicURL)
+	}
+	return append(env, c.HookEnv...)
+}
+
+// RunLocalCommand runs a command for an event, e.g. LocalCommandPre, with the
+// system shell, sharing the terminal. env is added to the environment
+// describing the connection and overrides it.
+func (c *Client) RunLocalCommand(event, command string, env ...string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	if c.Stdio {
+		// Standard input and output carry the session
+		cmd.Stdin, cmd.Stdout = nil, os.Stderr
+	}
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(append(os.Environ(), c.hookEnv(event)...), env...)
+	logrus.Debugf("Running local %s command: %q", event, command)
+	return cmd.Run()
+}
@@ -0,0 +1,103 @@
+package gottyclient
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDialProxy(t *testing.T) {
+	Convey("Testing HTTP CONNECT proxy tunneling", t, func() {
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("tunneled"))
+		}))
+		defer target.Close()
+
+		var connectHeader http.Header
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			connectHeader = r.Header.Clone()
+			if r.Method != "CONNECT" || r.Header.Get("Proxy-Authorization") == "" {
+				w.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+			upstream, err := net.Dial("tcp", r.Host)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			conn, buf, _ := w.(http.Hijacker).Hijack()
+			go io.Copy(upstream, buf)
+			go func() {
+				io.Copy(conn, upstream)
+				conn.Close()
+			}()
+		}))
+		defer proxy.Close()
+
+		client := &Client{
+			ProxyURL:     "http://alice:secret@" + proxy.Listener.Addr().String(),
+			ProxyHeaders: http.Header{"X-Corp-Token": {"abc"}},
+		}
+		conn, err := client.dialProxy(context.Background(), "tcp", target.Listener.Addr().String())
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		So(connectHeader.Get("Proxy-Authorization"), ShouldEqual, "Basic YWxpY2U6c2VjcmV0")
+		So(connectHeader.Get("X-Corp-Token"), ShouldEqual, "abc")
+
+		req, _ := http.NewRequest("GET", target.URL, nil)
+		So(req.Write(conn), ShouldBeNil)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+		So(err, ShouldBeNil)
+		body, _ := io.ReadAll(resp.Body)
+		So(string(body), ShouldEqual, "tunneled")
+
+		Convey("Credentials are required by the proxy", func() {
+			client.ProxyURL = proxy.URL
+			_, err := client.dialProxy(context.Background(), "tcp", target.Listener.Addr().String())
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestParseProxyHeaders(t *testing.T) {
+	Convey("Testing proxy header parsing", t, func() {
+		Convey("Names and values are trimmed, repeated names kept", func() {
+			header, err := ParseProxyHeaders([]string{"X-Tenant: club", "proxy-authorization:Bearer abc", "X-Tenant:  lab  ", "X-Empty:"})
+			So(err, ShouldBeNil)
+			So(header, ShouldResemble, http.Header{
+				"X-Tenant":            {"club", "lab"},
+				"Proxy-Authorization": {"Bearer abc"},
+				"X-Empty":             {""},
+			})
+		})
+
+		Convey("Values may contain colons", func() {
+			header, err := ParseProxyHeaders([]string{"X-Forwarded-For: [::1]:8080"})
+			So(err, ShouldBeNil)
+			So(header.Get("X-Forwarded-For"), ShouldEqual, "[::1]:8080")
+		})
+
+		Convey("Empty input gives no headers", func() {
+			for _, pairs := range [][]string{nil, {}} {
+				header, err := ParseProxyHeaders(pairs)
+				So(err, ShouldBeNil)
+				So(header, ShouldBeEmpty)
+			}
+		})
+
+		Convey("Malformed pairs are errors", func() {
+			for _, pair := range []string{"", "X-Tenant", ": club", "  : club", "X Tenant: club", "X-Tenant: club\r\nX-Admin: 1"} {
+				_, err := ParseProxyHeaders([]string{"X-Ok: 1", pair})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "expected \"Name: value\"")
+			}
+		})
+	})
+}
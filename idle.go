@@ -0,0 +1,262 @@
+package gottyclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// IdleReportInterval is how often Loop writes the session's activity to
+// ActivityPath; attachment files not updated for three intervals are taken
+// to be left over by a client that crashed
+const IdleReportInterval = 15 * time.Second
+
+// Activity is when a client attached to its session and last received
+// output from it or sent it typed input; keepalive input does not count
+type Activity struct {
+	Attached   time.Time `json:"attached"`
+	LastOutput time.Time `json:"last_output"`
+	LastInput  time.Time `json:"last_input"`
+}
+
+// Last returns the latest of the attachment, output and input times
+func (a Activity) Last() time.Time {
+	last := a.Attached
+	for _, t := range []time.Time{a.LastOutput, a.LastInput} {
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// Idle returns how long the session had neither output nor input at now
+func (a Activity) Idle(now time.Time) time.Duration {
+	if a.Last().IsZero() {
+		return 0
+	}
+	return now.Sub(a.Last())
+}
+
+// idleHook is a callback registered with OnIdle
+type idleHook struct {
+	threshold time.Duration
+	fn        func(Activity)
+	fired     bool
+}
+
+// Activity returns the activity of the session attached to by Loop
+func (c *Client) Activity() Activity {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	return c.activity
+}
+
+// OnIdle registers fn to be called by Loop once the session has had neither
+// output nor typed input for threshold, e.g. to restart a stuck decoder. It
+// is called once per silence, from a goroutine of Loop, and must not block.
+func (c *Client) OnIdle(threshold time.Duration, fn func(Activity)) {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	c.idleHooks = append(c.idleHooks, &idleHook{threshold: threshold, fn: fn})
+}
+
+// touchOutput records that output was received
+func (c *Client) touchOutput() {
+	c.statsMutex.Lock()
+	c.activity.LastOutput = time.Now()
+	c.statsMutex.Unlock()
+}
+
+// touchTyped records that typed input was sent
+func (c *Client) touchTyped() {
+	c.statsMutex.Lock()
+	c.activity.LastInput = time.Now()
+	c.statsMutex.Unlock()
+}
+
+// idleTick returns how often idleLoop checks the hooks and reports activity
+func (c *Client) idleTick() time.Duration {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	tick := time.Duration(0)
+	if c.ActivityPath != "" {
+		tick = IdleReportInterval
+	}
+	for _, hook := range c.idleHooks {
+		if tick == 0 || hook.threshold/4 < tick {
+			tick = hook.threshold / 4
+		}
+	}
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	return tick
+}
+
+// checkIdle calls the hooks whose threshold the silence reached at now,
+// re-arming those of hooks the session was active again for
+func (c *Client) checkIdle(now time.Time) {
+	c.statsMutex.Lock()
+	activity := c.activity
+	var due []*idleHook
+	for _, hook := range c.idleHooks {
+		if activity.Idle(now) < hook.threshold {
+			hook.fired = false
+		} else if !hook.fired {
+			hook.fired = true
+			due = append(due, hook)
+		}
+	}
+	c.statsMutex.Unlock()
+
+	for _, hook := range due {
+		hook.fn(activity)
+	}
+}
+
+// idleLoop runs the OnIdle hooks and keeps ActivityPath up to date until
+// poisoned, then removes it
+func (c *Client) idleLoop(wg *sync.WaitGroup) poisonReason {
+	defer wg.Done()
+	fname := "idleLoop"
+
+	if c.ActivityPath != "" {
+		defer os.Remove(c.ActivityPath)
+	}
+	ticker := time.NewTicker(c.idleTick())
+	defer ticker.Stop()
+
+	var reported time.Time
+	for {
+		now := time.Now()
+		c.checkIdle(now)
+		if c.ActivityPath != "" && now.Sub(reported) >= IdleReportInterval {
+			if err := c.reportActivity(now); err != nil {
+				logrus.Debugf("Failed to report activity: %v", err)
+			}
+			reported = now
+		}
+
+		select {
+		case <-c.poison:
+			return die(fname, c.poison)
+		case <-ticker.C:
+		}
+	}
+}
+
+// AttachmentActivity is the activity of a session attached to by a client
+// on this machine, as written to its ActivityPath
+type AttachmentActivity struct {
+	Host string `json:"host"`
+	// Session is the attached session's name, Window the window name of a
+	// session created without one
+	Session string `json:"session,omitempty"`
+	Window  string `json:"window,omitempty"`
+	PID     int    `json:"pid"`
+	Activity
+	Updated time.Time `json:"updated"`
+}
+
+// GetDefaultAttachmentDir returns where attached clients write their
+// activity by default, one file per process
+func GetDefaultAttachmentDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gotty-client", "attachments")
+}
+
+// reportActivity writes the session's activity to ActivityPath
+func (c *Client) reportActivity(now time.Time) error {
+	query, err := GetURLQuery(c.URL)
+	if err != nil {
+		return err
+	}
+	attachment := AttachmentActivity{
+		Host:     c.Host(),
+		Session:  query.Get("session"),
+		Window:   query.Get("name"),
+		PID:      os.Getpid(),
+		Activity: c.Activity(),
+		Updated:  now,
+	}
+	data, err := json.MarshalIndent(attachment, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.ActivityPath), 0700); err != nil {
+		return fmt.Errorf("failed to create attachment directory: %v", err)
+	}
+	return writeFileAtomic(c.ActivityPath, 0600, func(writer *bufio.Writer) error {
+		_, err := writer.Write(data)
+		return err
+	})
+}
+
+// LoadAttachments reads the activity written by the clients attached from
+// this machine into dir, removing files left over by crashed clients
+func LoadAttachments(dir string) ([]AttachmentActivity, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read attachments: %v", err)
+	}
+
+	var attachments []AttachmentActivity
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var attachment AttachmentActivity
+		if err := json.Unmarshal(data, &attachment); err != nil {
+			logrus.Debugf("Skipping attachment %s: %v", file.Name(), err)
+			continue
+		}
+		if time.Since(attachment.Updated) > 3*IdleReportInterval {
+			os.Remove(path)
+			continue
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// FindAttachment returns the most recently active attachment to a session
+// of host, matched by name or, for sessions created without one, window
+// name; nil if no client on this machine is attached to it
+func FindAttachment(attachments []AttachmentActivity, host string, session SessionInfo) *AttachmentActivity {
+	var found *AttachmentActivity
+	for i := range attachments {
+		attachment := &attachments[i]
+		if attachment.Host != host {
+			continue
+		}
+		matches := attachment.Session == session.Name ||
+			(attachment.Session == "" && attachment.Window != "" && attachment.Window == session.WindowName)
+		if matches && (found == nil || attachment.Last().After(found.Last())) {
+			found = attachment
+		}
+	}
+	return found
+}
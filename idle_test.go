@@ -0,0 +1,86 @@
+package gottyclient
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIdle(t *testing.T) {
+	Convey("Testing idle detection", t, func() {
+		client, err := NewClient("http://localhost:8080/?session=ft8&name=decoder")
+		So(err, ShouldBeNil)
+		transport := &fakeTransport{}
+		client.Transport = transport
+		client.message = newMessageType(true)
+		client.Output = &bytes.Buffer{}
+		client.activity = Activity{Attached: time.Now()}
+
+		Convey("Hooks fire once per silence", func() {
+			var fired int32
+			client.OnIdle(40*time.Millisecond, func(activity Activity) {
+				atomic.AddInt32(&fired, 1)
+			})
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			go client.idleLoop(wg)
+			defer func() {
+				close(client.poison)
+				wg.Wait()
+			}()
+
+			time.Sleep(120 * time.Millisecond)
+			So(atomic.LoadInt32(&fired), ShouldEqual, 1)
+
+			client.handleMessage(OutputMessage{Data: []byte("decoded\r\n")})
+			So(client.Activity().Idle(time.Now()), ShouldBeLessThan, 40*time.Millisecond)
+			time.Sleep(120 * time.Millisecond)
+			So(atomic.LoadInt32(&fired), ShouldEqual, 2)
+		})
+
+		Convey("Typed input counts as activity, keepalives do not", func() {
+			So(client.sendInput([]byte("a")), ShouldBeNil)
+			So(client.Activity().LastInput.IsZero(), ShouldBeFalse)
+
+			keepalive := &Client{}
+			keepalive.touchInput()
+			So(keepalive.Activity().LastInput.IsZero(), ShouldBeTrue)
+		})
+
+		Convey("Attachments are reported and found by session", func() {
+			dir := t.TempDir()
+			client.ActivityPath = filepath.Join(dir, "1234.json")
+			now := time.Now()
+			client.activity = Activity{Attached: now.Add(-time.Hour), LastOutput: now.Add(-time.Minute)}
+			So(client.reportActivity(now), ShouldBeNil)
+
+			// Left over by a crashed client
+			So(os.WriteFile(filepath.Join(dir, "99.json"), []byte(`{"host":"localhost:8080","session":"old","updated":"2000-01-01T00:00:00Z"}`), 0600), ShouldBeNil)
+
+			attachments, err := LoadAttachments(dir)
+			So(err, ShouldBeNil)
+			So(len(attachments), ShouldEqual, 1)
+			So(attachments[0].Session, ShouldEqual, "ft8")
+			So(attachments[0].PID, ShouldEqual, os.Getpid())
+			_, err = os.Stat(filepath.Join(dir, "99.json"))
+			So(os.IsNotExist(err), ShouldBeTrue)
+
+			found := FindAttachment(attachments, "localhost:8080", SessionInfo{Name: "ft8"})
+			So(found, ShouldNotBeNil)
+			So(found.Idle(now), ShouldEqual, time.Minute)
+			So(FindAttachment(attachments, "localhost:8080", SessionInfo{Name: "old"}), ShouldBeNil)
+			So(FindAttachment(attachments, "other:8080", SessionInfo{Name: "ft8"}), ShouldBeNil)
+
+			// Sessions created without a name are matched by window name
+			attachments[0].Session = ""
+			So(FindAttachment(attachments, "localhost:8080", SessionInfo{Name: "s3", WindowName: "decoder"}), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,144 @@
+package gottyclient
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultInputHistory is the number of lines an InputHistory keeps when Max
+// is zero
+const DefaultInputHistory = 500
+
+// InputHistory is the lines entered in line mode, oldest first, optionally
+// kept in a file so that they can be recalled in later sessions
+type InputHistory struct {
+	Max int
+
+	path  string
+	mutex sync.Mutex
+	lines []string
+}
+
+// GetDefaultInputHistoryPath returns the default input history file of a
+// host, one per host so commands are recalled on the SDR they are for
+func GetDefaultInputHistoryPath(host string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, host)
+	return filepath.Join(home, ".gotty-client", "history", name)
+}
+
+// LoadInputHistory loads the input history kept at path, empty if the file
+// does not exist; an empty path keeps it in memory only
+func LoadInputHistory(path string) (*InputHistory, error) {
+	history := &InputHistory{path: path}
+	if path == "" {
+		return history, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return nil, fmt.Errorf("failed to read input history: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			history.lines = append(history.lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input history: %v", err)
+	}
+	// The file only grows as lines are added, so trim it on loading
+	if max := history.max(); len(history.lines) > max {
+		history.lines = history.lines[len(history.lines)-max:]
+		if err := history.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+func (h *InputHistory) max() int {
+	if h.Max == 0 {
+		return DefaultInputHistory
+	}
+	return h.Max
+}
+
+// Lines returns the lines, oldest first
+func (h *InputHistory) Lines() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]string(nil), h.lines...)
+}
+
+// Add appends a line unless it is empty or repeats the last one, and adds
+// it to the file
+func (h *InputHistory) Add(line string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if line == "" || strings.ContainsAny(line, "\r\n") || (len(h.lines) > 0 && h.lines[len(h.lines)-1] == line) {
+		return nil
+	}
+	h.lines = append(h.lines, line)
+	if len(h.lines) > h.max() {
+		h.lines = h.lines[len(h.lines)-h.max():]
+	}
+	if h.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return fmt.Errorf("failed to create input history directory: %v", err)
+	}
+	file, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write input history: %v", err)
+	}
+	defer file.Close()
+	_, err = fmt.Fprintln(file, line)
+	return err
+}
+
+// rewrite replaces the file with the lines kept
+func (h *InputHistory) rewrite() error {
+	return writeFileAtomic(h.path, 0600, func(writer *bufio.Writer) error {
+		for _, line := range h.lines {
+			if _, err := fmt.Fprintln(writer, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Search returns the index of the latest line before index before that
+// contains query, or -1
+func (h *InputHistory) Search(query string, before int) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if before > len(h.lines) {
+		before = len(h.lines)
+	}
+	for i := before - 1; i >= 0; i-- {
+		if strings.Contains(h.lines[i], query) {
+			return i
+		}
+	}
+	return -1
+}
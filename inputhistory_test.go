@@ -0,0 +1,68 @@
+package gottyclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInputHistory(t *testing.T) {
+	Convey("Testing the input history", t, func() {
+		path := filepath.Join(t.TempDir(), "history", "sdr.example.org_8073")
+
+		Convey("Lines are kept per host across sessions", func() {
+			So(filepath.Base(GetDefaultInputHistoryPath("sdr.example.org:8073")), ShouldEqual, "sdr.example.org_8073")
+
+			history, err := LoadInputHistory(path)
+			So(err, ShouldBeNil)
+			So(history.Lines(), ShouldBeEmpty)
+			So(history.Add("freq 7074000"), ShouldBeNil)
+			So(history.Add("freq 7074000"), ShouldBeNil)
+			So(history.Add(""), ShouldBeNil)
+			So(history.Add("mode usb"), ShouldBeNil)
+
+			history, err = LoadInputHistory(path)
+			So(err, ShouldBeNil)
+			So(history.Lines(), ShouldResemble, []string{"freq 7074000", "mode usb"})
+			So(history.Search("freq", 2), ShouldEqual, 0)
+			So(history.Search("freq", 0), ShouldEqual, -1)
+		})
+
+		Convey("The file is trimmed to Max lines on loading", func() {
+			history, _ := LoadInputHistory(path)
+			for i := 0; i < DefaultInputHistory+2; i++ {
+				So(history.Add(strconv.Itoa(i)), ShouldBeNil)
+			}
+			history, err := LoadInputHistory(path)
+			So(err, ShouldBeNil)
+			lines := history.Lines()
+			So(lines, ShouldHaveLength, DefaultInputHistory)
+			So(lines[0], ShouldEqual, "2")
+			data, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(strings.Count(string(data), "\n"), ShouldEqual, DefaultInputHistory)
+		})
+
+		Convey("The escape menu recalls recent lines", func() {
+			history, _ := LoadInputHistory("")
+			So(history.Add("status"), ShouldBeNil)
+			So(history.Add("freq 7074000"), ShouldBeNil)
+			var output bytes.Buffer
+			client := &Client{Output: &output, inputHistory: history}
+			client.lineEditor = NewLineEditor(&output, history)
+
+			client.handleEscapeMenuKey('h')
+			So(output.String(), ShouldContainSubstring, "1: freq 7074000")
+			So(output.String(), ShouldContainSubstring, "2: status")
+			So(client.menuPending, ShouldNotBeNil)
+			client.handleEscapeMenuKey('2')
+			So(client.menuPending, ShouldBeNil)
+			So(string(client.lineEditor.Feed([]byte("\r"))), ShouldEqual, "status\r")
+		})
+	})
+}
@@ -0,0 +1,68 @@
+package gottyclient
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// InputLogger records every byte sequence sent to the server as Input, one
+// timestamped line per message, so shared sessions can be audited afterwards.
+//
+// The transcript contains exactly what was typed, passwords included; it is
+// created with 0600 permissions and should be treated as sensitive.
+type InputLogger struct {
+	mutex    sync.Mutex
+	w        io.WriteCloser
+	Operator string
+}
+
+// NewInputLogger opens (or creates) path in append mode and returns a logger
+// writing to it. The local username is recorded as the operator on each line.
+func NewInputLogger(path string) (*InputLogger, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input log: %v", err)
+	}
+
+	operator := "unknown"
+	if u, err := user.Current(); err == nil {
+		operator = u.Username
+	}
+
+	return &InputLogger{w: file, Operator: operator}, nil
+}
+
+// Start writes a header line marking the beginning of a connection to target
+func (l *InputLogger) Start(target string) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, _ = fmt.Fprintf(l.w, "# %s %s connected to %s\n", time.Now().Format(time.RFC3339Nano), l.Operator, target)
+}
+
+// Log appends a single input record. Data is written Go-quoted so control
+// characters and escape sequences remain readable and unambiguous.
+func (l *InputLogger) Log(data []byte) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, _ = fmt.Fprintf(l.w, "%s\t%s\t%q\n", time.Now().Format(time.RFC3339Nano), l.Operator, data)
+}
+
+// Close closes the underlying log file
+func (l *InputLogger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.w.Close()
+}
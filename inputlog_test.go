@@ -0,0 +1,76 @@
+package gottyclient
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInputLogger(t *testing.T) {
+	Convey("Testing the input logger", t, func() {
+		path := filepath.Join(t.TempDir(), "input.log")
+
+		Convey("Input is appended as quoted, timestamped records", func() {
+			logger, err := NewInputLogger(path)
+			So(err, ShouldBeNil)
+			logger.Operator = "op"
+
+			logger.Start("https://sdr.example.com/terminal/")
+			logger.Log([]byte("ls -l\r"))
+			logger.Log([]byte("\x1b[A"))
+			So(logger.Close(), ShouldBeNil)
+
+			data, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			So(len(lines), ShouldEqual, 3)
+			So(lines[0], ShouldStartWith, "# ")
+			So(lines[0], ShouldEndWith, " op connected to https://sdr.example.com/terminal/")
+
+			fields := strings.Split(lines[1], "\t")
+			So(len(fields), ShouldEqual, 3)
+			So(fields[1], ShouldEqual, "op")
+			So(fields[2], ShouldEqual, `"ls -l\r"`)
+			So(strings.Split(lines[2], "\t")[2], ShouldEqual, `"\x1b[A"`)
+		})
+
+		Convey("The log is private and reopened in append mode", func() {
+			logger, err := NewInputLogger(path)
+			So(err, ShouldBeNil)
+			logger.Log([]byte("first"))
+			So(logger.Close(), ShouldBeNil)
+
+			logger, err = NewInputLogger(path)
+			So(err, ShouldBeNil)
+			logger.Log([]byte("second"))
+			So(logger.Close(), ShouldBeNil)
+
+			data, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, `"first"`)
+			So(string(data), ShouldContainSubstring, `"second"`)
+
+			info, err := os.Stat(path)
+			So(err, ShouldBeNil)
+			if runtime.GOOS != "windows" {
+				So(info.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+			}
+		})
+
+		Convey("A nil logger is a no-op", func() {
+			var logger *InputLogger
+			logger.Start("target")
+			logger.Log([]byte("x"))
+			So(logger.Close(), ShouldBeNil)
+		})
+
+		Convey("Opening fails when the directory does not exist", func() {
+			_, err := NewInputLogger(filepath.Join(t.TempDir(), "missing", "input.log"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
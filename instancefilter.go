@@ -0,0 +1,67 @@
+package gottyclient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// InstanceFilter selects instances from a listing. Zero values match everything.
+type InstanceFilter struct {
+	MinClientsFree int // only instances with at least this many free client slots
+}
+
+// Empty reports whether the filter matches every instance
+func (f InstanceFilter) Empty() bool {
+	return f.MinClientsFree <= 0
+}
+
+// Match reports whether an instance passes the filter
+func (f InstanceFilter) Match(instance Instance) bool {
+	return instance.AvailableClients >= f.MinClientsFree
+}
+
+// Filter returns a new listing with only the instances matching f
+func (r *InstanceListResponse) Filter(f InstanceFilter) *InstanceListResponse {
+	result := &InstanceListResponse{Instances: []Instance{}}
+	for _, instance := range r.Instances {
+		if f.Match(instance) {
+			result.Instances = append(result.Instances, instance)
+		}
+	}
+	result.Count = len(result.Instances)
+	return result
+}
+
+// Instance sort orders accepted by InstanceListResponse.Sort
+const (
+	SortByCallsign = "callsign"
+	SortBySNR      = "snr"
+	SortByClients  = "clients"
+)
+
+// Sort orders the instances in place: by callsign, by SNR (best 1.8-30 MHz
+// SNR first, then best 0-30 MHz SNR) or by free client slots (most first)
+func (r *InstanceListResponse) Sort(by string) error {
+	var less func(a, b Instance) bool
+	switch by {
+	case SortByCallsign:
+		less = func(a, b Instance) bool { return strings.ToUpper(a.Callsign) < strings.ToUpper(b.Callsign) }
+	case SortBySNR:
+		less = func(a, b Instance) bool {
+			if a.SNR1830MHz != b.SNR1830MHz {
+				return a.SNR1830MHz > b.SNR1830MHz
+			}
+			return a.SNR030MHz > b.SNR030MHz
+		}
+	case SortByClients:
+		less = func(a, b Instance) bool { return a.AvailableClients > b.AvailableClients }
+	default:
+		return fmt.Errorf("unknown sort order %q (expected %s, %s or %s)", by, SortByCallsign, SortBySNR, SortByClients)
+	}
+
+	sort.SliceStable(r.Instances, func(i, j int) bool {
+		return less(r.Instances[i], r.Instances[j])
+	})
+	return nil
+}
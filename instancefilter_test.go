@@ -0,0 +1,44 @@
+package gottyclient
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInstanceFilterAndSort(t *testing.T) {
+	Convey("Testing instance filtering and sorting", t, func() {
+		list := &InstanceListResponse{
+			Instances: []Instance{
+				{Callsign: "m9psy", AvailableClients: 1, SNR030MHz: 20, SNR1830MHz: 18},
+				{Callsign: "G4ABC", AvailableClients: 5, SNR030MHz: 25, SNR1830MHz: 22},
+				{Callsign: "K1XYZ", AvailableClients: 0, SNR030MHz: 30, SNR1830MHz: 18},
+			},
+			Count: 3,
+		}
+		callsigns := func() []string {
+			var result []string
+			for _, instance := range list.Instances {
+				result = append(result, instance.Callsign)
+			}
+			return result
+		}
+
+		Convey("Filter", func() {
+			So(InstanceFilter{}.Empty(), ShouldBeTrue)
+			So(list.Filter(InstanceFilter{}).Count, ShouldEqual, 3)
+			So(list.Filter(InstanceFilter{MinClientsFree: 1}).Count, ShouldEqual, 2)
+			So(list.Filter(InstanceFilter{MinClientsFree: 2}).Instances[0].Callsign, ShouldEqual, "G4ABC")
+		})
+
+		Convey("Sort", func() {
+			So(list.Sort(SortBySNR), ShouldBeNil)
+			So(callsigns(), ShouldResemble, []string{"G4ABC", "K1XYZ", "m9psy"})
+			So(list.Sort(SortByClients), ShouldBeNil)
+			So(callsigns(), ShouldResemble, []string{"G4ABC", "m9psy", "K1XYZ"})
+			So(list.Sort(SortByCallsign), ShouldBeNil)
+			So(callsigns(), ShouldResemble, []string{"G4ABC", "K1XYZ", "m9psy"})
+			So(list.Sort("noise"), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,200 @@
+//go:build integration
+// +build integration
+
+package gottyclient
+
+// End-to-end tests against real GoTTY servers running in Docker:
+//
+//	go test -tags integration -run Integration -v .
+//
+// The gotty 1.x and 2.x images are built from contrib/integration unless
+// GOTTY_V1_IMAGE or GOTTY_V2_IMAGE name prebuilt ones. The ubersdr-gotty
+// tests run when UBERSDR_GOTTY_IMAGE names an image serving on port 8080.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// docker runs a docker command, returning its trimmed output
+func docker(args ...string) (string, error) {
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func requireDocker(t *testing.T) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker not installed")
+	}
+	if _, err := docker("info"); err != nil {
+		t.Skipf("docker not available: %v", err)
+	}
+}
+
+// gottyImage returns the image named by env, or builds it from dockerfile
+func gottyImage(t *testing.T, env, dockerfile string) string {
+	if image := os.Getenv(env); image != "" {
+		return image
+	}
+	tag := "gotty-client-integration-" + strings.TrimSuffix(dockerfile, ".Dockerfile")
+	if _, err := docker("build", "-t", tag, "-f", "contrib/integration/"+dockerfile, "contrib/integration"); err != nil {
+		t.Fatal(err)
+	}
+	return tag
+}
+
+// startServer runs image and returns its URL once it answers
+func startServer(t *testing.T, image string, args ...string) string {
+	id, err := docker(append([]string{"run", "-d", "--rm", "-p", "127.0.0.1::8080", image}, args...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _, _ = docker("rm", "-f", id) })
+
+	addr, err := docker("port", id, "8080/tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "http://" + strings.Split(addr, "\n")[0] + "/"
+	for deadline := time.Now().Add(30 * time.Second); time.Now().Before(deadline); time.Sleep(200 * time.Millisecond) {
+		if resp, err := http.Get(target); err == nil {
+			resp.Body.Close()
+			return target
+		}
+	}
+	logs, _ := docker("logs", id)
+	t.Fatalf("%s did not start:\n%s", image, logs)
+	return ""
+}
+
+// terminal collects the output of a connected client
+type terminal struct {
+	client *Client
+	mutex  sync.Mutex
+	output bytes.Buffer
+}
+
+func connectTerminal(target string, v2 bool) (*terminal, error) {
+	client, err := NewClient(target)
+	if err != nil {
+		return nil, err
+	}
+	client.V2 = v2
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+	term := &terminal{client: client}
+	go func(transport Transport) {
+		for {
+			data, err := client.readFrame(transport)
+			if err != nil {
+				return
+			}
+			if message, err := ParseServerMessage(v2, data); err == nil {
+				if output, ok := message.(OutputMessage); ok {
+					term.mutex.Lock()
+					term.output.Write(output.Data)
+					term.mutex.Unlock()
+				}
+			}
+		}
+	}(client.transport())
+	return term, nil
+}
+
+func (t *terminal) resize(rows, columns uint16) error {
+	size, err := json.Marshal(WinSize{Rows: rows, Columns: columns})
+	if err != nil {
+		return err
+	}
+	return t.client.write(append([]byte{t.client.messages().resizeTerminal}, size...))
+}
+
+// waitFor returns whether the output contains s within a few seconds
+func (t *terminal) waitFor(s string) bool {
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		t.mutex.Lock()
+		found := strings.Contains(t.output.String(), s)
+		t.mutex.Unlock()
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIntegrationGotty(t *testing.T) {
+	requireDocker(t)
+	servers := []struct {
+		name string
+		url  string
+		v2   bool
+	}{
+		{"gotty 1.x", startServer(t, gottyImage(t, "GOTTY_V1_IMAGE", "gotty-v1.Dockerfile")), false},
+		{"gotty 2.x", startServer(t, gottyImage(t, "GOTTY_V2_IMAGE", "gotty-v2.Dockerfile")), true},
+	}
+
+	for _, server := range servers {
+		Convey("Testing against "+server.name, t, func() {
+			term, err := connectTerminal(server.url, server.v2)
+			So(err, ShouldBeNil)
+			defer term.client.Close()
+			So(term.client.IsConnected(), ShouldBeTrue)
+
+			Convey("Input reaches the shell and its output comes back", func() {
+				So(term.client.sendInput([]byte("echo integration-$((6*7))\r")), ShouldBeNil)
+				So(term.waitFor("integration-42"), ShouldBeTrue)
+			})
+
+			Convey("The terminal size follows resizes", func() {
+				So(term.resize(40, 100), ShouldBeNil)
+				So(term.client.sendInput([]byte("stty size\r")), ShouldBeNil)
+				So(term.waitFor("40 100"), ShouldBeTrue)
+			})
+		})
+	}
+}
+
+func TestIntegrationUberSDRGotty(t *testing.T) {
+	image := os.Getenv("UBERSDR_GOTTY_IMAGE")
+	if image == "" {
+		t.Skip("UBERSDR_GOTTY_IMAGE not set")
+	}
+	requireDocker(t)
+	target := startServer(t, image)
+
+	Convey("Testing against ubersdr-gotty", t, func() {
+		session := fmt.Sprintf("it%d", time.Now().Unix()%100000)
+		term, err := connectTerminal(target+"?session="+session+"&name="+session, true)
+		So(err, ShouldBeNil)
+		defer term.client.Close()
+
+		So(term.client.sendInput([]byte("echo integration-$((6*7))\r")), ShouldBeNil)
+		So(term.waitFor("integration-42"), ShouldBeTrue)
+
+		Convey("The session is listed and can be destroyed", func() {
+			sessions, err := term.client.ListSessions()
+			So(err, ShouldBeNil)
+			So(sessions.Find(session), ShouldNotBeNil)
+
+			_, err = term.client.DestroySession(session)
+			So(err, ShouldBeNil)
+			sessions, err = term.client.ListSessions()
+			So(err, ShouldBeNil)
+			So(sessions.Find(session), ShouldBeNil)
+		})
+	})
+}
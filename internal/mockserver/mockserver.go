@@ -0,0 +1,236 @@
+// Package mockserver provides an in-process GoTTY 2.x server for the bench
+// command and the tests, so they can run without a real server.
+package mockserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// GoTTY 2.x message types, as in the gottyclient package
+const (
+	output         = '1'
+	pong           = '2'
+	setWindowTitle = '3'
+	input          = '1'
+	ping           = '2'
+)
+
+// FloodLine is the line a Server streams for the "yes" command
+var FloodLine = []byte("y\r\n")
+
+// FrameSize is the size of the output frames a Server streams, close to
+// what a pty read returns
+const FrameSize = 4096
+
+// Server is an in-process GoTTY 2.x server. It echoes input back as output
+// and, when "yes" is entered, streams lines until interrupted with ^C. It
+// also implements the session write lock API.
+type Server struct {
+	// URL is the terminal URL to connect to
+	URL string
+
+	listener net.Listener
+	server   *http.Server
+	mutex    sync.Mutex
+	locks    map[string]string
+	sessions map[*websocket.Conn]bool
+}
+
+// lockInfo is the body of the session write lock API
+type lockInfo struct {
+	Session string `json:"session"`
+	Holder  string `json:"holder,omitempty"`
+}
+
+// New starts a Server listening on the loopback interface
+func New() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("mockserver: failed to listen: " + err.Error())
+	}
+	upgrader := websocket.Upgrader{}
+	s := &Server{listener: listener, locks: map[string]string{}, sessions: map[*websocket.Conn]bool{}}
+	s.server = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "auth_token.js") {
+			w.Write([]byte("var gotty_auth_token = '';"))
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/api/sessions/lock") {
+			s.serveLock(w, r)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		s.track(conn, true)
+		defer s.track(conn, false)
+		defer conn.Close()
+		newSession(conn).serve()
+	})}
+	go s.server.Serve(listener)
+	s.URL = "http://" + listener.Addr().String() + "/"
+	return s
+}
+
+// track adds or removes a websocket connection Close has to close, as the
+// http.Server forgets connections once they are hijacked
+func (s *Server) track(conn *websocket.Conn, add bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if add {
+		s.sessions[conn] = true
+	} else {
+		delete(s.sessions, conn)
+	}
+}
+
+// Close shuts the server down and closes its connections
+func (s *Server) Close() {
+	s.server.Close()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for conn := range s.sessions {
+		conn.Close()
+	}
+}
+
+// serveLock grants, reports and releases session write locks, refusing to
+// hand a held lock to another holder
+func (s *Server) serveLock(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	name := r.URL.Query().Get("name")
+	holder := r.URL.Query().Get("holder")
+	status := http.StatusOK
+	switch r.Method {
+	case http.MethodPost:
+		if current := s.locks[name]; current != "" && current != holder {
+			status = http.StatusConflict
+		} else {
+			s.locks[name] = holder
+		}
+	case http.MethodDelete:
+		if s.locks[name] == holder {
+			delete(s.locks, name)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(lockInfo{Session: name, Holder: s.locks[name]})
+}
+
+// session is one websocket connection to a Server
+type session struct {
+	conn     *websocket.Conn
+	mutex    sync.Mutex
+	line     []byte
+	flooding chan struct{}
+}
+
+func newSession(conn *websocket.Conn) *session {
+	return &session{conn: conn}
+}
+
+func (s *session) write(kind byte, payload []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.conn.WriteMessage(websocket.TextMessage, append([]byte{kind}, payload...))
+}
+
+func (s *session) output(data []byte) error {
+	return s.write(output, []byte(base64.StdEncoding.EncodeToString(data)))
+}
+
+func (s *session) serve() {
+	defer s.stopFlood()
+	// The first message carries the auth token and arguments
+	if _, _, err := s.conn.ReadMessage(); err != nil {
+		return
+	}
+	// Like GoTTY, answer it by setting the window title
+	if err := s.write(setWindowTitle, []byte("mock")); err != nil {
+		return
+	}
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil || len(data) == 0 {
+			return
+		}
+		switch data[0] {
+		case ping:
+			err = s.write(pong, nil)
+		case input:
+			err = s.input(data[1:])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// input echoes data like a terminal in cooked mode and runs entered lines
+func (s *session) input(data []byte) error {
+	for _, b := range data {
+		switch b {
+		case 0x03:
+			s.stopFlood()
+			s.line = s.line[:0]
+			if err := s.output([]byte("^C\r\n")); err != nil {
+				return err
+			}
+		case 0x15:
+			s.line = s.line[:0]
+		case '\r', '\n':
+			if err := s.output([]byte("\r\n")); err != nil {
+				return err
+			}
+			if strings.TrimSpace(string(s.line)) == "yes" {
+				s.startFlood()
+			}
+			s.line = s.line[:0]
+		default:
+			s.line = append(s.line, b)
+			if err := s.output([]byte{b}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *session) startFlood() {
+	if s.flooding != nil {
+		return
+	}
+	stop := make(chan struct{})
+	s.flooding = stop
+	frame := bytes.Repeat(FloodLine, FrameSize/len(FloodLine))
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := s.output(frame); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func (s *session) stopFlood() {
+	if s.flooding != nil {
+		close(s.flooding)
+		s.flooding = nil
+	}
+}
@@ -0,0 +1,129 @@
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// OutputFormat selects how a Table is printed: as aligned columns (plain),
+// without truncation (wide), as JSON or CSV, or through a Go template
+type OutputFormat string
+
+const (
+	// FormatPlain prints aligned columns fitted to the terminal
+	FormatPlain OutputFormat = "plain"
+	// FormatWide prints every column without truncating
+	FormatWide OutputFormat = "wide"
+	// FormatJSON prints the items as an indented JSON array
+	FormatJSON OutputFormat = "json"
+	// FormatCSV prints the rows with a header of column names
+	FormatCSV OutputFormat = "csv"
+)
+
+// templateFuncs are available in --format templates
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v interface{}) (string, error) {
+		buf, err := json.Marshal(v)
+		return string(buf), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// parseTemplate parses a --format template. Unknown map keys are errors
+// rather than "<no value>", so typos are noticed.
+func parseTemplate(s string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(templateFuncs).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// ParseOutputFormat parses a --format value; anything containing "{{" is a
+// Go template executed once per item, e.g. '{{.Callsign}} {{.PublicURL}}'
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	if strings.Contains(s, "{{") {
+		if _, err := parseTemplate(s); err != nil {
+			return "", err
+		}
+		return OutputFormat(s), nil
+	}
+	switch format := OutputFormat(strings.ToLower(strings.TrimSpace(s))); format {
+	case "", "table":
+		return FormatPlain, nil
+	case FormatPlain, FormatWide, FormatJSON, FormatCSV:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid output format %q (expected plain, wide, json, csv or a Go template)", s)
+}
+
+// Tabular reports whether the format prints aligned columns for people,
+// rather than output meant for scripts
+func (f OutputFormat) Tabular() bool {
+	return f == FormatPlain || f == FormatWide
+}
+
+// RenderFormat writes the table in the given format. JSON and templates use
+// the table's Items when set, so they see every field rather than only the
+// columns shown.
+func (t *Table) RenderFormat(w io.Writer, format OutputFormat) error {
+	switch format {
+	case FormatPlain:
+		t.Render(w)
+		return nil
+	case FormatWide:
+		width := t.Width
+		t.Width = 0
+		t.Render(w)
+		t.Width = width
+		return nil
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(t.items())
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(t.ColumnNames()); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(t.Rows); err != nil {
+			return err
+		}
+		return writer.Error()
+	}
+
+	tmpl, err := parseTemplate(string(format))
+	if err != nil {
+		return err
+	}
+	for _, item := range t.items() {
+		if err := tmpl.Execute(w, item); err != nil {
+			return fmt.Errorf("format template: %v", err)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// items returns the values behind the rows: Items, or a map of column
+// names to cells for tables without them
+func (t *Table) items() []interface{} {
+	if t.Items != nil {
+		return t.Items
+	}
+	items := make([]interface{}, len(t.Rows))
+	for r, row := range t.Rows {
+		item := make(map[string]string, len(t.Columns))
+		for i, column := range t.Columns {
+			item[column.Name] = row[i]
+		}
+		items[r] = item
+	}
+	return items
+}
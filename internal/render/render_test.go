@@ -0,0 +1,86 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOutputFormat(t *testing.T) {
+	Convey("Testing output formats", t, func() {
+		newTable := func() *Table {
+			return &Table{
+				Columns: []TableColumn{
+					{Name: "callsign", Header: "CALLSIGN"},
+					{Name: "name", Header: "NAME", MinWidth: 4, Flexible: true},
+				},
+				Rows: [][]string{
+					{"M0ABC", "Reading, UK, websdr"},
+					{"N0CALL", "Kansas"},
+				},
+				Width: 16,
+			}
+		}
+		render := func(table *Table, format string) string {
+			parsed, err := ParseOutputFormat(format)
+			So(err, ShouldBeNil)
+			var buf bytes.Buffer
+			So(table.RenderFormat(&buf, parsed), ShouldBeNil)
+			return buf.String()
+		}
+
+		Convey("Formats are parsed", func() {
+			format, err := ParseOutputFormat("")
+			So(err, ShouldBeNil)
+			So(format, ShouldEqual, FormatPlain)
+			format, err = ParseOutputFormat("JSON")
+			So(err, ShouldBeNil)
+			So(format, ShouldEqual, FormatJSON)
+			So(format.Tabular(), ShouldBeFalse)
+			_, err = ParseOutputFormat("yaml")
+			So(err, ShouldNotBeNil)
+			_, err = ParseOutputFormat("{{.Callsign")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Plain output fits the width, wide output does not truncate", func() {
+			So(render(newTable(), "plain"), ShouldContainSubstring, "M0ABC    Read...")
+			So(render(newTable(), "wide"), ShouldContainSubstring, "M0ABC    Reading, UK, websdr")
+		})
+
+		Convey("CSV quotes cells as needed", func() {
+			So(render(newTable(), "csv"), ShouldEqual, "callsign,name\nM0ABC,\"Reading, UK, websdr\"\nN0CALL,Kansas\n")
+		})
+
+		Convey("JSON and templates use the items behind the rows", func() {
+			type instance struct {
+				Callsign   string `json:"callsign"`
+				PublicURL  string `json:"public_url"`
+				SNR1830MHz int    `json:"snr_18_30_mhz"`
+				TLS        bool   `json:"tls"`
+			}
+			table := newTable()
+			table.Items = []interface{}{
+				instance{Callsign: "M0ABC", PublicURL: "https://m0abc.example"},
+				instance{Callsign: "N0CALL", PublicURL: "https://n0call.example"},
+			}
+			So(render(table, "{{.Callsign}} {{.PublicURL}}"), ShouldEqual, "M0ABC https://m0abc.example\nN0CALL https://n0call.example\n")
+			So(render(table, `{{.Callsign}} {{.SNR1830MHz}}{{if .TLS}} tls{{end}}`), ShouldEqual, "M0ABC 0\nN0CALL 0\n")
+
+			format, err := ParseOutputFormat("{{.Snr}}")
+			So(err, ShouldBeNil)
+			So(table.RenderFormat(&bytes.Buffer{}, format), ShouldNotBeNil)
+			So(render(table, "json"), ShouldContainSubstring, `"public_url": "https://n0call.example"`)
+		})
+
+		Convey("Without items, columns are the fields", func() {
+			So(render(newTable(), "{{.callsign | lower}}"), ShouldEqual, "m0abc\nn0call\n")
+			So(render(&Table{Columns: newTable().Columns}, "json"), ShouldEqual, "[]\n")
+
+			format, err := ParseOutputFormat("{{.snr}}")
+			So(err, ShouldBeNil)
+			So(newTable().RenderFormat(&bytes.Buffer{}, format), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,212 @@
+// Package render prints lists as tables for people or as JSON, CSV or
+// templates for scripts, as chosen with the command line's --format.
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// ColorMode controls whether tables are colored
+type ColorMode string
+
+const (
+	// ColorAuto colors output to terminals unless NO_COLOR is set
+	ColorAuto ColorMode = "auto"
+	// ColorAlways colors output even when piped
+	ColorAlways ColorMode = "always"
+	// ColorNever disables colors
+	ColorNever ColorMode = "never"
+)
+
+// ParseColorMode parses a --color value; empty means auto
+func ParseColorMode(s string) (ColorMode, error) {
+	switch mode := ColorMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return ColorAuto, nil
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	}
+	return ColorAuto, fmt.Errorf("invalid color mode %q (expected auto, always or never)", s)
+}
+
+// Enabled reports whether to color output going to a terminal or not
+func (m ColorMode) Enabled(terminal bool) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return terminal && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+}
+
+// ANSI styles used in tables
+const (
+	styleBold  = "\x1b[1m"
+	styleDim   = "\x1b[2m"
+	styleGreen = "\x1b[32m"
+	styleReset = "\x1b[0m"
+)
+
+// TableColumn describes one column of a Table
+type TableColumn struct {
+	// Name selects the column with --columns
+	Name   string
+	Header string
+	// MinWidth is the narrowest a Flexible column is shrunk to
+	MinWidth int
+	// Flexible columns are truncated when the table is wider than the terminal
+	Flexible bool
+	// Highlight, if set, returns whether a cell is shown in green
+	Highlight func(value string) bool
+}
+
+// Table renders rows as aligned columns sized to their content and, if
+// Width is set, shrunk to fit the terminal
+type Table struct {
+	Columns []TableColumn
+	Rows    [][]string
+	// Items optionally hold the value behind each row, for JSON and
+	// template output
+	Items []interface{}
+	// Width is the terminal width; 0 means unlimited, e.g. when piped
+	Width int
+	Color bool
+}
+
+// ColumnNames returns the names of the table's columns
+func (t *Table) ColumnNames() []string {
+	names := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// SelectColumns keeps only the named columns, in the given order
+func (t *Table) SelectColumns(names []string) error {
+	index := make(map[string]int)
+	for i, column := range t.Columns {
+		index[column.Name] = i
+	}
+
+	var columns []TableColumn
+	rows := make([][]string, len(t.Rows))
+	for _, name := range names {
+		i, ok := index[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(t.ColumnNames(), ","))
+		}
+		columns = append(columns, t.Columns[i])
+		for r, row := range t.Rows {
+			rows[r] = append(rows[r], row[i])
+		}
+	}
+	t.Columns = columns
+	t.Rows = rows
+	return nil
+}
+
+// columnWidths returns the width of each column, shrinking flexible
+// columns, widest first, until the table fits Width
+func (t *Table) columnWidths() []int {
+	widths := make([]int, len(t.Columns))
+	for i, column := range t.Columns {
+		widths[i] = utf8.RuneCountInString(column.Header)
+		for _, row := range t.Rows {
+			if n := utf8.RuneCountInString(row[i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	if t.Width <= 0 {
+		return widths
+	}
+
+	for tableWidth(widths) > t.Width {
+		widest := -1
+		for i, column := range t.Columns {
+			if column.Flexible && widths[i] > column.MinWidth && (widest < 0 || widths[i] > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		widths[widest]--
+	}
+	return widths
+}
+
+func tableWidth(widths []int) int {
+	total := 0
+	for _, width := range widths {
+		total += width
+	}
+	if len(widths) > 1 {
+		total += len(widths) - 1
+	}
+	return total
+}
+
+// Render writes the header, a separator and the rows
+func (t *Table) Render(w io.Writer) {
+	widths := t.columnWidths()
+
+	headers := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		headers[i] = column.Header
+	}
+	header := t.formatRow(headers, widths, nil)
+	separator := strings.Repeat("-", tableWidth(widths))
+	if t.Color {
+		header = styleBold + header + styleReset
+		separator = styleDim + separator + styleReset
+	}
+	fmt.Fprintln(w, header)
+	fmt.Fprintln(w, separator)
+
+	for _, row := range t.Rows {
+		fmt.Fprintln(w, t.formatRow(row, widths, t.highlight))
+	}
+}
+
+// highlight colors a cell if its column asks for it
+func (t *Table) highlight(column int, value, padded string) string {
+	if !t.Color || t.Columns[column].Highlight == nil || !t.Columns[column].Highlight(value) {
+		return padded
+	}
+	return styleGreen + padded + styleReset
+}
+
+func (t *Table) formatRow(cells []string, widths []int, style func(column int, value, padded string) string) string {
+	parts := make([]string, len(cells))
+	for i, cell := range cells {
+		value := truncateCell(cell, widths[i])
+		padded := value
+		if i < len(cells)-1 {
+			padded += strings.Repeat(" ", widths[i]-utf8.RuneCountInString(value))
+		}
+		if style != nil {
+			padded = style(i, cell, padded)
+		}
+		parts[i] = padded
+	}
+	return strings.TrimRight(strings.Join(parts, " "), " ")
+}
+
+// truncateCell shortens s to width runes, marking the cut with "..."
+func truncateCell(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	if width <= 3 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-3]) + "..."
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTable(t *testing.T) {
+	Convey("Testing table rendering", t, func() {
+		newTable := func() *Table {
+			return &Table{
+				Columns: []TableColumn{
+					{Name: "name", Header: "NAME", MinWidth: 6, Flexible: true},
+					{Name: "attached", Header: "ATTACHED", Highlight: func(value string) bool { return value == "yes" }},
+					{Name: "window", Header: "WINDOW"},
+				},
+				Rows: [][]string{
+					{"ft8-monitor-40m", "yes", "bash"},
+					{"wspr", "no", "htop"},
+				},
+			}
+		}
+		render := func(table *Table) []string {
+			var buf bytes.Buffer
+			table.Render(&buf)
+			return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+		}
+
+		Convey("Columns are sized to their content", func() {
+			So(render(newTable()), ShouldResemble, []string{
+				"NAME            ATTACHED WINDOW",
+				"-------------------------------",
+				"ft8-monitor-40m yes      bash",
+				"wspr            no       htop",
+			})
+		})
+
+		Convey("Flexible columns shrink to the terminal width", func() {
+			table := newTable()
+			table.Width = 25
+			So(render(table), ShouldResemble, []string{
+				"NAME      ATTACHED WINDOW",
+				"-------------------------",
+				"ft8-mo... yes      bash",
+				"wspr      no       htop",
+			})
+			table = newTable()
+			table.Width = 5
+			So(render(table)[2], ShouldEqual, "ft8... yes      bash")
+		})
+
+		Convey("Columns can be selected and reordered", func() {
+			table := newTable()
+			So(table.SelectColumns([]string{"window", "Name"}), ShouldBeNil)
+			So(render(table)[2], ShouldEqual, "bash   ft8-monitor-40m")
+			So(newTable().SelectColumns([]string{"load"}), ShouldNotBeNil)
+		})
+
+		Convey("Colors", func() {
+			table := newTable()
+			table.Color = true
+			lines := render(table)
+			So(lines[0], ShouldStartWith, styleBold)
+			So(lines[2], ShouldContainSubstring, styleGreen+"yes     "+styleReset)
+			So(lines[3], ShouldNotContainSubstring, styleGreen)
+
+			mode, err := ParseColorMode("")
+			So(err, ShouldBeNil)
+			So(mode, ShouldEqual, ColorAuto)
+			So(ColorAlways.Enabled(false), ShouldBeTrue)
+			So(ColorNever.Enabled(true), ShouldBeFalse)
+			So(ColorAuto.Enabled(false), ShouldBeFalse)
+			_, err = ParseColorMode("rainbow")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,198 @@
+package gottyclient
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ParseJumpHost splits a [user@]host[:port] jump host specification,
+// defaulting to the local user and port 22
+func ParseJumpHost(spec string) (string, string, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return "", "", fmt.Errorf("empty jump host")
+	}
+
+	username := ""
+	if at := strings.LastIndex(spec, "@"); at >= 0 {
+		username, spec = spec[:at], spec[at+1:]
+	}
+	if username == "" {
+		current, err := user.Current()
+		if err != nil {
+			return "", "", fmt.Errorf("cannot determine SSH user for jump host: %v", err)
+		}
+		username = current.Username
+	}
+
+	addr := spec
+	if _, _, err := net.SplitHostPort(spec); err != nil {
+		addr = net.JoinHostPort(spec, "22")
+	}
+	return username, addr, nil
+}
+
+// sshAuthMethods returns the SSH agent and the unencrypted default key files
+// as authentication methods, along with a function closing the connection to
+// the agent once authentication is over
+func sshAuthMethods() ([]ssh.AuthMethod, func()) {
+	var methods []ssh.AuthMethod
+	closeAgent := func() {}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			closeAgent = func() { conn.Close() }
+		} else {
+			logrus.Debugf("Cannot reach SSH agent: %v", err)
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return methods, closeAgent
+	}
+	var signers []ssh.Signer
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		data, err := ioutil.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			logrus.Debugf("Skipping SSH key %s: %v", name, err)
+			continue
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	return methods, closeAgent
+}
+
+// jumpTimeout bounds connecting to the jump host when Client.RequestTimeout is unset
+const jumpTimeout = 30 * time.Second
+
+// jumpClient returns the SSH connection to c.JumpHost, connecting on first
+// use within ctx. The jump host key must be in ~/.ssh/known_hosts.
+func (c *Client) jumpClient(ctx context.Context) (*ssh.Client, error) {
+	c.jumpMutex.Lock()
+	defer c.jumpMutex.Unlock()
+	if c.jump != nil {
+		return c.jump, nil
+	}
+
+	username, addr, err := ParseJumpHost(c.JumpHost)
+	if err != nil {
+		return nil, err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ~/.ssh/known_hosts for jump host: %v", err)
+	}
+
+	timeout := jumpTimeout
+	if c.RequestTimeout > 0 {
+		timeout = c.RequestTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	logrus.Debugf("Connecting to jump host %s@%s", username, addr)
+	conn, err := c.netDialer().DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to jump host %s: %v", addr, err)
+	}
+
+	// The handshake has no context of its own: bound it with a deadline
+	// and abort it by closing the connection when ctx ends
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	handshakeDone := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-handshakeDone:
+		}
+	}()
+
+	auth, closeAgent := sshAuthMethods()
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	})
+	close(handshakeDone)
+	closeAgent()
+	if err == nil && ctx.Err() != nil {
+		sshConn.Close()
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to jump host %s: %v", addr, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	c.jump = ssh.NewClient(sshConn, chans, reqs)
+	return c.jump, nil
+}
+
+// dialJump opens a connection to addr tunneled through the jump host. The
+// address is resolved by the jump host.
+func (c *Client) dialJump(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := c.jumpClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	result := make(chan dialResult, 1)
+	go func() {
+		conn, err := client.Dial(network, addr)
+		result <- dialResult{conn, err}
+	}()
+	select {
+	case r := <-result:
+		return r.conn, r.err
+	case <-ctx.Done():
+		// Close the tunnel should it open after all
+		go func() {
+			if r := <-result; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// closeJump closes the SSH connection to the jump host, if any
+func (c *Client) closeJump() {
+	c.jumpMutex.Lock()
+	defer c.jumpMutex.Unlock()
+	if c.jump != nil {
+		c.jump.Close()
+		c.jump = nil
+	}
+}
@@ -0,0 +1,220 @@
+package gottyclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestParseJumpHost(t *testing.T) {
+	Convey("Testing ParseJumpHost", t, func() {
+		username, addr, err := ParseJumpHost("pi@bastion.example.com")
+		So(err, ShouldBeNil)
+		So(username, ShouldEqual, "pi")
+		So(addr, ShouldEqual, "bastion.example.com:22")
+
+		username, addr, err = ParseJumpHost("ops@[2001:db8::1]:2222")
+		So(err, ShouldBeNil)
+		So(username, ShouldEqual, "ops")
+		So(addr, ShouldEqual, "[2001:db8::1]:2222")
+
+		_, addr, err = ParseJumpHost("bastion")
+		So(err, ShouldBeNil)
+		So(addr, ShouldEqual, "bastion:22")
+
+		_, _, err = ParseJumpHost("")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+// startJumpHost runs an SSH server accepting any user and forwarding
+// direct-tcpip channels, and trusts its host key in a temporary HOME
+func startJumpHost(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveJumpConn(conn, config)
+		}
+	}()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	line := knownhosts.Line([]string{knownhosts.Normalize(listener.Addr().String())}, signer.PublicKey())
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return listener.Addr().String()
+}
+
+func serveJumpConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		// host string, port uint32, origin host string, origin port uint32
+		data := newChannel.ExtraData()
+		size := binary.BigEndian.Uint32(data)
+		host := string(data[4 : 4+size])
+		port := binary.BigEndian.Uint32(data[4+size:])
+		target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10)))
+		if err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			target.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go func() {
+			_, _ = io.Copy(channel, target)
+			channel.Close()
+		}()
+		go func() {
+			_, _ = io.Copy(target, channel)
+			target.Close()
+		}()
+	}
+}
+
+func TestDialJump(t *testing.T) {
+	Convey("Testing dialing through a jump host", t, func() {
+		jumpAddr := startJumpHost(t)
+
+		echo, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer echo.Close()
+		go func() {
+			for {
+				conn, err := echo.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					_, _ = io.Copy(conn, conn)
+					conn.Close()
+				}()
+			}
+		}()
+
+		client := &Client{JumpHost: "ops@" + jumpAddr}
+		defer client.closeJump()
+
+		Convey("Connections are tunneled to the target", func() {
+			conn, err := client.dialContext(context.Background(), "tcp", echo.Addr().String())
+			So(err, ShouldBeNil)
+			defer conn.Close()
+			_, err = conn.Write([]byte("ping"))
+			So(err, ShouldBeNil)
+			buf := make([]byte, 4)
+			_, err = io.ReadFull(conn, buf)
+			So(err, ShouldBeNil)
+			So(string(buf), ShouldEqual, "ping")
+		})
+
+		Convey("A cancelled context is honored", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := client.dialJump(ctx, "tcp", echo.Addr().String())
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("A silent jump host times out with the context", func() {
+			silent, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer silent.Close()
+			go func() {
+				for {
+					conn, err := silent.Accept()
+					if err != nil {
+						return
+					}
+					defer conn.Close()
+				}
+			}()
+
+			client := &Client{JumpHost: "ops@" + silent.Addr().String()}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			_, err = client.dialJump(ctx, "tcp", echo.Addr().String())
+			So(err, ShouldNotBeNil)
+			So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+		})
+
+		Convey("The agent connection is closed once authenticated", func() {
+			sock := filepath.Join(t.TempDir(), "agent.sock")
+			agentListener, err := net.Listen("unix", sock)
+			So(err, ShouldBeNil)
+			defer agentListener.Close()
+			served := make(chan struct{})
+			go func() {
+				conn, err := agentListener.Accept()
+				if err != nil {
+					return
+				}
+				_ = agent.ServeAgent(agent.NewKeyring(), conn)
+				close(served)
+			}()
+			t.Setenv("SSH_AUTH_SOCK", sock)
+
+			client := &Client{JumpHost: "ops@" + jumpAddr}
+			defer client.closeJump()
+			conn, err := client.dialJump(context.Background(), "tcp", echo.Addr().String())
+			So(err, ShouldBeNil)
+			conn.Close()
+
+			select {
+			case <-served:
+			case <-time.After(5 * time.Second):
+				t.Error("the agent connection was left open")
+			}
+		})
+	})
+}
@@ -0,0 +1,61 @@
+package gottyclient
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ParseKeepaliveData decodes a Go-escaped keepalive input such as \x00 or
+// true\r; the empty string is an empty Input message
+func ParseKeepaliveData(value string) ([]byte, error) {
+	data, err := strconv.Unquote(`"` + strings.Replace(value, `"`, `\"`, -1) + `"`)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(data), nil
+}
+
+// touchInput records that input was sent
+func (c *Client) touchInput() {
+	c.statsMutex.Lock()
+	c.lastInput = time.Now()
+	c.statsMutex.Unlock()
+}
+
+// inputIdle returns how long no input was sent
+func (c *Client) inputIdle() time.Duration {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	return time.Since(c.lastInput)
+}
+
+// keepaliveLoop sends KeepaliveData whenever no input was sent for
+// KeepaliveInput, so server-side idle timeouts spare unattended sessions
+func (c *Client) keepaliveLoop(wg *sync.WaitGroup) poisonReason {
+	defer wg.Done()
+	fname := "keepaliveLoop"
+
+	c.touchInput()
+	ticker := time.NewTicker(c.KeepaliveInput / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.poison:
+			return die(fname, c.poison)
+		case <-ticker.C:
+		}
+		if c.inputIdle() < c.KeepaliveInput || c.isReconnecting() || !c.HasControl() {
+			continue
+		}
+		logrus.Debugf("Sending keepalive input %q", c.KeepaliveData)
+		if err := c.write(append([]byte{c.messages().input}, c.KeepaliveData...)); err != nil {
+			logrus.Debugf("Keepalive input: %v", err)
+		}
+		c.touchInput()
+	}
+}
@@ -0,0 +1,65 @@
+package gottyclient
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKeepaliveInput(t *testing.T) {
+	Convey("Testing keepalive input", t, func() {
+		client, err := NewClient("http://localhost/")
+		So(err, ShouldBeNil)
+		transport := &fakeTransport{}
+		client.Transport = transport
+		client.message = newMessageType(true)
+		client.KeepaliveInput = 40 * time.Millisecond
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go client.keepaliveLoop(wg)
+		defer func() {
+			close(client.poison)
+			wg.Wait()
+		}()
+
+		Convey("An empty Input message is sent while idle", func() {
+			time.Sleep(100 * time.Millisecond)
+			written := transport.Written()
+			So(len(written), ShouldBeGreaterThanOrEqualTo, 1)
+			So(written[0], ShouldEqual, string(Input))
+		})
+
+		Convey("Typing postpones the keepalive", func() {
+			for i := 0; i < 5; i++ {
+				So(client.sendInput([]byte("a")), ShouldBeNil)
+				time.Sleep(15 * time.Millisecond)
+			}
+			So(transport.Written(), ShouldResemble, []string{"1a", "1a", "1a", "1a", "1a"})
+		})
+	})
+
+	Convey("Keepalive data is Go-escaped", t, func() {
+		data, err := ParseKeepaliveData(`\x00`)
+		So(err, ShouldBeNil)
+		So(data, ShouldResemble, []byte{0})
+		data, err = ParseKeepaliveData(`true\r`)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "true\r")
+		_, err = ParseKeepaliveData(`\q`)
+		So(err, ShouldNotBeNil)
+
+		path := filepath.Join(t.TempDir(), "config")
+		So(os.WriteFile(path, []byte("Host sdr\n    KeepaliveInput 5m\n    KeepaliveData \\x00\n"), 0600), ShouldBeNil)
+		config, err := LoadConfigFromPath(path)
+		So(err, ShouldBeNil)
+		client := &Client{}
+		config.GetHostConfig("sdr").ApplyToClient(client)
+		So(client.KeepaliveInput, ShouldEqual, 5*time.Minute)
+		So(client.KeepaliveData, ShouldResemble, []byte{0})
+	})
+}
@@ -0,0 +1,163 @@
+package gottyclient
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keyNames are the names usable in keymap files instead of escaped byte
+// sequences. Function keys are the xterm ones.
+var keyNames = map[string]string{
+	"backspace": "\x7f",
+	"ctrl-h":    "\b",
+	"delete":    "\x1b[3~",
+	"tab":       "\t",
+	"enter":     "\r",
+	"esc":       "\x1b",
+	"up":        "\x1b[A",
+	"down":      "\x1b[B",
+	"right":     "\x1b[C",
+	"left":      "\x1b[D",
+	"home":      "\x1b[H",
+	"end":       "\x1b[F",
+	"insert":    "\x1b[2~",
+	"pageup":    "\x1b[5~",
+	"pagedown":  "\x1b[6~",
+	"f1":        "\x1bOP",
+	"f2":        "\x1bOQ",
+	"f3":        "\x1bOR",
+	"f4":        "\x1bOS",
+	"f5":        "\x1b[15~",
+	"f6":        "\x1b[17~",
+	"f7":        "\x1b[18~",
+	"f8":        "\x1b[19~",
+	"f9":        "\x1b[20~",
+	"f10":       "\x1b[21~",
+	"f11":       "\x1b[23~",
+	"f12":       "\x1b[24~",
+}
+
+type keymapEntry struct {
+	from []byte
+	to   []byte
+}
+
+// Keymap rewrites local key sequences into other byte sequences before they
+// are sent, e.g. for remote programs with odd terminfo. Each line of a keymap
+// file maps a key to another:
+//
+//	# swap backspace and delete
+//	backspace  delete
+//	delete     backspace
+//	# F1 as sent by the linux console
+//	f1         \x1b[[A
+//
+// Keys are names (see keyNames) or Go-escaped strings such as \x1b[11~.
+type Keymap struct {
+	entries []keymapEntry
+}
+
+// LoadKeymap reads a keymap file; a leading ~ is the home directory
+func LoadKeymap(path string) (*Keymap, error) {
+	file, err := os.Open(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open keymap: %v", err)
+	}
+	defer file.Close()
+	keymap, err := ParseKeymap(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return keymap, nil
+}
+
+// ParseKeymap parses keymap lines
+func ParseKeymap(r io.Reader) (*Keymap, error) {
+	keymap := &Keymap{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a key and its replacement", lineNum)
+		}
+		from, err := parseKey(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		to, err := parseKey(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		keymap.entries = append(keymap.entries, keymapEntry{from: from, to: to})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Longest sequences first, so "delete" wins over a mapping of esc
+	sort.SliceStable(keymap.entries, func(i, j int) bool {
+		return len(keymap.entries[i].from) > len(keymap.entries[j].from)
+	})
+	return keymap, nil
+}
+
+func parseKey(s string) ([]byte, error) {
+	if seq, ok := keyNames[strings.ToLower(s)]; ok {
+		return []byte(seq), nil
+	}
+	seq, err := strconv.Unquote(`"` + strings.Replace(s, `"`, `\"`, -1) + `"`)
+	if err != nil || seq == "" {
+		return nil, fmt.Errorf("invalid key %q", s)
+	}
+	return []byte(seq), nil
+}
+
+// Apply rewrites the mapped sequences of input. Keys are matched within one
+// read, which holds a whole key press in practice.
+func (k *Keymap) Apply(input []byte) []byte {
+	if k == nil || len(k.entries) == 0 {
+		return input
+	}
+	var output []byte
+	for i := 0; i < len(input); {
+		matched := false
+		for _, entry := range k.entries {
+			if bytes.HasPrefix(input[i:], entry.from) {
+				output = append(output, entry.to...)
+				i += len(entry.from)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			output = append(output, input[i])
+			i++
+		}
+	}
+	return output
+}
+
+// expandHome replaces a leading ~ by the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,42 @@
+package gottyclient
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKeymap(t *testing.T) {
+	Convey("Testing keymaps", t, func() {
+		keymap, err := ParseKeymap(strings.NewReader(`
+# swap backspace and delete
+backspace  delete
+delete     backspace
+f1         \x1b[[A
+esc        \x1b\x1b
+`))
+		So(err, ShouldBeNil)
+
+		Convey("Keys are rewritten", func() {
+			So(string(keymap.Apply([]byte("ab\x7f"))), ShouldEqual, "ab\x1b[3~")
+			So(string(keymap.Apply([]byte("\x1b[3~"))), ShouldEqual, "\x7f")
+			So(string(keymap.Apply([]byte("\x1bOP"))), ShouldEqual, "\x1b[[A")
+		})
+		Convey("Longer sequences win over their prefixes", func() {
+			So(string(keymap.Apply([]byte("\x1b"))), ShouldEqual, "\x1b\x1b")
+			So(string(keymap.Apply([]byte("\x1b[3~\x1b"))), ShouldEqual, "\x7f\x1b\x1b")
+		})
+		Convey("Unmapped input and nil keymaps pass through", func() {
+			So(string(keymap.Apply([]byte("ls -l\r"))), ShouldEqual, "ls -l\r")
+			var none *Keymap
+			So(string(none.Apply([]byte("\x7f"))), ShouldEqual, "\x7f")
+		})
+		Convey("Invalid lines are reported", func() {
+			_, err := ParseKeymap(strings.NewReader("backspace\n"))
+			So(err, ShouldNotBeNil)
+			_, err = ParseKeymap(strings.NewReader("f1 \\xZZ\n"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
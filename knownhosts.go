@@ -0,0 +1,223 @@
+package gottyclient
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetDefaultKnownHostsPath returns the default known_hosts file path
+func GetDefaultKnownHostsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gotty-client", "known_hosts")
+}
+
+// CertificateFingerprint returns the SHA256 fingerprint of a certificate in
+// the same format as OpenSSH host keys
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// HostCertificateChangedError is returned when a trusted-on-first-use host
+// presents a certificate different from the one recorded in known_hosts
+type HostCertificateChangedError struct {
+	Host     string
+	Expected string
+	Got      string
+	Path     string
+}
+
+func (e *HostCertificateChangedError) Error() string {
+	return fmt.Sprintf("WARNING: CERTIFICATE OF HOST %s HAS CHANGED!\n"+
+		"Someone could be intercepting the connection, or the server certificate was replaced.\n"+
+		"Expected fingerprint: %s\n"+
+		"Received fingerprint: %s\n"+
+		"Remove the host from %s to trust the new certificate.",
+		e.Host, e.Expected, e.Got, e.Path)
+}
+
+// UnknownHostError aborts the TLS handshake with a host whose certificate is
+// neither trusted nor recorded, so that ConfirmHost is asked after the request
+// failed instead of while its timeout runs
+type UnknownHostError struct {
+	Host        string
+	Fingerprint string
+	Err         error
+}
+
+func (e *UnknownHostError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the verification error
+func (e *UnknownHostError) Unwrap() error { return e.Err }
+
+// KnownHosts is a trust-on-first-use store of server certificate
+// fingerprints, one "host fingerprint" pair per line. It is only consulted
+// for certificates that fail the regular verification, e.g. self-signed ones.
+type KnownHosts struct {
+	mutex sync.Mutex
+	path  string
+	hosts map[string]string
+}
+
+// LoadKnownHosts loads the known_hosts file at path, returning an empty store if it does not exist;
+// an empty path keeps it in memory only
+func LoadKnownHosts(path string) (*KnownHosts, error) {
+	kh := &KnownHosts{path: path, hosts: make(map[string]string)}
+	if path == "" {
+		return kh, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return kh, nil
+		}
+		return nil, fmt.Errorf("failed to read known hosts: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		kh.hosts[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read known hosts: %v", err)
+	}
+	return kh, nil
+}
+
+// Lookup returns the recorded fingerprint of a host
+func (kh *KnownHosts) Lookup(host string) (string, bool) {
+	kh.mutex.Lock()
+	defer kh.mutex.Unlock()
+	fingerprint, ok := kh.hosts[host]
+	return fingerprint, ok
+}
+
+// Add records the fingerprint of a host and saves the file
+func (kh *KnownHosts) Add(host, fingerprint string) error {
+	kh.mutex.Lock()
+	defer kh.mutex.Unlock()
+	kh.hosts[host] = fingerprint
+	return kh.save()
+}
+
+func (kh *KnownHosts) save() error {
+	if kh.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(kh.path), 0700); err != nil {
+		return fmt.Errorf("failed to create known hosts directory: %v", err)
+	}
+
+	hosts := make([]string, 0, len(kh.hosts))
+	for host := range kh.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var b strings.Builder
+	for _, host := range hosts {
+		fmt.Fprintf(&b, "%s %s\n", host, kh.hosts[host])
+	}
+	if err := os.WriteFile(kh.path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write known hosts: %v", err)
+	}
+	return nil
+}
+
+// verifyConnection verifies the certificate of host, the host[:port]
+// dialed, normally and falls back to its known_hosts entry when that fails.
+// Unknown hosts fail with an UnknownHostError for confirmHost to ask
+// c.ConfirmHost about.
+func (c *Client) verifyConnection(host string, cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("server presented no certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+
+	opts := x509.VerifyOptions{DNSName: cs.ServerName, Intermediates: x509.NewCertPool()}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, verifyErr := leaf.Verify(opts)
+	if verifyErr == nil {
+		return nil
+	}
+
+	fingerprint := CertificateFingerprint(leaf)
+	if known, ok := c.KnownHosts.Lookup(host); ok {
+		if known != fingerprint {
+			return &HostCertificateChangedError{Host: host, Expected: known, Got: fingerprint, Path: c.KnownHosts.path}
+		}
+		return nil
+	}
+
+	if c.ConfirmHost == nil {
+		return verifyErr
+	}
+	return &UnknownHostError{Host: host, Fingerprint: fingerprint, Err: verifyErr}
+}
+
+// confirmHost asks c.ConfirmHost about the host err was refused for, with
+// the progress display paused, and records an accepted host. It reports
+// whether the refused request is worth sending again.
+func (c *Client) confirmHost(err error) bool {
+	var unknown *UnknownHostError
+	if c.ConfirmHost == nil || !errors.As(err, &unknown) {
+		return false
+	}
+	// Concurrent requests, e.g. racing alternate URLs, ask only once
+	c.confirmMutex.Lock()
+	defer c.confirmMutex.Unlock()
+	if known, ok := c.KnownHosts.Lookup(unknown.Host); ok {
+		return known == unknown.Fingerprint
+	}
+	resume := pauseProgress(c.Progress)
+	defer resume()
+	if !c.ConfirmHost(unknown.Host, unknown.Fingerprint, unknown.Err) {
+		return false
+	}
+	if err := c.KnownHosts.Add(unknown.Host, unknown.Fingerprint); err != nil {
+		logrus.Warnf("Failed to remember host %s: %v", unknown.Host, err)
+		return false
+	}
+	return true
+}
+
+// resend sends req again with send after confirmHost accepted its host. A
+// failed handshake never sent the body, which the transport closed anyway.
+func resend(req *http.Request, send func() (*http.Response, error)) (*http.Response, error) {
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+	return send()
+}
@@ -0,0 +1,113 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// pauseRecorder is a ProgressReporter remembering whether it is paused
+type pauseRecorder struct {
+	recordingProgress
+	paused bool
+}
+
+func (p *pauseRecorder) Pause()  { p.paused = true }
+func (p *pauseRecorder) Resume() { p.paused = false }
+
+func TestKnownHosts(t *testing.T) {
+	Convey("Testing trust-on-first-use certificates", t, func() {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("var gotty_auth_token = 'token'"))
+		})
+		server := httptest.NewTLSServer(handler)
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		newClient := func(confirm bool) *Client {
+			knownHosts, err := LoadKnownHosts(path)
+			So(err, ShouldBeNil)
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			client.RetryPolicy = NoRetry
+			client.KnownHosts = knownHosts
+			client.ConfirmHost = func(host, fingerprint string, err error) bool { return confirm }
+			return client
+		}
+
+		Convey("Unknown hosts are rejected without confirmation", func() {
+			_, err := newClient(false).GetAuthToken()
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Time spent confirming does not count against the request timeout", func() {
+			spinner := &pauseRecorder{}
+			client := newClient(true)
+			client.RequestTimeout = 200 * time.Millisecond
+			client.Progress = spinner
+			asked := 0
+			client.ConfirmHost = func(host, fingerprint string, err error) bool {
+				asked++
+				So(spinner.paused, ShouldBeTrue)
+				time.Sleep(2 * client.RequestTimeout)
+				return true
+			}
+			_, err := client.GetAuthToken()
+			So(err, ShouldBeNil)
+			So(asked, ShouldEqual, 1)
+			So(spinner.paused, ShouldBeFalse)
+		})
+
+		Convey("Confirmed hosts are remembered", func() {
+			_, err := newClient(true).GetAuthToken()
+			So(err, ShouldBeNil)
+
+			token, err := newClient(false).GetAuthToken()
+			So(err, ShouldBeNil)
+			So(token, ShouldEqual, "token")
+
+			Convey("A changed certificate fails loudly", func() {
+				knownHosts, err := LoadKnownHosts(path)
+				So(err, ShouldBeNil)
+				So(knownHosts.Add(server.Listener.Addr().String(), "SHA256:other"), ShouldBeNil)
+
+				_, err = newClient(true).GetAuthToken()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "HAS CHANGED")
+			})
+		})
+
+		Convey("Each host is checked against its own entry", func() {
+			other := httptest.NewTLSServer(handler)
+			defer other.Close()
+
+			knownHosts, err := LoadKnownHosts(path)
+			So(err, ShouldBeNil)
+			So(knownHosts.Add(server.Listener.Addr().String(), "SHA256:other"), ShouldBeNil)
+
+			client := newClient(true)
+			var asked []string
+			client.ConfirmHost = func(host, fingerprint string, err error) bool {
+				asked = append(asked, host)
+				return true
+			}
+			req, err := http.NewRequest("GET", other.URL+"/", nil)
+			So(err, ShouldBeNil)
+			resp, err := client.do(req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			So(asked, ShouldResemble, []string{other.Listener.Addr().String()})
+
+			knownHosts, err = LoadKnownHosts(path)
+			So(err, ShouldBeNil)
+			fingerprint, _ := knownHosts.Lookup(server.Listener.Addr().String())
+			So(fingerprint, ShouldEqual, "SHA256:other")
+			_, ok := knownHosts.Lookup(other.Listener.Addr().String())
+			So(ok, ShouldBeTrue)
+		})
+	})
+}
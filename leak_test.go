@@ -0,0 +1,64 @@
+package gottyclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.uber.org/goleak"
+)
+
+func TestGoroutineShutdown(t *testing.T) {
+	Convey("Testing goroutine shutdown", t, func() {
+		// Goroutines of earlier tests are not ours to find
+		ignore := goleak.IgnoreCurrent()
+		server := newEchoServer()
+
+		Convey("Close stops the ping loop", func() {
+			for i := 0; i < 3; i++ {
+				client, err := NewClient(server.URL + "/")
+				So(err, ShouldBeNil)
+				client.V2 = true
+				So(client.Connect(), ShouldBeNil)
+				So(client.Close(), ShouldBeNil)
+				// Closing twice is harmless
+				So(client.Close(), ShouldBeNil)
+			}
+			server.Close()
+			So(goleak.Find(ignore), ShouldBeNil)
+		})
+
+		Convey("Cancelling the context ends LoopContext and its loops", func() {
+			stdin, input, err := os.Pipe()
+			So(err, ShouldBeNil)
+			defer input.Close()
+
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			client.V2 = true
+			client.Stdio = true
+			client.Stdin = stdin
+			client.SetOutput(&bytes.Buffer{})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- client.LoopContext(ctx) }()
+			time.Sleep(100 * time.Millisecond)
+			cancel()
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				err = fmt.Errorf("LoopContext did not return")
+			}
+			So(err, ShouldBeNil)
+
+			So(client.Close(), ShouldBeNil)
+			server.Close()
+			So(goleak.Find(ignore), ShouldBeNil)
+		})
+	})
+}
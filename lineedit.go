@@ -0,0 +1,438 @@
+package gottyclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LineEditor edits input a line at a time locally, for links too slow to
+// type on or for composing commands carefully: keys are echoed to Output and
+// the line is sent on Enter, with its echo erased for the server's to
+// replace it. Left and right, Home and End (or ^A and ^E), Backspace, ^U and
+// ^W edit the line, up and down recall earlier lines and ^R searches them.
+// ^C drops the line and is sent; other control keys are sent after the line
+// typed so far.
+type LineEditor struct {
+	Output io.Writer
+
+	mutex   sync.Mutex
+	line    []rune
+	pos     int
+	history *InputHistory
+	// recalled is the history entry shown, len(history) for the new line,
+	// which is kept in draft while browsing
+	recalled int
+	draft    []rune
+	pending  []byte
+	// parked is when pending was kept, and lone takes an Escape pending
+	// for longer than escapeTimeout as typed alone
+	parked time.Time
+	lone   bool
+	// unechoed is the line last sent, kept in the history once it is found
+	// in the output heard since
+	unechoed string
+	heard    []byte
+	// shown is the column of the cursor within the echo
+	shown int
+	// searching is set during a ^R search for query, matching the history
+	// entry match, -1 for none; the line is restored on cancelling
+	searching bool
+	query     []rune
+	match     int
+}
+
+// escapeTimeout is how long an Escape waits for the rest of a cursor key
+// before it is sent as typed alone
+const escapeTimeout = 100 * time.Millisecond
+
+// NewLineEditor returns a LineEditor echoing to output and recalling lines
+// from history, or from memory if nil
+func NewLineEditor(output io.Writer, history *InputHistory) *LineEditor {
+	if history == nil {
+		history = &InputHistory{}
+	}
+	return &LineEditor{Output: output, history: history, recalled: len(history.Lines())}
+}
+
+// History returns the history lines are recalled from
+func (e *LineEditor) History() *InputHistory {
+	return e.history
+}
+
+// Feed edits the line with typed data and returns the input to send, if any
+func (e *LineEditor) Feed(data []byte) []byte {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.feed(data, time.Now())
+}
+
+// Expire sends an Escape that has been waiting for the rest of a key since
+// before now less escapeTimeout as typed alone, returning the input to send,
+// if any. Without it a lone Escape would only go with the next key; a nil
+// LineEditor has nothing to send.
+func (e *LineEditor) Expire(now time.Time) []byte {
+	if e == nil {
+		return nil
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if !bytes.Equal(e.pending, []byte{0x1b}) || now.Sub(e.parked) < escapeTimeout {
+		return nil
+	}
+	e.lone = true
+	defer func() { e.lone = false }()
+	return e.feed(nil, now)
+}
+
+func (e *LineEditor) feed(data []byte, now time.Time) []byte {
+	data = append(e.pending, data...)
+	e.pending = nil
+	var send, echo bytes.Buffer
+	for len(data) > 0 {
+		n, complete := e.key(data, &send, &echo)
+		if !complete {
+			e.pending = append([]byte(nil), data...)
+			e.parked = now
+			break
+		}
+		data = data[n:]
+	}
+	if echo.Len() > 0 {
+		_, _ = e.Output.Write(echo.Bytes())
+	}
+	return send.Bytes()
+}
+
+// Heard notes output from the server, keeping the line last sent in the
+// history once the server echoes it back. Lines typed while the server does
+// not echo, e.g. at password prompts, are never kept.
+func (e *LineEditor) Heard(output []byte) {
+	if e == nil {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.unechoed == "" {
+		return
+	}
+	e.heard = append(e.heard, output...)
+	if !bytes.Contains(e.heard, []byte(e.unechoed)) {
+		// Only the end may be the start of an echo split across outputs
+		if keep := len(e.unechoed) - 1; len(e.heard) > keep {
+			e.heard = append(e.heard[:0], e.heard[len(e.heard)-keep:]...)
+		}
+		return
+	}
+	// The line being typed stays the new one after the lines kept
+	typing := e.recalled >= len(e.history.Lines())
+	if err := e.history.Add(e.unechoed); err != nil {
+		logrus.Debugf("Failed to save input history: %v", err)
+	}
+	e.unechoed, e.heard = "", nil
+	if typing {
+		e.recalled = len(e.history.Lines())
+	}
+}
+
+// SetLine replaces the line being edited, e.g. with one picked from the
+// history, leaving the cursor at its end
+func (e *LineEditor) SetLine(line string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	var echo bytes.Buffer
+	e.searching = false
+	e.line = []rune(line)
+	e.pos = len(e.line)
+	e.recalled = len(e.history.Lines())
+	e.redraw(&echo)
+	_, _ = e.Output.Write(echo.Bytes())
+}
+
+// key handles the key data starts with, returning its length, or false when
+// data ends within the key. A length of 0 handles the key again, once a
+// search is over.
+func (e *LineEditor) key(data []byte, send, echo *bytes.Buffer) (int, bool) {
+	if e.searching {
+		return e.searchKey(data, echo)
+	}
+
+	switch b := data[0]; {
+	case b == '\r' || b == '\n':
+		e.erase(echo)
+		send.WriteString(string(e.line) + "\r")
+		// The line is only kept once the server echoes it
+		e.unechoed, e.heard = string(e.line), nil
+		e.line, e.pos, e.draft = nil, 0, nil
+		e.recalled = len(e.history.Lines())
+		return 1, true
+	case b == 0x7f || b == 0x08:
+		if e.pos > 0 {
+			e.edit(echo, append(e.line[:e.pos-1:e.pos-1], e.line[e.pos:]...), e.pos-1)
+		}
+		return 1, true
+	case b == 0x15: // ^U
+		e.edit(echo, e.line[e.pos:], 0)
+		return 1, true
+	case b == 0x17: // ^W
+		start := e.pos
+		for start > 0 && e.line[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && e.line[start-1] != ' ' {
+			start--
+		}
+		e.edit(echo, append(e.line[:start:start], e.line[e.pos:]...), start)
+		return 1, true
+	case b == 0x01: // ^A
+		e.edit(echo, e.line, 0)
+		return 1, true
+	case b == 0x05: // ^E
+		e.edit(echo, e.line, len(e.line))
+		return 1, true
+	case b == 0x12: // ^R
+		e.draft = e.line
+		e.searching, e.query, e.match = true, nil, -1
+		e.redraw(echo)
+		return 1, true
+	case b == 0x03: // ^C
+		e.erase(echo)
+		e.line, e.pos = nil, 0
+		e.recalled = len(e.history.Lines())
+		send.WriteByte(b)
+		return 1, true
+	case b == 0x1b:
+		return e.escape(data, send, echo)
+	case b < 0x20:
+		e.flush(send, echo)
+		send.WriteByte(b)
+		return 1, true
+	}
+
+	if !utf8.FullRune(data) {
+		return 0, false
+	}
+	r, n := utf8.DecodeRune(data)
+	line := append(append(append([]rune(nil), e.line[:e.pos]...), r), e.line[e.pos:]...)
+	e.edit(echo, line, e.pos+1)
+	return n, true
+}
+
+// searchKey handles a key during a ^R search: typing narrows it, ^R finds
+// the next older match, ^G or ^C cancel it and any other key takes the
+// match into the line and is handled as usual
+func (e *LineEditor) searchKey(data []byte, echo *bytes.Buffer) (int, bool) {
+	switch b := data[0]; {
+	case b == 0x12: // ^R
+		if e.match >= 0 {
+			if older := e.history.Search(string(e.query), e.match); older >= 0 {
+				e.match = older
+			}
+		}
+		e.redraw(echo)
+		return 1, true
+	case b == 0x7f || b == 0x08:
+		if len(e.query) > 0 {
+			e.query = e.query[:len(e.query)-1]
+			e.match = e.history.Search(string(e.query), len(e.history.Lines()))
+		}
+		e.redraw(echo)
+		return 1, true
+	case b == 0x07 || b == 0x03: // ^G, ^C
+		e.searching = false
+		e.line, e.pos = e.draft, len(e.draft)
+		e.redraw(echo)
+		return 1, true
+	case b >= 0x20:
+		if !utf8.FullRune(data) {
+			return 0, false
+		}
+		r, n := utf8.DecodeRune(data)
+		e.query = append(e.query, r)
+		// The match is kept while it still matches
+		before := len(e.history.Lines())
+		if e.match >= 0 {
+			before = e.match + 1
+		}
+		e.match = e.history.Search(string(e.query), before)
+		e.redraw(echo)
+		return n, true
+	}
+
+	e.searching = false
+	if e.match >= 0 {
+		e.line = []rune(e.history.Lines()[e.match])
+		e.recalled = e.match
+	} else {
+		e.line = e.draft
+	}
+	e.pos = len(e.line)
+	e.redraw(echo)
+	return 0, true
+}
+
+// escape handles an escape sequence: cursor keys edit the line, others are
+// sent after it
+func (e *LineEditor) escape(data []byte, send, echo *bytes.Buffer) (int, bool) {
+	if len(data) < 2 {
+		if !e.lone {
+			return 0, false
+		}
+		e.flush(send, echo)
+		send.WriteByte(0x1b)
+		return 1, true
+	}
+	n := 2
+	switch data[1] {
+	case '[':
+		for n < len(data) && (data[n] < 0x40 || data[n] > 0x7e) {
+			n++
+		}
+		if n == len(data) {
+			return 0, false
+		}
+		n++
+	case 'O':
+		if len(data) < 3 {
+			return 0, false
+		}
+		n = 3
+	default:
+		// A lone Escape
+		e.flush(send, echo)
+		send.WriteByte(0x1b)
+		return 1, true
+	}
+
+	switch string(data[1:n]) {
+	case "[A", "OA":
+		e.recall(echo, e.recalled-1)
+	case "[B", "OB":
+		e.recall(echo, e.recalled+1)
+	case "[C", "OC":
+		if e.pos < len(e.line) {
+			e.edit(echo, e.line, e.pos+1)
+		}
+	case "[D", "OD":
+		if e.pos > 0 {
+			e.edit(echo, e.line, e.pos-1)
+		}
+	case "[H", "OH", "[1~":
+		e.edit(echo, e.line, 0)
+	case "[F", "OF", "[4~":
+		e.edit(echo, e.line, len(e.line))
+	default:
+		e.flush(send, echo)
+		send.Write(data[:n])
+	}
+	return n, true
+}
+
+// recall shows the history entry index, the new line past the last one
+func (e *LineEditor) recall(echo *bytes.Buffer, index int) {
+	lines := e.history.Lines()
+	if index < 0 || index > len(lines) {
+		return
+	}
+	if e.recalled >= len(lines) {
+		e.draft = e.line
+	}
+	e.recalled = index
+	line := e.draft
+	if index < len(lines) {
+		line = []rune(lines[index])
+	}
+	e.edit(echo, append([]rune(nil), line...), len(line))
+}
+
+// flush sends the line typed so far, erasing its echo
+func (e *LineEditor) flush(send, echo *bytes.Buffer) {
+	if len(e.line) == 0 {
+		return
+	}
+	e.erase(echo)
+	send.WriteString(string(e.line))
+	e.line, e.pos = nil, 0
+	e.recalled = len(e.history.Lines())
+}
+
+// display returns what is echoed, the line or the search prompt, and the
+// cursor's column in it
+func (e *LineEditor) display() ([]rune, int) {
+	if !e.searching {
+		return e.line, e.pos
+	}
+	prompt := "(reverse-i-search)`"
+	match := ""
+	if e.match >= 0 {
+		match = e.history.Lines()[e.match]
+	} else if len(e.query) > 0 {
+		prompt = "(failed reverse-i-search)`"
+	}
+	text := []rune(prompt + string(e.query) + "': " + match)
+	return text, len(text)
+}
+
+// erase moves the cursor back to the start of the echo and clears it
+func (e *LineEditor) erase(echo *bytes.Buffer) {
+	if e.shown > 0 {
+		fmt.Fprintf(echo, "\033[%dD", e.shown)
+	}
+	echo.WriteString("\033[K")
+	e.shown = 0
+}
+
+// draw echoes the line or search prompt, from the start of the echo
+func (e *LineEditor) draw(echo *bytes.Buffer) {
+	text, cursor := e.display()
+	echo.WriteString(string(text))
+	if back := len(text) - cursor; back > 0 {
+		fmt.Fprintf(echo, "\033[%dD", back)
+	}
+	e.shown = cursor
+}
+
+// redraw echoes the line or search prompt anew
+func (e *LineEditor) redraw(echo *bytes.Buffer) {
+	e.erase(echo)
+	e.draw(echo)
+}
+
+// edit replaces the line and cursor position and echoes the change
+func (e *LineEditor) edit(echo *bytes.Buffer, line []rune, pos int) {
+	// Typing at the end of the line only needs the new text echoed
+	if pos == len(line) && len(line) > len(e.line) && e.pos == len(e.line) && string(line[:len(e.line)]) == string(e.line) {
+		echo.WriteString(string(line[len(e.line):]))
+		e.line, e.pos, e.shown = line, pos, pos
+		return
+	}
+	e.line, e.pos = line, pos
+	e.redraw(echo)
+}
+
+// Interrupt takes the line's echo off the screen while write writes output,
+// then echoes it again after the output
+func (e *LineEditor) Interrupt(write func()) {
+	if e == nil {
+		write()
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if len(e.line) == 0 && !e.searching {
+		write()
+		return
+	}
+	var echo bytes.Buffer
+	e.erase(&echo)
+	_, _ = e.Output.Write(echo.Bytes())
+	write()
+	echo.Reset()
+	e.draw(&echo)
+	_, _ = e.Output.Write(echo.Bytes())
+}
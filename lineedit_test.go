@@ -0,0 +1,102 @@
+package gottyclient
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLineEditor(t *testing.T) {
+	Convey("Testing line mode", t, func() {
+		var output bytes.Buffer
+		editor := NewLineEditor(&output, nil)
+		feed := func(keys ...string) string {
+			var sent string
+			for _, key := range keys {
+				data := editor.Feed([]byte(key))
+				// The server echoes what it is sent
+				editor.Heard(data)
+				sent += string(data)
+			}
+			return sent
+		}
+
+		Convey("Lines are echoed and sent on Enter", func() {
+			So(feed("l", "s", " -l"), ShouldEqual, "")
+			So(output.String(), ShouldEqual, "ls -l")
+			output.Reset()
+			So(feed("\r"), ShouldEqual, "ls -l\r")
+			So(output.String(), ShouldEqual, "\033[5D\033[K")
+			So(editor.History().Lines(), ShouldResemble, []string{"ls -l"})
+		})
+
+		Convey("Keys edit the line", func() {
+			So(feed("freq 7074\x7f\x7f\x7f\x7f14074\r"), ShouldEqual, "freq 14074\r")
+			So(feed("mode usb lsb\x17am\r"), ShouldEqual, "mode usb am\r")
+			So(feed("tune\x15freq 7\r"), ShouldEqual, "freq 7\r")
+			So(feed("tx on\x1b[D\x1b[D\x1b[Dxx\x1b[F!\r"), ShouldEqual, "txxx on!\r")
+			So(feed("é\x1b[H\x01>\x05<\r"), ShouldEqual, ">é<\r")
+		})
+
+		Convey("Up and down recall earlier lines, keeping the new one", func() {
+			feed("one\r", "two\r", "two\r")
+			So(editor.History().Lines(), ShouldResemble, []string{"one", "two"})
+			So(feed("thr", "\x1b[A", "\x1b[A", "\x1b[A", "\r"), ShouldEqual, "one\r")
+			So(feed("thr", "\x1b[A", "\x1b[B", "ee\r"), ShouldEqual, "three\r")
+		})
+
+		Convey("Control keys are sent after the line, ^C drops it", func() {
+			So(feed("sys\t"), ShouldEqual, "sys\t")
+			So(feed("rm -rf\x03"), ShouldEqual, "\x03")
+			So(feed("\x04"), ShouldEqual, "\x04")
+			So(feed("ab\x1b[15~"), ShouldEqual, "ab\x1b[15~")
+		})
+
+		Convey("Keys split across reads are kept until complete", func() {
+			So(feed("\xc3", "\xa9", "\x1b", "[", "A"), ShouldEqual, "")
+			So(feed("\r"), ShouldEqual, "é\r")
+		})
+
+		Convey("Lines the server does not echo are not kept", func() {
+			So(editor.Feed([]byte("user\r")), ShouldResemble, []byte("user\r"))
+			editor.Heard([]byte("us"))
+			editor.Heard([]byte("er\r\nPassword: "))
+			So(editor.Feed([]byte("secret\r")), ShouldResemble, []byte("secret\r"))
+			editor.Heard([]byte("\r\nWelcome\r\n$ "))
+			So(editor.History().Lines(), ShouldResemble, []string{"user"})
+			So(feed("\x1b[A\r"), ShouldEqual, "user\r")
+		})
+
+		Convey("An Escape typed alone is sent once the rest of a key is overdue", func() {
+			So(feed("vi", "\x1b"), ShouldEqual, "")
+			So(string(editor.Expire(time.Now())), ShouldEqual, "")
+			So(string(editor.Expire(time.Now().Add(escapeTimeout))), ShouldEqual, "vi\x1b")
+			So(string(editor.Expire(time.Now().Add(escapeTimeout))), ShouldEqual, "")
+			So(feed("[A\r"), ShouldEqual, "[A\r")
+
+			var none *LineEditor
+			So(none.Expire(time.Now()), ShouldBeNil)
+		})
+
+		Convey("^R searches the history, newest first", func() {
+			feed("freq 7074000\r", "mode usb\r", "freq 14074000\r", "status\r")
+			output.Reset()
+			feed("\x12fr")
+			So(output.String(), ShouldEndWith, "(reverse-i-search)`fr': freq 14074000")
+			So(feed("\x12\r"), ShouldEqual, "freq 7074000\r")
+			So(feed("\x12eq 14\x1b[D5\r"), ShouldEqual, "freq 140740050\r")
+			So(feed("draft\x12zzz"), ShouldEqual, "")
+			So(output.String(), ShouldEndWith, "(failed reverse-i-search)`zzz': ")
+			So(feed("\x07!\r"), ShouldEqual, "draft!\r")
+		})
+
+		Convey("Output lifts the line being edited", func() {
+			feed("freq")
+			output.Reset()
+			editor.Interrupt(func() { output.WriteString("[tuned]\r\n") })
+			So(output.String(), ShouldEqual, "\033[4D\033[K[tuned]\r\nfreq")
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package gottyclient
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// CRLFMode translates the line endings of typed input, for remote sessions
+// bridging to serial devices that expect something else than a bare CR
+type CRLFMode string
+
+const (
+	// CRLFOff sends input as typed
+	CRLFOff CRLFMode = ""
+	// CRLFSendCRLF sends Enter (CR) as CR LF
+	CRLFSendCRLF CRLFMode = "crlf"
+	// CRLFSendLF sends Enter (CR) as LF
+	CRLFSendLF CRLFMode = "lf"
+	// CRLFSendCR sends LF, and CR LF, as CR
+	CRLFSendCR CRLFMode = "cr"
+)
+
+// ParseCRLFMode parses a --crlf value
+func ParseCRLFMode(s string) (CRLFMode, error) {
+	switch mode := CRLFMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "off", "none", "no", "false":
+		return CRLFOff, nil
+	case CRLFOff, CRLFSendCRLF, CRLFSendLF, CRLFSendCR:
+		return mode, nil
+	}
+	return CRLFOff, fmt.Errorf("invalid line ending mode %q (expected crlf, lf, cr or off)", s)
+}
+
+// Translate returns input with its line endings translated
+func (m CRLFMode) Translate(input []byte) []byte {
+	switch m {
+	case CRLFSendCRLF:
+		return bytes.Replace(input, []byte("\r"), []byte("\r\n"), -1)
+	case CRLFSendLF:
+		return bytes.Replace(input, []byte("\r"), []byte("\n"), -1)
+	case CRLFSendCR:
+		input = bytes.Replace(input, []byte("\r\n"), []byte("\r"), -1)
+		return bytes.Replace(input, []byte("\n"), []byte("\r"), -1)
+	}
+	return input
+}
+
+// outputNormalizer turns bare LFs into CR LF, fixing the staircase output of
+// sessions whose PTY does not translate newlines. It remembers whether the
+// previous chunk ended with a CR, as a CR LF pair may span two messages.
+type outputNormalizer struct {
+	lastCR bool
+}
+
+func (n *outputNormalizer) normalize(output []byte) []byte {
+	if bytes.IndexByte(output, '\n') < 0 {
+		if len(output) > 0 {
+			n.lastCR = output[len(output)-1] == '\r'
+		}
+		return output
+	}
+
+	normalized := make([]byte, 0, len(output)+8)
+	for _, b := range output {
+		if b == '\n' && !n.lastCR {
+			normalized = append(normalized, '\r')
+		}
+		normalized = append(normalized, b)
+		n.lastCR = b == '\r'
+	}
+	return normalized
+}
@@ -0,0 +1,35 @@
+package gottyclient
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLineEndings(t *testing.T) {
+	Convey("Testing line ending translation", t, func() {
+		Convey("ParseCRLFMode", func() {
+			mode, err := ParseCRLFMode("CRLF")
+			So(err, ShouldBeNil)
+			So(mode, ShouldEqual, CRLFSendCRLF)
+			mode, err = ParseCRLFMode("off")
+			So(err, ShouldBeNil)
+			So(mode, ShouldEqual, CRLFOff)
+			_, err = ParseCRLFMode("dos")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Input is translated", func() {
+			So(string(CRLFSendCRLF.Translate([]byte("ls\r"))), ShouldEqual, "ls\r\n")
+			So(string(CRLFSendLF.Translate([]byte("ls\r"))), ShouldEqual, "ls\n")
+			So(string(CRLFSendCR.Translate([]byte("a\r\nb\n"))), ShouldEqual, "a\rb\r")
+			So(string(CRLFOff.Translate([]byte("ls\r"))), ShouldEqual, "ls\r")
+		})
+		Convey("Bare LFs in the output become CR LF", func() {
+			n := &outputNormalizer{}
+			So(string(n.normalize([]byte("a\nb\r\nc\r"))), ShouldEqual, "a\r\nb\r\nc\r")
+			// The CR of the previous chunk pairs with this LF
+			So(string(n.normalize([]byte("\nd"))), ShouldEqual, "\nd")
+			So(string(n.normalize([]byte("\n"))), ShouldEqual, "\r\n")
+		})
+	})
+}
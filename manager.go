@@ -0,0 +1,282 @@
+package gottyclient
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultStagger spaces out connecting and reconnecting the clients of a
+// Manager without a Stagger
+const DefaultStagger = 250 * time.Millisecond
+
+// ManagedEvent is an event of one of a Manager's clients
+type ManagedEvent struct {
+	Alias string
+	OutputEvent
+}
+
+// Manager owns several clients keyed by alias, e.g. for daemons, broadcasts
+// and fleet commands. It resolves aliases with a shared config, spaces out
+// connections so a restarted server is not hit by every client at once and
+// fans the clients' events in. Its methods are safe for concurrent use.
+type Manager struct {
+	// Config resolves the aliases passed to Add; nil means only URLs
+	Config *Config
+	// Stagger is the delay between connecting successive clients, also
+	// added to each client's ReconnectDelay in turn
+	Stagger time.Duration
+	// Configure, if set, is called on every client Add creates before it is
+	// managed, e.g. to apply command line settings
+	Configure func(alias string, client *Client) error
+
+	mutex       sync.Mutex
+	clients     map[string]*Client
+	order       []string
+	subscribers map[int]*managerSubscriber
+	nextID      int
+	closed      bool
+}
+
+// managerSubscriber forwards the events of every client to one channel
+type managerSubscriber struct {
+	events  chan ManagedEvent
+	done    chan struct{}
+	stops   []func()
+	pending sync.WaitGroup
+}
+
+// NewManager returns a manager resolving aliases with config, which may be nil
+func NewManager(config *Config) *Manager {
+	return &Manager{Config: config}
+}
+
+// stagger returns the delay between successive clients
+func (m *Manager) stagger() time.Duration {
+	if m.Stagger > 0 {
+		return m.Stagger
+	}
+	return DefaultStagger
+}
+
+// Add creates a client for a configured alias or a URL, configured like the
+// host's config merged over the Host * defaults
+func (m *Manager) Add(alias string) (*Client, error) {
+	config := m.Config
+	if config == nil {
+		config = &Config{}
+	}
+	url, host, err := config.Resolve(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(url)
+	if err != nil {
+		return nil, err
+	}
+	client.V2 = true
+	host.ApplyToClient(client)
+	client.HookEnv = append(client.HookEnv, "UBERTERM_ALIAS="+alias)
+	if m.Configure != nil {
+		if err := m.Configure(alias, client); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.AddClient(alias, client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// AddClient manages an existing client under alias
+func (m *Manager) AddClient(alias string, client *Client) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		return fmt.Errorf("manager is closed")
+	}
+	if _, ok := m.clients[alias]; ok {
+		return fmt.Errorf("a client named '%s' is already managed", alias)
+	}
+	if m.clients == nil {
+		m.clients = make(map[string]*Client)
+	}
+
+	// Later clients wait longer before reconnecting, so they come back one by one
+	if client.ReconnectDelay <= 0 {
+		client.ReconnectDelay = time.Second
+	}
+	client.ReconnectDelay += time.Duration(len(m.order)) * m.stagger()
+
+	m.clients[alias] = client
+	m.order = append(m.order, alias)
+	for _, subscriber := range m.subscribers {
+		subscriber.forward(alias, client)
+	}
+	return nil
+}
+
+// Get returns the client managed under alias, or nil
+func (m *Manager) Get(alias string) *Client {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.clients[alias]
+}
+
+// Aliases returns the aliases of the managed clients in alphabetical order
+func (m *Manager) Aliases() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	aliases := append([]string{}, m.order...)
+	sort.Strings(aliases)
+	return aliases
+}
+
+// Remove closes the client managed under alias and stops managing it
+func (m *Manager) Remove(alias string) error {
+	m.mutex.Lock()
+	client, ok := m.clients[alias]
+	if ok {
+		delete(m.clients, alias)
+		for i, name := range m.order {
+			if name == alias {
+				m.order = append(m.order[:i], m.order[i+1:]...)
+				break
+			}
+		}
+	}
+	m.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no client named '%s' is managed", alias)
+	}
+	return client.Close()
+}
+
+// ConnectAll connects the managed clients that are not connected yet,
+// starting them Stagger apart, and returns the errors by alias
+func (m *Manager) ConnectAll() map[string]error {
+	m.mutex.Lock()
+	aliases := append([]string{}, m.order...)
+	clients := make([]*Client, len(aliases))
+	for i, alias := range aliases {
+		clients[i] = m.clients[alias]
+	}
+	m.mutex.Unlock()
+
+	errs := make(map[string]error)
+	errsMutex := sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	delay := time.Duration(0)
+	for i, client := range clients {
+		if client.IsConnected() {
+			continue
+		}
+		wg.Add(1)
+		go func(alias string, client *Client, delay time.Duration) {
+			defer wg.Done()
+			time.Sleep(delay)
+			if err := client.Connect(); err != nil {
+				errsMutex.Lock()
+				errs[alias] = err
+				errsMutex.Unlock()
+			}
+		}(aliases[i], client, delay)
+		delay += m.stagger()
+	}
+	wg.Wait()
+	return errs
+}
+
+// Events returns a channel receiving the events of every managed client,
+// including clients added later, and a function ending the subscription.
+// The channel is closed when the subscription ends or the manager is closed.
+func (m *Manager) Events() (<-chan ManagedEvent, func()) {
+	subscriber := &managerSubscriber{
+		events: make(chan ManagedEvent, SubscriberBuffer),
+		done:   make(chan struct{}),
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		close(subscriber.events)
+		return subscriber.events, func() {}
+	}
+	if m.subscribers == nil {
+		m.subscribers = make(map[int]*managerSubscriber)
+	}
+	id := m.nextID
+	m.nextID++
+	m.subscribers[id] = subscriber
+	for _, alias := range m.order {
+		subscriber.forward(alias, m.clients[alias])
+	}
+
+	return subscriber.events, func() {
+		m.mutex.Lock()
+		_, ok := m.subscribers[id]
+		delete(m.subscribers, id)
+		m.mutex.Unlock()
+		if ok {
+			subscriber.stop()
+		}
+	}
+}
+
+// forward relays the events of a client; called with the manager locked
+func (s *managerSubscriber) forward(alias string, client *Client) {
+	events, stop := client.Subscribe()
+	s.stops = append(s.stops, stop)
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		for event := range events {
+			select {
+			case s.events <- ManagedEvent{Alias: alias, OutputEvent: event}:
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// stop ends the client subscriptions and closes the channel
+func (s *managerSubscriber) stop() {
+	close(s.done)
+	for _, stop := range s.stops {
+		stop()
+	}
+	s.pending.Wait()
+	close(s.events)
+}
+
+// Close closes every managed client and ends the event subscriptions
+func (m *Manager) Close() error {
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+		return nil
+	}
+	m.closed = true
+	clients := make([]*Client, 0, len(m.order))
+	for _, alias := range m.order {
+		clients = append(clients, m.clients[alias])
+	}
+	subscribers := m.subscribers
+	m.subscribers = nil
+	m.mutex.Unlock()
+
+	var firstErr error
+	for _, client := range clients {
+		if err := client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, subscriber := range subscribers {
+		subscriber.stop()
+	}
+	return firstErr
+}
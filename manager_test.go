@@ -0,0 +1,84 @@
+package gottyclient
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moul/gotty-client/internal/mockserver"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestManager(t *testing.T) {
+	Convey("Testing the connection manager", t, func() {
+		lab, club := mockserver.New(), mockserver.New()
+		defer lab.Close()
+		defer club.Close()
+
+		path := filepath.Join(t.TempDir(), "config")
+		So(os.WriteFile(path, []byte("Host *\n    User op\nHost lab\n    URL "+lab.URL+"\nHost club\n    URL "+club.URL+"\nHost empty\n    User x\n"), 0600), ShouldBeNil)
+		config, err := LoadConfigFromPath(path)
+		So(err, ShouldBeNil)
+
+		manager := NewManager(config)
+		manager.Stagger = 10 * time.Millisecond
+		manager.Configure = func(alias string, client *Client) error {
+			client.Output = ioutil.Discard
+			return nil
+		}
+		defer manager.Close()
+
+		labClient, err := manager.Add("lab")
+		So(err, ShouldBeNil)
+		So(labClient.User, ShouldEqual, "op")
+		So(labClient.HookEnv, ShouldContain, "UBERTERM_ALIAS=lab")
+		_, err = manager.Add("lab")
+		So(err, ShouldNotBeNil)
+		_, err = manager.Add("empty")
+		So(err, ShouldNotBeNil)
+
+		events, stop := manager.Events()
+		defer stop()
+		clubClient, err := manager.Add("club")
+		So(err, ShouldBeNil)
+		So(manager.Aliases(), ShouldResemble, []string{"club", "lab"})
+		So(manager.Get("club"), ShouldEqual, clubClient)
+		So(clubClient.ReconnectDelay, ShouldEqual, time.Second+manager.Stagger)
+
+		Convey("Clients connect and their events are fanned in", func() {
+			So(manager.ConnectAll(), ShouldBeEmpty)
+			So(labClient.IsConnected(), ShouldBeTrue)
+			So(clubClient.IsConnected(), ShouldBeTrue)
+
+			seen := map[string]bool{}
+			for len(seen) < 2 {
+				event := <-events
+				So(event.Type, ShouldEqual, EventState)
+				if event.State == StateConnected {
+					seen[event.Alias] = true
+				}
+			}
+
+			clubClient.handleMessage(OutputMessage{Data: []byte("73")})
+			event := <-events
+			So(event.Alias, ShouldEqual, "club")
+			So(event.Data, ShouldResemble, []byte("73"))
+		})
+
+		Convey("Removing a client closes it", func() {
+			So(manager.Remove("lab"), ShouldBeNil)
+			So(manager.Get("lab"), ShouldBeNil)
+			So(manager.Remove("lab"), ShouldNotBeNil)
+		})
+
+		Convey("Closing the manager ends the events", func() {
+			So(manager.Close(), ShouldBeNil)
+			for range events {
+			}
+			_, err := manager.Add("lab")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,334 @@
+package gottyclient
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mdnsServices are the DNS-SD service types browsed by DiscoverLocal, in
+// order of precedence for receivers announcing several
+var mdnsServices = []string{"_ubersdr._tcp", "_gotty._tcp"}
+
+// mdnsAddr is the mDNS multicast group and port queries are sent to
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// DNS record types used by DNS-SD
+const (
+	dnsTypeA    = 1
+	dnsTypePTR  = 12
+	dnsTypeTXT  = 16
+	dnsTypeAAAA = 28
+	dnsTypeSRV  = 33
+)
+
+// mdnsService is what the LAN announced about one service instance
+type mdnsService struct {
+	target string
+	port   int
+	txt    map[string]string
+	source net.IP
+}
+
+// mdnsBrowse gathers the records of the answers to a DNS-SD browse
+type mdnsBrowse struct {
+	instances map[string][]string // service type -> instance names
+	services  map[string]*mdnsService
+	addresses map[string][]net.IP // host name -> addresses
+}
+
+// DiscoverLocal browses the LAN with multicast DNS for the receivers
+// announcing _ubersdr._tcp or _gotty._tcp, waiting wait for answers. The instances are
+// tagged with source "mdns" and sorted by callsign; none being found is not
+// an error.
+func DiscoverLocal(ctx context.Context, wait time.Duration) ([]Instance, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mDNS socket: %v", err)
+	}
+	defer conn.Close()
+
+	// Queries from a port other than 5353 get unicast answers, so there is
+	// no need to join the multicast group or share port 5353
+	query := mdnsQuery(mdnsServices)
+	logrus.Debugf("Browsing mDNS for %s", strings.Join(mdnsServices, ", "))
+	if _, err := conn.WriteToUDP(query, mdnsAddr); err != nil {
+		return nil, fmt.Errorf("failed to send mDNS query: %v", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	browse := &mdnsBrowse{
+		instances: make(map[string][]string),
+		services:  make(map[string]*mdnsService),
+		addresses: make(map[string][]net.IP),
+	}
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			return nil, fmt.Errorf("failed to read mDNS answer: %v", err)
+		}
+		if err := browse.add(buf[:n], from.IP); err != nil {
+			logrus.Debugf("Ignoring malformed mDNS answer from %s: %v", from.IP, err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return browse.result(), nil
+}
+
+// mdnsQuery builds a query for the PTR records of services
+func mdnsQuery(services []string) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[4:], uint16(len(services)))
+	for _, service := range services {
+		msg = appendDNSName(msg, service+".local.")
+		// Class IN with the unicast-response bit set
+		msg = append(msg, 0, dnsTypePTR, 0x80, 1)
+	}
+	return msg
+}
+
+// appendDNSName appends name in DNS wire format
+func appendDNSName(msg []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	return append(msg, 0)
+}
+
+// readDNSName reads a possibly compressed name at off, returning it with a
+// trailing dot and the offset following it
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, fmt.Errorf("name out of bounds")
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xC0 == 0xC0:
+			if off+1 >= len(msg) {
+				return "", 0, fmt.Errorf("name pointer out of bounds")
+			}
+			if jumps++; jumps > 16 {
+				return "", 0, fmt.Errorf("name pointer loop")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+		default:
+			if off+1+length > len(msg) {
+				return "", 0, fmt.Errorf("label out of bounds")
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+}
+
+// add records the answers of one mDNS message received from source
+func (b *mdnsBrowse) add(msg []byte, source net.IP) error {
+	if len(msg) < 12 {
+		return fmt.Errorf("message too short")
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	records := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+
+	off := 12
+	for i := 0; i < questions; i++ {
+		_, next, err := readDNSName(msg, off)
+		if err != nil {
+			return err
+		}
+		off = next + 4
+	}
+
+	for i := 0; i < records; i++ {
+		name, next, err := readDNSName(msg, off)
+		if err != nil {
+			return err
+		}
+		if next+10 > len(msg) {
+			return fmt.Errorf("record out of bounds")
+		}
+		recordType := binary.BigEndian.Uint16(msg[next:])
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		if start+length > len(msg) {
+			return fmt.Errorf("record data out of bounds")
+		}
+		data := msg[start : start+length]
+		off = start + length
+
+		name = strings.ToLower(name)
+		switch recordType {
+		case dnsTypePTR:
+			instance, _, err := readDNSName(msg, start)
+			if err != nil {
+				return err
+			}
+			b.instances[name] = appendUnique(b.instances[name], strings.ToLower(instance))
+		case dnsTypeSRV:
+			if length < 7 {
+				return fmt.Errorf("SRV record too short")
+			}
+			target, _, err := readDNSName(msg, start+6)
+			if err != nil {
+				return err
+			}
+			service := b.service(name, source)
+			service.port = int(binary.BigEndian.Uint16(data[4:]))
+			service.target = strings.ToLower(target)
+		case dnsTypeTXT:
+			service := b.service(name, source)
+			for len(data) > 0 {
+				n := int(data[0])
+				if 1+n > len(data) {
+					break
+				}
+				entry := string(data[1 : 1+n])
+				data = data[1+n:]
+				if i := strings.Index(entry, "="); i >= 0 {
+					service.txt[strings.ToLower(entry[:i])] = entry[i+1:]
+				} else if entry != "" {
+					service.txt[strings.ToLower(entry)] = ""
+				}
+			}
+		case dnsTypeA, dnsTypeAAAA:
+			if length == net.IPv4len || length == net.IPv6len {
+				b.addresses[name] = append(b.addresses[name], net.IP(append([]byte{}, data...)))
+			}
+		}
+	}
+	return nil
+}
+
+// service returns the service instance called name, creating it
+func (b *mdnsBrowse) service(name string, source net.IP) *mdnsService {
+	service, ok := b.services[name]
+	if !ok {
+		service = &mdnsService{txt: make(map[string]string), source: source}
+		b.services[name] = service
+	}
+	return service
+}
+
+// appendUnique appends value to values unless already present
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// result turns the records into instances, one per callsign
+func (b *mdnsBrowse) result() []Instance {
+	var instances []Instance
+	seen := make(map[string]bool)
+	for _, serviceType := range mdnsServices {
+		names := b.instances[strings.ToLower(serviceType)+".local."]
+		for _, name := range names {
+			service, ok := b.services[name]
+			if !ok || service.port == 0 {
+				continue
+			}
+			instance := service.instance(strings.TrimSuffix(name, "."+strings.ToLower(serviceType)+".local."), b.addresses[service.target])
+			callsign := strings.ToUpper(instance.Callsign)
+			if seen[callsign] {
+				continue
+			}
+			seen[callsign] = true
+			instances = append(instances, instance)
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return strings.ToUpper(instances[i].Callsign) < strings.ToUpper(instances[j].Callsign)
+	})
+	return instances
+}
+
+// instance describes the service as an instance reachable at one of
+// addresses, or the address that answered
+func (s *mdnsService) instance(label string, addresses []net.IP) Instance {
+	host := s.source.String()
+	for _, address := range addresses {
+		if address.To4() != nil {
+			host = address.String()
+			break
+		}
+	}
+
+	instance := Instance{
+		Source:   "mdns",
+		ID:       label,
+		Callsign: s.txt["callsign"],
+		Name:     s.txt["name"],
+		Location: s.txt["location"],
+		Version:  s.txt["version"],
+		Host:     host,
+		Port:     s.port,
+		TLS:      s.txt["tls"] == "1" || s.txt["tls"] == "true",
+	}
+	if instance.Callsign == "" {
+		instance.Callsign = strings.ToUpper(label)
+	}
+	if instance.Name == "" {
+		instance.Name = label
+	}
+	if clients, err := strconv.Atoi(s.txt["max_clients"]); err == nil {
+		instance.MaxClients = clients
+	}
+	if clients, err := strconv.Atoi(s.txt["available_clients"]); err == nil {
+		instance.AvailableClients = clients
+	}
+
+	scheme := "http"
+	if instance.TLS {
+		scheme = "https"
+	}
+	path := s.txt["path"]
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	instance.PublicURL = fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(s.port)), path)
+	return instance
+}
@@ -0,0 +1,99 @@
+package gottyclient
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// dnsRecord appends a resource record with data to msg
+func dnsRecord(msg []byte, name string, recordType uint16, data []byte) []byte {
+	msg = appendDNSName(msg, name)
+	header := make([]byte, 10)
+	binary.BigEndian.PutUint16(header, recordType)
+	binary.BigEndian.PutUint16(header[2:], 1)
+	binary.BigEndian.PutUint32(header[4:], 120)
+	binary.BigEndian.PutUint16(header[8:], uint16(len(data)))
+	return append(append(msg, header...), data...)
+}
+
+// mdnsAnswer builds the answer of a receiver announcing _ubersdr._tcp
+func mdnsAnswer(label string, port int, txt ...string) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[2:], 0x8400)
+	binary.BigEndian.PutUint16(msg[6:], 4)
+
+	instance := label + "._ubersdr._tcp.local."
+	msg = dnsRecord(msg, "_ubersdr._tcp.local.", dnsTypePTR, appendDNSName(nil, instance))
+	srv := make([]byte, 6)
+	binary.BigEndian.PutUint16(srv[4:], uint16(port))
+	msg = dnsRecord(msg, instance, dnsTypeSRV, appendDNSName(srv, "sdr.local."))
+	var entries []byte
+	for _, entry := range txt {
+		entries = append(append(entries, byte(len(entry))), entry...)
+	}
+	msg = dnsRecord(msg, instance, dnsTypeTXT, entries)
+	return dnsRecord(msg, "sdr.local.", dnsTypeA, net.IPv4(192, 168, 1, 20).To4())
+}
+
+func TestDiscoverLocal(t *testing.T) {
+	Convey("Testing mDNS discovery", t, func() {
+		Convey("Names are read through compression pointers", func() {
+			msg := appendDNSName(make([]byte, 12), "_ubersdr._tcp.local.")
+			msg = append(msg, 3, 'a', 'b', 'c', 0xC0, 12)
+			name, next, err := readDNSName(msg, len(msg)-6)
+			So(err, ShouldBeNil)
+			So(name, ShouldEqual, "abc._ubersdr._tcp.local.")
+			So(next, ShouldEqual, len(msg))
+
+			_, _, err = readDNSName([]byte{0xC0, 0}, 0)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Answers become instances", func() {
+			browse := &mdnsBrowse{instances: map[string][]string{}, services: map[string]*mdnsService{}, addresses: map[string][]net.IP{}}
+			So(browse.add(mdnsAnswer("Shack", 8073, "callsign=M9PSY", "name=Shack SDR", "path=/terminal", "tls=1"), net.IPv4(10, 0, 0, 1)), ShouldBeNil)
+			So(browse.add(mdnsAnswer("bench", 8080), net.IPv4(10, 0, 0, 2)), ShouldBeNil)
+			So(browse.add([]byte{1, 2, 3}, nil), ShouldNotBeNil)
+
+			instances := browse.result()
+			So(instances, ShouldHaveLength, 2)
+			So(instances[0].Callsign, ShouldEqual, "BENCH")
+			So(instances[0].PublicURL, ShouldEqual, "http://192.168.1.20:8080")
+			So(instances[1].Callsign, ShouldEqual, "M9PSY")
+			So(instances[1].Name, ShouldEqual, "Shack SDR")
+			So(instances[1].Source, ShouldEqual, "mdns")
+			So(instances[1].PublicURL, ShouldEqual, "https://192.168.1.20:8073/terminal")
+		})
+
+		Convey("The LAN is queried and answers are gathered until the wait ends", func() {
+			responder, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			So(err, ShouldBeNil)
+			defer responder.Close()
+			saved := mdnsAddr
+			mdnsAddr = responder.LocalAddr().(*net.UDPAddr)
+			defer func() { mdnsAddr = saved }()
+
+			queries := make(chan int, 1)
+			go func() {
+				buf := make([]byte, 1500)
+				n, from, err := responder.ReadFromUDP(buf)
+				if err != nil {
+					return
+				}
+				queries <- int(binary.BigEndian.Uint16(buf[4:n]))
+				responder.WriteToUDP(mdnsAnswer("shack", 8073, "callsign=M9PSY"), from)
+			}()
+
+			instances, err := DiscoverLocal(context.Background(), 300*time.Millisecond)
+			So(err, ShouldBeNil)
+			So(<-queries, ShouldEqual, len(mdnsServices))
+			So(instances, ShouldHaveLength, 1)
+			So(instances[0].Callsign, ShouldEqual, "M9PSY")
+		})
+	})
+}
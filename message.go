@@ -0,0 +1,102 @@
+package gottyclient
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrEmptyMessage is returned by ParseServerMessage for a frame without a
+// message type
+var ErrEmptyMessage = errors.New("empty message")
+
+// Message is a message received from the server
+type Message interface {
+	isMessage()
+}
+
+// OutputMessage carries terminal output
+type OutputMessage struct {
+	Data []byte
+}
+
+// PongMessage answers a ping
+type PongMessage struct{}
+
+// SetWindowTitleMessage sets the terminal title. Control characters are
+// removed, so a title cannot inject escape sequences.
+type SetWindowTitleMessage struct {
+	Title string
+}
+
+// SetPreferencesMessage carries the server's terminal preferences as JSON
+type SetPreferencesMessage struct {
+	Preferences json.RawMessage
+}
+
+// SetReconnectMessage tells how long the server waits for a reconnect, in
+// seconds
+type SetReconnectMessage struct {
+	Timeout int
+}
+
+func (OutputMessage) isMessage()         {}
+func (PongMessage) isMessage()           {}
+func (SetWindowTitleMessage) isMessage() {}
+func (SetPreferencesMessage) isMessage() {}
+func (SetReconnectMessage) isMessage()   {}
+
+// MalformedMessageError is returned by ParseServerMessage for frames that
+// are not valid protocol messages, e.g. mangled by a proxy
+type MalformedMessageError struct {
+	Type   byte
+	Reason string
+}
+
+func (e *MalformedMessageError) Error() string {
+	return fmt.Sprintf("malformed message of type %q: %s", e.Type, e.Reason)
+}
+
+// ParseServerMessage parses a frame received from a GoTTY server speaking
+// the 1.x protocol or, if v2 is set, the 2.0 one
+func ParseServerMessage(v2 bool, data []byte) (Message, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	message := newMessageType(v2)
+	kind, payload := data[0], data[1:]
+
+	switch kind {
+	case message.output:
+		buf, err := base64.StdEncoding.DecodeString(string(payload))
+		if err != nil {
+			return nil, &MalformedMessageError{Type: kind, Reason: "invalid base64 output"}
+		}
+		return OutputMessage{Data: buf}, nil
+	case message.pong:
+		return PongMessage{}, nil
+	case message.setWindowTitle:
+		title := strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) || r == unicode.ReplacementChar {
+				return -1
+			}
+			return r
+		}, string(payload))
+		return SetWindowTitleMessage{Title: title}, nil
+	case message.setPreferences:
+		if !json.Valid(payload) {
+			return nil, &MalformedMessageError{Type: kind, Reason: "invalid preferences JSON"}
+		}
+		return SetPreferencesMessage{Preferences: json.RawMessage(payload)}, nil
+	case message.setReconnect:
+		var timeout int
+		if err := json.Unmarshal(payload, &timeout); err != nil {
+			return nil, &MalformedMessageError{Type: kind, Reason: "invalid reconnect timeout"}
+		}
+		return SetReconnectMessage{Timeout: timeout}, nil
+	}
+	return nil, &MalformedMessageError{Type: kind, Reason: "unknown message type"}
+}
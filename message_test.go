@@ -0,0 +1,74 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"testing"
+	"unicode"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseServerMessage(t *testing.T) {
+	Convey("Testing ParseServerMessage", t, func() {
+		Convey("Output is base64 decoded", func() {
+			message, err := ParseServerMessage(true, []byte("1aGVsbG8="))
+			So(err, ShouldBeNil)
+			So(message, ShouldResemble, OutputMessage{Data: []byte("hello")})
+
+			message, err = ParseServerMessage(false, []byte("0aGVsbG8="))
+			So(err, ShouldBeNil)
+			So(message, ShouldResemble, OutputMessage{Data: []byte("hello")})
+		})
+		Convey("Control messages are typed", func() {
+			message, err := ParseServerMessage(true, []byte("2"))
+			So(err, ShouldBeNil)
+			So(message, ShouldResemble, PongMessage{})
+
+			message, err = ParseServerMessage(true, []byte("5"+"30"))
+			So(err, ShouldBeNil)
+			So(message, ShouldResemble, SetReconnectMessage{Timeout: 30})
+
+			message, err = ParseServerMessage(true, []byte(`4{"font-size":14}`))
+			So(err, ShouldBeNil)
+			So(string(message.(SetPreferencesMessage).Preferences), ShouldEqual, `{"font-size":14}`)
+		})
+		Convey("Titles cannot inject escape sequences", func() {
+			message, err := ParseServerMessage(true, []byte("3sdr\x07\x1b]0;pwned"))
+			So(err, ShouldBeNil)
+			So(message, ShouldResemble, SetWindowTitleMessage{Title: "sdr]0;pwned"})
+		})
+		Convey("Malformed frames are errors", func() {
+			_, err := ParseServerMessage(true, nil)
+			So(err, ShouldEqual, ErrEmptyMessage)
+			for _, frame := range []string{"1not base64!", "4{", "5soon", "9"} {
+				_, err := ParseServerMessage(true, []byte(frame))
+				So(err, ShouldHaveSameTypeAs, &MalformedMessageError{})
+			}
+		})
+	})
+}
+
+func FuzzParseServerMessage(f *testing.F) {
+	for _, seed := range []string{"1aGVsbG8=", "0aGVsbG8=", "2", "3title", `4{"a":1}`, "530", "", "9x"} {
+		f.Add(true, []byte(seed))
+		f.Add(false, []byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, v2 bool, data []byte) {
+		message, err := ParseServerMessage(v2, data)
+		if (message == nil) == (err == nil) {
+			t.Fatalf("expected either a message or an error, got %#v, %v", message, err)
+		}
+		switch message := message.(type) {
+		case SetWindowTitleMessage:
+			for _, r := range message.Title {
+				if unicode.IsControl(r) {
+					t.Fatalf("control character %q in title %q", r, message.Title)
+				}
+			}
+		case SetPreferencesMessage:
+			if !json.Valid(message.Preferences) {
+				t.Fatalf("invalid preferences %q", message.Preferences)
+			}
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package gottyclient
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// SplitCommandLine splits a shell command line into words, honoring single
+// and double quotes and backslash escapes
+func SplitCommandLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", line)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// HostConfigFromCommand turns a gotty-client command line, as used with the
+// upstream moul/gotty-client, or a bare URL into a host configuration.
+// Credentials in the URL become User and Password, and the URL is used as
+// given, as the upstream client does, with PathSuffix "/". The alias is the
+// URL's host name.
+func HostConfigFromCommand(args []string) (*HostConfig, error) {
+	if len(args) > 0 {
+		switch strings.TrimSuffix(filepath.Base(args[0]), ".exe") {
+		case "gotty-client", "uberterm":
+			args = args[1:]
+		}
+	}
+
+	host := &HostConfig{PathSuffix: "/"}
+	var target string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := arg, "", false
+		if strings.HasPrefix(arg, "-") {
+			if eq := strings.Index(arg, "="); eq >= 0 {
+				name, value, hasValue = arg[:eq], arg[eq+1:], true
+			}
+		}
+		// takeValue returns the value of a flag given as --flag=value or
+		// --flag value
+		takeValue := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch name {
+		case "--skip-tls-verify":
+			host.SkipTLSVerify = !hasValue || parseBool(value)
+		case "--use-proxy-from-env":
+			host.UseProxyFromEnv = !hasValue || parseBool(value)
+		case "--v2":
+			host.V2 = !hasValue || parseBool(value)
+		case "--ws-origin", "-w":
+			origin, err := takeValue()
+			if err != nil {
+				return nil, err
+			}
+			host.WSOrigin = origin
+		case "--detach-keys":
+			// Detach keys are not a per-host setting
+			if _, err := takeValue(); err != nil {
+				return nil, err
+			}
+		case "--debug", "-D":
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("unsupported gotty-client option %s", name)
+			}
+			if target != "" {
+				return nil, fmt.Errorf("several URLs given: %s and %s", target, arg)
+			}
+			target = arg
+		}
+	}
+	if target == "" {
+		return nil, fmt.Errorf("no URL given")
+	}
+
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", target)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", parsed.Scheme)
+	}
+	if parsed.User != nil {
+		host.User = parsed.User.Username()
+		host.Password, _ = parsed.User.Password()
+		parsed.User = nil
+	}
+	host.URL = parsed.String()
+	host.Host = parsed.Hostname()
+	return host, nil
+}
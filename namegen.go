@@ -0,0 +1,217 @@
+package gottyclient
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// NameGenerator generates names for new sessions/windows
+type NameGenerator interface {
+	Generate() (string, error)
+}
+
+// DefaultAdjectives is the default adjective list of the word list generator
+var DefaultAdjectives = []string{
+	"happy", "clever", "brave", "swift", "bright", "calm", "eager", "gentle",
+	"jolly", "kind", "lively", "merry", "nice", "proud", "silly", "witty",
+	"zany", "bold", "cool", "daring", "fancy", "grand", "lucky", "mighty",
+	"noble", "quick", "smart", "wise", "agile", "cosmic", "dynamic", "epic",
+}
+
+// DefaultNouns is the default noun list of the word list generator
+var DefaultNouns = []string{
+	"panda", "tiger", "eagle", "dolphin", "falcon", "lion", "wolf", "bear",
+	"hawk", "fox", "owl", "shark", "dragon", "phoenix", "unicorn", "griffin",
+	"raven", "cobra", "jaguar", "lynx", "otter", "badger", "ferret", "mink",
+	"viper", "python", "condor", "sparrow", "robin", "wren", "finch", "lark",
+}
+
+// WordListGenerator generates random adjective-noun names
+type WordListGenerator struct {
+	Adjectives []string
+	Nouns      []string
+	mutex      sync.Mutex
+	rand       *rand.Rand
+}
+
+// NewWordListGenerator returns a generator using the default word lists.
+// The same seed always produces the same sequence of names.
+func NewWordListGenerator(seed int64) *WordListGenerator {
+	return &WordListGenerator{
+		Adjectives: DefaultAdjectives,
+		Nouns:      DefaultNouns,
+		rand:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+// LoadWordListGenerator returns a generator using word lists read from a file.
+// The file lists one word per line under [adjectives] and [nouns] headers;
+// empty lines and lines starting with # are ignored. A missing section falls
+// back to the default list.
+func LoadWordListGenerator(path string, seed int64) (*WordListGenerator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open word list: %v", err)
+	}
+	defer file.Close()
+
+	generator := NewWordListGenerator(seed)
+	var adjectives, nouns []string
+	var section *[]string
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch strings.ToLower(line) {
+		case "[adjectives]":
+			section = &adjectives
+			continue
+		case "[nouns]":
+			section = &nouns
+			continue
+		}
+		if section == nil {
+			return nil, fmt.Errorf("line %d: word outside of [adjectives] or [nouns] section", lineNum)
+		}
+		*section = append(*section, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading word list: %v", err)
+	}
+
+	if len(adjectives) > 0 {
+		generator.Adjectives = adjectives
+	}
+	if len(nouns) > 0 {
+		generator.Nouns = nouns
+	}
+	return generator, nil
+}
+
+// Generate returns a random adjective-noun name
+func (g *WordListGenerator) Generate() (string, error) {
+	return g.words(), nil
+}
+
+func (g *WordListGenerator) words() string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	adj := g.Adjectives[g.rand.Intn(len(g.Adjectives))]
+	noun := g.Nouns[g.rand.Intn(len(g.Nouns))]
+	return SanitizeSessionName(fmt.Sprintf("%s-%s", adj, noun))
+}
+
+// SequentialGenerator generates numbered names such as session-1, session-2
+type SequentialGenerator struct {
+	Prefix string
+	mutex  sync.Mutex
+	next   int
+}
+
+// NewSequentialGenerator returns a generator numbering names from start
+func NewSequentialGenerator(prefix string, start int) *SequentialGenerator {
+	return &SequentialGenerator{Prefix: prefix, next: start}
+}
+
+// Generate returns the next numbered name
+func (g *SequentialGenerator) Generate() (string, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	name := fmt.Sprintf("%s%d", g.Prefix, g.next)
+	g.next++
+	return SanitizeSessionName(name), nil
+}
+
+// NameTemplateData is the data available to name templates
+type NameTemplateData struct {
+	User  string // local username
+	Host  string // local hostname
+	Date  string // current date, 20060102
+	Time  string // current time, 150405
+	Rand  string // four random lowercase letters and digits
+	Words string // random adjective-noun pair
+	Seq   int    // number of names generated so far, starting at 1
+}
+
+// TemplateGenerator generates names from a text/template such as
+// {{.User}}-{{.Date}}-{{.Rand}}
+type TemplateGenerator struct {
+	template *template.Template
+	words    *WordListGenerator
+	mutex    sync.Mutex
+	rand     *rand.Rand
+	seq      int
+	now      func() time.Time
+}
+
+// NewTemplateGenerator parses a name template. The seed makes the random
+// parts of the template deterministic. The template is rendered once with
+// sample data, so unknown fields and the like are reported here rather than
+// when the first name is generated.
+func NewTemplateGenerator(text string, seed int64) (*TemplateGenerator, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %v", err)
+	}
+	sample := NameTemplateData{User: "user", Host: "host", Date: "20060102", Time: "150405", Rand: "a1b2", Words: "happy-panda", Seq: 1}
+	if err := tmpl.Execute(ioutil.Discard, sample); err != nil {
+		return nil, fmt.Errorf("invalid name template: %v", err)
+	}
+	return &TemplateGenerator{
+		template: tmpl,
+		words:    NewWordListGenerator(seed),
+		rand:     rand.New(rand.NewSource(seed)),
+		now:      time.Now,
+	}, nil
+}
+
+// Generate renders the template
+func (g *TemplateGenerator) Generate() (string, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.seq++
+	now := g.now()
+	data := NameTemplateData{
+		Date:  now.Format("20060102"),
+		Time:  now.Format("150405"),
+		Rand:  g.randomString(4),
+		Words: g.words.words(),
+		Seq:   g.seq,
+	}
+	if u, err := user.Current(); err == nil {
+		data.User = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		data.Host = host
+	}
+
+	var buf bytes.Buffer
+	if err := g.template.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render name template: %v", err)
+	}
+	return SanitizeSessionName(buf.String()), nil
+}
+
+func (g *TemplateGenerator) randomString(n int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[g.rand.Intn(len(alphabet))]
+	}
+	return string(b)
+}
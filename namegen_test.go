@@ -0,0 +1,69 @@
+package gottyclient
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNameGenerators(t *testing.T) {
+	Convey("Testing name generators", t, func() {
+		generate := func(generator NameGenerator) string {
+			name, err := generator.Generate()
+			So(err, ShouldBeNil)
+			return name
+		}
+		Convey("Word lists are deterministic for a seed", func() {
+			a := NewWordListGenerator(42)
+			b := NewWordListGenerator(42)
+			for i := 0; i < 5; i++ {
+				nameA, err := a.Generate()
+				So(err, ShouldBeNil)
+				nameB, _ := b.Generate()
+				So(nameA, ShouldEqual, nameB)
+			}
+		})
+		Convey("Word lists from a file", func() {
+			path := filepath.Join(t.TempDir(), "words")
+			So(ioutil.WriteFile(path, []byte("# club words\n[adjectives]\nnoisy\n\n[nouns]\nband\n"), 0600), ShouldBeNil)
+			generator, err := LoadWordListGenerator(path, 1)
+			So(err, ShouldBeNil)
+			So(generate(generator), ShouldEqual, "noisy-band")
+
+			So(ioutil.WriteFile(path, []byte("orphan\n"), 0600), ShouldBeNil)
+			_, err = LoadWordListGenerator(path, 1)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Sequential numbering", func() {
+			generator := NewSequentialGenerator("ops-", 1)
+			So(generate(generator), ShouldEqual, "ops-1")
+			So(generate(generator), ShouldEqual, "ops-2")
+		})
+		Convey("Templates", func() {
+			generator, err := NewTemplateGenerator("net-{{.Date}}-{{.Seq}}", 7)
+			So(err, ShouldBeNil)
+			generator.now = func() time.Time { return time.Date(2026, 1, 30, 19, 30, 0, 0, time.UTC) }
+			So(generate(generator), ShouldEqual, "net-20260130-1")
+			So(generate(generator), ShouldEqual, "net-20260130-2")
+
+			_, err = NewTemplateGenerator("{{.Nope", 7)
+			So(err, ShouldNotBeNil)
+
+			_, err = NewTemplateGenerator("{{.Nope}}-{{.Seq}}", 7)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "invalid name template")
+
+			_, err = NewTemplateGenerator(`{{index .User 99}}`, 7)
+			So(err, ShouldNotBeNil)
+
+			generator, err = NewTemplateGenerator(`op{{if eq .Seq 2}}{{index .User 99}}{{end}}`, 7)
+			So(err, ShouldBeNil)
+			So(generate(generator), ShouldEqual, "op")
+			_, err = generator.Generate()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,158 @@
+package gottyclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultOTPHeader is the header one-time codes are sent in by default
+const DefaultOTPHeader = "X-OTP-Code"
+
+// OTPInPassword as OTPHeader appends the code to the basic auth password
+// instead, for gateways reading both from a single field
+const OTPInPassword = "password"
+
+// OTPChallengeHeader marks the responses of servers asking for a one-time code
+const OTPChallengeHeader = "X-OTP-Required"
+
+// maxOTPPrompts bounds how many times a code is asked for per connection
+const maxOTPPrompts = 3
+
+// ErrOTPRequired is returned when the server asks for a one-time code and
+// none could be given
+var ErrOTPRequired = fmt.Errorf("server requires a one-time code")
+
+// TOTP returns the RFC 6238 code of the base32 secret at t: 6 digits,
+// HMAC-SHA1 over 30 second steps, as used by authenticator apps
+func TOTP(secret string, t time.Time) (string, error) {
+	secret = strings.ToUpper(strings.Replace(strings.TrimSpace(secret), " ", "", -1))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %v", err)
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(t.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0F
+	code := binary.BigEndian.Uint32(sum[offset:]) & 0x7FFFFFFF
+	return fmt.Sprintf("%06d", code%1000000), nil
+}
+
+// isOTPChallenge reports whether a response asks for a one-time code
+func (c *Client) isOTPChallenge(status int, header http.Header) bool {
+	if header.Get(OTPChallengeHeader) != "" {
+		return true
+	}
+	return c.OTPChallengeStatus != 0 && status == c.OTPChallengeStatus
+}
+
+// otpCode returns the one-time code to send, if any: the one typed at the
+// prompt until used, else one from TOTPSecret or TOTPCommand. The command,
+// e.g. a password manager, is run once per 30 second step.
+func (c *Client) otpCode() (string, error) {
+	c.otpMutex.Lock()
+	defer c.otpMutex.Unlock()
+	if c.otpPrompted != "" {
+		return c.otpPrompted, nil
+	}
+	if c.TOTPSecret != "" {
+		return TOTP(c.TOTPSecret, time.Now())
+	}
+	if c.TOTPCommand == "" {
+		return "", nil
+	}
+	step := time.Now().Unix() / 30
+	if c.otpCommandCode != "" && c.otpCommandStep == step {
+		return c.otpCommandCode, nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", c.TOTPCommand)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", c.TOTPCommand)
+	}
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), c.hookEnv("otp")...)
+	logrus.Debugf("Running TOTP command: %q", c.TOTPCommand)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("TOTP command failed: %v", err)
+	}
+	c.otpCommandCode, c.otpCommandStep = strings.TrimSpace(string(output)), step
+	return c.otpCommandCode, nil
+}
+
+// forgetOTP drops the typed code once a connection used it, and the
+// command's cached code as well when the server refused it
+func (c *Client) forgetOTP(refused bool) {
+	c.otpMutex.Lock()
+	defer c.otpMutex.Unlock()
+	c.otpPrompted = ""
+	if refused {
+		c.otpCommandCode = ""
+	}
+}
+
+// promptOTP asks for a one-time code after a challenge, reporting whether
+// one was typed and the request is worth retrying
+func (c *Client) promptOTP() bool {
+	// The challenge refused any code sent
+	c.forgetOTP(true)
+	if c.OTPPrompt == nil || c.otpPrompts >= maxOTPPrompts {
+		return false
+	}
+	c.otpPrompts++
+	resume := pauseProgress(c.Progress)
+	code, err := c.OTPPrompt()
+	resume()
+	if err != nil || strings.TrimSpace(code) == "" {
+		return false
+	}
+	c.otpMutex.Lock()
+	c.otpPrompted = strings.TrimSpace(code)
+	c.otpMutex.Unlock()
+	return true
+}
+
+// otpAuth sends the client's one-time code, if any, in OTPHeader
+type otpAuth struct {
+	client *Client
+}
+
+// ApplyHeaders implements AuthProvider
+func (a otpAuth) ApplyHeaders(req *http.Request) { a.ApplyWSHeaders(req.Header) }
+
+// ApplyWSHeaders implements AuthProvider
+func (a otpAuth) ApplyWSHeaders(header http.Header) {
+	if a.client.OTPHeader == OTPInPassword {
+		return
+	}
+	code, err := a.client.otpCode()
+	if err != nil {
+		logrus.Warnf("Failed to compute one-time code: %v", err)
+		return
+	}
+	if code == "" {
+		return
+	}
+	name := a.client.OTPHeader
+	if name == "" {
+		name = DefaultOTPHeader
+	}
+	header.Set(name, code)
+}
@@ -0,0 +1,129 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTOTP(t *testing.T) {
+	Convey("Testing one-time codes", t, func() {
+		Convey("RFC 6238 test vectors", func() {
+			// The ASCII secret 12345678901234567890
+			secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+			code, err := TOTP(secret, time.Unix(59, 0))
+			So(err, ShouldBeNil)
+			So(code, ShouldEqual, "287082")
+
+			code, err = TOTP("gezd gnbv gy3t qojq gezd gnbv gy3t qojq", time.Unix(1111111109, 0))
+			So(err, ShouldBeNil)
+			So(code, ShouldEqual, "081804")
+
+			_, err = TOTP("not base32!", time.Now())
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Codes are prompted for when challenged", func() {
+			var mu sync.Mutex
+			var codes []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				codes = append(codes, r.Header.Get(DefaultOTPHeader))
+				mu.Unlock()
+				if r.Header.Get(DefaultOTPHeader) != "424242" {
+					w.Header().Set(OTPChallengeHeader, "totp")
+					http.Error(w, "one-time code required", http.StatusUnauthorized)
+					return
+				}
+				switch r.URL.Path {
+				case "/auth_token.js":
+					w.Write([]byte("var gotty_auth_token = 'token'"))
+				case "/ws":
+					serveTerminal(w, r)
+				}
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			client.V2 = true
+
+			So(client.Connect(), ShouldEqual, ErrOTPRequired)
+
+			typed := []string{"111111", "424242"}
+			client.OTPPrompt = func() (string, error) {
+				code := typed[0]
+				typed = typed[1:]
+				return code, nil
+			}
+			So(client.Connect(), ShouldBeNil)
+			defer client.Close()
+			mu.Lock()
+			defer mu.Unlock()
+			So(codes, ShouldResemble, []string{"", "", "111111", "424242", "424242"})
+
+			// The typed code is used up by the connection
+			code, err := client.otpCode()
+			So(err, ShouldBeNil)
+			So(code, ShouldBeEmpty)
+		})
+
+		Convey("The TOTP command runs once per time step", func() {
+			if runtime.GOOS == "windows" {
+				return
+			}
+			runs := filepath.Join(t.TempDir(), "runs")
+			client := &Client{TOTPCommand: "echo run >> " + runs + "; echo 123456"}
+			wg := &sync.WaitGroup{}
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					code, err := client.otpCode()
+					if err != nil || code != "123456" {
+						t.Errorf("otpCode() = %q, %v", code, err)
+					}
+				}()
+			}
+			wg.Wait()
+			countRuns := func() int {
+				data, err := os.ReadFile(runs)
+				So(err, ShouldBeNil)
+				return strings.Count(string(data), "run")
+			}
+			// Unless the step ended meanwhile
+			So(countRuns(), ShouldBeBetweenOrEqual, 1, 2)
+
+			before := countRuns()
+			client.forgetOTP(true)
+			_, err := client.otpCode()
+			So(err, ShouldBeNil)
+			So(countRuns(), ShouldEqual, before+1)
+		})
+
+		Convey("Codes can be appended to the password", func() {
+			client, err := NewClient("http://alice:pw@test.com/")
+			So(err, ShouldBeNil)
+			client.TOTPCommand = "echo 123456"
+			client.OTPHeader = OTPInPassword
+
+			header := http.Header{}
+			client.authProvider().ApplyWSHeaders(header)
+			So(header.Get("Authorization"), ShouldEqual, "Basic YWxpY2U6cHcxMjM0NTY=")
+			So(header.Get(DefaultOTPHeader), ShouldBeEmpty)
+
+			client.OTPHeader = ""
+			header = http.Header{}
+			client.authProvider().ApplyWSHeaders(header)
+			So(header.Get(DefaultOTPHeader), ShouldEqual, "123456")
+		})
+	})
+}
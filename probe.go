@@ -0,0 +1,173 @@
+package gottyclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ProbeResult describes what a server looks like from outside, as found by
+// Probe without credentials
+type ProbeResult struct {
+	URL       string `json:"url"`
+	Reachable bool   `json:"reachable"`
+	// StatusCode is the status of the auth token request
+	StatusCode int           `json:"status_code,omitempty"`
+	Latency    time.Duration `json:"latency"`
+	TLS        bool          `json:"tls"`
+	// TLSValid is set when the certificate verifies with the client's TLS
+	// settings; TLSError says why not
+	TLSValid  bool       `json:"tls_valid"`
+	TLSError  string     `json:"tls_error,omitempty"`
+	TLSExpiry *time.Time `json:"tls_expiry,omitempty"`
+	// Server is "ubersdr-gotty" for servers with the session API, "gotty"
+	// for other GoTTY servers and empty when no GoTTY was found
+	Server  string `json:"server,omitempty"`
+	Version string `json:"version,omitempty"`
+	// AuthRequired is set when the terminal or its API needs credentials
+	// of the AuthKinds: basic, bearer or other WWW-Authenticate schemes,
+	// admin-password, otp, or sso for redirects to another host
+	AuthRequired bool     `json:"auth_required"`
+	AuthKinds    []string `json:"auth_kinds,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// Probe checks the server's reachability, certificate, GoTTY flavor and
+// version, and which authentication it requires, without sending any of the
+// client's credentials or cookies. Only an invalid URL is an error; other
+// failures are reported in the result.
+func (c *Client) Probe() (*ProbeResult, error) {
+	target, err := terminalEndpoint(c.URL, "auth_token.js", c.AuthTokenPath)
+	if err != nil {
+		return nil, err
+	}
+	target.User = nil
+	terminal, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	terminal.User = nil
+	result := &ProbeResult{URL: terminal.String(), TLS: target.Scheme == "https"}
+
+	httpClient := c.httpClient()
+	httpClient.Jar = nil
+	defer httpClient.CloseIdleConnections()
+	start := time.Now()
+	resp, err := c.probeGet(httpClient, target.String())
+	if err != nil && result.TLS && isCertificateError(err) {
+		// Carry on without verification to describe the server anyway
+		result.TLSError = err.Error()
+		httpClient.Transport = &hostTransport{newTransport: func(host string) *http.Transport {
+			tr := c.httpTransport(host)
+			tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true, ServerName: c.ServerName}
+			return tr
+		}}
+		start = time.Now()
+		resp, err = c.probeGet(httpClient, target.String())
+	} else if result.TLS && err == nil {
+		result.TLSValid = true
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Latency = time.Since(start)
+	result.Reachable = true
+	result.StatusCode = resp.StatusCode
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		expiry := resp.TLS.PeerCertificates[0].NotAfter
+		result.TLSExpiry = &expiry
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.Request.URL.Host != target.Host {
+		result.addAuthKind("sso")
+	}
+	result.addAuthKinds(resp)
+	if resp.StatusCode == http.StatusOK && strings.Contains(string(body), "gotty_auth_token") {
+		result.Server = "gotty"
+	}
+
+	// The session API of ubersdr-gotty reports the version, and tells
+	// whether the admin password is needed
+	for _, path := range []string{"/api/info", "/api/sessions"} {
+		endpoint := *terminal
+		endpoint.Path = strings.TrimRight(endpoint.Path, "/") + path
+		endpoint.RawPath = ""
+		resp, err := c.probeGet(httpClient, endpoint.String())
+		if err != nil {
+			logrus.Debugf("Probing %s: %v", path, err)
+			continue
+		}
+		switch {
+		case resp.StatusCode == http.StatusOK && path == "/api/info":
+			var serverInfo ServerInfo
+			if json.NewDecoder(resp.Body).Decode(&serverInfo) == nil {
+				result.Server, result.Version = "ubersdr-gotty", serverInfo.Version
+			}
+		case resp.StatusCode == http.StatusOK:
+			result.Server = "ubersdr-gotty"
+		default:
+			result.addAuthKinds(resp)
+		}
+		resp.Body.Close()
+	}
+	return result, nil
+}
+
+// probeGet sends an unauthenticated GET, once
+func (c *Client) probeGet(httpClient *http.Client, target string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.HostHeader != "" {
+		req.Host = c.HostHeader
+	}
+	identify(req.Header, c.UserAgent, c.InstanceID)
+	logrus.Debugf("Probing %q", target)
+	return httpClient.Do(req)
+}
+
+// addAuthKinds records the authentication a response asks for
+func (r *ProbeResult) addAuthKinds(resp *http.Response) {
+	if resp.Header.Get(OTPChallengeHeader) != "" {
+		r.addAuthKind("otp")
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		challenges := resp.Header["Www-Authenticate"]
+		for _, challenge := range challenges {
+			if scheme := strings.Fields(challenge); len(scheme) > 0 {
+				r.addAuthKind(strings.ToLower(scheme[0]))
+			}
+		}
+		if len(challenges) == 0 && resp.Header.Get(OTPChallengeHeader) == "" {
+			r.addAuthKind("admin-password")
+		}
+	case http.StatusForbidden:
+		r.addAuthKind("admin-password")
+	}
+}
+
+func (r *ProbeResult) addAuthKind(kind string) {
+	r.AuthRequired = true
+	r.AuthKinds = appendUnique(r.AuthKinds, kind)
+}
+
+// isCertificateError reports whether err is a failed certificate check
+func isCertificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &invalid) || errors.As(err, &hostname)
+}
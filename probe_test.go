@@ -0,0 +1,80 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProbe(t *testing.T) {
+	Convey("Testing probes", t, func() {
+		var credentials []string
+		record := func(r *http.Request) {
+			credentials = append(credentials, r.Header.Get("Authorization")+r.Header.Get("X-Admin-Password"))
+		}
+
+		Convey("Detect ubersdr-gotty and its admin password", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				record(r)
+				switch r.URL.Path {
+				case "/auth_token.js":
+					w.Write([]byte("var gotty_auth_token = ''"))
+				case "/api/info":
+					w.Write([]byte(`{"version":"2.3.0"}`))
+				default:
+					http.Error(w, "forbidden", http.StatusForbidden)
+				}
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			client.User, client.Password, client.AdminPassword = "alice", "pw", "admin"
+			result, err := client.Probe()
+			So(err, ShouldBeNil)
+			So(result.Reachable, ShouldBeTrue)
+			So(result.TLS, ShouldBeFalse)
+			So(result.Server, ShouldEqual, "ubersdr-gotty")
+			So(result.Version, ShouldEqual, "2.3.0")
+			So(result.AuthKinds, ShouldResemble, []string{"admin-password"})
+			So(credentials, ShouldResemble, []string{"", "", ""})
+		})
+
+		Convey("Detect basic auth and invalid certificates", func() {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				record(r)
+				w.Header().Set("WWW-Authenticate", `Basic realm="sdr"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			result, err := client.Probe()
+			So(err, ShouldBeNil)
+			So(result.Reachable, ShouldBeTrue)
+			So(result.TLS, ShouldBeTrue)
+			So(result.TLSValid, ShouldBeFalse)
+			So(result.TLSError, ShouldNotBeEmpty)
+			So(result.TLSExpiry, ShouldNotBeNil)
+			So(result.StatusCode, ShouldEqual, http.StatusUnauthorized)
+			So(result.Server, ShouldBeEmpty)
+			So(result.AuthRequired, ShouldBeTrue)
+			So(result.AuthKinds, ShouldResemble, []string{"basic"})
+		})
+
+		Convey("Report unreachable servers", func() {
+			server := httptest.NewServer(http.NotFoundHandler())
+			server.Close()
+
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			result, err := client.Probe()
+			So(err, ShouldBeNil)
+			So(result.Reachable, ShouldBeFalse)
+			So(result.Error, ShouldNotBeEmpty)
+		})
+	})
+}
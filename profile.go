@@ -0,0 +1,193 @@
+package gottyclient
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProfileDirName is the directory, next to the config file, holding profiles
+// kept as separate files: profile NAME is read from profiles/NAME
+const ProfileDirName = "profiles"
+
+// checkProfileName rejects profile names that cannot be a file name
+func checkProfileName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\ `) || name == "." || name == ".." {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	return nil
+}
+
+// profileDir returns the directory of the profile files of the configuration
+func (c *Config) profileDir() string {
+	if c.path == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(c.path), ProfileDirName)
+}
+
+// ProfileNames returns the names of the Profile sections and profile files,
+// in alphabetical order
+func (c *Config) ProfileNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for name := range c.Profiles {
+		seen[name] = true
+		names = append(names, name)
+	}
+	if dir := c.profileDir(); dir != "" {
+		files, _ := ioutil.ReadDir(dir)
+		for _, file := range files {
+			name := file.Name()
+			if file.IsDir() || strings.HasPrefix(name, ".") || seen[name] {
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the configuration of profile name: its hosts over the
+// top-level hosts, its Defaults block over the top-level one, and its
+// registries instead of the top-level ones when it has any. A Profile section
+// takes precedence over a profile file.
+func (c *Config) Profile(name string) (*Config, error) {
+	if err := checkProfileName(name); err != nil {
+		return nil, err
+	}
+	profile := c.Profiles[name]
+	if profile == nil {
+		dir := c.profileDir()
+		if dir == "" {
+			return nil, fmt.Errorf("unknown profile %q", name)
+		}
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("unknown profile %q: no Profile section nor %s", name, path)
+		}
+		var err error
+		if profile, err = LoadConfigFromPath(path); err != nil {
+			return nil, fmt.Errorf("profile %s: %v", name, err)
+		}
+	}
+
+	merged := &Config{
+		Hosts:      append([]*HostConfig{}, c.Hosts...),
+		Registries: c.Registries,
+		path:       c.path,
+	}
+	if c.Defaults != nil || profile.Defaults != nil {
+		merged.Defaults = MergeHostConfigs(c.Defaults, profile.Defaults)
+	}
+	for _, host := range profile.Hosts {
+		merged.SetHost(host)
+	}
+	if len(profile.Registries) > 0 {
+		merged.Registries = profile.Registries
+	}
+	return merged, nil
+}
+
+// InlineProfileFiles reads the profile files of the configuration into
+// Profile sections, so that writing it out, e.g. to export it, includes them
+func (c *Config) InlineProfileFiles() error {
+	for _, name := range c.ProfileNames() {
+		if c.Profiles[name] != nil || checkProfileName(name) != nil {
+			continue
+		}
+		profile, err := LoadConfigFromPath(filepath.Join(c.profileDir(), name))
+		if err != nil {
+			return fmt.Errorf("profile %s: %v", name, err)
+		}
+		if c.Profiles == nil {
+			c.Profiles = make(map[string]*Config)
+		}
+		c.Profiles[name] = profile
+	}
+	return nil
+}
+
+// LoadConfigProfile loads the configuration at path as seen by profile, or by
+// the file's DefaultProfile when profile is empty
+func LoadConfigProfile(path, profile string) (*Config, error) {
+	config, err := LoadConfigFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		profile = config.DefaultProfile
+	}
+	if profile == "" {
+		return config, nil
+	}
+	return config.Profile(profile)
+}
+
+// SetDefaultProfile makes name the DefaultProfile of the config file at path,
+// or clears it when name is empty, leaving the rest of the file untouched
+func SetDefaultProfile(path, name string) error {
+	unlock, err := lockConfig(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := LoadConfigFromPath(path)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		if _, err := config.Profile(name); err != nil {
+			return err
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	// Drop the current directive and put the new one before the first
+	// setting, after the leading comments
+	insert := -1
+	kept := lines[:0]
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "DefaultProfile ") {
+			continue
+		}
+		if insert < 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			insert = len(kept)
+		}
+		kept = append(kept, line)
+	}
+	lines = kept
+	if name != "" {
+		if insert < 0 {
+			insert = len(lines)
+		}
+		directive := []string{"DefaultProfile " + name}
+		if insert < len(lines) {
+			directive = append(directive, "")
+		}
+		lines = append(lines[:insert], append(directive, lines[insert:]...)...)
+	}
+
+	if err := writeFileAtomic(path, 0600, func(writer *bufio.Writer) error {
+		_, err := writer.WriteString(strings.Join(lines, "\n") + "\n")
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	return nil
+}
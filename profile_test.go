@@ -0,0 +1,91 @@
+package gottyclient
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProfiles(t *testing.T) {
+	Convey("Testing config profiles", t, func() {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config")
+		So(ioutil.WriteFile(path, []byte(`# My hosts
+Registry public https://instances.example.org/api/instances
+
+Host shared
+    URL http://shared:8080
+
+Host lab
+    URL http://home-lab:8080
+
+Profile club
+Registry club https://club.example.org/api/instances
+
+Host lab
+    URL http://club-lab:8080
+`), 0600), ShouldBeNil)
+		So(os.MkdirAll(filepath.Join(dir, ProfileDirName), 0700), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, ProfileDirName, "work"), []byte("Host desk\n    URL http://desk:8080\n"), 0600), ShouldBeNil)
+
+		Convey("Profile sections override hosts and registries", func() {
+			config, err := LoadConfigProfile(path, "club")
+			So(err, ShouldBeNil)
+			So(config.Host("lab").URL, ShouldEqual, "http://club-lab:8080")
+			So(config.Host("shared").URL, ShouldEqual, "http://shared:8080")
+			So(config.Registries, ShouldHaveLength, 1)
+			So(config.Registries[0].Name, ShouldEqual, "club")
+
+			config, err = LoadConfigProfile(path, "")
+			So(err, ShouldBeNil)
+			So(config.Host("lab").URL, ShouldEqual, "http://home-lab:8080")
+			So(config.Profiles, ShouldContainKey, "club")
+		})
+
+		Convey("Profile files are found next to the config", func() {
+			config, err := LoadConfigProfile(path, "work")
+			So(err, ShouldBeNil)
+			So(config.Host("desk").URL, ShouldEqual, "http://desk:8080")
+			So(config.Registries[0].Name, ShouldEqual, "public")
+
+			base, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			So(base.ProfileNames(), ShouldResemble, []string{"club", "work"})
+
+			_, err = LoadConfigProfile(path, "home")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("The default profile is persisted in place", func() {
+			So(SetDefaultProfile(path, "club"), ShouldBeNil)
+			data, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldStartWith, "# My hosts\nDefaultProfile club\n\nRegistry public")
+
+			config, err := LoadConfigProfile(path, "")
+			So(err, ShouldBeNil)
+			So(config.Host("lab").URL, ShouldEqual, "http://club-lab:8080")
+
+			So(SetDefaultProfile(path, "home"), ShouldNotBeNil)
+			So(SetDefaultProfile(path, ""), ShouldBeNil)
+			base, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			So(base.DefaultProfile, ShouldEqual, "")
+		})
+
+		Convey("Profiles survive rewriting the file", func() {
+			base, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			base.DefaultProfile = "club"
+			So(WriteConfig(path, base), ShouldBeNil)
+
+			config, err := LoadConfigProfile(path, "")
+			So(err, ShouldBeNil)
+			So(config.Host("lab").URL, ShouldEqual, "http://club-lab:8080")
+			So(config.Registries[0].Name, ShouldEqual, "club")
+		})
+	})
+}
@@ -0,0 +1,177 @@
+package gottyclient
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressReporter is told about slow operations such as registry lookups,
+// auth token fetches and websocket dials, and about their retries
+type ProgressReporter interface {
+	// Start begins reporting an operation
+	Start(message string)
+	// Retry reports that an attempt failed and another follows after wait
+	Retry(attempt, attempts int, wait time.Duration, reason string)
+	// Stop ends the current operation
+	Stop()
+}
+
+// startProgress reports an operation to progress, if not nil, returning the
+// function ending it
+func startProgress(progress ProgressReporter, message string) func() {
+	if progress == nil {
+		return func() {}
+	}
+	progress.Start(message)
+	return progress.Stop
+}
+
+// startProgress reports an operation of the client, unless it is
+// reconnecting from within Loop where the terminal belongs to the session
+func (c *Client) startProgress(message string) func() {
+	if c.isReconnecting() {
+		return func() {}
+	}
+	return startProgress(c.Progress, message)
+}
+
+// progressPauser is implemented by reporters that draw on the terminal and
+// must step aside while the user answers a prompt
+type progressPauser interface {
+	Pause()
+	Resume()
+}
+
+// pauseProgress takes progress off the terminal while the user is asked
+// something, returning the function bringing it back
+func pauseProgress(progress ProgressReporter) func() {
+	pauser, ok := progress.(progressPauser)
+	if !ok {
+		return func() {}
+	}
+	pauser.Pause()
+	return pauser.Resume
+}
+
+// reportRetry tells progress, if not nil, about a retry
+func reportRetry(progress ProgressReporter, attempt, attempts int, wait time.Duration, reason string) {
+	if progress != nil {
+		progress.Retry(attempt, attempts, wait, reason)
+	}
+}
+
+// spinnerFrames are drawn in turn while an operation is running
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Spinner is a ProgressReporter drawing a spinner with the elapsed time on a
+// terminal. Operations finishing within Delay are not shown at all, so fast
+// connections stay quiet.
+type Spinner struct {
+	W     io.Writer
+	Delay time.Duration
+
+	mutex   sync.Mutex
+	message string
+	retry   string
+	started time.Time
+	frame   int
+	drawn   bool
+	paused  time.Time
+	done    chan struct{}
+}
+
+// NewSpinner returns a spinner writing to w, usually os.Stderr
+func NewSpinner(w io.Writer) *Spinner {
+	return &Spinner{W: w, Delay: 300 * time.Millisecond}
+}
+
+// Start implements ProgressReporter
+func (s *Spinner) Start(message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.message = message
+	s.retry = ""
+	s.started = time.Now()
+	if s.done != nil {
+		// Already spinning, just show the new operation
+		return
+	}
+	s.done = make(chan struct{})
+	go s.spin(s.done)
+}
+
+// Retry implements ProgressReporter
+func (s *Spinner) Retry(attempt, attempts int, wait time.Duration, reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.retry = fmt.Sprintf("attempt %d/%d failed (%s), retrying in %v", attempt, attempts, reason, wait.Round(100*time.Millisecond))
+}
+
+// Stop implements ProgressReporter
+func (s *Spinner) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.done == nil {
+		return
+	}
+	close(s.done)
+	s.done = nil
+	if s.drawn {
+		fmt.Fprint(s.W, "\r\x1b[K")
+		s.drawn = false
+	}
+}
+
+// Pause clears the spinner and keeps it from drawing until Resume, so that a
+// prompt shown meanwhile is not overwritten
+func (s *Spinner) Pause() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.paused.IsZero() {
+		return
+	}
+	s.paused = time.Now()
+	if s.drawn {
+		fmt.Fprint(s.W, "\r\x1b[K")
+		s.drawn = false
+	}
+}
+
+// Resume draws the spinner again after Pause, leaving the time spent paused
+// out of the elapsed time
+func (s *Spinner) Resume() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.paused.IsZero() {
+		return
+	}
+	s.started = s.started.Add(time.Since(s.paused))
+	s.paused = time.Time{}
+}
+
+func (s *Spinner) spin(done chan struct{}) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		s.mutex.Lock()
+		elapsed := time.Since(s.started)
+		if s.done == done && s.paused.IsZero() && elapsed >= s.Delay {
+			line := fmt.Sprintf("%s %s %.1fs", spinnerFrames[s.frame%len(spinnerFrames)], s.message, elapsed.Seconds())
+			if s.retry != "" {
+				line += " - " + s.retry
+			}
+			fmt.Fprint(s.W, "\r\x1b[K"+line)
+			s.frame++
+			s.drawn = true
+		}
+		s.mutex.Unlock()
+	}
+}
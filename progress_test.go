@@ -0,0 +1,91 @@
+package gottyclient
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recordingProgress struct {
+	started []string
+	retries []string
+	stopped int
+}
+
+func (p *recordingProgress) Start(message string) { p.started = append(p.started, message) }
+func (p *recordingProgress) Retry(attempt, attempts int, wait time.Duration, reason string) {
+	p.retries = append(p.retries, reason)
+}
+func (p *recordingProgress) Stop() { p.stopped++ }
+
+func TestProgress(t *testing.T) {
+	Convey("Testing progress reporting", t, func() {
+		Convey("Spinner shows the elapsed time and retries, then clears the line", func() {
+			var buf bytes.Buffer
+			spinner := NewSpinner(&buf)
+			spinner.Delay = 0
+			spinner.Start("Connecting to example.com")
+			spinner.Retry(1, 3, time.Second, "503")
+			time.Sleep(250 * time.Millisecond)
+			spinner.Stop()
+
+			output := buf.String()
+			So(output, ShouldContainSubstring, "Connecting to example.com")
+			So(output, ShouldContainSubstring, "attempt 1/3 failed (503), retrying in 1s")
+			So(strings.HasSuffix(output, "\r\x1b[K"), ShouldBeTrue)
+		})
+		Convey("A paused spinner clears the line and stays off the terminal", func() {
+			var buf bytes.Buffer
+			spinner := NewSpinner(&buf)
+			spinner.Delay = 0
+			spinner.Start("Fetching auth token")
+			time.Sleep(250 * time.Millisecond)
+			spinner.Pause()
+			So(strings.HasSuffix(buf.String(), "\r\x1b[K"), ShouldBeTrue)
+
+			drawn := buf.String()
+			time.Sleep(250 * time.Millisecond)
+			So(buf.String(), ShouldEqual, drawn)
+
+			spinner.Resume()
+			time.Sleep(250 * time.Millisecond)
+			spinner.Stop()
+			So(len(buf.String()), ShouldBeGreaterThan, len(drawn))
+		})
+		Convey("Spinner stays quiet for fast operations", func() {
+			var buf bytes.Buffer
+			spinner := NewSpinner(&buf)
+			spinner.Start("Fetching auth token")
+			spinner.Stop()
+			So(buf.String(), ShouldEqual, "")
+		})
+		Convey("RetryPolicy reports retries", func() {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls < 2 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			recorder := &recordingProgress{}
+			req, err := http.NewRequest("GET", server.URL, nil)
+			So(err, ShouldBeNil)
+			stop := startProgress(recorder, "Looking up registry")
+			_, err = (&RetryPolicy{Attempts: 3, Backoff: time.Millisecond}).do(http.DefaultClient, req, recorder)
+			stop()
+			So(err, ShouldBeNil)
+			So(recorder.started, ShouldResemble, []string{"Looking up registry"})
+			So(recorder.retries, ShouldResemble, []string{"502"})
+			So(recorder.stopped, ShouldEqual, 1)
+		})
+	})
+}
@@ -27,6 +27,14 @@ func (EscapeError) Error() string {
 	return "read escape sequence"
 }
 
+// MenuEscapeError is returned by a menu proxy reader's Read() method when the
+// escape menu key sequence is read.
+type MenuEscapeError struct{}
+
+func (MenuEscapeError) Error() string {
+	return "read menu escape sequence"
+}
+
 // escapeProxy is used only for attaches with a TTY. It is used to proxy
 // stdin keypresses from the underlying reader and look for the passed in
 // escape key sequence to signal a detach.
@@ -34,6 +42,7 @@ type escapeProxy struct {
 	escapeKeys   []byte
 	escapeKeyPos int
 	r            io.Reader
+	err          error
 }
 
 // NewEscapeProxy returns a new TTY proxy reader which wraps the given reader
@@ -43,6 +52,18 @@ func NewEscapeProxy(r io.Reader, escapeKeys []byte) io.Reader {
 	return &escapeProxy{
 		escapeKeys: escapeKeys,
 		r:          r,
+		err:        EscapeError{},
+	}
+}
+
+// NewMenuProxy works like NewEscapeProxy but returns a MenuEscapeError when
+// the menu keys are read, so the caller can open the escape menu instead of
+// detaching.
+func NewMenuProxy(r io.Reader, menuKeys []byte) io.Reader {
+	return &escapeProxy{
+		escapeKeys: menuKeys,
+		r:          r,
+		err:        MenuEscapeError{},
 	}
 }
 
@@ -78,7 +99,8 @@ func (r *escapeProxy) Read(buf []byte) (int, error) {
 	}
 
 	if r.escapeKeyPos == len(r.escapeKeys)-1 {
-		return 0, EscapeError{}
+		r.escapeKeyPos = 0
+		return 0, r.err
 	}
 
 	// Looks like we've got an escape key, but we need to match again on the next
@@ -0,0 +1,59 @@
+package gottyclient
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// byteReader returns one byte per Read, like a terminal in raw mode
+type byteReader struct {
+	data []byte
+}
+
+func (r *byteReader) Read(buf []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, nil
+	}
+	buf[0] = r.data[0]
+	r.data = r.data[1:]
+	return 1, nil
+}
+
+func TestMenuProxy(t *testing.T) {
+	Convey("Testing NewMenuProxy", t, func() {
+		Convey("Menu keys inside detach proxy", func() {
+			detach := NewEscapeProxy(&byteReader{data: []byte{'a', 29, 'c', 29}}, []byte{16, 17})
+			pr := NewMenuProxy(detach, []byte{29})
+			buf := make([]byte, 8)
+
+			n, err := pr.Read(buf)
+			So(err, ShouldBeNil)
+			So(buf[:n], ShouldResemble, []byte{'a'})
+
+			_, err = pr.Read(buf)
+			So(err, ShouldHaveSameTypeAs, MenuEscapeError{})
+
+			n, err = pr.Read(buf)
+			So(err, ShouldBeNil)
+			So(buf[:n], ShouldResemble, []byte{'c'})
+
+			// The menu can be opened more than once
+			_, err = pr.Read(buf)
+			So(err, ShouldHaveSameTypeAs, MenuEscapeError{})
+		})
+		Convey("Detach keys still work", func() {
+			detach := NewEscapeProxy(&byteReader{data: []byte{16, 17}}, []byte{16, 17})
+			pr := NewMenuProxy(detach, []byte{29})
+			buf := make([]byte, 8)
+
+			n, err := pr.Read(buf)
+			So(err, ShouldBeNil)
+			So(bytes.Count(buf[:n], []byte{16}), ShouldEqual, 0)
+
+			_, err = pr.Read(buf)
+			So(err, ShouldHaveSameTypeAs, EscapeError{})
+		})
+	})
+}
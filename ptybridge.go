@@ -0,0 +1,87 @@
+package gottyclient
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ptyBridgeQueue is how many output chunks a PTYBridge holds for a program
+// that is not reading; later ones are dropped, as a serial port overruns
+const ptyBridgeQueue = 256
+
+// PTYBridge is a local pseudo-terminal standing in for a serial device, e.g.
+// for CAT control programs such as fldigi or WSJT-X. Set as both the Stdin and
+// the Output of a Stdio client, it carries the remote session's byte stream:
+// what programs write to Path is sent to the session, and the session's
+// output can be read from Path.
+type PTYBridge struct {
+	// Path is the device programs open, e.g. /dev/pts/3
+	Path string
+
+	pty       ptyPair
+	queue     chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+	dropped   int
+}
+
+// newPTYBridge starts relaying output to the pseudo-terminal
+func newPTYBridge(path string, pty ptyPair) *PTYBridge {
+	b := &PTYBridge{Path: path, pty: pty, queue: make(chan []byte, ptyBridgeQueue), closed: make(chan struct{})}
+	go b.writeLoop()
+	return b
+}
+
+// Read reads what programs wrote to the device
+func (b *PTYBridge) Read(p []byte) (int, error) {
+	return b.pty.Read(p)
+}
+
+// Write queues output for programs reading the device without blocking,
+// dropping it when nobody has read the device for a while. Each overrun is
+// logged when it starts and when it ends.
+func (b *PTYBridge) Write(p []byte) (int, error) {
+	select {
+	case b.queue <- append([]byte(nil), p...):
+		if b.dropped > 0 {
+			logrus.Warnf("Dropped %d bytes of output nobody read from %s", b.dropped, b.Path)
+			b.dropped = 0
+		}
+	case <-b.closed:
+	default:
+		if b.dropped == 0 {
+			logrus.Warnf("Nothing is reading %s, dropping output", b.Path)
+		}
+		b.dropped += len(p)
+	}
+	return len(p), nil
+}
+
+func (b *PTYBridge) writeLoop() {
+	for {
+		select {
+		case data := <-b.queue:
+			if _, err := b.pty.Write(data); err != nil {
+				logrus.Debugf("Failed to write to %s: %v", b.Path, err)
+			}
+		case <-b.closed:
+			return
+		}
+	}
+}
+
+// Fd returns the descriptor Loop waits on for input
+func (b *PTYBridge) Fd() uintptr {
+	return b.pty.Fd()
+}
+
+// Close removes the device
+func (b *PTYBridge) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.closed)
+		err = b.pty.Close()
+	})
+	return err
+}
@@ -0,0 +1,22 @@
+//go:build !darwin && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !darwin,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package gottyclient
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// ptyPair is not available without pseudo-terminals
+type ptyPair interface {
+	io.ReadWriteCloser
+	Fd() uintptr
+}
+
+// OpenPTYBridge is not supported on this platform: virtual COM ports on
+// Windows need a driver
+func OpenPTYBridge() (*PTYBridge, error) {
+	return nil, fmt.Errorf("pseudo-terminal bridges are not supported on %s, where virtual serial ports need a driver", runtime.GOOS)
+}
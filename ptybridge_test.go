@@ -0,0 +1,83 @@
+//go:build darwin || freebsd || linux || netbsd || openbsd || solaris
+// +build darwin freebsd linux netbsd openbsd solaris
+
+package gottyclient
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPTYBridge(t *testing.T) {
+	Convey("Testing the pseudo-terminal bridge", t, func() {
+		bridge, err := OpenPTYBridge()
+		So(err, ShouldBeNil)
+		defer bridge.Close()
+		So(bridge.Path, ShouldNotBeEmpty)
+
+		device, err := os.OpenFile(bridge.Path, os.O_RDWR, 0)
+		So(err, ShouldBeNil)
+		defer device.Close()
+
+		Convey("Bytes pass unchanged both ways", func() {
+			command := []byte("FA;\r\n\x03\x7f")
+			_, err := device.Write(command)
+			So(err, ShouldBeNil)
+			buf := make([]byte, 64)
+			n, err := bridge.Read(buf)
+			So(err, ShouldBeNil)
+			So(buf[:n], ShouldResemble, command)
+
+			answer := []byte("FA00014074000;\r\n")
+			_, err = bridge.Write(answer)
+			So(err, ShouldBeNil)
+			device.SetReadDeadline(time.Now().Add(2 * time.Second))
+			n, err = device.Read(buf)
+			So(err, ShouldBeNil)
+			So(buf[:n], ShouldResemble, answer)
+		})
+
+		Convey("Reading survives programs closing the device", func() {
+			So(device.Close(), ShouldBeNil)
+			device, err = os.OpenFile(bridge.Path, os.O_RDWR, 0)
+			So(err, ShouldBeNil)
+			_, err = device.Write([]byte("IF;"))
+			So(err, ShouldBeNil)
+			buf := make([]byte, 64)
+			n, err := bridge.Read(buf)
+			So(err, ShouldBeNil)
+			So(string(buf[:n]), ShouldEqual, "IF;")
+		})
+
+		Convey("Output nobody reads is dropped without blocking", func() {
+			chunk := make([]byte, 1024)
+			done := make(chan struct{})
+			go func() {
+				for i := 0; i < 4*ptyBridgeQueue; i++ {
+					bridge.Write(chunk)
+				}
+				close(done)
+			}()
+			blocked := false
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				blocked = true
+			}
+			So(blocked, ShouldBeFalse)
+			So(bridge.dropped, ShouldBeGreaterThan, 0)
+
+			// Once a program reads again, the next overrun is reported anew
+			buf := make([]byte, 4096)
+			for deadline := time.Now().Add(5 * time.Second); len(bridge.queue) > 0 && time.Now().Before(deadline); {
+				device.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+				device.Read(buf)
+			}
+			bridge.Write(chunk)
+			So(bridge.dropped, ShouldEqual, 0)
+		})
+	})
+}
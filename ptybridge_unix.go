@@ -0,0 +1,45 @@
+//go:build darwin || freebsd || linux || netbsd || openbsd || solaris
+// +build darwin freebsd linux netbsd openbsd solaris
+
+package gottyclient
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/containerd/console"
+	xterm "golang.org/x/crypto/ssh/terminal"
+)
+
+// ptyPair is the master of a pseudo-terminal and its slave, held open so
+// reading the master does not fail while no program has the device open
+type ptyPair struct {
+	console.Console
+	slave *os.File
+}
+
+func (p ptyPair) Close() error {
+	p.slave.Close()
+	return p.Console.Close()
+}
+
+// OpenPTYBridge allocates a pseudo-terminal in raw mode, so bytes pass
+// unchanged as on a serial line
+func OpenPTYBridge() (*PTYBridge, error) {
+	master, path, err := console.NewPty()
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate a pseudo-terminal: %v", err)
+	}
+	slave, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		master.Close()
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	// Unlike console's SetRaw, MakeRaw turns output processing off too
+	if _, err := xterm.MakeRaw(int(slave.Fd())); err != nil {
+		slave.Close()
+		master.Close()
+		return nil, fmt.Errorf("failed to set %s to raw mode: %v", path, err)
+	}
+	return newPTYBridge(path, ptyPair{Console: master, slave: slave}), nil
+}
@@ -0,0 +1,227 @@
+package gottyclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// RaceDelay is how long a connection attempt gets before the next candidate,
+// an AlternateURL or another address of a host, is tried alongside it. A
+// failed attempt starts the next candidate at once.
+const RaceDelay = 250 * time.Millisecond
+
+// candidateURLs returns URL followed by the AlternateURLs with URL's path
+// and parameters, without duplicates
+func (c *Client) candidateURLs() ([]string, error) {
+	primary, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	candidates := []string{c.URL}
+	seen := map[string]bool{primary.Scheme + "://" + primary.Host: true}
+	for _, alternate := range c.AlternateURLs {
+		parsed, err := ParseURL(alternate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alternate URL %q: %v", alternate, err)
+		}
+		origin, err := url.Parse(parsed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alternate URL %q: %v", alternate, err)
+		}
+		if seen[origin.Scheme+"://"+origin.Host] {
+			continue
+		}
+		seen[origin.Scheme+"://"+origin.Host] = true
+		candidate := *primary
+		candidate.Scheme, candidate.Host = origin.Scheme, origin.Host
+		candidates = append(candidates, candidate.String())
+	}
+	return candidates, nil
+}
+
+// raceResult is the outcome of one candidate of a race
+type raceResult struct {
+	index int
+	token string
+	err   error
+}
+
+// race runs attempt on each of n candidates, starting the next one after
+// RaceDelay or as soon as an attempt fails, and returns the first
+// successful result, cancelling the others. It returns every error when all
+// attempts fail.
+func race(ctx context.Context, n int, attempt func(ctx context.Context, index int) (string, error)) (raceResult, []error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan raceResult, n)
+	started, pending := 0, 0
+	start := func() <-chan time.Time {
+		index := started
+		started++
+		pending++
+		go func() {
+			token, err := attempt(ctx, index)
+			results <- raceResult{index, token, err}
+		}()
+		if started < n {
+			return time.After(RaceDelay)
+		}
+		return nil
+	}
+
+	errs := make([]error, n)
+	next := start()
+	for pending > 0 {
+		select {
+		case <-next:
+			next = start()
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				return result, nil
+			}
+			errs[result.index] = result.err
+			if started < n {
+				next = start()
+			}
+		}
+	}
+	return raceResult{}, errs
+}
+
+// raceAlternates races the websocket handshakes of the candidate URLs and
+// makes the first server to complete one the client's URL, returning its
+// connection for connect to carry on with. A server refusing the handshake,
+// e.g. the credentials, or whose certificate is yet to be confirmed counts
+// as an answer too, to be dealt with by the connection, which then has to
+// dial again. Each candidate's certificate is checked against its own
+// known_hosts entry. Only the first race won changes URL: reconnections,
+// running alongside loops reading URL, keep the winner and return nil.
+func (c *Client) raceAlternates(ctx context.Context) (*WebsocketTransport, error) {
+	if c.raced {
+		return nil, nil
+	}
+	if c.raceURLs == nil {
+		candidates, err := c.candidateURLs()
+		if err != nil {
+			return nil, err
+		}
+		c.raceURLs = candidates
+	}
+	if len(c.raceURLs) < 2 {
+		return nil, nil
+	}
+
+	primary, err := url.Parse(c.raceURLs[0])
+	if err != nil {
+		return nil, err
+	}
+	stop := c.startProgress(fmt.Sprintf("Connecting to %s (%d addresses)", primary.Host, len(c.raceURLs)))
+	var won int32
+	var winner *WebsocketTransport
+	result, errs := race(ctx, len(c.raceURLs), func(ctx context.Context, index int) (string, error) {
+		target, header, opts, err := c.websocketRequestTo(c.raceURLs[index])
+		if err != nil {
+			return "", err
+		}
+		// The candidates' dials must not share the Dialer they configure
+		dialer := &websocket.Dialer{}
+		if c.Dialer != nil {
+			*dialer = *c.Dialer
+		}
+		transport := &WebsocketTransport{Dialer: dialer, OnPong: c.handlePong}
+		err = transport.Dial(ctx, target.String(), *header, opts)
+		var handshakeErr *HandshakeError
+		var unknown *UnknownHostError
+		if err != nil && !errors.As(err, &handshakeErr) && !errors.As(err, &unknown) {
+			return "", err
+		}
+		// Connections completed just after the winner's are closed
+		if !atomic.CompareAndSwapInt32(&won, 0, 1) {
+			_ = transport.Close()
+			return "", fmt.Errorf("another address answered first")
+		}
+		if err == nil {
+			winner = transport
+		}
+		return "", nil
+	})
+	stop()
+	if errs != nil {
+		var reasons []string
+		for i, err := range errs {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", c.raceURLs[i], err))
+		}
+		return nil, fmt.Errorf("no address of the server answered: %s", strings.Join(reasons, "; "))
+	}
+
+	c.URL = c.raceURLs[result.index]
+	c.raced = true
+	logrus.Debugf("Connecting through %s, the first address to answer", c.URL)
+	return winner, nil
+}
+
+// raceDial dials the addresses of a host, alternating IPv6 and IPv4 from the
+// family of the first, and returns the first connection established, as in
+// RFC 8305 "Happy Eyeballs". The default dialer does the same by itself.
+func (c *Client) raceDial(ctx context.Context, dialer *net.Dialer, network string, addrs []string) (net.Conn, error) {
+	addrs = interleaveFamilies(addrs)
+	var won int32
+	var winner net.Conn
+	_, errs := race(ctx, len(addrs), func(ctx context.Context, index int) (string, error) {
+		conn, err := c.tcpDial(ctx, dialer, network, addrs[index])
+		if err != nil {
+			return "", err
+		}
+		// Connections established just after the winner's are closed
+		if !atomic.CompareAndSwapInt32(&won, 0, 1) {
+			conn.Close()
+			return "", fmt.Errorf("another address connected first")
+		}
+		winner = conn
+		return "", nil
+	})
+	if errs != nil {
+		return nil, errs[0]
+	}
+	return winner, nil
+}
+
+// interleaveFamilies orders host:port addresses alternating between IP
+// families, starting with the family of the first
+func interleaveFamilies(addrs []string) []string {
+	var first, second []string
+	firstIPv4 := false
+	for i, addr := range addrs {
+		host, _, _ := net.SplitHostPort(addr)
+		ipv4 := net.ParseIP(host).To4() != nil
+		if i == 0 {
+			firstIPv4 = ipv4
+		}
+		if ipv4 == firstIPv4 {
+			first = append(first, addr)
+		} else {
+			second = append(second, addr)
+		}
+	}
+	ordered := make([]string, 0, len(addrs))
+	for len(first) > 0 || len(second) > 0 {
+		if len(first) > 0 {
+			ordered, first = append(ordered, first[0]), first[1:]
+		}
+		if len(second) > 0 {
+			ordered, second = append(ordered, second[0]), second[1:]
+		}
+	}
+	return ordered
+}
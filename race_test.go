@@ -0,0 +1,188 @@
+package gottyclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAlternateURLs(t *testing.T) {
+	Convey("Testing racing alternate URLs", t, func() {
+		upgrader := websocket.Upgrader{}
+		fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/sdr/auth_token.js":
+				w.Write([]byte("var gotty_auth_token = 'fast'"))
+			case "/sdr/ws":
+				if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+					conn.Close()
+				}
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer fast.Close()
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}))
+		defer slow.Close()
+
+		Convey("Candidates keep the URL's path and parameters", func() {
+			client, err := NewClient("http://primary.example.com:8080/sdr/?arg=1")
+			So(err, ShouldBeNil)
+			client.AlternateURLs = []string{"https://10.0.0.5", "http://primary.example.com:8080", "http://[fd00::5]:8080/ignored/"}
+			candidates, err := client.candidateURLs()
+			So(err, ShouldBeNil)
+			So(candidates, ShouldResemble, []string{
+				"http://primary.example.com:8080/sdr/?arg=1",
+				"https://10.0.0.5/sdr/?arg=1",
+				"http://[fd00::5]:8080/sdr/?arg=1",
+			})
+		})
+
+		Convey("The first server to answer becomes the URL", func() {
+			client, err := NewClient(slow.URL + "/sdr/")
+			So(err, ShouldBeNil)
+			client.RetryPolicy = NoRetry
+			client.AlternateURLs = []string{fast.URL}
+			start := time.Now()
+			So(client.HealthCheck(), ShouldBeNil)
+			So(time.Since(start), ShouldBeLessThan, 2*time.Second)
+			So(client.URL, ShouldEqual, fast.URL+"/sdr/")
+		})
+
+		Convey("A refused primary starts the next candidate at once", func() {
+			closed := httptest.NewServer(http.NotFoundHandler())
+			closed.Close()
+			client, err := NewClient(closed.URL + "/sdr/")
+			So(err, ShouldBeNil)
+			client.RetryPolicy = NoRetry
+			client.AlternateURLs = []string{fast.URL}
+			transport, err := client.raceAlternates(context.Background())
+			So(err, ShouldBeNil)
+			So(transport, ShouldNotBeNil)
+			So(transport.Close(), ShouldBeNil)
+			So(client.URL, ShouldEqual, fast.URL+"/sdr/")
+
+			// Reconnections keep the winner
+			fast.Close()
+			transport, err = client.raceAlternates(context.Background())
+			So(err, ShouldBeNil)
+			So(transport, ShouldBeNil)
+			So(client.URL, ShouldEqual, fast.URL+"/sdr/")
+		})
+
+		Convey("The winning handshake is the connection", func() {
+			var mutex sync.Mutex
+			upgrades := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/sdr/auth_token.js":
+					w.Write([]byte("var gotty_auth_token = 'token'"))
+				case "/sdr/ws":
+					mutex.Lock()
+					upgrades++
+					mutex.Unlock()
+					if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+						defer conn.Close()
+						conn.ReadMessage()
+						conn.WriteMessage(websocket.TextMessage, []byte("3"))
+						conn.ReadMessage()
+					}
+				}
+			}))
+			defer server.Close()
+
+			client, err := NewClient(slow.URL + "/sdr/")
+			So(err, ShouldBeNil)
+			client.RetryPolicy = NoRetry
+			client.V2 = true
+			client.AlternateURLs = []string{server.URL}
+			So(client.Connect(), ShouldBeNil)
+			defer client.Close()
+			So(client.URL, ShouldEqual, server.URL+"/sdr/")
+			mutex.Lock()
+			defer mutex.Unlock()
+			So(upgrades, ShouldEqual, 1)
+		})
+
+		Convey("Each candidate is checked against its own known_hosts entry", func() {
+			secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+					conn.Close()
+				}
+			}))
+			defer secure.Close()
+
+			knownHosts, err := LoadKnownHosts(filepath.Join(t.TempDir(), "known_hosts"))
+			So(err, ShouldBeNil)
+			So(knownHosts.Add("primary.invalid", "SHA256:primary"), ShouldBeNil)
+			So(knownHosts.Add(secure.Listener.Addr().String(), CertificateFingerprint(secure.Certificate())), ShouldBeNil)
+
+			client, err := NewClient("https://primary.invalid/sdr/")
+			So(err, ShouldBeNil)
+			client.KnownHosts = knownHosts
+			client.ConfirmHost = func(host, fingerprint string, err error) bool {
+				So("asked about "+host, ShouldBeEmpty)
+				return false
+			}
+			client.AlternateURLs = []string{secure.URL}
+			transport, err := client.raceAlternates(context.Background())
+			So(err, ShouldBeNil)
+			So(transport, ShouldNotBeNil)
+			So(transport.Close(), ShouldBeNil)
+			So(client.URL, ShouldEqual, secure.URL+"/sdr/")
+		})
+
+		Convey("No answer reports every candidate", func() {
+			closed := httptest.NewServer(http.NotFoundHandler())
+			closed.Close()
+			client, err := NewClient(closed.URL + "/sdr/")
+			So(err, ShouldBeNil)
+			client.RetryPolicy = NoRetry
+			client.AlternateURLs = []string{"http://127.0.0.1:1"}
+			_, err = client.raceAlternates(context.Background())
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "no address of the server answered")
+			So(err.Error(), ShouldContainSubstring, "127.0.0.1:1")
+		})
+	})
+}
+
+func TestRaceDial(t *testing.T) {
+	Convey("Testing racing the addresses of a host", t, func() {
+		Convey("Families alternate from the first address's", func() {
+			So(interleaveFamilies([]string{"[::1]:80", "[::2]:80", "1.1.1.1:80", "2.2.2.2:80", "3.3.3.3:80"}), ShouldResemble,
+				[]string{"[::1]:80", "1.1.1.1:80", "[::2]:80", "2.2.2.2:80", "3.3.3.3:80"})
+			So(interleaveFamilies([]string{"1.1.1.1:80", "2.2.2.2:80", "[::1]:80"}), ShouldResemble,
+				[]string{"1.1.1.1:80", "[::1]:80", "2.2.2.2:80"})
+		})
+
+		Convey("A refused address falls through to one listening", func() {
+			closed, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			closedAddr := closed.Addr().String()
+			closed.Close()
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer listener.Close()
+
+			client := &Client{}
+			conn, err := client.raceDial(context.Background(), &net.Dialer{Timeout: time.Second}, "tcp", []string{closedAddr, listener.Addr().String()})
+			So(err, ShouldBeNil)
+			So(conn.RemoteAddr().String(), ShouldEqual, listener.Addr().String())
+			conn.Close()
+		})
+	})
+}
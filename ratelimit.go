@@ -0,0 +1,144 @@
+package gottyclient
+
+import (
+	"time"
+)
+
+// DefaultInputBurst is the input burst allowed when only InputRate is set
+const DefaultInputBurst = 4096
+
+// pasteQuietTime is how long input must pause before a single key is taken
+// as the answer to the paste confirmation rather than the end of the paste
+const pasteQuietTime = 200 * time.Millisecond
+
+// maxHeldInput caps the input held back or queued by the rate limit; the
+// rest of a longer paste is dropped
+const maxHeldInput = 1 << 20
+
+// inputLimiter is a token bucket over the bytes of typed input, holding
+// back pastes exceeding it until they are confirmed and then sending them
+// at the limited rate
+type inputLimiter struct {
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	pending []byte
+	pasted  time.Time
+	queued  []byte
+	dropped bool
+}
+
+func newInputLimiter(rate, burst int) *inputLimiter {
+	if burst <= 0 {
+		burst = DefaultInputBurst
+	}
+	return &inputLimiter{rate: float64(rate), burst: float64(burst), tokens: float64(burst)}
+}
+
+// refill adds the tokens earned since the last call
+func (l *inputLimiter) refill(now time.Time) {
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+// allow takes n tokens if available
+func (l *inputLimiter) allow(n int, now time.Time) bool {
+	l.refill(now)
+	if float64(n) > l.tokens {
+		return false
+	}
+	l.tokens -= float64(n)
+	return true
+}
+
+// take takes up to n whole tokens, returning how many it took
+func (l *inputLimiter) take(n int, now time.Time) int {
+	l.refill(now)
+	if available := int(l.tokens); available < n {
+		n = available
+	}
+	l.tokens -= float64(n)
+	return n
+}
+
+// limitInput returns the input to send now. Input over the InputRate limit
+// is held back and the user is asked whether to send it; the paste is
+// queued on 'y', to be sent by pacedInput, and discarded on any other key.
+func (c *Client) limitInput(data []byte, now time.Time) []byte {
+	if c.InputRate <= 0 {
+		return data
+	}
+	if c.inputLimiter == nil {
+		c.inputLimiter = newInputLimiter(c.InputRate, c.InputBurst)
+	}
+	l := c.inputLimiter
+
+	if l.pending != nil {
+		if len(data) != 1 || now.Sub(l.pasted) < pasteQuietTime {
+			// Still pasting
+			l.pending = c.holdInput(l.pending, data)
+			l.pasted = now
+			return nil
+		}
+		pending := l.pending
+		l.pending, l.dropped = nil, false
+		if data[0] == 'y' || data[0] == 'Y' {
+			c.statusf("sending %d bytes at %d bytes/s", len(pending), c.InputRate)
+			l.queued = pending
+			return c.pacedInput(now)
+		}
+		c.statusf("paste of %d bytes discarded", len(pending))
+		return nil
+	}
+
+	if len(l.queued) > 0 {
+		// Typing goes after the paste being sent
+		l.queued = c.holdInput(l.queued, data)
+		return c.pacedInput(now)
+	}
+	if l.allow(len(data), now) {
+		return data
+	}
+	l.pending = c.holdInput([]byte{}, data)
+	l.pasted = now
+	c.statusf("input exceeds %d bytes/s, paste held back; send it? [y/N]", c.InputRate)
+	return nil
+}
+
+// holdInput appends data to input held back or queued, dropping what does
+// not fit within maxHeldInput
+func (c *Client) holdInput(held, data []byte) []byte {
+	if room := maxHeldInput - len(held); len(data) > room {
+		if room < 0 {
+			room = 0
+		}
+		data = data[:room]
+		if !c.inputLimiter.dropped {
+			c.inputLimiter.dropped = true
+			c.statusf("input beyond %d bytes dropped", maxHeldInput)
+		}
+	}
+	return append(held, data...)
+}
+
+// pacedInput returns as much of a confirmed paste as InputRate allows now;
+// writeLoop calls it between reads until the paste is sent
+func (c *Client) pacedInput(now time.Time) []byte {
+	l := c.inputLimiter
+	if l == nil || len(l.queued) == 0 {
+		return nil
+	}
+	n := l.take(len(l.queued), now)
+	chunk := l.queued[:n]
+	l.queued = l.queued[n:]
+	if len(l.queued) == 0 {
+		l.queued, l.dropped = nil, false
+	}
+	return chunk
+}
@@ -0,0 +1,67 @@
+package gottyclient
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInputRateLimit(t *testing.T) {
+	Convey("Testing the input rate limit", t, func() {
+		var output bytes.Buffer
+		client := &Client{Output: &output, InputRate: 100, InputBurst: 10}
+		now := time.Now()
+		paste := []byte(strings.Repeat("x", 50))
+
+		Convey("Typing within the limit passes", func() {
+			So(string(client.limitInput([]byte("ls\r"), now)), ShouldEqual, "ls\r")
+			So(string(client.limitInput([]byte("pwd\r"), now.Add(10*time.Millisecond))), ShouldEqual, "pwd\r")
+			So(output.Len(), ShouldEqual, 0)
+		})
+		Convey("A large paste is held back and sent on confirmation", func() {
+			So(client.limitInput(paste[:30], now), ShouldBeNil)
+			So(output.String(), ShouldContainSubstring, "send it? [y/N]")
+			// The rest of the paste is added to the held back input
+			So(client.limitInput(paste[30:], now.Add(time.Millisecond)), ShouldBeNil)
+			// Once confirmed the paste is sent at the input rate
+			now = now.Add(time.Second)
+			sent := client.limitInput([]byte("y"), now)
+			So(len(sent), ShouldEqual, 10)
+			So(client.pacedInput(now), ShouldBeEmpty)
+			for i := 0; i < 4; i++ {
+				now = now.Add(100 * time.Millisecond)
+				chunk := client.pacedInput(now)
+				So(len(chunk), ShouldEqual, 10)
+				sent = append(sent, chunk...)
+			}
+			So(string(sent), ShouldEqual, string(paste))
+			So(output.String(), ShouldContainSubstring, "sending 50 bytes at 100 bytes/s")
+
+			Convey("Typing while it is sent goes after the paste", func() {
+				So(client.pacedInput(now.Add(time.Second)), ShouldBeEmpty)
+				client.inputLimiter.queued = []byte("rest")
+				So(string(client.limitInput([]byte("ls\r"), now.Add(2*time.Second))), ShouldEqual, "restls\r")
+			})
+		})
+		Convey("Held back input is capped", func() {
+			So(client.limitInput(paste, now), ShouldBeNil)
+			big := make([]byte, maxHeldInput)
+			So(client.limitInput(big, now.Add(time.Millisecond)), ShouldBeNil)
+			So(len(client.inputLimiter.pending), ShouldEqual, maxHeldInput)
+			So(output.String(), ShouldContainSubstring, "dropped")
+		})
+		Convey("A held back paste is discarded on any other key", func() {
+			So(client.limitInput(paste, now), ShouldBeNil)
+			So(client.limitInput([]byte("n"), now.Add(time.Second)), ShouldBeNil)
+			So(output.String(), ShouldContainSubstring, "paste of 50 bytes discarded")
+			So(string(client.limitInput([]byte("a"), now.Add(2*time.Second))), ShouldEqual, "a")
+		})
+		Convey("No limit by default", func() {
+			client.InputRate = 0
+			So(string(client.limitInput(paste, now)), ShouldEqual, string(paste))
+		})
+	})
+}
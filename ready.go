@@ -0,0 +1,65 @@
+package gottyclient
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultReadyTimeout is how long Connect waits for the server's first frame
+// after authenticating, for clients without a ReadyTimeout
+const DefaultReadyTimeout = 2 * time.Second
+
+// frame is the result of one Transport.Read
+type frame struct {
+	data []byte
+	err  error
+}
+
+// awaitReady waits for the first frame the server sends once it has
+// processed the auth message, e.g. the window title GoTTY sends, so the
+// initial resize is not sent to a server still setting the session up.
+// A silent server is given up on after ReadyTimeout. The frame, or the
+// error of a server closing the connection instead, is kept for readFrame.
+func (c *Client) awaitReady(transport Transport) {
+	read := make(chan frame, 1)
+	go func() {
+		data, err := transport.Read()
+		read <- frame{data: data, err: err}
+	}()
+	c.stateMutex.Lock()
+	c.handshakeRead = read
+	c.stateMutex.Unlock()
+
+	timeout := c.ReadyTimeout
+	if timeout <= 0 {
+		timeout = DefaultReadyTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case first := <-read:
+		read <- first
+	case <-timer.C:
+		logrus.Debugf("No frame from the server within %v, continuing", timeout)
+	}
+}
+
+// readFrame reads the next frame from transport, starting with the one read
+// by awaitReady if transport is still the client's
+func (c *Client) readFrame(transport Transport) ([]byte, error) {
+	c.stateMutex.Lock()
+	read := c.handshakeRead
+	if read != nil && c.Transport == transport {
+		c.handshakeRead = nil
+	} else {
+		read = nil
+	}
+	c.stateMutex.Unlock()
+
+	if read != nil {
+		first := <-read
+		return first.data, first.err
+	}
+	return transport.Read()
+}
@@ -0,0 +1,66 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAwaitReady(t *testing.T) {
+	Convey("Testing waiting for the server after authenticating", t, func() {
+		delay := 300 * time.Millisecond
+		silent := false
+		upgrader := websocket.Upgrader{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "auth_token.js") {
+				w.Write([]byte("var gotty_auth_token = 'token'"))
+				return
+			}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.ReadMessage()
+			time.Sleep(delay)
+			if !silent {
+				conn.WriteMessage(websocket.TextMessage, []byte("3slow server"))
+			}
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		client.V2 = true
+		defer client.Close()
+
+		Convey("Connect returns once a slow server has answered", func() {
+			start := time.Now()
+			So(client.Connect(), ShouldBeNil)
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo, delay)
+
+			data, err := client.readFrame(client.transport())
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "3slow server")
+		})
+
+		Convey("A silent server is given up on after ReadyTimeout", func() {
+			silent = true
+			delay = 0
+			client.ReadyTimeout = 100 * time.Millisecond
+			start := time.Now()
+			So(client.Connect(), ShouldBeNil)
+			So(time.Since(start), ShouldBeBetween, 100*time.Millisecond, time.Second)
+		})
+	})
+}
@@ -0,0 +1,153 @@
+package gottyclient
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultInputBufferSize bounds the keystrokes kept while reconnecting by
+// clients without an InputBufferSize
+const DefaultInputBufferSize = 4096
+
+// reconnectState tracks an in-progress reconnect and the input typed meanwhile
+type reconnectState struct {
+	mutex        sync.Mutex
+	reconnecting bool
+	buffer       []byte
+	notified     bool
+	full         bool
+}
+
+// isReconnecting reports whether the connection is being re-established
+func (c *Client) isReconnecting() bool {
+	c.reconnection.mutex.Lock()
+	defer c.reconnection.mutex.Unlock()
+	return c.reconnection.reconnecting
+}
+
+// queueInput keeps input typed while reconnecting, bounded by
+// InputBufferSize. It returns false when not reconnecting, in which case the
+// input must be sent as usual.
+func (c *Client) queueInput(data []byte) bool {
+	c.reconnection.mutex.Lock()
+	defer c.reconnection.mutex.Unlock()
+	if !c.reconnection.reconnecting {
+		return false
+	}
+
+	if c.NoInputBuffer {
+		if !c.reconnection.notified {
+			c.reconnection.notified = true
+			c.statusf("reconnecting, input is discarded until the session is reattached")
+		}
+		return true
+	}
+
+	size := c.InputBufferSize
+	if size <= 0 {
+		size = DefaultInputBufferSize
+	}
+	room := size - len(c.reconnection.buffer)
+	if len(data) > room {
+		data = data[:room]
+		if !c.reconnection.full {
+			c.reconnection.full = true
+			c.statusf("input buffer full, further input is dropped")
+		}
+	}
+	c.reconnection.buffer = append(c.reconnection.buffer, data...)
+	if !c.reconnection.notified {
+		c.reconnection.notified = true
+		c.statusf("reconnecting, input will be sent once the session is reattached")
+	}
+	return true
+}
+
+// beginReconnect starts queueing input. Input already queued by a failed
+// write is kept; the buffer is only emptied by resume, once sent.
+func (c *Client) beginReconnect() {
+	c.reconnection.mutex.Lock()
+	defer c.reconnection.mutex.Unlock()
+	if c.reconnection.reconnecting {
+		return
+	}
+	c.reconnection.reconnecting = true
+	c.reconnection.notified = false
+	c.reconnection.full = false
+}
+
+// shouldReconnect reports whether a read error warrants reconnecting rather
+// than ending the session
+func (c *Client) shouldReconnect(err error) bool {
+	if !c.Reconnect {
+		return false
+	}
+	if err == io.EOF || websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		return false
+	}
+	return true
+}
+
+// reconnectLoop re-establishes the connection with backoff until it succeeds
+// or the loops are poisoned, then sends the input buffered meanwhile. It
+// returns false if the client is shutting down instead.
+func (c *Client) reconnectLoop() bool {
+	c.beginReconnect()
+	c.setState(StateReconnecting)
+	c.statusf("connection lost, reconnecting...")
+	_ = c.transport().Close()
+
+	backoff := c.ReconnectDelay
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.poison:
+			return false
+		case <-time.After(backoff):
+		}
+
+		err := c.Connect()
+		if err == nil {
+			break
+		}
+		logrus.Debugf("Reconnect attempt %d failed: %v", attempt, err)
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+
+	c.resume("reconnected")
+	return true
+}
+
+// resume sends the window size and the input buffered while reconnecting to
+// the new connection, reporting it as done
+func (c *Client) resume(done string) {
+	if b, err := c.winSizePayload(); err == nil {
+		_ = c.write(append([]byte{c.messages().resizeTerminal}, b...))
+	}
+
+	// Flush while holding the mutex so newer keystrokes cannot overtake
+	// the buffered ones
+	c.reconnection.mutex.Lock()
+	defer c.reconnection.mutex.Unlock()
+	buffered := c.reconnection.buffer
+	c.reconnection.buffer = nil
+	c.reconnection.reconnecting = false
+	if len(buffered) > 0 {
+		if err := c.write(append([]byte{c.messages().input}, buffered...)); err != nil {
+			c.statusf("%s, but buffered input could not be sent: %v", done, err)
+			return
+		}
+		c.InputLog.Log(buffered)
+		c.statusf("%s, sent %d buffered bytes", done, len(buffered))
+	} else {
+		c.statusf("%s", done)
+	}
+}
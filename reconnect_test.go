@@ -0,0 +1,70 @@
+package gottyclient
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReconnectInputQueue(t *testing.T) {
+	Convey("Testing input queued while reconnecting", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("var gotty_auth_token = 'token'"))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		output := &bytes.Buffer{}
+		client.Output = output
+		client.V2 = true
+		client.Reconnect = true
+		transport := &fakeTransport{}
+		client.Transport = transport
+		So(client.Connect(), ShouldBeNil)
+		defer client.Close()
+		transport.Written()
+
+		client.beginReconnect()
+		So(client.sendInput([]byte("ls")), ShouldBeNil)
+		So(client.sendInput([]byte("\r")), ShouldBeNil)
+		So(transport.Written(), ShouldBeEmpty)
+		So(output.String(), ShouldContainSubstring, "input will be sent")
+
+		Convey("Buffered input is flushed once reconnected", func() {
+			So(client.reconnectLoop(), ShouldBeTrue)
+			So(client.isReconnecting(), ShouldBeFalse)
+			written := transport.Written()
+			So(written[len(written)-1], ShouldEqual, string(client.messages().input)+"ls\r")
+		})
+
+		Convey("The buffer is bounded", func() {
+			So(client.sendInput(make([]byte, DefaultInputBufferSize)), ShouldBeNil)
+			So(len(client.reconnection.buffer), ShouldEqual, DefaultInputBufferSize)
+			So(output.String(), ShouldContainSubstring, "input buffer full")
+		})
+
+		Convey("Input of a failed write is sent once reconnected", func() {
+			So(client.reconnectLoop(), ShouldBeTrue)
+			transport.Written()
+			transport.fail = true
+			So(client.sendInput([]byte("pwd\r")), ShouldBeNil)
+			So(client.isReconnecting(), ShouldBeTrue)
+			transport.fail = false
+
+			So(client.reconnectLoop(), ShouldBeTrue)
+			written := transport.Written()
+			So(written[len(written)-1], ShouldEqual, string(client.messages().input)+"pwd\r")
+		})
+
+		Convey("Input is discarded with NoInputBuffer", func() {
+			client.NoInputBuffer = true
+			client.reconnection.buffer = nil
+			So(client.sendInput([]byte("rm")), ShouldBeNil)
+			So(client.reconnection.buffer, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,46 @@
+package gottyclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultMaxRedirects is the number of redirects followed when Client.MaxRedirects is zero
+const DefaultMaxRedirects = 10
+
+// credentialHeaders are stripped from requests redirected to another origin
+var credentialHeaders = []string{"Authorization", "X-Admin-Password", "Cookie", "Proxy-Authorization"}
+
+// sameOrigin reports whether two URLs share scheme, host and port
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && a.Host == b.Host
+}
+
+// checkRedirect enforces the client's redirect policy: redirects may be
+// disabled entirely, are limited in number, and credentials are only
+// forwarded to the origin of the original request so tokens and admin
+// passwords never leak to a third-party host.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if c.NoFollowRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	maxRedirects := c.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	if !sameOrigin(req.URL, via[0].URL) {
+		logrus.Warnf("Redirected from %s to %s, not forwarding credentials", via[0].URL.Host, req.URL.Host)
+		for _, header := range credentialHeaders {
+			req.Header.Del(header)
+		}
+	}
+	return nil
+}
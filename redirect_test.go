@@ -0,0 +1,60 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheckRedirect(t *testing.T) {
+	Convey("Testing Client redirect policy", t, func() {
+		var thirdParty http.Header
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			thirdParty = r.Header.Clone()
+			w.Write([]byte("var gotty_auth_token = 'other'"))
+		}))
+		defer other.Close()
+
+		var origin http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/auth_token.js":
+				http.Redirect(w, r, "/moved/auth_token.js", http.StatusFound)
+			case "/moved/auth_token.js":
+				origin = r.Header.Clone()
+				http.Redirect(w, r, other.URL+"/auth_token.js", http.StatusFound)
+			}
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		client.AdminPassword = "secret"
+		client.RetryPolicy = NoRetry
+
+		Convey("Credentials are only forwarded to the same origin", func() {
+			token, err := client.GetAuthToken()
+			So(err, ShouldBeNil)
+			So(token, ShouldEqual, "other")
+			So(origin.Get("X-Admin-Password"), ShouldEqual, "secret")
+			So(thirdParty.Get("X-Admin-Password"), ShouldEqual, "")
+		})
+
+		Convey("Redirects are limited", func() {
+			client.MaxRedirects = 1
+			_, err := client.GetAuthToken()
+			So(err, ShouldNotBeNil)
+			So(thirdParty, ShouldBeNil)
+		})
+
+		Convey("Redirects can be disabled", func() {
+			client.NoFollowRedirects = true
+			_, err := client.GetAuthToken()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "redirects are disabled")
+			So(origin, ShouldBeNil)
+		})
+	})
+}
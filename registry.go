@@ -0,0 +1,155 @@
+package gottyclient
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Registry is an instance registry, e.g. the public one or a club's private one
+type Registry struct {
+	// Name tags the instances listed by the registry, see Instance.Source
+	Name string
+	URL  string
+}
+
+// DefaultRegistryCacheTTL is the CacheTTL of NewRegistryClient
+const DefaultRegistryCacheTTL = time.Minute
+
+// RegistryClient looks instances up in instance registries
+type RegistryClient struct {
+	// Registries are queried for instances, in order of precedence: an
+	// instance listed by several registries is taken from the first. Empty
+	// means the public registry at InstancesURL alone.
+	Registries []Registry
+	// CacheTTL is how long a cached registry response is used without
+	// asking the registry again. Once expired, the registry is asked with
+	// If-None-Match/If-Modified-Since so an unchanged list costs a 304 only.
+	CacheTTL time.Duration
+	// CacheDir is where registry responses are cached between invocations;
+	// empty keeps them off the filesystem
+	CacheDir string
+	// RetryPolicy defaults to DefaultRetryPolicy()
+	RetryPolicy *RetryPolicy
+	// RequestTimeout bounds each lookup attempt; 0 means no limit
+	RequestTimeout time.Duration
+	// UserAgent and InstanceID identify the client as for Client
+	UserAgent  string
+	InstanceID string
+	// AgentSocketPath, if set, is the socket of an agent sharing the
+	// cached responses between invocations
+	AgentSocketPath string
+	// Progress, if set, is told about lookups and their retries
+	Progress ProgressReporter
+}
+
+// NewRegistryClient returns a client of the public registry with the
+// default settings
+func NewRegistryClient() *RegistryClient {
+	return &RegistryClient{
+		CacheTTL:        DefaultRegistryCacheTTL,
+		CacheDir:        GetDefaultCacheDir(),
+		InstanceID:      processInstanceID,
+		AgentSocketPath: GetDefaultAgentSocketPath(),
+	}
+}
+
+// registries returns the registries to query
+func (r *RegistryClient) registries() []Registry {
+	if len(r.Registries) > 0 {
+		return r.Registries
+	}
+	return []Registry{{Name: "public", URL: InstancesURL}}
+}
+
+// ParseRegistry parses a Registry directive, "[NAME] URL"; the name defaults
+// to the registry's host
+func ParseRegistry(value string) (Registry, error) {
+	fields := strings.Fields(value)
+	var registry Registry
+	switch len(fields) {
+	case 1:
+		registry.URL = fields[0]
+	case 2:
+		registry.Name, registry.URL = fields[0], fields[1]
+	default:
+		return registry, fmt.Errorf("expected [NAME] URL")
+	}
+	// URLs with a password may be encrypted by EncryptSecrets, until imported
+	if IsEncryptedSecret(registry.URL) && registry.Name != "" {
+		return registry, nil
+	}
+	parsed, err := url.Parse(registry.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return registry, fmt.Errorf("invalid registry URL %q", registry.URL)
+	}
+	if registry.Name == "" {
+		registry.Name = parsed.Host
+	}
+	return registry, nil
+}
+
+// String formats the registry as a Registry directive value
+func (r Registry) String() string {
+	return r.Name + " " + r.URL
+}
+
+// listInstances lists the instances of every registry, merged by callsign,
+// without reporting progress if quiet. Registries that fail are skipped with
+// a warning as long as one answers.
+func (r *RegistryClient) listInstances(limit int, quiet bool) (*InstanceListResponse, error) {
+	registries := r.registries()
+	lists := make([]*InstanceListResponse, len(registries))
+	errs := make([]error, len(registries))
+	wg := &sync.WaitGroup{}
+	for i, registry := range registries {
+		wg.Add(1)
+		go func(i int, registry Registry) {
+			defer wg.Done()
+			lists[i], errs[i] = r.listRegistry(registry, limit, quiet)
+		}(i, registry)
+	}
+	wg.Wait()
+
+	if len(registries) == 1 {
+		return lists[0], errs[0]
+	}
+
+	result := &InstanceListResponse{Instances: []Instance{}}
+	seen := make(map[string]bool)
+	answered := 0
+	for i, list := range lists {
+		if errs[i] != nil {
+			logrus.Warnf("Registry %s: %v", registries[i].Name, errs[i])
+			continue
+		}
+		answered++
+		total := list.Total
+		if total < len(list.Instances) {
+			total = len(list.Instances)
+		}
+		for _, instance := range list.Instances {
+			callsign := strings.ToUpper(instance.Callsign)
+			if seen[callsign] {
+				total--
+				continue
+			}
+			seen[callsign] = true
+			result.Instances = append(result.Instances, instance)
+		}
+		result.Total += total
+	}
+	if answered == 0 {
+		return nil, errs[0]
+	}
+
+	if limit > 0 && len(result.Instances) > limit {
+		result.Instances = result.Instances[:limit]
+	}
+	result.Count = len(result.Instances)
+	return result, nil
+}
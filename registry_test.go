@@ -0,0 +1,71 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistries(t *testing.T) {
+	Convey("Testing several instance registries", t, func() {
+		registry := func(body string) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(body))
+			}))
+		}
+		club := registry(`{"count":2,"instances":[{"callsign":"M9PSY","public_url":"https://private.example.com"},{"callsign":"G4CLB"}]}`)
+		defer club.Close()
+		public := registry(`{"count":2,"instances":[{"callsign":"m9psy","public_url":"https://sdr.example.com"},{"callsign":"K1XYZ"}]}`)
+		defer public.Close()
+
+		registries := NewRegistryClient()
+		registries.CacheDir = ""
+		registries.Registries = []Registry{{Name: "club", URL: club.URL}, {Name: "public", URL: public.URL}}
+
+		Convey("Listings are merged by callsign, the first registry winning", func() {
+			list, err := registries.ListInstances()
+			So(err, ShouldBeNil)
+			So(list.Count, ShouldEqual, 3)
+			So(list.Total, ShouldEqual, 3)
+			So(list.Instances[0].Source, ShouldEqual, "club")
+			So(list.Instances[0].PublicURL, ShouldEqual, "https://private.example.com")
+			So(list.Instances[2].Callsign, ShouldEqual, "K1XYZ")
+			So(list.Instances[2].Source, ShouldEqual, "public")
+
+			instance, err := registries.FindInstanceByCallsign("k1xyz")
+			So(err, ShouldBeNil)
+			So(instance.Source, ShouldEqual, "public")
+		})
+
+		Convey("A failing registry is skipped", func() {
+			registries.Registries = append([]Registry{{Name: "down", URL: "http://127.0.0.1:1/api/instances"}}, registries.Registries...)
+			registries.RetryPolicy = NoRetry
+			list, err := registries.ListInstances()
+			So(err, ShouldBeNil)
+			So(list.Count, ShouldEqual, 3)
+		})
+
+		Convey("Registry directives are read from the config", func() {
+			path := filepath.Join(t.TempDir(), "config")
+			So(os.WriteFile(path, []byte("Registry club "+club.URL+"\nHost sdr\n    Callsign M9PSY\nRegistry https://instances.ubersdr.org/api/instances\n"), 0600), ShouldBeNil)
+			config, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			So(config.Registries, ShouldResemble, []Registry{
+				{Name: "club", URL: club.URL},
+				{Name: "instances.ubersdr.org", URL: "https://instances.ubersdr.org/api/instances"},
+			})
+
+			So(WriteConfig(path, config), ShouldBeNil)
+			reread, err := LoadConfigFromPath(path)
+			So(err, ShouldBeNil)
+			So(reread.Registries, ShouldResemble, config.Registries)
+
+			_, err = ParseRegistry("club ftp://example.com")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
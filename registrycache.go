@@ -0,0 +1,155 @@
+package gottyclient
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetDefaultCacheDir returns where registry responses and auth tokens are
+// cached by default
+func GetDefaultCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gotty-client", "cache")
+}
+
+// registryCacheEntry is a cached registry response with its validators
+type registryCacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+	Body         []byte    `json:"body"`
+}
+
+func registryCachePath(dir, target string) string {
+	sum := sha1.Sum([]byte(target))
+	return filepath.Join(dir, "registry-"+hex.EncodeToString(sum[:])+".json")
+}
+
+func (r *RegistryClient) loadRegistryCache(target string) *registryCacheEntry {
+	if agent := ConnectAgent(r.AgentSocketPath); agent != nil {
+		if entry := agent.getRegistry(target); entry != nil {
+			return entry
+		}
+	}
+	if r.CacheDir == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(registryCachePath(r.CacheDir, target))
+	if err != nil {
+		return nil
+	}
+	var entry registryCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != target {
+		return nil
+	}
+	return &entry
+}
+
+func (r *RegistryClient) saveRegistryCache(entry *registryCacheEntry) {
+	if agent := ConnectAgent(r.AgentSocketPath); agent != nil {
+		agent.putRegistry(entry)
+	}
+	if r.CacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(r.CacheDir, 0700); err != nil {
+		logrus.Debugf("Failed to create registry cache directory: %v", err)
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := ioutil.WriteFile(registryCachePath(r.CacheDir, entry.URL), data, 0600); err != nil {
+		logrus.Debugf("Failed to write registry cache: %v", err)
+	}
+}
+
+// fetchRegistry returns the body of a registry GET request, served from the
+// cache while fresh and revalidated with conditional requests afterwards.
+// Progress is reported unless quiet.
+func (r *RegistryClient) fetchRegistry(target string, quiet bool) ([]byte, error) {
+	entry := r.loadRegistryCache(target)
+	if entry != nil && time.Since(entry.Fetched) < r.CacheTTL {
+		logrus.Debugf("Using cached registry response for %q", target)
+		return entry.Body, nil
+	}
+
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	identify(req.Header, r.UserAgent, r.InstanceID)
+	unversioned, versioned := unversionedRegistryURL(target)
+	if versioned {
+		req.Header.Set("Accept", registryAccept)
+	}
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	client := http.DefaultClient
+	if r.RequestTimeout > 0 {
+		client = &http.Client{Timeout: r.RequestTimeout}
+	}
+	stop := func() {}
+	if !quiet {
+		stop = startProgress(r.Progress, "Looking up "+req.URL.Host)
+	}
+	policy := r.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+	resp, err := policy.do(client, req, r.Progress)
+	stop()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		logrus.Debugf("Registry response for %q not modified", target)
+		entry.Fetched = time.Now()
+		r.saveRegistryCache(entry)
+		return entry.Body, nil
+	case versioned && (resp.StatusCode == http.StatusNotAcceptable || resp.StatusCode == http.StatusBadRequest):
+		// Registries predating schema versions may reject the negotiation
+		logrus.Debugf("Registry rejected schema version %d, retrying without", RegistrySchemaVersion)
+		return r.fetchRegistry(unversioned, quiet)
+	case resp.StatusCode != 200:
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%d %s - %s", resp.StatusCode, http.StatusText(resp.StatusCode), string(body))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.saveRegistryCache(&registryCacheEntry{
+		URL:          target,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Fetched:      time.Now(),
+		Body:         body,
+	})
+	return body, nil
+}
@@ -0,0 +1,60 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistryCache(t *testing.T) {
+	Convey("Testing registry caching", t, func() {
+		requests, full := 0, 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			full++
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(`{"count":1,"instances":[{"callsign":"M9PSY"}]}`))
+		}))
+		defer server.Close()
+
+		registries := NewRegistryClient()
+		registries.CacheDir = t.TempDir()
+		registries.Registries = []Registry{{Name: "test", URL: server.URL}}
+
+		Convey("Fresh entries are served from the cache", func() {
+			registries.CacheTTL = time.Hour
+			for i := 0; i < 3; i++ {
+				list, err := registries.ListInstances()
+				So(err, ShouldBeNil)
+				So(list.Instances[0].Callsign, ShouldEqual, "M9PSY")
+			}
+			So(requests, ShouldEqual, 1)
+		})
+		Convey("Expired entries are revalidated with the ETag", func() {
+			registries.CacheTTL = 0
+			for i := 0; i < 3; i++ {
+				list, err := registries.ListInstances()
+				So(err, ShouldBeNil)
+				So(list.Instances[0].Callsign, ShouldEqual, "M9PSY")
+			}
+			So(requests, ShouldEqual, 3)
+			So(full, ShouldEqual, 1)
+		})
+		Convey("An empty CacheDir keeps responses off the filesystem", func() {
+			registries.CacheTTL = time.Hour
+			registries.CacheDir = ""
+			for i := 0; i < 2; i++ {
+				_, err := registries.ListInstances()
+				So(err, ShouldBeNil)
+			}
+			So(requests, ShouldEqual, 2)
+		})
+	})
+}
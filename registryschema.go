@@ -0,0 +1,144 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RegistrySchemaVersion is the newest instance registry schema this client
+// understands. It is asked for with the Accept header and a schema_version
+// query parameter; registries that reject either are asked again without.
+const RegistrySchemaVersion = 1
+
+// registryAccept is the Accept header of versioned registry requests
+var registryAccept = fmt.Sprintf("application/json; version=%d", RegistrySchemaVersion)
+
+// versionedRegistryURL adds the schema version to a registry request URL
+func versionedRegistryURL(target string) string {
+	separator := "?"
+	if strings.Contains(target, "?") {
+		separator = "&"
+	}
+	return target + separator + "schema_version=" + strconv.Itoa(RegistrySchemaVersion)
+}
+
+// unversionedRegistryURL strips the schema version from a registry request
+// URL, reporting whether it had one
+func unversionedRegistryURL(target string) (string, bool) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return target, false
+	}
+	query := parsed.Query()
+	if query.Get("schema_version") == "" {
+		return target, false
+	}
+	query.Del("schema_version")
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), true
+}
+
+// instanceFields maps the JSON names of Instance's fields to their index
+var (
+	instanceFields     map[string]int
+	instanceFieldsOnce sync.Once
+)
+
+// instanceFieldIndex returns the index of the Instance field named name in JSON
+func instanceFieldIndex(name string) (int, bool) {
+	instanceFieldsOnce.Do(func() {
+		instanceFields = make(map[string]int)
+		t := reflect.TypeOf(Instance{})
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			if tag != "" && tag != "-" {
+				instanceFields[tag] = i
+			}
+		}
+	})
+	index, ok := instanceFields[name]
+	return index, ok
+}
+
+// UnmarshalJSON decodes a registry entry leniently, so a registry changing
+// its schema does not break listings: missing fields are left empty, values
+// of an unexpected type are converted where possible and skipped otherwise,
+// and unknown fields are kept in RawExtra.
+func (i *Instance) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*i = Instance{}
+	value := reflect.ValueOf(i).Elem()
+	for name, raw := range fields {
+		index, ok := instanceFieldIndex(name)
+		if !ok {
+			if i.RawExtra == nil {
+				i.RawExtra = make(map[string]json.RawMessage)
+			}
+			i.RawExtra[name] = raw
+			continue
+		}
+		if err := decodeLenient(raw, value.Field(index)); err != nil {
+			logrus.Debugf("Ignoring registry field %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// decodeLenient decodes raw into field, converting between numbers, strings
+// and booleans and accepting a single string for a list
+func decodeLenient(raw json.RawMessage, field reflect.Value) error {
+	err := json.Unmarshal(raw, field.Addr().Interface())
+	if err == nil {
+		return nil
+	}
+
+	var loose interface{}
+	if json.Unmarshal(raw, &loose) != nil {
+		return err
+	}
+	text := strings.Trim(string(raw), `"`)
+	switch field.Kind() {
+	case reflect.Int:
+		if number, convErr := strconv.ParseFloat(text, 64); convErr == nil {
+			field.SetInt(int64(number))
+			return nil
+		}
+	case reflect.Float64:
+		if number, convErr := strconv.ParseFloat(text, 64); convErr == nil {
+			field.SetFloat(number)
+			return nil
+		}
+	case reflect.Bool:
+		switch strings.ToLower(text) {
+		case "true", "1", "yes":
+			field.SetBool(true)
+			return nil
+		case "false", "0", "no", "":
+			field.SetBool(false)
+			return nil
+		}
+	case reflect.String:
+		switch loose.(type) {
+		case float64, bool:
+			field.SetString(string(raw))
+			return nil
+		}
+	case reflect.Slice:
+		if s, ok := loose.(string); ok && field.Type().Elem().Kind() == reflect.String {
+			field.Set(reflect.ValueOf([]string{s}))
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistrySchema(t *testing.T) {
+	Convey("Testing registry schema tolerance", t, func() {
+		Convey("Unexpected types are converted and unknown fields kept", func() {
+			var instance Instance
+			err := json.Unmarshal([]byte(`{
+				"callsign": "M9PSY",
+				"max_clients": "10",
+				"available_clients": 4.0,
+				"snr_0_30_mhz": {"value": 12},
+				"tls": "true",
+				"version": 2,
+				"public_iq_modes": "iq48",
+				"antenna": "loop"
+			}`), &instance)
+			So(err, ShouldBeNil)
+			So(instance.Callsign, ShouldEqual, "M9PSY")
+			So(instance.MaxClients, ShouldEqual, 10)
+			So(instance.AvailableClients, ShouldEqual, 4)
+			So(instance.SNR030MHz, ShouldEqual, 0)
+			So(instance.TLS, ShouldBeTrue)
+			So(instance.Version, ShouldEqual, "2")
+			So(instance.PublicIQModes, ShouldResemble, []string{"iq48"})
+			So(string(instance.RawExtra["antenna"]), ShouldEqual, `"loop"`)
+
+			So(json.Unmarshal([]byte(`{
+				"callsign": "M9PSY",
+				"operator_name": "Jo",
+				"operator_email": "jo@example.com",
+				"qrz_url": "https://www.qrz.com/db/M9PSY",
+				"description": "Loop on a hill"
+			}`), &instance), ShouldBeNil)
+			So(instance.OperatorName, ShouldEqual, "Jo")
+			So(instance.OperatorEmail, ShouldEqual, "jo@example.com")
+			So(instance.QRZURL, ShouldEqual, "https://www.qrz.com/db/M9PSY")
+			So(instance.Description, ShouldEqual, "Loop on a hill")
+			So(instance.RawExtra, ShouldBeNil)
+
+			So(json.Unmarshal([]byte(`[1]`), &instance), ShouldNotBeNil)
+		})
+
+		Convey("The schema version is negotiated and dropped when rejected", func() {
+			var accepts []string
+			strict := true
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				accepts = append(accepts, r.Header.Get("Accept"))
+				if strict && r.URL.Query().Get("schema_version") != "" {
+					w.WriteHeader(http.StatusNotAcceptable)
+					return
+				}
+				_, _ = w.Write([]byte(`{"schema_version":7,"count":1,"instances":[{"callsign":"K1XYZ","future":true}]}`))
+			}))
+			defer server.Close()
+
+			registries := NewRegistryClient()
+			registries.CacheDir = ""
+			registries.Registries = []Registry{{Name: "test", URL: server.URL}}
+
+			list, err := registries.ListInstances()
+			So(err, ShouldBeNil)
+			So(accepts, ShouldResemble, []string{registryAccept, ""})
+			So(list.SchemaVersion, ShouldEqual, 7)
+			So(list.Instances[0].Callsign, ShouldEqual, "K1XYZ")
+			So(list.Instances[0].RawExtra, ShouldContainKey, "future")
+
+			strict = false
+			accepts = nil
+			_, err = registries.ListInstances()
+			So(err, ShouldBeNil)
+			So(accepts, ShouldResemble, []string{registryAccept})
+		})
+
+		Convey("Registry URLs gain and lose the version parameter", func() {
+			target := versionedRegistryURL("https://example.com/api/instances?page=1")
+			So(target, ShouldEqual, "https://example.com/api/instances?page=1&schema_version=1")
+			unversioned, ok := unversionedRegistryURL(target)
+			So(ok, ShouldBeTrue)
+			So(unversioned, ShouldEqual, "https://example.com/api/instances?page=1")
+			_, ok = unversionedRegistryURL(unversioned)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,100 @@
+package gottyclient
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// registryWatch remembers what was last seen of the instance in the registry
+type registryWatch struct {
+	callsign string
+	url      string
+	listed   bool
+	stale    bool
+}
+
+// newRegistryWatch starts watching from the entry the client was resolved from
+func newRegistryWatch(instance *Instance) *registryWatch {
+	return &registryWatch{callsign: instance.Callsign, url: instance.PublicURL, listed: true}
+}
+
+// update compares a registry listing with what was seen before and returns
+// the warnings about changes, e.g. the instance going offline
+func (w *registryWatch) update(instances []Instance, maxReportAge time.Duration) []string {
+	var instance *Instance
+	for i := range instances {
+		if strings.EqualFold(instances[i].Callsign, w.callsign) {
+			instance = &instances[i]
+			break
+		}
+	}
+
+	var warnings []string
+	if instance == nil {
+		if w.listed {
+			warnings = append(warnings, fmt.Sprintf("%s is no longer listed in the instance registry, the receiver may be offline", w.callsign))
+		}
+		w.listed = false
+		return warnings
+	}
+	if !w.listed {
+		warnings = append(warnings, fmt.Sprintf("%s is listed in the instance registry again", w.callsign))
+		w.listed = true
+	}
+
+	if instance.PublicURL != w.url {
+		warnings = append(warnings, fmt.Sprintf("%s moved to %s, reconnect to follow it", w.callsign, instance.PublicURL))
+		w.url = instance.PublicURL
+	}
+
+	age := time.Duration(instance.LastReportAgeSeconds) * time.Second
+	switch stale := maxReportAge > 0 && age > maxReportAge; {
+	case stale && !w.stale:
+		warnings = append(warnings, fmt.Sprintf("%s last reported to the instance registry %s ago, the receiver may be down", w.callsign, age))
+	case !stale && w.stale:
+		warnings = append(warnings, fmt.Sprintf("%s is reporting to the instance registry again", w.callsign))
+	}
+	w.stale = maxReportAge > 0 && age > maxReportAge
+	return warnings
+}
+
+// registry returns the client's registry client, or a default one
+func (c *Client) registry() *RegistryClient {
+	if c.Registry != nil {
+		return c.Registry
+	}
+	return NewRegistryClient()
+}
+
+// registryLoop re-checks the registry entry of Instance every RegistryCheck
+// and warns on the status line when it goes offline, moves or stops
+// reporting, so a frozen session can be explained
+func (c *Client) registryLoop(wg *sync.WaitGroup) poisonReason {
+	defer wg.Done()
+	fname := "registryLoop"
+
+	watch := newRegistryWatch(c.Instance)
+	ticker := time.NewTicker(c.RegistryCheck)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.poison:
+			return die(fname, c.poison)
+		case <-ticker.C:
+		}
+		instances, err := c.registry().listInstances(0, true)
+		if err != nil {
+			// The registry being unreachable says nothing about the instance
+			logrus.Debugf("Registry check failed: %v", err)
+			continue
+		}
+		for _, warning := range watch.update(instances.Instances, c.MaxReportAge) {
+			c.statusf("\a%s", warning)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package gottyclient
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistryWatch(t *testing.T) {
+	Convey("Testing registry re-checks", t, func() {
+		watch := newRegistryWatch(&Instance{Callsign: "M9PSY", PublicURL: "https://sdr.example.com"})
+		listing := func(url string, age int) []Instance {
+			return []Instance{
+				{Callsign: "G4ABC", PublicURL: "https://other.example.com"},
+				{Callsign: "m9psy", PublicURL: url, LastReportAgeSeconds: age},
+			}
+		}
+
+		So(watch.update(listing("https://sdr.example.com", 30), 10*time.Minute), ShouldBeEmpty)
+
+		Convey("Going offline and coming back is reported once each", func() {
+			warnings := watch.update(listing("", 0)[:1], 10*time.Minute)
+			So(warnings, ShouldHaveLength, 1)
+			So(warnings[0], ShouldContainSubstring, "no longer listed")
+			So(watch.update(nil, 10*time.Minute), ShouldBeEmpty)
+			warnings = watch.update(listing("https://sdr.example.com", 30), 10*time.Minute)
+			So(warnings, ShouldHaveLength, 1)
+			So(warnings[0], ShouldContainSubstring, "listed in the instance registry again")
+		})
+
+		Convey("A new URL is reported", func() {
+			warnings := watch.update(listing("https://new.example.com", 30), 10*time.Minute)
+			So(warnings, ShouldResemble, []string{"M9PSY moved to https://new.example.com, reconnect to follow it"})
+			So(watch.update(listing("https://new.example.com", 30), 10*time.Minute), ShouldBeEmpty)
+		})
+
+		Convey("Stale reports are reported until they resume", func() {
+			warnings := watch.update(listing("https://sdr.example.com", 900), 10*time.Minute)
+			So(warnings, ShouldResemble, []string{"M9PSY last reported to the instance registry 15m0s ago, the receiver may be down"})
+			So(watch.update(listing("https://sdr.example.com", 960), 10*time.Minute), ShouldBeEmpty)
+			So(watch.update(listing("https://sdr.example.com", 20), 10*time.Minute), ShouldResemble, []string{"M9PSY is reporting to the instance registry again"})
+			So(watch.update(listing("https://sdr.example.com", 9000), 0), ShouldBeEmpty)
+		})
+	})
+}
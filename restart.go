@@ -0,0 +1,87 @@
+package gottyclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// restartState tracks the restarts of a session by RestartOnExit
+type restartState struct {
+	restarts int
+}
+
+// prepareRestart makes URL recreate the session once it ends: the session's
+// own URL names the session and its window, directory and command, and the
+// window name of a session attached to by name only is looked up and added.
+// Its command cannot be, so such a session is recreated running the default
+// shell. URL is only changed here, before the loops reading it start.
+func (c *Client) prepareRestart() {
+	query, err := GetURLQuery(c.URL)
+	if err != nil || query.Get("session") == "" || query.Get("name") != "" {
+		return
+	}
+	if query.Get("cmd") == "" {
+		logrus.Warnf("Session %s will be restarted running the default shell, as its command is not known", query.Get("session"))
+	}
+	sessions, err := c.ListSessions()
+	if err != nil {
+		logrus.Debugf("Could not look up the window of session %s: %v", query.Get("session"), err)
+		return
+	}
+	if session := sessions.Find(query.Get("session")); session != nil && session.WindowName != "" {
+		if restartURL, err := BuildSessionURL(c.URL, SessionURLOptions{Name: session.WindowName}); err == nil {
+			c.URL = restartURL
+		}
+	}
+}
+
+// restartLoop recreates the session after it ended, waiting RestartDelay,
+// doubled on each restart up to 30s, before each of at most RestartOnExit
+// restarts, then sends the input typed meanwhile. It returns false once
+// the restarts are used up or the client is shutting down.
+func (c *Client) restartLoop() bool {
+	if c.restart.restarts >= c.RestartOnExit {
+		c.statusf("session ended, %d restarts used up", c.RestartOnExit)
+		return false
+	}
+	c.beginReconnect()
+	c.setState(StateReconnecting)
+	_ = c.transport().Close()
+
+	backoff := c.RestartDelay
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for i := 0; i < c.restart.restarts && backoff < 30*time.Second; i++ {
+		backoff *= 2
+	}
+	if backoff > 30*time.Second {
+		backoff = 30 * time.Second
+	}
+
+	var err error
+	for c.restart.restarts < c.RestartOnExit {
+		c.restart.restarts++
+		c.statusf("session ended, restarting it in %v (%d/%d)", backoff, c.restart.restarts, c.RestartOnExit)
+		select {
+		case <-c.poison:
+			return false
+		case <-time.After(backoff):
+		}
+
+		if err = c.Connect(); err == nil {
+			query, _ := GetURLQuery(c.URL)
+			c.Audit("restart", query.Get("session"), fmt.Sprintf("restart %d of %d", c.restart.restarts, c.RestartOnExit))
+			c.resume("session restarted")
+			return true
+		}
+		logrus.Debugf("Restart %d failed: %v", c.restart.restarts, err)
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+	c.loopErr = fmt.Errorf("session ended and could not be restarted: %v", err)
+	return false
+}
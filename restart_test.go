@@ -0,0 +1,75 @@
+package gottyclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRestartOnExit(t *testing.T) {
+	Convey("Testing restarting ended sessions", t, func() {
+		upgrader := websocket.Upgrader{}
+		var mutex sync.Mutex
+		var queries []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasSuffix(r.URL.Path, "auth_token.js"):
+				w.Write([]byte("var gotty_auth_token = 'token'"))
+				return
+			case strings.HasSuffix(r.URL.Path, "/api/sessions"):
+				w.Write([]byte(`{"sessions":[{"name":"decoder","window_name":"ft8"}],"count":1}`))
+				return
+			}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			mutex.Lock()
+			queries = append(queries, r.URL.RawQuery)
+			run := len(queries)
+			mutex.Unlock()
+			// The program prints a line and exits
+			conn.ReadMessage()
+			conn.WriteMessage(websocket.TextMessage, []byte(string(Output)+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("run %d;", run)))))
+		}))
+		defer server.Close()
+
+		stdin, input, err := os.Pipe()
+		So(err, ShouldBeNil)
+		defer input.Close()
+
+		client, err := NewClient(server.URL + "/?session=decoder")
+		So(err, ShouldBeNil)
+		client.V2 = true
+		client.Stdio = true
+		client.Stdin = stdin
+		client.RestartOnExit = 2
+		client.RestartDelay = 10 * time.Millisecond
+		var output bytes.Buffer
+		client.SetOutput(&output)
+		defer client.Close()
+
+		start := time.Now()
+		So(client.Loop(), ShouldBeNil)
+		So(output.String(), ShouldEqual, "run 1;run 2;run 3;")
+		// 10ms, then 20ms
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 30*time.Millisecond)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		So(queries, ShouldResemble, []string{"session=decoder", "name=ft8&session=decoder", "name=ft8&session=decoder"})
+		// The URL is only changed before the loops start
+		So(client.URL, ShouldEqual, server.URL+"/?name=ft8&session=decoder")
+	})
+}
@@ -0,0 +1,171 @@
+package gottyclient
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetryPolicy controls how REST calls are retried on connection errors,
+// 5xx responses and 429 Too Many Requests
+type RetryPolicy struct {
+	// Attempts is the total number of tries; 1 or less disables retries
+	Attempts int
+	// Backoff is the delay before the first retry, doubled on each retry
+	Backoff time.Duration
+	// MaxBackoff caps the delay between tries. A Retry-After header asking
+	// for a longer wait ends the retries instead.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy of clients without a RetryPolicy
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		Attempts:   3,
+		Backoff:    500 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+	}
+}
+
+// NoRetry disables retries
+var NoRetry = &RetryPolicy{Attempts: 1}
+
+// IdempotencyKeyHeader marks a request as safe to send again although its
+// method is not idempotent
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Do sends req with client, retrying according to the policy. Requests must
+// not have a body, so they can be sent again as-is. Only idempotent methods
+// and requests carrying an IdempotencyKeyHeader are retried: a POST whose
+// response was lost may have been applied already.
+func (p *RetryPolicy) Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	return p.do(client, req, nil)
+}
+
+// do is Do telling progress, if not nil, about the retries
+func (p *RetryPolicy) do(client *http.Client, req *http.Request, progress ProgressReporter) (*http.Response, error) {
+	backoff := p.Backoff
+	retryable := isIdempotent(req)
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Do(req)
+		// A cancelled request, e.g. one that lost a race, is not retried
+		if attempt >= p.Attempts || !retryable || !shouldRetry(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait := backoff
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				if p.MaxBackoff > 0 && retryAfter > p.MaxBackoff {
+					return resp, err
+				}
+				wait = retryAfter
+			}
+			// Drain the body so the connection can be reused
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+			wait = p.MaxBackoff
+		}
+
+		if err != nil {
+			logrus.Debugf("%s %s failed (attempt %d/%d): %v, retrying in %v", req.Method, req.URL.String(), attempt, p.Attempts, err, wait)
+			reportRetry(progress, attempt, p.Attempts, wait, retryReason(err))
+		} else {
+			logrus.Debugf("%s %s returned %d (attempt %d/%d), retrying in %v", req.Method, req.URL.String(), resp.StatusCode, attempt, p.Attempts, wait)
+			reportRetry(progress, attempt, p.Attempts, wait, strconv.Itoa(resp.StatusCode))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		backoff *= 2
+	}
+}
+
+// isIdempotent reports whether req can be sent again without repeating its effect
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// shouldRetry reports whether a request outcome is worth retrying
+func shouldRetry(resp *http.Response, err error) bool {
+	var unknown *UnknownHostError
+	if errors.As(err, &unknown) {
+		// The certificate will not change by asking again
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// retryReason shortens a request error for progress messages, e.g. "timeout"
+// instead of the whole URL
+func retryReason(err error) string {
+	if isTimeout(err) {
+		return "timeout"
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		wait := time.Until(t)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// retryPolicy returns the client's retry policy, or the default one
+func (c *Client) retryPolicy() *RetryPolicy {
+	if c.RetryPolicy != nil {
+		return c.RetryPolicy
+	}
+	return DefaultRetryPolicy()
+}
+
+// do sends an API request honoring the client's TLS, proxy, Host header and retry settings
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.HostHeader != "" {
+		req.Host = c.HostHeader
+	}
+	c.affinity().apply(req.Header)
+	identify(req.Header, c.UserAgent, c.InstanceID)
+	send := func() (*http.Response, error) { return c.retryPolicy().do(c.httpClient(), req, c.Progress) }
+	resp, err := send()
+	if err != nil && c.confirmHost(err) {
+		resp, err = resend(req, send)
+	}
+	c.affinity().capture(resp)
+	return resp, err
+}
@@ -0,0 +1,84 @@
+package gottyclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	Convey("Testing RetryPolicy", t, func() {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		req, err := http.NewRequest("GET", server.URL, nil)
+		So(err, ShouldBeNil)
+
+		Convey("Retries 5xx until success", func() {
+			policy := &RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+			resp, err := policy.Do(http.DefaultClient, req)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(calls, ShouldEqual, 3)
+		})
+		Convey("Gives up after the last attempt", func() {
+			resp, err := NoRetry.Do(http.DefaultClient, req)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			So(calls, ShouldEqual, 1)
+		})
+		Convey("Does not retry non-idempotent requests", func() {
+			post, err := http.NewRequest("POST", server.URL, nil)
+			So(err, ShouldBeNil)
+			policy := &RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+			resp, err := policy.Do(http.DefaultClient, post)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+			So(calls, ShouldEqual, 1)
+
+			Convey("unless they carry an idempotency key", func() {
+				post.Header.Set(IdempotencyKeyHeader, "handover-ft8-1")
+				resp, err := policy.Do(http.DefaultClient, post)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			})
+		})
+		Convey("Stops waiting when the request is cancelled", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			policy := &RetryPolicy{Attempts: 3, Backoff: time.Minute}
+			// Without Retry-After the full backoff would apply
+			unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer unavailable.Close()
+			slow, err := http.NewRequest("GET", unavailable.URL, nil)
+			So(err, ShouldBeNil)
+			start := time.Now()
+			_, err = policy.Do(http.DefaultClient, slow.WithContext(ctx))
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+		})
+		Convey("Parses Retry-After", func() {
+			wait, ok := parseRetryAfter("7")
+			So(ok, ShouldBeTrue)
+			So(wait, ShouldEqual, 7*time.Second)
+			_, ok = parseRetryAfter("soon")
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,229 @@
+package gottyclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ScheduleCatchUp is how late a job still runs, e.g. when the machine slept
+// through its time or the scheduler was started after it
+const ScheduleCatchUp = time.Hour
+
+// ScheduledJob connects to a server every day at a time to run a command in
+// a new session
+type ScheduledJob struct {
+	ID int `json:"id"`
+	// At is the local time of day, HH:MM
+	At string `json:"at"`
+	// Target is a URL or host alias, empty with Callsign
+	Target   string `json:"target,omitempty"`
+	Callsign string `json:"callsign,omitempty"`
+	// Exec is the command the new session runs; the job ends with it
+	Exec string `json:"exec"`
+	// Record saves the session's output
+	Record bool `json:"record,omitempty"`
+	// MaxDuration ends the job after this long, 0 waits for Exec to end
+	MaxDuration time.Duration `json:"max_duration,omitempty"`
+	Added       time.Time     `json:"added"`
+	// LastRun is the time of day the job last ran for
+	LastRun time.Time `json:"last_run,omitempty"`
+}
+
+// ParseTimeOfDay parses a 24 hour HH:MM time of day
+func ParseTimeOfDay(s string) (hour, minute int, err error) {
+	parsed, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time %q, expected HH:MM, e.g. 02:00", s)
+	}
+	return parsed.Hour(), parsed.Minute(), nil
+}
+
+// Previous returns the job's latest time of day at or before t, in t's
+// location
+func (j *ScheduledJob) Previous(t time.Time) time.Time {
+	hour, minute, _ := ParseTimeOfDay(j.At)
+	at := time.Date(t.Year(), t.Month(), t.Day(), hour, minute, 0, 0, t.Location())
+	if at.After(t) {
+		at = time.Date(t.Year(), t.Month(), t.Day()-1, hour, minute, 0, 0, t.Location())
+	}
+	return at
+}
+
+// Next returns the job's first time of day after t, in t's location
+func (j *ScheduledJob) Next(t time.Time) time.Time {
+	at := j.Previous(t)
+	hour, minute, _ := ParseTimeOfDay(j.At)
+	return time.Date(at.Year(), at.Month(), at.Day()+1, hour, minute, 0, 0, t.Location())
+}
+
+// Due reports whether the job is to run at now: its latest time of day has
+// passed since it was added and last ran, by at most ScheduleCatchUp
+func (j *ScheduledJob) Due(now time.Time) bool {
+	at := j.Previous(now)
+	return at.After(j.Added) && at.After(j.LastRun) && now.Sub(at) <= ScheduleCatchUp
+}
+
+// Schedule is the list of scheduled jobs, kept in a file
+type Schedule struct {
+	path string
+	Jobs []*ScheduledJob `json:"jobs"`
+}
+
+// GetDefaultSchedulePath returns the default schedule path
+func GetDefaultSchedulePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gotty-client", "schedule.json")
+}
+
+// GetDefaultRecordingDir returns where the output of recorded jobs goes by
+// default
+func GetDefaultRecordingDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gotty-client", "recordings")
+}
+
+// CreateRecording creates the file in dir recording the run of job started
+// at start
+func CreateRecording(dir string, job *ScheduledJob, start time.Time) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %v", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%d-%s.log", job.ID, start.Format("20060102-150405")))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %v", err)
+	}
+	return file, nil
+}
+
+// LoadSchedule loads the schedule, returning an empty one if the file does
+// not exist or path is empty
+func LoadSchedule(path string) (*Schedule, error) {
+	schedule := &Schedule{path: path}
+	if path == "" {
+		return schedule, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return schedule, nil
+		}
+		return nil, fmt.Errorf("failed to read schedule: %v", err)
+	}
+	if err := json.Unmarshal(data, schedule); err != nil {
+		return nil, fmt.Errorf("failed to decode schedule: %v", err)
+	}
+	return schedule, nil
+}
+
+// Add adds a job with the next free ID, checking its time of day
+func (s *Schedule) Add(job *ScheduledJob) error {
+	if _, _, err := ParseTimeOfDay(job.At); err != nil {
+		return err
+	}
+	if job.Exec == "" {
+		return fmt.Errorf("a scheduled job needs a command")
+	}
+	if job.Target == "" && job.Callsign == "" {
+		return fmt.Errorf("a scheduled job needs a URL, host alias or callsign")
+	}
+	job.ID = 1
+	for _, existing := range s.Jobs {
+		if existing.ID >= job.ID {
+			job.ID = existing.ID + 1
+		}
+	}
+	if job.Added.IsZero() {
+		job.Added = time.Now()
+	}
+	s.Jobs = append(s.Jobs, job)
+	return nil
+}
+
+// Job returns the job with this ID, or nil
+func (s *Schedule) Job(id int) *ScheduledJob {
+	for _, job := range s.Jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// Remove removes the job with this ID, reporting whether there was one
+func (s *Schedule) Remove(id int) bool {
+	for i, job := range s.Jobs {
+		if job.ID == id {
+			s.Jobs = append(s.Jobs[:i], s.Jobs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Due returns the jobs to run at now, see ScheduledJob.Due
+func (s *Schedule) Due(now time.Time) []*ScheduledJob {
+	var due []*ScheduledJob
+	for _, job := range s.Jobs {
+		if job.Due(now) {
+			due = append(due, job)
+		}
+	}
+	return due
+}
+
+// Sorted returns the jobs in the order they next run after now
+func (s *Schedule) Sorted(now time.Time) []*ScheduledJob {
+	jobs := append([]*ScheduledJob(nil), s.Jobs...)
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Next(now).Before(jobs[j].Next(now))
+	})
+	return jobs
+}
+
+// Save writes the schedule back to its file
+func (s *Schedule) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create schedule directory: %v", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, 0600, func(writer *bufio.Writer) error {
+		_, err := writer.Write(data)
+		return err
+	})
+}
+
+// UpdateSchedule loads the schedule at path, lets update change it and
+// writes it back, holding the file's lock so that a scheduler marking jobs
+// as run and a schedule add do not overwrite each other
+func UpdateSchedule(path string, update func(*Schedule) error) error {
+	unlock, err := lockConfig(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	schedule, err := LoadSchedule(path)
+	if err != nil {
+		return err
+	}
+	if err := update(schedule); err != nil {
+		return err
+	}
+	return schedule.Save()
+}
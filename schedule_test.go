@@ -0,0 +1,76 @@
+package gottyclient
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSchedule(t *testing.T) {
+	Convey("Testing scheduled jobs", t, func() {
+		day := func(hour, minute int) time.Time {
+			return time.Date(2026, 3, 10, hour, minute, 0, 0, time.UTC)
+		}
+		job := &ScheduledJob{At: "02:00", Target: "sdr", Exec: "scan", Added: day(0, 0).AddDate(0, 0, -1)}
+
+		Convey("Times of day are HH:MM", func() {
+			hour, minute, err := ParseTimeOfDay("02:30")
+			So(err, ShouldBeNil)
+			So(hour, ShouldEqual, 2)
+			So(minute, ShouldEqual, 30)
+			for _, value := range []string{"2am", "25:00", "02:60", ""} {
+				_, _, err := ParseTimeOfDay(value)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("Jobs run once a day at their time", func() {
+			So(job.Previous(day(1, 59)), ShouldResemble, day(2, 0).AddDate(0, 0, -1))
+			So(job.Previous(day(2, 0)), ShouldResemble, day(2, 0))
+			So(job.Next(day(2, 0)), ShouldResemble, day(2, 0).AddDate(0, 0, 1))
+			So(job.Next(day(1, 0)), ShouldResemble, day(2, 0))
+
+			So(job.Due(day(2, 0)), ShouldBeTrue)
+			job.LastRun = day(2, 0)
+			So(job.Due(day(2, 1)), ShouldBeFalse)
+			So(job.Due(day(2, 0).AddDate(0, 0, 1)), ShouldBeTrue)
+		})
+
+		Convey("Jobs missed by more than ScheduleCatchUp wait for the next day", func() {
+			So(job.Due(day(2, 0).Add(ScheduleCatchUp)), ShouldBeTrue)
+			So(job.Due(day(2, 1).Add(ScheduleCatchUp)), ShouldBeFalse)
+		})
+
+		Convey("Jobs added after their time wait for the next day", func() {
+			job.Added = day(2, 0).Add(time.Minute)
+			So(job.Due(day(2, 5)), ShouldBeFalse)
+			So(job.Due(day(2, 0).AddDate(0, 0, 1)), ShouldBeTrue)
+		})
+
+		Convey("The schedule is kept in a file", func() {
+			path := filepath.Join(t.TempDir(), "schedule.json")
+			So(UpdateSchedule(path, func(schedule *Schedule) error {
+				So(schedule.Add(job), ShouldBeNil)
+				return schedule.Add(&ScheduledJob{At: "01:00", Callsign: "M9PSY", Exec: "scan", Record: true})
+			}), ShouldBeNil)
+
+			schedule, err := LoadSchedule(path)
+			So(err, ShouldBeNil)
+			So(schedule.Jobs, ShouldHaveLength, 2)
+			So(schedule.Job(1).Target, ShouldEqual, "sdr")
+			So(schedule.Job(2).Record, ShouldBeTrue)
+			So(schedule.Sorted(day(1, 30))[0].ID, ShouldEqual, 1)
+			So(schedule.Due(day(2, 10)), ShouldHaveLength, 1)
+
+			So(schedule.Remove(1), ShouldBeTrue)
+			So(schedule.Remove(1), ShouldBeFalse)
+			So(schedule.Add(&ScheduledJob{At: "03:00", Target: "sdr", Exec: "scan"}), ShouldBeNil)
+			So(schedule.Job(3), ShouldNotBeNil)
+
+			So(schedule.Add(&ScheduledJob{At: "3pm", Target: "sdr", Exec: "scan"}), ShouldNotBeNil)
+			So(schedule.Add(&ScheduledJob{At: "03:00", Exec: "scan"}), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,806 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Screen is a model of the terminal screen, maintained by interpreting the
+// session output like a VT100/xterm would. It is what the user sees rather
+// than the raw bytes, so it can be searched, snapshotted and exported
+// without the escape sequences getting in the way. Only the common
+// sequences are interpreted; others are ignored.
+type Screen struct {
+	mutex   sync.Mutex
+	rows    int
+	cols    int
+	grid    [][]screenCell
+	primary [][]screenCell // the main screen while the alternate one is shown
+
+	row, col    int
+	wrapPending bool
+	style       cellStyle
+	saved       savedCursor
+	top, bottom int // scroll region
+
+	state   parserState
+	params  []byte
+	pending []byte // incomplete UTF-8 sequence
+
+	dirty   []bool
+	watches []*screenWatch
+}
+
+type screenCell struct {
+	r     rune
+	style cellStyle
+}
+
+// color is a palette index (0-255), a 24-bit RGB value with colorRGB set,
+// or colorDefault
+type color int32
+
+const (
+	colorDefault color = -1
+	colorRGB     color = 1 << 24
+)
+
+type cellStyle struct {
+	fg, bg    color
+	bold      bool
+	underline bool
+	inverse   bool
+}
+
+var defaultStyle = cellStyle{fg: colorDefault, bg: colorDefault}
+
+type savedCursor struct {
+	row, col int
+	style    cellStyle
+}
+
+type parserState int
+
+const (
+	stateGround parserState = iota
+	stateEscape
+	stateEscapeIntermediate
+	stateCSI
+	stateOSC
+	stateOSCEscape
+)
+
+// screenWatch calls fn for lines matching re
+type screenWatch struct {
+	re   *regexp.Regexp
+	fn   func(line string)
+	last map[int]string
+}
+
+// NewScreen returns an empty screen of the given size
+func NewScreen(rows, cols int) *Screen {
+	s := &Screen{style: defaultStyle, saved: savedCursor{style: defaultStyle}}
+	s.resize(rows, cols)
+	return s
+}
+
+// resizeScreen applies the payload of a resize message to the screen
+// model, if any
+func (c *Client) resizeScreen(size []byte) {
+	var ws WinSize
+	if c.Screen != nil && json.Unmarshal(size, &ws) == nil && ws.Rows > 0 && ws.Columns > 0 {
+		c.Screen.Resize(int(ws.Rows), int(ws.Columns))
+	}
+}
+
+// Resize changes the screen size, keeping the top-left content
+func (s *Screen) Resize(rows, cols int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.resize(rows, cols)
+}
+
+func (s *Screen) resize(rows, cols int) {
+	if rows < 1 {
+		rows = 1
+	}
+	if cols < 1 {
+		cols = 1
+	}
+	s.grid = resizeGrid(s.grid, rows, cols)
+	if s.primary != nil {
+		s.primary = resizeGrid(s.primary, rows, cols)
+	}
+	s.rows, s.cols = rows, cols
+	s.top, s.bottom = 0, rows-1
+	s.row, s.col = clamp(s.row, 0, rows-1), clamp(s.col, 0, cols-1)
+	s.wrapPending = false
+	s.dirty = make([]bool, rows)
+}
+
+func resizeGrid(grid [][]screenCell, rows, cols int) [][]screenCell {
+	resized := make([][]screenCell, rows)
+	for i := range resized {
+		resized[i] = blankLine(cols, defaultStyle)
+		if i < len(grid) {
+			copy(resized[i], grid[i])
+		}
+	}
+	return resized
+}
+
+func blankLine(cols int, style cellStyle) []screenCell {
+	line := make([]screenCell, cols)
+	for i := range line {
+		line[i] = screenCell{r: ' ', style: cellStyle{fg: colorDefault, bg: style.bg}}
+	}
+	return line
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// Size returns the number of rows and columns
+func (s *Screen) Size() (rows, cols int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.rows, s.cols
+}
+
+// Cursor returns the cursor position, counted from 0
+func (s *Screen) Cursor() (row, col int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.row, s.col
+}
+
+// Write interprets terminal output
+func (s *Screen) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	for _, b := range p {
+		s.feed(b)
+	}
+	matches := s.checkWatches()
+	s.mutex.Unlock()
+
+	for _, match := range matches {
+		match()
+	}
+	return len(p), nil
+}
+
+// Lines returns the text of each row, without trailing blanks
+func (s *Screen) Lines() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	lines := make([]string, s.rows)
+	for i := range lines {
+		lines[i] = s.lineText(i)
+	}
+	return lines
+}
+
+// Text returns the screen as text, without trailing blank lines
+func (s *Screen) Text() string {
+	lines := s.Lines()
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Find returns the lines on screen matching re
+func (s *Screen) Find(re *regexp.Regexp) []string {
+	var found []string
+	for _, line := range s.Lines() {
+		if re.MatchString(line) {
+			found = append(found, line)
+		}
+	}
+	return found
+}
+
+// Watch calls fn, outside of Write, with each line that is changed by the
+// output and then matches re. A line is reported again only if it changes.
+func (s *Screen) Watch(re *regexp.Regexp, fn func(line string)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.watches = append(s.watches, &screenWatch{re: re, fn: fn, last: make(map[int]string)})
+}
+
+func (s *Screen) lineText(row int) string {
+	var b strings.Builder
+	for _, cell := range s.grid[row] {
+		b.WriteRune(cell.r)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// checkWatches returns the watch callbacks due for the rows changed since
+// the last call
+func (s *Screen) checkWatches() []func() {
+	var matches []func()
+	for row, dirty := range s.dirty {
+		if !dirty {
+			continue
+		}
+		s.dirty[row] = false
+		if len(s.watches) == 0 {
+			continue
+		}
+		line := s.lineText(row)
+		for _, watch := range s.watches {
+			if watch.last[row] == line || !watch.re.MatchString(line) {
+				continue
+			}
+			watch.last[row] = line
+			fn := watch.fn
+			matches = append(matches, func() { fn(line) })
+		}
+	}
+	return matches
+}
+
+// feed advances the parser by one byte
+func (s *Screen) feed(b byte) {
+	switch s.state {
+	case stateEscape:
+		s.escape(b)
+		return
+	case stateEscapeIntermediate:
+		// Character set designations and the like: ignored
+		s.state = stateGround
+		return
+	case stateCSI:
+		switch {
+		case b >= 0x30 && b <= 0x3f:
+			s.params = append(s.params, b)
+		case b >= 0x40 && b <= 0x7e:
+			s.csi(b)
+			s.state = stateGround
+		case b == 0x1b:
+			s.state = stateEscape
+		case b >= 0x20:
+			// Intermediate bytes: ignored
+		default:
+			s.control(b)
+		}
+		return
+	case stateOSC:
+		switch b {
+		case 0x07:
+			s.state = stateGround
+		case 0x1b:
+			s.state = stateOSCEscape
+		}
+		return
+	case stateOSCEscape:
+		// ESC \ ends the string; anything else starts a new sequence
+		s.state = stateGround
+		if b != '\\' {
+			s.feed(0x1b)
+			s.feed(b)
+		}
+		return
+	}
+
+	if len(s.pending) > 0 && b < 0x80 {
+		// Truncated UTF-8 sequence
+		s.pending = s.pending[:0]
+		s.print(utf8.RuneError)
+	}
+	if b >= 0x80 {
+		s.pending = append(s.pending, b)
+		if !utf8.FullRune(s.pending) {
+			return
+		}
+		r, _ := utf8.DecodeRune(s.pending)
+		s.pending = s.pending[:0]
+		s.print(r)
+		return
+	}
+	if b < 0x20 || b == 0x7f {
+		s.control(b)
+		return
+	}
+	s.print(rune(b))
+}
+
+// control runs a C0 control character
+func (s *Screen) control(b byte) {
+	switch b {
+	case 0x08:
+		if s.col > 0 {
+			s.col--
+		}
+		s.wrapPending = false
+	case 0x09:
+		s.col = clamp((s.col/8+1)*8, 0, s.cols-1)
+		s.wrapPending = false
+	case 0x0a, 0x0b, 0x0c:
+		s.lineFeed()
+	case 0x0d:
+		s.col = 0
+		s.wrapPending = false
+	case 0x1b:
+		s.state = stateEscape
+	}
+}
+
+func (s *Screen) print(r rune) {
+	if s.wrapPending {
+		s.col = 0
+		s.lineFeed()
+	}
+	s.grid[s.row][s.col] = screenCell{r: r, style: s.style}
+	s.dirty[s.row] = true
+	if s.col == s.cols-1 {
+		s.wrapPending = true
+	} else {
+		s.col++
+	}
+}
+
+func (s *Screen) lineFeed() {
+	s.wrapPending = false
+	if s.row == s.bottom {
+		s.scrollUp(1)
+	} else if s.row < s.rows-1 {
+		s.row++
+	}
+}
+
+// scrollUp moves the lines of the scroll region up by n
+func (s *Screen) scrollUp(n int) {
+	n = clamp(n, 0, s.bottom-s.top+1)
+	region := s.grid[s.top : s.bottom+1]
+	copy(region, region[n:])
+	for i := len(region) - n; i < len(region); i++ {
+		region[i] = blankLine(s.cols, s.style)
+	}
+	s.shiftWatches(s.top, s.bottom, -n)
+}
+
+// scrollDown moves the lines of the scroll region down by n
+func (s *Screen) scrollDown(n int) {
+	n = clamp(n, 0, s.bottom-s.top+1)
+	region := s.grid[s.top : s.bottom+1]
+	copy(region[n:], region)
+	for i := 0; i < n; i++ {
+		region[i] = blankLine(s.cols, s.style)
+	}
+	s.shiftWatches(s.top, s.bottom, n)
+}
+
+// shiftWatches moves the watches' memory of lines along with scrolled
+// lines, so scrolling does not report them again
+func (s *Screen) shiftWatches(top, bottom, by int) {
+	for _, watch := range s.watches {
+		last := make(map[int]string, len(watch.last))
+		for row, line := range watch.last {
+			if row < top || row > bottom {
+				last[row] = line
+			} else if moved := row + by; moved >= top && moved <= bottom {
+				last[moved] = line
+			}
+		}
+		watch.last = last
+	}
+}
+
+func (s *Screen) escape(b byte) {
+	s.state = stateGround
+	switch b {
+	case '[':
+		s.params = s.params[:0]
+		s.state = stateCSI
+	case ']', 'P', '_', '^', 'X':
+		// OSC, DCS and other strings: skipped
+		s.state = stateOSC
+	case '(', ')', '*', '+', '#', '%':
+		s.state = stateEscapeIntermediate
+	case 'D':
+		s.lineFeed()
+	case 'E':
+		s.col = 0
+		s.lineFeed()
+	case 'M':
+		s.wrapPending = false
+		if s.row == s.top {
+			s.scrollDown(1)
+		} else if s.row > 0 {
+			s.row--
+		}
+	case '7':
+		s.saved = savedCursor{row: s.row, col: s.col, style: s.style}
+	case '8':
+		s.restoreCursor()
+	case 'c':
+		s.primary = nil
+		s.style = defaultStyle
+		s.grid = nil
+		s.row, s.col = 0, 0
+		s.resize(s.rows, s.cols)
+		s.markAllDirty()
+	}
+}
+
+func (s *Screen) restoreCursor() {
+	s.row, s.col = clamp(s.saved.row, 0, s.rows-1), clamp(s.saved.col, 0, s.cols-1)
+	s.style = s.saved.style
+	s.wrapPending = false
+}
+
+func (s *Screen) markAllDirty() {
+	for i := range s.dirty {
+		s.dirty[i] = true
+	}
+}
+
+// csiParams parses the parameters of a control sequence; missing ones are 0
+func (s *Screen) csiParams() (private bool, params []int) {
+	raw := string(s.params)
+	if strings.HasPrefix(raw, "?") || strings.HasPrefix(raw, ">") || strings.HasPrefix(raw, "=") {
+		private = raw[0] == '?'
+		raw = raw[1:]
+	}
+	if raw == "" {
+		return private, nil
+	}
+	for _, field := range strings.FieldsFunc(raw, func(r rune) bool { return r == ';' || r == ':' }) {
+		n, _ := strconv.Atoi(field)
+		params = append(params, n)
+	}
+	return private, params
+}
+
+// param returns the i-th parameter, or def if it is missing or 0
+func param(params []int, i, def int) int {
+	if i < len(params) && params[i] != 0 {
+		return params[i]
+	}
+	return def
+}
+
+func (s *Screen) csi(final byte) {
+	private, params := s.csiParams()
+	if private {
+		if final == 'h' || final == 'l' {
+			for _, mode := range params {
+				s.setPrivateMode(mode, final == 'h')
+			}
+		}
+		return
+	}
+
+	n := param(params, 0, 1)
+	if final != 'm' {
+		s.wrapPending = false
+	}
+	switch final {
+	case 'A':
+		s.row = clamp(s.row-n, 0, s.rows-1)
+	case 'B', 'e':
+		s.row = clamp(s.row+n, 0, s.rows-1)
+	case 'C', 'a':
+		s.col = clamp(s.col+n, 0, s.cols-1)
+	case 'D':
+		s.col = clamp(s.col-n, 0, s.cols-1)
+	case 'E':
+		s.row, s.col = clamp(s.row+n, 0, s.rows-1), 0
+	case 'F':
+		s.row, s.col = clamp(s.row-n, 0, s.rows-1), 0
+	case 'G', '`':
+		s.col = clamp(n-1, 0, s.cols-1)
+	case 'd':
+		s.row = clamp(n-1, 0, s.rows-1)
+	case 'H', 'f':
+		s.row = clamp(param(params, 0, 1)-1, 0, s.rows-1)
+		s.col = clamp(param(params, 1, 1)-1, 0, s.cols-1)
+	case 'J':
+		s.eraseDisplay(param(params, 0, 0))
+	case 'K':
+		s.eraseLine(param(params, 0, 0))
+	case 'L':
+		if s.row >= s.top && s.row <= s.bottom {
+			top := s.top
+			s.top = s.row
+			s.scrollDown(n)
+			s.top = top
+			s.markAllDirty()
+		}
+	case 'M':
+		if s.row >= s.top && s.row <= s.bottom {
+			top := s.top
+			s.top = s.row
+			s.scrollUp(n)
+			s.top = top
+			s.markAllDirty()
+		}
+	case 'P':
+		line := s.grid[s.row]
+		n = clamp(n, 0, s.cols-s.col)
+		copy(line[s.col:], line[s.col+n:])
+		s.erase(s.row, s.cols-n, s.cols)
+	case '@':
+		line := s.grid[s.row]
+		n = clamp(n, 0, s.cols-s.col)
+		copy(line[s.col+n:], line[s.col:])
+		s.erase(s.row, s.col, s.col+n)
+	case 'X':
+		s.erase(s.row, s.col, s.col+n)
+	case 'S':
+		s.scrollUp(n)
+		s.markAllDirty()
+	case 'T':
+		s.scrollDown(n)
+		s.markAllDirty()
+	case 'm':
+		s.setStyle(params)
+	case 'r':
+		top, bottom := param(params, 0, 1)-1, param(params, 1, s.rows)-1
+		if top < bottom && bottom < s.rows {
+			s.top, s.bottom = top, bottom
+			s.row, s.col = 0, 0
+		}
+	case 's':
+		s.saved = savedCursor{row: s.row, col: s.col, style: s.style}
+	case 'u':
+		s.restoreCursor()
+	}
+}
+
+func (s *Screen) setPrivateMode(mode int, set bool) {
+	switch mode {
+	case 47, 1047, 1049:
+		if set == (s.primary != nil) {
+			return
+		}
+		if set {
+			if mode == 1049 {
+				s.saved = savedCursor{row: s.row, col: s.col, style: s.style}
+			}
+			s.primary = s.grid
+			s.grid = resizeGrid(nil, s.rows, s.cols)
+		} else {
+			s.grid = s.primary
+			s.primary = nil
+			if mode == 1049 {
+				s.restoreCursor()
+			}
+		}
+		s.markAllDirty()
+	}
+}
+
+// erase blanks the columns [from, to) of a row
+func (s *Screen) erase(row, from, to int) {
+	from, to = clamp(from, 0, s.cols), clamp(to, 0, s.cols)
+	for i := from; i < to; i++ {
+		s.grid[row][i] = screenCell{r: ' ', style: cellStyle{fg: colorDefault, bg: s.style.bg}}
+	}
+	s.dirty[row] = true
+}
+
+func (s *Screen) eraseLine(mode int) {
+	switch mode {
+	case 0:
+		s.erase(s.row, s.col, s.cols)
+	case 1:
+		s.erase(s.row, 0, s.col+1)
+	case 2:
+		s.erase(s.row, 0, s.cols)
+	}
+}
+
+func (s *Screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.eraseLine(0)
+		for row := s.row + 1; row < s.rows; row++ {
+			s.erase(row, 0, s.cols)
+		}
+	case 1:
+		for row := 0; row < s.row; row++ {
+			s.erase(row, 0, s.cols)
+		}
+		s.eraseLine(1)
+	case 2, 3:
+		for row := 0; row < s.rows; row++ {
+			s.erase(row, 0, s.cols)
+		}
+	}
+}
+
+// setStyle applies an SGR sequence
+func (s *Screen) setStyle(params []int) {
+	if len(params) == 0 {
+		params = []int{0}
+	}
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case p == 0:
+			s.style = defaultStyle
+		case p == 1:
+			s.style.bold = true
+		case p == 4:
+			s.style.underline = true
+		case p == 7:
+			s.style.inverse = true
+		case p == 22:
+			s.style.bold = false
+		case p == 24:
+			s.style.underline = false
+		case p == 27:
+			s.style.inverse = false
+		case p >= 30 && p <= 37:
+			s.style.fg = color(p - 30)
+		case p == 38:
+			s.style.fg, i = extendedColor(params, i)
+		case p == 39:
+			s.style.fg = colorDefault
+		case p >= 40 && p <= 47:
+			s.style.bg = color(p - 40)
+		case p == 48:
+			s.style.bg, i = extendedColor(params, i)
+		case p == 49:
+			s.style.bg = colorDefault
+		case p >= 90 && p <= 97:
+			s.style.fg = color(p - 90 + 8)
+		case p >= 100 && p <= 107:
+			s.style.bg = color(p - 100 + 8)
+		}
+	}
+}
+
+// extendedColor parses the 38/48 color at params[i], returning it and the
+// index of its last parameter
+func extendedColor(params []int, i int) (color, int) {
+	if i+2 < len(params) && params[i+1] == 5 {
+		return color(params[i+2] & 0xff), i + 2
+	}
+	if i+4 < len(params) && params[i+1] == 2 {
+		rgb := (params[i+2]&0xff)<<16 | (params[i+3]&0xff)<<8 | params[i+4]&0xff
+		return colorRGB | color(rgb), i + 4
+	}
+	return colorDefault, len(params)
+}
+
+// basicColors are the 16 standard colors, as in xterm
+var basicColors = [16]int{
+	0x000000, 0xcd0000, 0x00cd00, 0xcdcd00, 0x0000ee, 0xcd00cd, 0x00cdcd, 0xe5e5e5,
+	0x7f7f7f, 0xff0000, 0x00ff00, 0xffff00, 0x5c5cff, 0xff00ff, 0x00ffff, 0xffffff,
+}
+
+// hex returns the color as #rrggbb, or def for the default color
+func (c color) hex(def string) string {
+	var rgb int
+	switch {
+	case c == colorDefault:
+		return def
+	case c&colorRGB != 0:
+		rgb = int(c &^ colorRGB)
+	case c < 16:
+		rgb = basicColors[c]
+	case c < 232:
+		// 6x6x6 color cube
+		levels := [6]int{0, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+		n := int(c) - 16
+		rgb = levels[n/36]<<16 | levels[n/6%6]<<8 | levels[n%6]
+	default:
+		gray := 8 + (int(c)-232)*10
+		rgb = gray<<16 | gray<<8 | gray
+	}
+	return fmt.Sprintf("#%06x", rgb)
+}
+
+// css returns the inline style of a cell
+func (st cellStyle) css() string {
+	fg, bg := st.fg.hex(""), st.bg.hex("")
+	if st.inverse {
+		fg, bg = st.bg.hex(screenBackground), st.fg.hex(screenForeground)
+	}
+	var rules []string
+	if fg != "" {
+		rules = append(rules, "color:"+fg)
+	}
+	if bg != "" {
+		rules = append(rules, "background:"+bg)
+	}
+	if st.bold {
+		rules = append(rules, "font-weight:bold")
+	}
+	if st.underline {
+		rules = append(rules, "text-decoration:underline")
+	}
+	return strings.Join(rules, ";")
+}
+
+// Colors of unstyled text in HTML exports
+const (
+	screenForeground = "#e5e5e5"
+	screenBackground = "#000000"
+)
+
+// SaveSnapshot writes the screen to path, as HTML if its extension is .html
+// or .htm and as text otherwise
+func (s *Screen) SaveSnapshot(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		err = s.WriteHTML(f)
+	default:
+		_, err = io.WriteString(f, s.Text()+"\n")
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// saveSnapshot is the escape menu action saving the screen to SnapshotPath
+func (c *Client) saveSnapshot() {
+	if err := c.Screen.SaveSnapshot(c.SnapshotPath); err != nil {
+		c.statusf("snapshot failed: %v", err)
+		return
+	}
+	c.statusf("screen saved to %s", c.SnapshotPath)
+}
+
+// WriteHTML writes the screen as a standalone HTML document, keeping colors
+// and text attributes
+func (s *Screen) WriteHTML(w io.Writer) error {
+	s.mutex.Lock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"></head>\n")
+	fmt.Fprintf(&b, "<body style=\"background:%s\"><pre style=\"color:%s;background:%s;font-family:monospace\">", screenBackground, screenForeground, screenBackground)
+	for row, line := range s.grid {
+		if row > 0 {
+			b.WriteByte('\n')
+		}
+		// Trailing blanks without a background are left out
+		end := len(line)
+		for end > 0 && line[end-1].r == ' ' && line[end-1].style.bg == colorDefault && !line[end-1].style.inverse {
+			end--
+		}
+		for start := 0; start < end; {
+			style := line[start].style
+			stop := start
+			var text strings.Builder
+			for stop < end && line[stop].style == style {
+				text.WriteRune(line[stop].r)
+				stop++
+			}
+			if css := style.css(); css != "" {
+				fmt.Fprintf(&b, "<span style=\"%s\">%s</span>", css, html.EscapeString(text.String()))
+			} else {
+				b.WriteString(html.EscapeString(text.String()))
+			}
+			start = stop
+		}
+	}
+	b.WriteString("</pre></body></html>\n")
+	s.mutex.Unlock()
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
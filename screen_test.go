@@ -0,0 +1,173 @@
+package gottyclient
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScreen(t *testing.T) {
+	Convey("Testing the screen model", t, func() {
+		screen := NewScreen(4, 10)
+		write := func(s string) {
+			_, err := screen.Write([]byte(s))
+			So(err, ShouldBeNil)
+		}
+
+		Convey("Text is placed at the cursor and wraps", func() {
+			write("hello\r\nworld, again")
+			So(screen.Lines(), ShouldResemble, []string{"hello", "world, aga", "in", ""})
+			row, col := screen.Cursor()
+			So([]int{row, col}, ShouldResemble, []int{2, 2})
+		})
+
+		Convey("Output scrolls at the bottom", func() {
+			write("1\r\n2\r\n3\r\n4\r\n5")
+			So(screen.Text(), ShouldEqual, "2\n3\n4\n5")
+		})
+
+		Convey("Escape sequences move the cursor and erase, they are not text", func() {
+			write("\x1b[1;31mred\x1b[0m \x1b]0;title\x07ok")
+			So(screen.Lines()[0], ShouldEqual, "red ok")
+			write("\x1b[2J\x1b[3;4Hx\x1b[1;1Habc\x1b[2D\x1b[K")
+			So(screen.Lines(), ShouldResemble, []string{"a", "", "   x", ""})
+		})
+
+		Convey("Carriage returns overwrite like on a terminal", func() {
+			write("tx 10%\rtx 99%")
+			So(screen.Lines()[0], ShouldEqual, "tx 99%")
+		})
+
+		Convey("Full screen programs use the alternate screen", func() {
+			write("shell$ ")
+			write("\x1b[?1049h\x1b[Hvim")
+			So(screen.Lines()[0], ShouldEqual, "vim")
+			write("\x1b[?1049l")
+			So(screen.Lines()[0], ShouldEqual, "shell$")
+		})
+
+		Convey("Multi-byte characters may span writes", func() {
+			write("73 \xe2\x9c")
+			write("\x93")
+			So(screen.Lines()[0], ShouldEqual, "73 ✓")
+		})
+
+		Convey("Resizing keeps the content", func() {
+			write("abcdefghij")
+			screen.Resize(2, 5)
+			So(screen.Lines(), ShouldResemble, []string{"abcde", ""})
+		})
+
+		Convey("Watches see rendered lines once", func() {
+			var seen []string
+			screen.Watch(regexp.MustCompile(`ERROR`), func(line string) { seen = append(seen, line) })
+			write("ok\r\nERR")
+			write("OR x\r\n")
+			write("\r\n\r\n")
+			So(seen, ShouldResemble, []string{"ERROR x"})
+			So(screen.Find(regexp.MustCompile(`^ERR`)), ShouldResemble, []string{"ERROR x"})
+		})
+
+		Convey("HTML export keeps colors and escapes text", func() {
+			write("\x1b[1;32m<ok>\x1b[0m & \x1b[38;5;196mred")
+			var buf bytes.Buffer
+			So(screen.WriteHTML(&buf), ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, `<span style="color:#00cd00;font-weight:bold">&lt;ok&gt;</span> &amp; <span style="color:#ff0000">red</span>`)
+		})
+	})
+}
+
+func TestScreenSequences(t *testing.T) {
+	Convey("Testing escape sequences on a 4x10 screen", t, func() {
+		for _, test := range []struct {
+			name  string
+			input string
+			lines []string
+			row   int
+			col   int
+		}{
+			{"cursor up, down, forward and back", "\x1b[H\x1b[2B\x1b[3C\x1b[Ax", []string{"1111", "222x", "3333", "4444"}, 1, 4},
+			{"positions are clamped to the screen", "\x1b[99;99Hz", []string{"1111", "2222", "3333", "4444     z"}, 3, 9},
+			{"column and row absolute", "\x1b[2;5H\x1b[7Gq\x1b[1dw", []string{"1111   w", "2222  q", "3333", "4444"}, 0, 8},
+			{"next and previous line", "\x1b[H\x1b[2Ex\x1b[Fy", []string{"1111", "y222", "x333", "4444"}, 1, 1},
+			{"erase in line", "\x1b[2;3H\x1b[1K\x1b[3;3H\x1b[K\x1b[4;1H\x1b[2K", []string{"1111", "   2", "33", ""}, 3, 0},
+			{"erase below", "\x1b[2;3H\x1b[J", []string{"1111", "22", "", ""}, 1, 2},
+			{"erase above", "\x1b[2;3H\x1b[1J", []string{"", "   2", "3333", "4444"}, 1, 2},
+			{"erase display", "\x1b[2J", []string{"", "", "", ""}, 3, 4},
+			{"delete characters", "\x1b[1;2H\x1b[2P", []string{"11", "2222", "3333", "4444"}, 0, 1},
+			{"insert characters", "\x1b[1;2H\x1b[2@", []string{"1  111", "2222", "3333", "4444"}, 0, 1},
+			{"erase characters", "\x1b[1;2H\x1b[2X", []string{"1  1", "2222", "3333", "4444"}, 0, 1},
+			{"insert lines", "\x1b[2H\x1b[L", []string{"1111", "", "2222", "3333"}, 1, 0},
+			{"delete lines", "\x1b[2H\x1b[M", []string{"1111", "3333", "4444", ""}, 1, 0},
+			{"scroll up", "\x1b[S", []string{"2222", "3333", "4444", ""}, 3, 4},
+			{"scroll down", "\x1b[T", []string{"", "1111", "2222", "3333"}, 3, 4},
+			{"save and restore the cursor", "\x1b[2;2H\x1b7\x1b[4;4H\x1b8x\x1b[3;2H\x1b[s\x1b[H\x1b[uy", []string{"1111", "2x22", "3y33", "4444"}, 2, 2},
+			{"index scrolls at the bottom", "\x1bD", []string{"2222", "3333", "4444", ""}, 3, 4},
+			{"reverse index scrolls at the top", "\x1b[H\x1bM", []string{"", "1111", "2222", "3333"}, 0, 0},
+			{"next line", "\x1b[1;3H\x1bEx", []string{"1111", "x222", "3333", "4444"}, 1, 1},
+			{"reset", "\x1bc", []string{"", "", "", ""}, 0, 0},
+			{"character sets are not text", "\x1b(B\x1b)0ok", []string{"1111", "2222", "3333", "4444ok"}, 3, 6},
+			{"line feeds scroll the region only", "\x1b[2;3r\x1b[3H\r\nx", []string{"1111", "3333", "x", "4444"}, 2, 1},
+			{"reverse index scrolls the region down", "\x1b[2;3r\x1b[2H\x1bM", []string{"1111", "", "2222", "4444"}, 1, 0},
+			{"scroll down within the region", "\x1b[2;3r\x1b[2T", []string{"1111", "", "", "4444"}, 0, 0},
+			{"insert lines push lines out of the region", "\x1b[1;3r\x1b[2H\x1b[L", []string{"1111", "", "2222", "4444"}, 1, 0},
+			{"below the region nothing scrolls", "\x1b[1;2r\x1b[4H\r\n", []string{"1111", "2222", "3333", "4444"}, 3, 0},
+			{"invalid regions are ignored", "\x1b[3;2r\x1b[S", []string{"2222", "3333", "4444", ""}, 3, 4},
+		} {
+			Convey(test.name, func() {
+				screen := NewScreen(4, 10)
+				_, err := screen.Write([]byte("1111\r\n2222\r\n3333\r\n4444" + test.input))
+				So(err, ShouldBeNil)
+				So(screen.Lines(), ShouldResemble, test.lines)
+				row, col := screen.Cursor()
+				So([]int{row, col}, ShouldResemble, []int{test.row, test.col})
+			})
+		}
+	})
+
+	Convey("Testing resize messages", t, func() {
+		client := newClient("http://localhost/")
+		client.Screen = NewScreen(4, 10)
+		_, err := client.Screen.Write([]byte("1111\r\n2222\r\n3333\r\n4444"))
+		So(err, ShouldBeNil)
+
+		for _, test := range []struct {
+			name    string
+			payload string
+			rows    int
+			cols    int
+			lines   []string
+		}{
+			{"shrinking crops the content", `{"columns":3,"rows":2}`, 2, 3, []string{"111", "222"}},
+			{"growing adds blank space", `{"columns":12,"rows":5}`, 5, 12, []string{"1111", "2222", "3333", "4444", ""}},
+			{"malformed payloads are ignored", `{"columns":`, 4, 10, []string{"1111", "2222", "3333", "4444"}},
+			{"empty sizes are ignored", `{"columns":0,"rows":3}`, 4, 10, []string{"1111", "2222", "3333", "4444"}},
+		} {
+			Convey(test.name, func() {
+				client.resizeScreen([]byte(test.payload))
+				rows, cols := client.Screen.Size()
+				So([]int{rows, cols}, ShouldResemble, []int{test.rows, test.cols})
+				So(client.Screen.Lines(), ShouldResemble, test.lines)
+			})
+		}
+
+		Convey("The cursor and scroll region are kept on the screen", func() {
+			_, err := client.Screen.Write([]byte("\x1b[2;3r\x1b[4;8H"))
+			So(err, ShouldBeNil)
+			client.resizeScreen([]byte(`{"columns":5,"rows":3}`))
+			row, col := client.Screen.Cursor()
+			So([]int{row, col}, ShouldResemble, []int{2, 4})
+			_, err = client.Screen.Write([]byte("\r\nx"))
+			So(err, ShouldBeNil)
+			So(client.Screen.Lines(), ShouldResemble, []string{"2222", "3333", "x"})
+		})
+
+		Convey("Clients without a screen ignore resizes", func() {
+			client.Screen = nil
+			client.resizeScreen([]byte(`{"columns":5,"rows":3}`))
+			So(client.Screen, ShouldBeNil)
+		})
+	})
+}
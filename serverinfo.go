@@ -0,0 +1,110 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrServerInfoUnsupported is returned when the server has no info endpoint
+var ErrServerInfoUnsupported = fmt.Errorf("server does not report its version and uptime")
+
+// ServerInfo describes a running GoTTY server
+type ServerInfo struct {
+	Version       string  `json:"version"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	// Clients is the number of websocket clients connected to the server
+	Clients int `json:"clients"`
+	// AdminPasswordEncodings are the X-Admin-Password encodings the server
+	// decodes, see AdminPasswordEncoding
+	AdminPasswordEncodings []string `json:"admin_password_encodings,omitempty"`
+	// Transports are the transports the server accepts besides websockets,
+	// with their protocol version, e.g. "sse/1" for SSETransport
+	Transports []string `json:"transports,omitempty"`
+}
+
+// Uptime returns how long the server has been running
+func (i *ServerInfo) Uptime() time.Duration {
+	return time.Duration(i.UptimeSeconds * float64(time.Second))
+}
+
+// ServerInfo retrieves the server's version, uptime and client count
+func (s *SessionsClient) ServerInfo() (*ServerInfo, error) {
+	req, err := s.newAPIRequest("GET", "/api/info", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Debugf("Fetching server info: %q", req.URL.String())
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var info ServerInfo
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %v", err)
+		}
+		return &info, nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return nil, ErrServerInfoUnsupported
+	default:
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get server info: %d %s - %s", resp.StatusCode, http.StatusText(resp.StatusCode), string(body))
+	}
+}
+
+// ServerInfo retrieves the server's version, uptime and client count
+func (c *Client) ServerInfo() (*ServerInfo, error) {
+	return c.Sessions().ServerInfo()
+}
+
+// Overview summarizes a server for fleet monitoring
+type Overview struct {
+	Sessions int `json:"sessions"`
+	// Attached is the number of sessions with a client attached
+	Attached int `json:"attached"`
+	// Info is nil for servers without an info endpoint
+	Info *ServerInfo `json:"info,omitempty"`
+}
+
+// Overview gathers the session counts and, where the server reports them,
+// the version, uptime and connected clients of the server
+func (s *SessionsClient) Overview() (*Overview, error) {
+	sessions, err := s.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %v", err)
+	}
+	overview := &Overview{Sessions: len(sessions.Sessions)}
+	if sessions.Total > overview.Sessions {
+		overview.Sessions = sessions.Total
+	}
+	for _, session := range sessions.Sessions {
+		if session.Attached {
+			overview.Attached++
+		}
+	}
+
+	info, err := s.ServerInfo()
+	switch err {
+	case nil:
+		overview.Info = info
+	case ErrServerInfoUnsupported:
+		logrus.Debugf("%v", err)
+	default:
+		return nil, err
+	}
+	return overview, nil
+}
+
+// Overview gathers the session counts, version and uptime of the server
+func (c *Client) Overview() (*Overview, error) {
+	return c.Sessions().Overview()
+}
@@ -0,0 +1,127 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestServerInfo(t *testing.T) {
+	Convey("Testing the server info", t, func() {
+		status, body := http.StatusOK, ""
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/info" {
+				http.NotFound(w, r)
+				return
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+		defer server.Close()
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		client.RetryPolicy = NoRetry
+
+		Convey("Every field is decoded", func() {
+			body = `{"version":"1.5.0","uptime_seconds":90.5,"clients":2,"admin_password_encodings":["rfc8187"],"transports":["sse/1"]}`
+			info, err := client.ServerInfo()
+			So(err, ShouldBeNil)
+			So(info, ShouldResemble, &ServerInfo{
+				Version:                "1.5.0",
+				UptimeSeconds:          90.5,
+				Clients:                2,
+				AdminPasswordEncodings: []string{"rfc8187"},
+				Transports:             []string{"sse/1"},
+			})
+			So(info.Uptime(), ShouldEqual, 90500*time.Millisecond)
+		})
+
+		Convey("Malformed responses are errors", func() {
+			body = `{"version":`
+			_, err := client.ServerInfo()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "failed to decode response")
+		})
+
+		Convey("Failures carry the status and body", func() {
+			status, body = http.StatusInternalServerError, "database locked"
+			_, err := client.ServerInfo()
+			So(err, ShouldNotBeNil)
+			So(err, ShouldNotEqual, ErrServerInfoUnsupported)
+			So(err.Error(), ShouldContainSubstring, "500 Internal Server Error - database locked")
+		})
+
+		Convey("405 means the endpoint is missing", func() {
+			status = http.StatusMethodNotAllowed
+			_, err := client.ServerInfo()
+			So(err, ShouldEqual, ErrServerInfoUnsupported)
+		})
+	})
+}
+
+func TestOverview(t *testing.T) {
+	Convey("Testing the server overview", t, func() {
+		hasInfo, infoFails := true, false
+		list := `{"sessions":[{"name":"ft8","attached":true},{"name":"wspr"}],"count":2}`
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/api/sessions":
+				w.Write([]byte(list))
+			case r.URL.Path == "/api/info" && infoFails:
+				http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			case r.URL.Path == "/api/info" && hasInfo:
+				w.Write([]byte(`{"version":"1.4.0","uptime_seconds":93600,"clients":3}`))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer server.Close()
+		sessions, err := NewSessionsClient(server.URL, nil, nil)
+		So(err, ShouldBeNil)
+		sessions.RetryPolicy = NoRetry
+
+		Convey("Sessions and server info are combined", func() {
+			overview, err := sessions.Overview()
+			So(err, ShouldBeNil)
+			So(overview.Sessions, ShouldEqual, 2)
+			So(overview.Attached, ShouldEqual, 1)
+			So(overview.Info.Version, ShouldEqual, "1.4.0")
+			So(overview.Info.Uptime(), ShouldEqual, 26*time.Hour)
+			So(overview.Info.Clients, ShouldEqual, 3)
+		})
+
+		Convey("Servers without an info endpoint still report sessions", func() {
+			hasInfo = false
+			_, err := sessions.ServerInfo()
+			So(err, ShouldEqual, ErrServerInfoUnsupported)
+			overview, err := sessions.Overview()
+			So(err, ShouldBeNil)
+			So(overview.Sessions, ShouldEqual, 2)
+			So(overview.Info, ShouldBeNil)
+		})
+
+		Convey("The total counts sessions beyond the first page", func() {
+			list = `{"sessions":[{"name":"ft8"}],"count":1,"total":40}`
+			overview, err := sessions.Overview()
+			So(err, ShouldBeNil)
+			So(overview.Sessions, ShouldEqual, 40)
+		})
+
+		Convey("Other info failures are errors", func() {
+			infoFails = true
+			_, err := sessions.Overview()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "overloaded")
+		})
+
+		Convey("Failing to list sessions is an error", func() {
+			list = `{"sessions":`
+			_, err := sessions.Overview()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "failed to list sessions")
+		})
+	})
+}
@@ -0,0 +1,178 @@
+package gottyclient
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionTimeLayouts are the timestamp formats servers use for Created/LastActive
+var sessionTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	time.RFC3339Nano,
+	time.RFC1123,
+	time.UnixDate,
+}
+
+// parseSessionTime parses a session timestamp as sent by the server. Unix
+// timestamps in seconds are accepted as well.
+func parseSessionTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), true
+	}
+	for _, layout := range sessionTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// sessionTime returns a parsed session timestamp, parsing the raw value if
+// the session was not decoded from JSON
+func sessionTime(parsed time.Time, raw string) (time.Time, bool) {
+	if !parsed.IsZero() {
+		return parsed, true
+	}
+	return parseSessionTime(raw)
+}
+
+// FormatRelativeTime describes t relative to now, e.g. "just now", "5m ago", "2h ago" or "3d ago"
+func FormatRelativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var value string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		value = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		value = fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		value = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+
+	if future {
+		return "in " + value
+	}
+	return value + " ago"
+}
+
+// ParseDuration works like time.ParseDuration but also accepts a "d" suffix for days, e.g. "3d"
+func ParseDuration(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(value, "d"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(value)
+}
+
+// SessionFilter selects sessions from a listing. Zero values match everything.
+type SessionFilter struct {
+	Attached  bool              // only sessions with a client attached
+	Detached  bool              // only sessions without a client attached
+	OlderThan time.Duration     // only sessions created longer ago than this
+	Window    string            // only sessions whose window name matches this glob
+	Tags      map[string]string // only sessions carrying all these tags
+}
+
+// Empty reports whether the filter matches every session
+func (f SessionFilter) Empty() bool {
+	return !f.Attached && !f.Detached && f.OlderThan == 0 && f.Window == "" && len(f.Tags) == 0
+}
+
+// Match reports whether a session passes the filter
+func (f SessionFilter) Match(session SessionInfo) bool {
+	if f.Attached && !session.Attached {
+		return false
+	}
+	if f.Detached && session.Attached {
+		return false
+	}
+	if f.OlderThan > 0 {
+		created, ok := sessionTime(session.CreatedAt, session.Created)
+		if !ok || time.Since(created) < f.OlderThan {
+			return false
+		}
+	}
+	if f.Window != "" {
+		if matched, err := path.Match(f.Window, session.WindowName); err != nil || !matched {
+			return false
+		}
+	}
+	return MatchTags(session.Tags, f.Tags)
+}
+
+// Filter returns a new listing with only the sessions matching f
+func (r *SessionListResponse) Filter(f SessionFilter) *SessionListResponse {
+	result := &SessionListResponse{Sessions: []SessionInfo{}}
+	for _, session := range r.Sessions {
+		if f.Match(session) {
+			result.Sessions = append(result.Sessions, session)
+		}
+	}
+	result.Count = len(result.Sessions)
+	return result
+}
+
+// Session sort orders accepted by SessionListResponse.Sort
+const (
+	SortByName    = "name"
+	SortByCreated = "created"
+	SortByActive  = "active"
+)
+
+// Sort orders the sessions in place: by name, by creation time (oldest first)
+// or by last activity (most recently active first). Sessions with
+// unparseable timestamps are sorted last.
+func (r *SessionListResponse) Sort(by string) error {
+	var less func(a, b SessionInfo) bool
+	switch by {
+	case SortByName:
+		less = func(a, b SessionInfo) bool { return a.Name < b.Name }
+	case SortByCreated:
+		less = func(a, b SessionInfo) bool {
+			return timeLess(a.CreatedAt, a.Created, b.CreatedAt, b.Created, false)
+		}
+	case SortByActive:
+		less = func(a, b SessionInfo) bool {
+			return timeLess(a.LastActiveAt, a.LastActive, b.LastActiveAt, b.LastActive, true)
+		}
+	default:
+		return fmt.Errorf("unknown sort order %q (expected %s, %s or %s)", by, SortByName, SortByCreated, SortByActive)
+	}
+
+	sort.SliceStable(r.Sessions, func(i, j int) bool {
+		return less(r.Sessions[i], r.Sessions[j])
+	})
+	return nil
+}
+
+// timeLess compares two session timestamps, sorting unparseable ones last
+func timeLess(parsedA time.Time, a string, parsedB time.Time, b string, newestFirst bool) bool {
+	ta, okA := sessionTime(parsedA, a)
+	tb, okB := sessionTime(parsedB, b)
+	if !okA || !okB {
+		return okA && !okB
+	}
+	if newestFirst {
+		return ta.After(tb)
+	}
+	return ta.Before(tb)
+}
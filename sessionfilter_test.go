@@ -0,0 +1,76 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSessionFilterAndSort(t *testing.T) {
+	Convey("Testing session filtering and sorting", t, func() {
+		old := time.Now().Add(-72 * time.Hour).Format("2006-01-02 15:04:05")
+		recent := time.Now().Add(-time.Hour).Format("2006-01-02 15:04:05")
+		list := &SessionListResponse{
+			Sessions: []SessionInfo{
+				{Name: "b", WindowName: "ft8-40m", Attached: true, Created: recent, LastActive: recent},
+				{Name: "a", WindowName: "wspr", Created: old, LastActive: old},
+				{Name: "c", WindowName: "ft8-20m", Created: "garbage", LastActive: "garbage"},
+			},
+			Count: 3,
+		}
+
+		Convey("Filter", func() {
+			So(list.Filter(SessionFilter{Attached: true}).Count, ShouldEqual, 1)
+			So(list.Filter(SessionFilter{Detached: true}).Count, ShouldEqual, 2)
+			So(list.Filter(SessionFilter{Window: "ft8-*"}).Count, ShouldEqual, 2)
+
+			older := list.Filter(SessionFilter{OlderThan: 24 * time.Hour})
+			So(older.Count, ShouldEqual, 1)
+			So(older.Sessions[0].Name, ShouldEqual, "a")
+		})
+		Convey("Sort", func() {
+			So(list.Sort(SortByName), ShouldBeNil)
+			So(list.Sessions[0].Name, ShouldEqual, "a")
+
+			So(list.Sort(SortByCreated), ShouldBeNil)
+			So([]string{list.Sessions[0].Name, list.Sessions[1].Name, list.Sessions[2].Name}, ShouldResemble, []string{"a", "b", "c"})
+
+			So(list.Sort(SortByActive), ShouldBeNil)
+			So([]string{list.Sessions[0].Name, list.Sessions[1].Name, list.Sessions[2].Name}, ShouldResemble, []string{"b", "a", "c"})
+
+			So(list.Sort("size"), ShouldNotBeNil)
+		})
+		Convey("ParseDuration", func() {
+			d, err := ParseDuration("3d")
+			So(err, ShouldBeNil)
+			So(d, ShouldEqual, 72*time.Hour)
+			d, err = ParseDuration("90m")
+			So(err, ShouldBeNil)
+			So(d, ShouldEqual, 90*time.Minute)
+		})
+	})
+}
+
+func TestSessionTimes(t *testing.T) {
+	Convey("Testing session timestamps", t, func() {
+		Convey("Decoding parses timestamps and keeps raw strings", func() {
+			var list SessionListResponse
+			err := json.Unmarshal([]byte(`{"sessions":[{"name":"a","created":"2026-01-30 19:30:15","last_active":"soon"}],"count":1}`), &list)
+			So(err, ShouldBeNil)
+			So(list.Sessions[0].Created, ShouldEqual, "2026-01-30 19:30:15")
+			So(list.Sessions[0].CreatedAt.Equal(time.Date(2026, 1, 30, 19, 30, 15, 0, time.Local)), ShouldBeTrue)
+			So(list.Sessions[0].LastActive, ShouldEqual, "soon")
+			So(list.Sessions[0].LastActiveAt.IsZero(), ShouldBeTrue)
+		})
+		Convey("FormatRelativeTime", func() {
+			now := time.Date(2026, 1, 30, 19, 30, 0, 0, time.UTC)
+			So(FormatRelativeTime(now.Add(-10*time.Second), now), ShouldEqual, "just now")
+			So(FormatRelativeTime(now.Add(-5*time.Minute), now), ShouldEqual, "5m ago")
+			So(FormatRelativeTime(now.Add(-150*time.Minute), now), ShouldEqual, "2h ago")
+			So(FormatRelativeTime(now.Add(-80*time.Hour), now), ShouldEqual, "3d ago")
+			So(FormatRelativeTime(now.Add(2*time.Hour), now), ShouldEqual, "in 2h")
+		})
+	})
+}
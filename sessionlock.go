@@ -0,0 +1,279 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSessionLockUnsupported is returned when the server has no session lock API
+var ErrSessionLockUnsupported = fmt.Errorf("server does not support session write locks")
+
+// SessionLockInfo describes which client currently holds input control of a
+// shared session. An empty Holder means nobody has control.
+type SessionLockInfo struct {
+	Session string `json:"session"`
+	Holder  string `json:"holder,omitempty"`
+	Since   string `json:"since,omitempty"`
+}
+
+// controlState tracks the cooperative write lock of the attached session
+type controlState struct {
+	mutex      sync.Mutex
+	session    string
+	holder     string
+	hasControl bool
+	// disabled is set when the server turns out to have no lock API,
+	// after which input is never locked
+	disabled   bool
+	lastNotice time.Time
+}
+
+// LocalOperator returns an identifier for the local operator in the form user@hostname
+func LocalOperator() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return name
+	}
+	return name + "@" + host
+}
+
+// GetSessionLock returns the current write lock holder of a session
+func (s *SessionsClient) GetSessionLock(sessionName string) (*SessionLockInfo, error) {
+	query := url.Values{}
+	query.Set("name", sessionName)
+	return s.sessionLockRequest("GET", query)
+}
+
+// AcquireSessionLock asks the server to grant input control of a session to holder.
+// If another client holds the lock, the returned info names it and err is non-nil.
+func (s *SessionsClient) AcquireSessionLock(sessionName, holder string) (*SessionLockInfo, error) {
+	query := url.Values{}
+	query.Set("name", sessionName)
+	query.Set("holder", holder)
+	return s.sessionLockRequest("POST", query)
+}
+
+// ReleaseSessionLock gives up input control of a session held by holder
+func (s *SessionsClient) ReleaseSessionLock(sessionName, holder string) error {
+	query := url.Values{}
+	query.Set("name", sessionName)
+	query.Set("holder", holder)
+	_, err := s.sessionLockRequest("DELETE", query)
+	return err
+}
+
+func (s *SessionsClient) sessionLockRequest(method string, query url.Values) (*SessionLockInfo, error) {
+	req, err := s.newAPIRequest(method, "/api/sessions/lock", query)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Debugf("Session lock request: %s %q", method, req.URL.String())
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusConflict:
+		var info SessionLockInfo
+		if err := json.Unmarshal(body, &info); err != nil {
+			return nil, fmt.Errorf("failed to decode session lock: %v", err)
+		}
+		if resp.StatusCode == http.StatusConflict {
+			return &info, fmt.Errorf("session %s is controlled by %s", info.Session, info.Holder)
+		}
+		return &info, nil
+	case apiUnsupported(resp.StatusCode, body):
+		return nil, ErrSessionLockUnsupported
+	default:
+		return nil, apiError("session lock request failed", resp.StatusCode, body)
+	}
+}
+
+// HasControl reports whether this client may currently send input. It is
+// always true unless WriteLock is enabled.
+func (c *Client) HasControl() bool {
+	if !c.controlEnabled() {
+		return true
+	}
+	c.control.mutex.Lock()
+	defer c.control.mutex.Unlock()
+	return c.control.hasControl
+}
+
+// controlEnabled reports whether input is subject to the write lock
+func (c *Client) controlEnabled() bool {
+	if !c.WriteLock || c.control == nil {
+		return false
+	}
+	c.control.mutex.Lock()
+	defer c.control.mutex.Unlock()
+	return !c.control.disabled
+}
+
+// startControl sets up the write lock for the attached session and tries to take control
+func (c *Client) startControl() {
+	if !c.WriteLock {
+		return
+	}
+	query, err := GetURLQuery(c.URL)
+	if err != nil || query.Get("session") == "" {
+		logrus.Warnf("Write lock requires a named session, input will not be locked")
+		c.WriteLock = false
+		return
+	}
+	c.control = &controlState{session: query.Get("session")}
+	if c.Operator == "" {
+		c.Operator = LocalOperator()
+	}
+	c.requestControl()
+}
+
+// requestControl tries to take input control of the session
+func (c *Client) requestControl() {
+	info, err := c.AcquireSessionLock(c.control.session, c.Operator)
+
+	c.control.mutex.Lock()
+	defer c.control.mutex.Unlock()
+	if err == ErrSessionLockUnsupported {
+		logrus.Warnf("%v, input will not be locked", err)
+		c.control.disabled = true
+		return
+	}
+	if err != nil && info == nil {
+		c.statusf("could not request control: %v", err)
+		return
+	}
+	c.control.holder = info.Holder
+	c.control.hasControl = err == nil
+	if c.control.hasControl {
+		c.statusf("you have control of session %s", c.control.session)
+	} else {
+		c.statusf("session %s is controlled by %s, input is read-only", c.control.session, info.Holder)
+	}
+}
+
+// releaseControl gives up input control of the session
+func (c *Client) releaseControl() {
+	c.control.mutex.Lock()
+	hasControl := c.control.hasControl
+	c.control.mutex.Unlock()
+	if !hasControl {
+		c.statusf("you do not have control of session %s", c.control.session)
+		return
+	}
+
+	if err := c.ReleaseSessionLock(c.control.session, c.Operator); err != nil {
+		c.statusf("could not release control: %v", err)
+		return
+	}
+
+	c.control.mutex.Lock()
+	defer c.control.mutex.Unlock()
+	c.control.hasControl = false
+	c.control.holder = ""
+	c.statusf("released control of session %s", c.control.session)
+}
+
+// refreshControl renews the lock while held, or reports a change of holder otherwise
+func (c *Client) refreshControl() {
+	c.control.mutex.Lock()
+	hasControl := c.control.hasControl
+	c.control.mutex.Unlock()
+
+	var info *SessionLockInfo
+	var err error
+	if hasControl {
+		info, err = c.AcquireSessionLock(c.control.session, c.Operator)
+	} else {
+		info, err = c.GetSessionLock(c.control.session)
+	}
+	if info == nil {
+		logrus.Debugf("Session lock refresh failed: %v", err)
+		return
+	}
+
+	c.control.mutex.Lock()
+	defer c.control.mutex.Unlock()
+	if hasControl && err != nil {
+		c.control.hasControl = false
+		c.statusf("control of session %s was taken by %s", c.control.session, info.Holder)
+	} else if !hasControl && info.Holder != c.control.holder {
+		if info.Holder == "" {
+			c.statusf("session %s is no longer controlled by anyone, request control to type", c.control.session)
+		} else {
+			c.statusf("session %s is now controlled by %s", c.control.session, info.Holder)
+		}
+	}
+	c.control.holder = info.Holder
+}
+
+// notifyReadOnly reminds the user that input is being discarded, at most every few seconds
+func (c *Client) notifyReadOnly() {
+	c.control.mutex.Lock()
+	defer c.control.mutex.Unlock()
+	if time.Since(c.control.lastNotice) < 5*time.Second {
+		return
+	}
+	c.control.lastNotice = time.Now()
+	holder := c.control.holder
+	if holder == "" {
+		holder = "nobody"
+	}
+	c.statusf("input ignored: session %s is controlled by %s, use the escape menu to request control", c.control.session, holder)
+}
+
+// controlLoop periodically refreshes the write lock until poisoned, then releases it
+func (c *Client) controlLoop(wg *sync.WaitGroup) poisonReason {
+	defer wg.Done()
+	fname := "controlLoop"
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.poison:
+			c.control.mutex.Lock()
+			hasControl := c.control.hasControl
+			c.control.mutex.Unlock()
+			if hasControl {
+				if err := c.ReleaseSessionLock(c.control.session, c.Operator); err != nil {
+					logrus.Debugf("Failed to release session lock: %v", err)
+				}
+			}
+			return die(fname, c.poison)
+		case <-ticker.C:
+			if c.controlEnabled() {
+				c.refreshControl()
+			}
+		}
+	}
+}
+
+// statusf prints a status line for the user in between terminal output
+func (c *Client) statusf(format string, args ...interface{}) {
+	if c.Stdio {
+		_, _ = fmt.Fprintf(os.Stderr, "[uberterm] "+format+"\n", args...)
+		return
+	}
+	c.lineEditor.Interrupt(func() {
+		_, _ = fmt.Fprintf(c.Output, "\r\n[uberterm] "+format+"\r\n", args...)
+	})
+}
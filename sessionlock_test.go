@@ -0,0 +1,118 @@
+package gottyclient
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/moul/gotty-client/internal/mockserver"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSessionLock(t *testing.T) {
+	Convey("Testing the session write lock", t, func() {
+		server := mockserver.New()
+		defer server.Close()
+
+		newClient := func(operator string) (*Client, *bytes.Buffer) {
+			output := &bytes.Buffer{}
+			return &Client{
+				URL:       server.URL + "?session=ft8",
+				WriteLock: true,
+				Operator:  operator,
+				Output:    output,
+			}, output
+		}
+
+		Convey("The first client takes control, the second is read-only", func() {
+			alice, aliceOutput := newClient("alice@shack")
+			alice.startControl()
+			So(alice.HasControl(), ShouldBeTrue)
+			So(aliceOutput.String(), ShouldContainSubstring, "you have control of session ft8")
+
+			bob, bobOutput := newClient("bob@club")
+			bob.startControl()
+			So(bob.HasControl(), ShouldBeFalse)
+			So(bobOutput.String(), ShouldContainSubstring, "controlled by alice@shack")
+
+			Convey("Releasing hands the lock over", func() {
+				alice.releaseControl()
+				So(alice.HasControl(), ShouldBeFalse)
+				So(aliceOutput.String(), ShouldContainSubstring, "released control of session ft8")
+
+				bob.refreshControl()
+				So(bobOutput.String(), ShouldContainSubstring, "no longer controlled by anyone")
+
+				bob.requestControl()
+				So(bob.HasControl(), ShouldBeTrue)
+
+				alice.refreshControl()
+				So(aliceOutput.String(), ShouldContainSubstring, "now controlled by bob@club")
+			})
+
+			Convey("Refreshing while others read the state is safe", func() {
+				wg := &sync.WaitGroup{}
+				for i := 0; i < 4; i++ {
+					wg.Add(2)
+					go func() {
+						defer wg.Done()
+						alice.refreshControl()
+					}()
+					go func() {
+						defer wg.Done()
+						_ = alice.HasControl()
+						_ = alice.escapeMenuItems()
+					}()
+				}
+				wg.Wait()
+				So(alice.HasControl(), ShouldBeTrue)
+			})
+		})
+
+		Convey("The menu offers the lock actions", func() {
+			client, _ := newClient("alice@shack")
+			client.startControl()
+			So(client.showEscapeMenu(), ShouldBeTrue)
+			So(len(client.escapeMenuItems()), ShouldEqual, 2)
+		})
+
+		Convey("Without a lock API input is never locked and the menu keys pass through", func() {
+			plain := httptest.NewServer(http.NotFoundHandler())
+			defer plain.Close()
+
+			output := &bytes.Buffer{}
+			client := &Client{URL: plain.URL + "/?session=ft8", WriteLock: true, Output: output}
+			client.startControl()
+			So(client.controlEnabled(), ShouldBeFalse)
+			So(client.HasControl(), ShouldBeTrue)
+			So(client.escapeMenuItems(), ShouldBeEmpty)
+			So(client.showEscapeMenu(), ShouldBeFalse)
+			So(output.String(), ShouldBeEmpty)
+		})
+
+		Convey("A session the server does not know keeps the lock enabled", func() {
+			missing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"success":false,"message":"session not found"}`))
+			}))
+			defer missing.Close()
+
+			output := &bytes.Buffer{}
+			client := &Client{URL: missing.URL + "/?session=ft8", WriteLock: true, Operator: "alice@shack", Output: output}
+			client.startControl()
+			So(client.controlEnabled(), ShouldBeTrue)
+			So(client.HasControl(), ShouldBeFalse)
+			So(output.String(), ShouldContainSubstring, "could not request control: session lock request failed: session not found")
+		})
+
+		Convey("A lock requires a named session", func() {
+			client, _ := newClient("alice@shack")
+			client.URL = server.URL
+			client.startControl()
+			So(client.WriteLock, ShouldBeFalse)
+			So(client.HasControl(), ShouldBeTrue)
+		})
+	})
+}
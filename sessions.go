@@ -0,0 +1,260 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrHandoverUnsupported is returned when the server has no handover API
+var ErrHandoverUnsupported = fmt.Errorf("server does not support handing sessions over")
+
+// SessionsClient talks to the session management API of a GoTTY server. Unlike
+// Client it has no websocket or terminal state, so tools that only
+// orchestrate sessions can use it on its own.
+type SessionsClient struct {
+	// BaseURL is the terminal URL the /api/sessions endpoints are relative to
+	BaseURL    string
+	Auth       AuthProvider
+	HTTPClient *http.Client
+	// RetryPolicy defaults to DefaultRetryPolicy()
+	RetryPolicy *RetryPolicy
+	// HostHeader overrides the Host header of requests
+	HostHeader string
+	// UserAgent and InstanceID identify the client as for Client
+	UserAgent  string
+	InstanceID string
+	// PageSize is the number of sessions asked for per page, DefaultPageSize
+	// if 0
+	PageSize int
+	// Progress, if set, is called whenever the server is heard from: on each
+	// line of the event stream, keepalives included, and each poll of
+	// WatchSessions
+	Progress func()
+	// affinity is shared with the Client the sessions client came from
+	affinity *affinity
+	// reporter is told about retries, as the Client's Progress
+	reporter ProgressReporter
+	// confirmHost asks about unknown hosts for that Client
+	confirmHost func(error) bool
+}
+
+// NewSessionsClient returns a sessions API client for baseURL. auth and
+// httpClient may be nil, in which case requests are unauthenticated and sent
+// with http.DefaultClient.
+func NewSessionsClient(baseURL string, auth AuthProvider, httpClient *http.Client) (*SessionsClient, error) {
+	parsed, err := ParseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionsClient{BaseURL: parsed, Auth: auth, HTTPClient: httpClient, InstanceID: processInstanceID}, nil
+}
+
+// newAPIRequest builds an authenticated request for an API endpoint relative to the base URL
+func (s *SessionsClient) newAPIRequest(method, path string, query url.Values) (*http.Request, error) {
+	target, err := url.Parse(s.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	target.Path = strings.TrimRight(target.Path, "/") + path
+	if query != nil {
+		merged := target.Query()
+		for key, values := range query {
+			merged[key] = values
+		}
+		target.RawQuery = merged.Encode()
+	}
+
+	credentials, _ := urlCredentials(target)
+	target.User = nil
+	req, err := http.NewRequest(method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	credentials.ApplyHeaders(req)
+	if s.Auth != nil {
+		s.Auth.ApplyHeaders(req)
+	}
+	return req, nil
+}
+
+// progress calls Progress if set
+func (s *SessionsClient) progress() {
+	if s.Progress != nil {
+		s.Progress()
+	}
+}
+
+// do sends an API request honoring the Host header and retry settings
+func (s *SessionsClient) do(req *http.Request) (*http.Response, error) {
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return s.send(req, httpClient)
+}
+
+// stream is do for requests whose response stays open, which RequestTimeout
+// must not cut short
+func (s *SessionsClient) stream(req *http.Request) (*http.Response, error) {
+	httpClient := http.Client{}
+	if s.HTTPClient != nil {
+		httpClient = *s.HTTPClient
+	}
+	httpClient.Timeout = 0
+	return s.send(req, &httpClient)
+}
+
+// send does the work of do and stream
+func (s *SessionsClient) send(req *http.Request, httpClient *http.Client) (*http.Response, error) {
+	if s.HostHeader != "" {
+		req.Host = s.HostHeader
+	}
+	s.affinity.apply(req.Header)
+	identify(req.Header, s.UserAgent, s.InstanceID)
+	policy := s.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+	send := func() (*http.Response, error) { return policy.do(httpClient, req, s.reporter) }
+	resp, err := send()
+	if err != nil && s.confirmHost != nil && s.confirmHost(err) {
+		resp, err = resend(req, send)
+	}
+	s.affinity.capture(resp)
+	return resp, err
+}
+
+// apiUnsupported reports whether a response means the server lacks the
+// endpoint altogether. Endpoints the server knows answer a 404, e.g. for an
+// unknown session, with a JSON message; a bare 404 or a 405 comes from a
+// server predating them.
+func apiUnsupported(statusCode int, body []byte) bool {
+	switch statusCode {
+	case http.StatusMethodNotAllowed:
+		return true
+	case http.StatusNotFound:
+		var actionResp SessionActionResponse
+		return json.Unmarshal(body, &actionResp) != nil || actionResp.Message == ""
+	}
+	return false
+}
+
+// apiError describes a failed API request, using the server's JSON message
+// when it sent one
+func apiError(action string, statusCode int, body []byte) error {
+	var actionResp SessionActionResponse
+	if json.Unmarshal(body, &actionResp) == nil && actionResp.Message != "" {
+		return fmt.Errorf("%s: %s", action, actionResp.Message)
+	}
+	return fmt.Errorf("%s: %d %s - %s", action, statusCode, http.StatusText(statusCode), strings.TrimSpace(string(body)))
+}
+
+// Sessions returns a sessions API client sharing the client's URL,
+// credentials and network settings
+func (c *Client) Sessions() *SessionsClient {
+	return &SessionsClient{
+		BaseURL:     c.URL,
+		Auth:        c.authProvider(),
+		HTTPClient:  c.httpClient(),
+		RetryPolicy: c.retryPolicy(),
+		HostHeader:  c.HostHeader,
+		UserAgent:   c.UserAgent,
+		InstanceID:  c.InstanceID,
+		PageSize:    c.PageSize,
+		affinity:    c.affinity(),
+		reporter:    c.Progress,
+		confirmHost: c.confirmHost,
+	}
+}
+
+// ListSessions retrieves the list of available tmux sessions
+func (c *Client) ListSessions() (*SessionListResponse, error) {
+	return c.Sessions().ListSessions()
+}
+
+// ListSessionsWithLimit retrieves at most limit tmux sessions (0 means all)
+func (c *Client) ListSessionsWithLimit(limit int) (*SessionListResponse, error) {
+	return c.Sessions().ListSessionsWithLimit(limit)
+}
+
+// DestroySession destroys a tmux session by name
+func (c *Client) DestroySession(sessionName string) (*SessionActionResponse, error) {
+	return c.Sessions().DestroySession(sessionName)
+}
+
+// DestroySessions destroys several tmux sessions concurrently
+func (c *Client) DestroySessions(sessionNames []string) []DestroyResult {
+	return c.Sessions().DestroySessions(sessionNames)
+}
+
+// DestroyResult is the outcome of destroying one session
+type DestroyResult struct {
+	Session string
+	Err     error
+}
+
+// DestroySessions destroys several tmux sessions concurrently, returning
+// their results in the order of sessionNames
+func (s *SessionsClient) DestroySessions(sessionNames []string) []DestroyResult {
+	results := make([]DestroyResult, len(sessionNames))
+	wg := &sync.WaitGroup{}
+	for i, name := range sessionNames {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = DestroyResult{Session: name}
+			resp, err := s.DestroySession(name)
+			if err == nil && !resp.Success {
+				err = fmt.Errorf("failed to destroy session: %s", resp.Message)
+			}
+			results[i].Err = err
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+// HandoverSession hands a session over to another operator
+func (c *Client) HandoverSession(sessionName, to string) (*SessionActionResponse, error) {
+	return c.Sessions().HandoverSession(sessionName, to)
+}
+
+// DetachSessionClients detaches all clients currently attached to a session
+func (c *Client) DetachSessionClients(sessionName string) (*SessionActionResponse, error) {
+	return c.Sessions().DetachSessionClients(sessionName)
+}
+
+// SetSessionTags sets tags on a session
+func (c *Client) SetSessionTags(sessionName string, tags map[string]string) (*SessionActionResponse, error) {
+	return c.Sessions().SetSessionTags(sessionName, tags)
+}
+
+// GetSessionLock returns the current write lock holder of a session
+func (c *Client) GetSessionLock(sessionName string) (*SessionLockInfo, error) {
+	return c.Sessions().GetSessionLock(sessionName)
+}
+
+// AcquireSessionLock asks the server to grant input control of a session to holder
+func (c *Client) AcquireSessionLock(sessionName, holder string) (*SessionLockInfo, error) {
+	return c.Sessions().AcquireSessionLock(sessionName, holder)
+}
+
+// ReleaseSessionLock gives up input control of a session held by holder
+func (c *Client) ReleaseSessionLock(sessionName, holder string) error {
+	return c.Sessions().ReleaseSessionLock(sessionName, holder)
+}
+
+// ListWindows lists the windows of a session
+func (c *Client) ListWindows(sessionName string) (*WindowListResponse, error) {
+	return c.Sessions().ListWindows(sessionName)
+}
+
+// RenameWindow renames a window of a session
+func (c *Client) RenameWindow(sessionName, window, newName string) (*SessionActionResponse, error) {
+	return c.Sessions().RenameWindow(sessionName, window, newName)
+}
@@ -0,0 +1,199 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSessionsClient(t *testing.T) {
+	Convey("Testing the standalone sessions client", t, func() {
+		var authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			switch r.URL.Path {
+			case "/terminal/api/sessions":
+				w.Write([]byte(`{"sessions":[{"name":"ft8","attached":true}],"count":1}`))
+			case "/terminal/api/sessions/destroy":
+				if name := r.URL.Query().Get("name"); name == "missing" {
+					w.Write([]byte(`{"success":false,"message":"no such session"}`))
+				} else {
+					w.Write([]byte(`{"success":true,"message":"destroyed","session":"` + name + `"}`))
+				}
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer server.Close()
+
+		sessions, err := NewSessionsClient(server.URL+"/terminal/", BearerToken("secret"), nil)
+		So(err, ShouldBeNil)
+
+		list, err := sessions.ListSessions()
+		So(err, ShouldBeNil)
+		So(list.Count, ShouldEqual, 1)
+		So(list.Find("ft8").Attached, ShouldBeTrue)
+		So(authorization, ShouldEqual, "Bearer secret")
+
+		resp, err := sessions.DestroySession("ft8")
+		So(err, ShouldBeNil)
+		So(resp.Session, ShouldEqual, "ft8")
+
+		Convey("Several sessions are destroyed at once", func() {
+			results := sessions.DestroySessions([]string{"ft8", "missing", "wspr"})
+			So(len(results), ShouldEqual, 3)
+			So(results[0], ShouldResemble, DestroyResult{Session: "ft8"})
+			So(results[1].Session, ShouldEqual, "missing")
+			So(results[1].Err, ShouldNotBeNil)
+			So(results[2].Err, ShouldBeNil)
+		})
+
+		Convey("Client.Sessions shares the client's credentials", func() {
+			client, err := NewClient(server.URL + "/terminal/")
+			So(err, ShouldBeNil)
+			client.User = "alice"
+			client.Password = "pw"
+			_, err = client.ListSessions()
+			So(err, ShouldBeNil)
+			So(authorization, ShouldEqual, "Basic YWxpY2U6cHc=")
+		})
+	})
+}
+
+func TestDestroySessions(t *testing.T) {
+	Convey("Testing destroying several sessions", t, func() {
+		var mu sync.Mutex
+		var destroyed []string
+		endpoint := true
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/sessions/destroy" || !endpoint {
+				http.NotFound(w, r)
+				return
+			}
+			switch name := r.URL.Query().Get("name"); name {
+			case "gone":
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"success":false,"message":"session 'gone' not found"}`))
+			case "busy":
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"success":false,"message":"tmux failed"}`))
+			default:
+				mu.Lock()
+				destroyed = append(destroyed, name)
+				mu.Unlock()
+				w.Write([]byte(`{"success":true,"session":"` + name + `"}`))
+			}
+		}))
+		defer server.Close()
+
+		sessions, err := NewSessionsClient(server.URL+"/", nil, nil)
+		So(err, ShouldBeNil)
+		sessions.RetryPolicy = NoRetry
+
+		Convey("Every session is destroyed", func() {
+			results := sessions.DestroySessions([]string{"ft8", "wspr", "js8"})
+			So(results, ShouldResemble, []DestroyResult{{Session: "ft8"}, {Session: "wspr"}, {Session: "js8"}})
+			sort.Strings(destroyed)
+			So(destroyed, ShouldResemble, []string{"ft8", "js8", "wspr"})
+		})
+
+		Convey("Failures are reported per session, in order", func() {
+			results := sessions.DestroySessions([]string{"ft8", "busy", "gone", "wspr"})
+			So(len(results), ShouldEqual, 4)
+			So(results[0].Err, ShouldBeNil)
+			So(results[1].Session, ShouldEqual, "busy")
+			So(results[1].Err.Error(), ShouldContainSubstring, "tmux failed")
+			So(results[2].Session, ShouldEqual, "gone")
+			So(results[2].Err.Error(), ShouldContainSubstring, "session 'gone' not found")
+			So(results[3].Err, ShouldBeNil)
+			So(len(destroyed), ShouldEqual, 2)
+		})
+
+		Convey("Servers without the endpoint fail every session", func() {
+			endpoint = false
+			results := sessions.DestroySessions([]string{"ft8", "wspr"})
+			for _, result := range results {
+				So(result.Err, ShouldNotBeNil)
+			}
+			So(destroyed, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestConfigAliases(t *testing.T) {
+	Convey("Aliases lists the configured servers", t, func() {
+		path := filepath.Join(t.TempDir(), "config")
+		So(os.WriteFile(path, []byte("Host *\n    User op\nHost lab\n    URL http://lab:8080\nHost sdr\n    Callsign M9PSY\nHost defaults\n    User admin\n"), 0600), ShouldBeNil)
+		config, err := LoadConfigFromPath(path)
+		So(err, ShouldBeNil)
+		So(config.Aliases(), ShouldResemble, []string{"lab", "sdr"})
+	})
+}
+
+func TestHandoverSession(t *testing.T) {
+	Convey("Testing session handover", t, func() {
+		var query url.Values
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			switch {
+			case r.URL.Path != "/terminal/api/sessions/handover":
+				http.NotFound(w, r)
+			case r.Method != "POST":
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			case query.Get("name") == "missing":
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"success":false,"message":"no such session"}`))
+			case query.Get("to") == "":
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"success":false,"message":"missing operator"}`))
+			default:
+				w.Write([]byte(`{"success":true,"message":"handed over","session":"` + query.Get("name") + `"}`))
+			}
+		}))
+		defer server.Close()
+
+		client, err := NewClient("http://op:secret@" + server.Listener.Addr().String() + "/terminal/")
+		So(err, ShouldBeNil)
+
+		Convey("The session is handed over and its clients detached", func() {
+			resp, err := client.HandoverSession("ft8", "bob")
+			So(err, ShouldBeNil)
+			So(resp.Success, ShouldBeTrue)
+			So(resp.Session, ShouldEqual, "ft8")
+			So(query.Get("to"), ShouldEqual, "bob")
+			So(query.Get("detach"), ShouldEqual, "true")
+			So(query.Get("from"), ShouldNotBeEmpty)
+		})
+
+		Convey("An unknown session is reported as such", func() {
+			_, err := client.HandoverSession("missing", "bob")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "session missing not found")
+		})
+
+		Convey("Other failures carry the server's message once", func() {
+			_, err := client.HandoverSession("ft8", "")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "failed to hand over session: missing operator")
+		})
+
+		Convey("Servers without the endpoint are detected", func() {
+			client, err := NewClient(server.URL + "/old/")
+			So(err, ShouldBeNil)
+			_, err = client.HandoverSession("ft8", "bob")
+			So(err, ShouldEqual, ErrHandoverUnsupported)
+		})
+
+		Convey("Credentials are stripped from URLs shown to the user", func() {
+			So(StripURLCredentials(client.URL), ShouldEqual, "http://"+server.Listener.Addr().String()+"/terminal/")
+			So(StripURLCredentials("https://sdr.example.com/?session=ft8"), ShouldEqual, "https://sdr.example.com/?session=ft8")
+		})
+	})
+}
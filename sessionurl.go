@@ -0,0 +1,59 @@
+package gottyclient
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// SessionURLOptions selects the session a terminal URL attaches to
+type SessionURLOptions struct {
+	// Session is the tmux session to attach to, or to create with Name
+	Session string
+	// Name is the window name of a new session
+	Name string
+	// Dir and Cmd start a new session in a directory and/or running a command
+	Dir string
+	Cmd string
+	// Freq, in Hz, and Mode tune the receiver on servers offering receiver
+	// control, others ignore them
+	Freq int64
+	Mode string
+}
+
+// BuildSessionURL adds the non-empty session parameters to the terminal URL
+// base, percent-encoded, replacing any already there and keeping base's other
+// query parameters. Without any, base is returned exactly as given, so that
+// e.g. a --raw-url query is not re-encoded.
+func BuildSessionURL(base string, opts SessionURLOptions) (string, error) {
+	if opts == (SessionURLOptions{}) {
+		return base, nil
+	}
+	parsed, err := ParseURL(base)
+	if err != nil {
+		return "", err
+	}
+	target, err := url.Parse(parsed)
+	if err != nil {
+		return "", err
+	}
+
+	query := target.Query()
+	freq := ""
+	if opts.Freq > 0 {
+		freq = strconv.FormatInt(opts.Freq, 10)
+	}
+	for _, param := range []struct{ name, value string }{
+		{"session", opts.Session},
+		{"name", opts.Name},
+		{"dir", opts.Dir},
+		{"cmd", opts.Cmd},
+		{"freq", freq},
+		{"mode", opts.Mode},
+	} {
+		if param.value != "" {
+			query.Set(param.name, param.value)
+		}
+	}
+	target.RawQuery = query.Encode()
+	return target.String(), nil
+}
@@ -0,0 +1,52 @@
+package gottyclient
+
+import (
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBuildSessionURL(t *testing.T) {
+	Convey("Testing BuildSessionURL", t, func() {
+		Convey("Names are percent-encoded", func() {
+			target, err := BuildSessionURL("https://sdr.example.com/terminal/", SessionURLOptions{
+				Session: "ft8 monitor",
+				Name:    "fenêtre",
+				Dir:     "/home/pi/my logs",
+				Cmd:     "tail -f a&b.log",
+			})
+			So(err, ShouldBeNil)
+			So(target, ShouldEqual, "https://sdr.example.com/terminal/?cmd=tail+-f+a%26b.log&dir=%2Fhome%2Fpi%2Fmy+logs&name=fen%C3%AAtre&session=ft8+monitor")
+
+			parsed, err := url.Parse(target)
+			So(err, ShouldBeNil)
+			So(parsed.Query().Get("name"), ShouldEqual, "fenêtre")
+			So(parsed.Query().Get("cmd"), ShouldEqual, "tail -f a&b.log")
+		})
+
+		Convey("Existing parameters are kept and session parameters replaced", func() {
+			target, err := BuildSessionURL("sdr.example.com/terminal/?key=abc&session=old", SessionURLOptions{Session: "new"})
+			So(err, ShouldBeNil)
+			So(target, ShouldEqual, "http://sdr.example.com/terminal/?key=abc&session=new")
+		})
+
+		Convey("Frequency and mode are passed in Hz and lower case", func() {
+			target, err := BuildSessionURL("https://sdr.example.com/terminal/", SessionURLOptions{Freq: 7074000, Mode: "usb"})
+			So(err, ShouldBeNil)
+			So(target, ShouldEqual, "https://sdr.example.com/terminal/?freq=7074000&mode=usb")
+		})
+
+		Convey("Empty options leave the URL alone", func() {
+			target, err := BuildSessionURL("https://sdr.example.com/terminal/", SessionURLOptions{})
+			So(err, ShouldBeNil)
+			So(target, ShouldEqual, "https://sdr.example.com/terminal/")
+
+			// Not even re-encoded or reordered
+			raw := "https://sdr.example.com/terminal/?z=1&a=%7e&key=a+b"
+			target, err = BuildSessionURL(raw, SessionURLOptions{})
+			So(err, ShouldBeNil)
+			So(target, ShouldEqual, raw)
+		})
+	})
+}
@@ -0,0 +1,105 @@
+package gottyclient
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SessionEventType is the kind of change seen on a session
+type SessionEventType string
+
+const (
+	// SessionCreated is reported for a session that appeared
+	SessionCreated SessionEventType = "created"
+	// SessionDestroyed is reported for a session that disappeared
+	SessionDestroyed SessionEventType = "destroyed"
+	// SessionAttached is reported when a client attaches to a session
+	SessionAttached SessionEventType = "attached"
+	// SessionDetached is reported when the last client detaches from a session
+	SessionDetached SessionEventType = "detached"
+)
+
+// SessionEvent is a change of a server's sessions
+type SessionEvent struct {
+	Type    SessionEventType `json:"type"`
+	Session string           `json:"session"`
+	Window  string           `json:"window,omitempty"`
+	Time    time.Time        `json:"time"`
+}
+
+// DiffSessions returns the events turning the before listing into the after
+// listing, ordered by session name
+func DiffSessions(before, after []SessionInfo, now time.Time) []SessionEvent {
+	previous := make(map[string]SessionInfo, len(before))
+	for _, session := range before {
+		previous[session.Name] = session
+	}
+
+	var events []SessionEvent
+	event := func(eventType SessionEventType, session SessionInfo) {
+		events = append(events, SessionEvent{Type: eventType, Session: session.Name, Window: session.WindowName, Time: now})
+	}
+	for _, session := range after {
+		old, existed := previous[session.Name]
+		delete(previous, session.Name)
+		switch {
+		case !existed:
+			event(SessionCreated, session)
+			if session.Attached {
+				event(SessionAttached, session)
+			}
+		case session.Attached && !old.Attached:
+			event(SessionAttached, session)
+		case !session.Attached && old.Attached:
+			event(SessionDetached, session)
+		}
+	}
+	for _, session := range previous {
+		event(SessionDestroyed, session)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Session < events[j].Session
+	})
+	return events
+}
+
+// WatchSessions polls the session list every interval and calls fn with the
+// changes until ctx is done. Failing to list the sessions initially is
+// returned; later failures are logged and retried at the next poll.
+func (s *SessionsClient) WatchSessions(ctx context.Context, interval time.Duration, fn func(SessionEvent)) error {
+	list, err := s.ListSessions()
+	if err != nil {
+		return err
+	}
+	s.progress()
+	sessions := list.Sessions
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			list, err := s.ListSessions()
+			if err != nil {
+				logrus.Warnf("Failed to list sessions: %v", err)
+				continue
+			}
+			s.progress()
+			for _, event := range DiffSessions(sessions, list.Sessions, now) {
+				fn(event)
+			}
+			sessions = list.Sessions
+		}
+	}
+}
+
+// WatchSessions polls the session list and reports changes until ctx is done
+func (c *Client) WatchSessions(ctx context.Context, interval time.Duration, fn func(SessionEvent)) error {
+	return c.Sessions().WatchSessions(ctx, interval, fn)
+}
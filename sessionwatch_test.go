@@ -0,0 +1,76 @@
+package gottyclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSessionWatch(t *testing.T) {
+	Convey("Testing session watching", t, func() {
+		now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+		Convey("Listings are diffed into events", func() {
+			before := []SessionInfo{
+				{Name: "ft8", WindowName: "wsjtx", Attached: true},
+				{Name: "old"},
+				{Name: "wspr"},
+			}
+			after := []SessionInfo{
+				{Name: "ft8", WindowName: "wsjtx"},
+				{Name: "new", Attached: true},
+				{Name: "wspr", Attached: true},
+			}
+			So(DiffSessions(before, after, now), ShouldResemble, []SessionEvent{
+				{Type: SessionDetached, Session: "ft8", Window: "wsjtx", Time: now},
+				{Type: SessionCreated, Session: "new", Time: now},
+				{Type: SessionAttached, Session: "new", Time: now},
+				{Type: SessionDestroyed, Session: "old", Time: now},
+				{Type: SessionAttached, Session: "wspr", Time: now},
+			})
+			So(DiffSessions(after, after, now), ShouldBeEmpty)
+		})
+
+		Convey("Polling reports changes", func() {
+			var mutex sync.Mutex
+			body := `{"sessions":[{"name":"ft8"}],"count":1}`
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				w.Write([]byte(body))
+			}))
+			defer server.Close()
+			sessions, err := NewSessionsClient(server.URL, nil, nil)
+			So(err, ShouldBeNil)
+			var polls int32
+			sessions.Progress = func() { atomic.AddInt32(&polls, 1) }
+
+			ctx, cancel := context.WithCancel(context.Background())
+			events := make(chan SessionEvent, 10)
+			done := make(chan error)
+			go func() {
+				done <- sessions.WatchSessions(ctx, 10*time.Millisecond, func(event SessionEvent) {
+					events <- event
+				})
+			}()
+
+			time.Sleep(30 * time.Millisecond)
+			mutex.Lock()
+			body = `{"sessions":[{"name":"ft8","attached":true}],"count":1}`
+			mutex.Unlock()
+			event := <-events
+			So(event.Type, ShouldEqual, SessionAttached)
+			So(event.Session, ShouldEqual, "ft8")
+			So(atomic.LoadInt32(&polls), ShouldBeGreaterThan, 1)
+
+			cancel()
+			So(<-done, ShouldEqual, context.Canceled)
+		})
+	})
+}
@@ -0,0 +1,21 @@
+package gottyclient
+
+import (
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// setUserTimeout sets TCP_USER_TIMEOUT, the time unacknowledged data may
+// stay in flight before the kernel drops the connection
+func setUserTimeout(conn syscall.RawConn, timeout time.Duration) error {
+	var sockErr error
+	err := conn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT, int(timeout/time.Millisecond))
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
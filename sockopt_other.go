@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package gottyclient
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// setUserTimeout is only supported on Linux
+func setUserTimeout(conn syscall.RawConn, timeout time.Duration) error {
+	logrus.Debugf("TCP user timeout is not supported on this platform, ignoring")
+	return nil
+}
@@ -0,0 +1,73 @@
+package gottyclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStdio(t *testing.T) {
+	Convey("Testing the stdin/stdout bridge", t, func() {
+		upgrader := websocket.Upgrader{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "auth_token.js") {
+				w.Write([]byte("var gotty_auth_token = 'token'"))
+				return
+			}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.ReadMessage()
+			conn.WriteMessage(websocket.TextMessage, []byte{SetWindowTitle, 't'})
+			// Echo the input in upper case, like a shell until Ctrl-D
+			for {
+				_, message, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				if len(message) == 0 || message[0] != Input {
+					continue
+				}
+				if string(message[1:]) == "\x04" {
+					conn.WriteMessage(websocket.TextMessage, []byte(string(Output)+base64.StdEncoding.EncodeToString([]byte("bye"))))
+					return
+				}
+				conn.WriteMessage(websocket.TextMessage, []byte(string(Output)+base64.StdEncoding.EncodeToString(bytes.ToUpper(message[1:]))))
+			}
+		}))
+		defer server.Close()
+
+		stdin, input, err := os.Pipe()
+		So(err, ShouldBeNil)
+		oldStdin := os.Stdin
+		os.Stdin = stdin
+		defer func() { os.Stdin = oldStdin }()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		client.V2 = true
+		client.Stdio = true
+		client.InputCRLF = CRLFSendLF
+		client.EscapeKeys = []byte{0x10, 0x11}
+		var output bytes.Buffer
+		client.SetOutput(&output)
+		defer client.Close()
+
+		// Escape keys and line endings go through unchanged
+		_, err = input.Write([]byte("ls\r\n\x10\x11"))
+		So(err, ShouldBeNil)
+		So(input.Close(), ShouldBeNil)
+
+		So(client.Loop(), ShouldBeNil)
+		So(output.String(), ShouldEqual, "LS\r\n\x10\x11bye")
+	})
+}
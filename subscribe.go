@@ -0,0 +1,202 @@
+package gottyclient
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SubscriberBuffer is the number of events queued for each subscriber.
+// Events for a subscriber whose queue is full are dropped rather than
+// stalling the session.
+var SubscriberBuffer = 1024
+
+// OutputEventType tells what an OutputEvent carries
+type OutputEventType int
+
+const (
+	// EventOutput carries decoded terminal output in Data
+	EventOutput OutputEventType = iota
+	// EventTitle carries a window title change in Title
+	EventTitle
+	// EventState carries a connection state transition in State
+	EventState
+)
+
+// ConnectionState is the state of a client's connection
+type ConnectionState string
+
+const (
+	// StateDisconnected is the state of clients not connected yet, or whose
+	// connection attempt failed
+	StateDisconnected ConnectionState = "disconnected"
+	// StateResolving is entered when Connect starts, while the server is
+	// being looked up and reached
+	StateResolving ConnectionState = "resolving"
+	// StateAuthenticating is entered while the auth token is fetched
+	StateAuthenticating ConnectionState = "authenticating"
+	// StateDialing is entered while the websocket is opened and the session
+	// set up
+	StateDialing ConnectionState = "dialing"
+	// StateConnected is entered when a connection is established
+	StateConnected ConnectionState = "connected"
+	// StateReconnecting is entered when the connection was lost and is
+	// being re-established
+	StateReconnecting ConnectionState = "reconnecting"
+	// StateClosed is entered when the client is closed; no events follow
+	StateClosed ConnectionState = "closed"
+)
+
+// OutputEvent is delivered to subscribers. Data is shared between them and
+// must not be modified.
+type OutputEvent struct {
+	Type  OutputEventType
+	Data  []byte
+	Title string
+	State ConnectionState
+}
+
+// subscriptions fans events out to the subscribers; the zero value has none
+type subscriptions struct {
+	mutex    sync.Mutex
+	channels map[int]chan OutputEvent
+	nextID   int
+	closed   bool
+	dropped  map[int]bool
+}
+
+// Subscribe returns a channel receiving the client's output, title changes
+// and state transitions, and a function ending the subscription. Any number
+// of consumers may subscribe alongside Output; the channel is closed when
+// the subscription ends or the client is closed.
+func (c *Client) Subscribe() (<-chan OutputEvent, func()) {
+	s := &c.subscriptions
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	events := make(chan OutputEvent, SubscriberBuffer)
+	if s.closed {
+		close(events)
+		return events, func() {}
+	}
+	if s.channels == nil {
+		s.channels = make(map[int]chan OutputEvent)
+		s.dropped = make(map[int]bool)
+	}
+	id := s.nextID
+	s.nextID++
+	s.channels[id] = events
+
+	return events, func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if events, ok := s.channels[id]; ok {
+			delete(s.channels, id)
+			delete(s.dropped, id)
+			close(events)
+		}
+	}
+}
+
+// publish delivers an event to every subscriber without blocking
+func (c *Client) publish(event OutputEvent) {
+	s := &c.subscriptions
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for id, events := range s.channels {
+		select {
+		case events <- event:
+			s.dropped[id] = false
+		default:
+			if !s.dropped[id] {
+				s.dropped[id] = true
+				logrus.Warnf("Subscriber is not keeping up, dropping events")
+			}
+		}
+	}
+}
+
+// State returns the state of the client's connection
+func (c *Client) State() ConnectionState {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+	if c.state == "" {
+		return StateDisconnected
+	}
+	return c.state
+}
+
+// StateChanges returns a channel receiving the client's state transitions,
+// and a function ending the subscription. The channel is closed after
+// StateClosed, or when the subscription ends; transitions a reader has not
+// taken by then are discarded rather than holding the forwarding goroutine.
+func (c *Client) StateChanges() (<-chan ConnectionState, func()) {
+	events, stop := c.Subscribe()
+	states := make(chan ConnectionState, SubscriberBuffer)
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		defer close(states)
+		for event := range events {
+			if event.Type != EventState {
+				continue
+			}
+			select {
+			case states <- event.State:
+				continue
+			default:
+			}
+			// The reader is behind: wait for it only while it can still come
+			select {
+			case states <- event.State:
+			case <-done:
+				return
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+	return states, func() {
+		once.Do(func() { close(done) })
+		stop()
+	}
+}
+
+// setState enters state, publishing the transition; a closed client stays
+// closed
+func (c *Client) setState(state ConnectionState) {
+	c.advanceState("", state)
+}
+
+// advanceState enters state if the client is in state from, or in any
+// state but StateClosed if from is empty
+func (c *Client) advanceState(from, state ConnectionState) {
+	c.stateMutex.Lock()
+	current := c.state
+	if current == "" {
+		current = StateDisconnected
+	}
+	if current == state || current == StateClosed || (from != "" && current != from) {
+		c.stateMutex.Unlock()
+		return
+	}
+	c.state = state
+	c.stateMutex.Unlock()
+	c.publishState(state)
+}
+
+// publishState delivers a state transition; closing ends every subscription
+func (c *Client) publishState(state ConnectionState) {
+	c.publish(OutputEvent{Type: EventState, State: state})
+	if state != StateClosed {
+		return
+	}
+	s := &c.subscriptions
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	for id, events := range s.channels {
+		delete(s.channels, id)
+		close(events)
+	}
+}
@@ -0,0 +1,139 @@
+package gottyclient
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/moul/gotty-client/internal/mockserver"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.uber.org/goleak"
+)
+
+func TestSubscribe(t *testing.T) {
+	Convey("Testing output subscriptions", t, func() {
+		client, err := NewClient("http://localhost/")
+		So(err, ShouldBeNil)
+		client.Output = ioutil.Discard
+
+		Convey("Events fan out to every subscriber", func() {
+			gui, stopGUI := client.Subscribe()
+			logger, stopLogger := client.Subscribe()
+			defer stopLogger()
+
+			client.handleMessage(OutputMessage{Data: []byte("hello")})
+			client.handleMessage(SetWindowTitleMessage{Title: "ft8"})
+			for _, events := range []<-chan OutputEvent{gui, logger} {
+				So(<-events, ShouldResemble, OutputEvent{Type: EventOutput, Data: []byte("hello")})
+				So(<-events, ShouldResemble, OutputEvent{Type: EventTitle, Title: "ft8"})
+			}
+
+			stopGUI()
+			stopGUI()
+			_, ok := <-gui
+			So(ok, ShouldBeFalse)
+			client.handleMessage(OutputMessage{Data: []byte("more")})
+			So((<-logger).Data, ShouldResemble, []byte("more"))
+		})
+
+		Convey("A stalled subscriber loses events instead of blocking", func() {
+			events, stop := client.Subscribe()
+			defer stop()
+			for i := 0; i < SubscriberBuffer+10; i++ {
+				client.handleMessage(OutputMessage{Data: []byte("x")})
+			}
+			So(len(events), ShouldEqual, SubscriberBuffer)
+		})
+
+		Convey("Closing the client ends subscriptions", func() {
+			events, stop := client.Subscribe()
+			defer stop()
+			So(client.Close(), ShouldBeNil)
+			So(<-events, ShouldResemble, OutputEvent{Type: EventState, State: StateClosed})
+			_, ok := <-events
+			So(ok, ShouldBeFalse)
+
+			late, _ := client.Subscribe()
+			_, ok = <-late
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Connecting is reported", func() {
+			server := mockserver.New()
+			defer server.Close()
+			client, err := NewClient(server.URL)
+			So(err, ShouldBeNil)
+			client.V2 = true
+			events, stop := client.Subscribe()
+			defer stop()
+			So(client.State(), ShouldEqual, StateDisconnected)
+			So(client.Connect(), ShouldBeNil)
+			defer client.Close()
+			So(client.State(), ShouldEqual, StateConnected)
+			for _, state := range []ConnectionState{StateResolving, StateAuthenticating, StateDialing, StateConnected} {
+				So(<-events, ShouldResemble, OutputEvent{Type: EventState, State: state})
+			}
+		})
+
+		Convey("State changes are reported", func() {
+			server := mockserver.New()
+			defer server.Close()
+			client, err := NewClient(server.URL)
+			So(err, ShouldBeNil)
+			client.V2 = true
+			states, stop := client.StateChanges()
+			defer stop()
+			So(client.Connect(), ShouldBeNil)
+			client.handleMessage(OutputMessage{Data: []byte("output")})
+			So(client.Close(), ShouldBeNil)
+
+			var seen []ConnectionState
+			for state := range states {
+				seen = append(seen, state)
+			}
+			So(seen, ShouldResemble, []ConnectionState{StateResolving, StateAuthenticating, StateDialing, StateConnected, StateClosed})
+		})
+
+		Convey("Connecting with a cached auth token still reports authenticating", func() {
+			server := mockserver.New()
+			defer server.Close()
+			first, err := NewClient(server.URL)
+			So(err, ShouldBeNil)
+			first.V2 = true
+			So(first.Connect(), ShouldBeNil)
+			So(first.Close(), ShouldBeNil)
+
+			client, err := NewClient(server.URL)
+			So(err, ShouldBeNil)
+			client.V2 = true
+			events, stop := client.Subscribe()
+			defer stop()
+			So(client.Connect(), ShouldBeNil)
+			defer client.Close()
+			for _, state := range []ConnectionState{StateResolving, StateAuthenticating, StateDialing, StateConnected} {
+				So(<-events, ShouldResemble, OutputEvent{Type: EventState, State: state})
+			}
+		})
+
+		Convey("State changes nobody reads do not hold the client after it closes", func() {
+			defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+			client, err := NewClient("http://localhost/")
+			So(err, ShouldBeNil)
+			client.StateChanges()
+			for i := 0; i <= SubscriberBuffer; i++ {
+				client.setState(StateResolving)
+				client.setState(StateDisconnected)
+			}
+			So(client.Close(), ShouldBeNil)
+		})
+
+		Convey("Failed connections return to disconnected", func() {
+			server := mockserver.New()
+			client, err := NewClient(server.URL)
+			So(err, ShouldBeNil)
+			client.RetryPolicy = &RetryPolicy{Attempts: 1}
+			server.Close()
+			So(client.Connect(), ShouldNotBeNil)
+			So(client.State(), ShouldEqual, StateDisconnected)
+		})
+	})
+}
@@ -0,0 +1,108 @@
+package gottyclient
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Service manager notifications, see sd_notify(3)
+const (
+	SdNotifyReady    = "READY=1"
+	SdNotifyStopping = "STOPPING=1"
+	SdNotifyWatchdog = "WATCHDOG=1"
+)
+
+// SdNotify sends state to the service manager, e.g. systemd with
+// Type=notify, through the socket named by $NOTIFY_SOCKET. It reports whether
+// the notification was sent: without $NOTIFY_SOCKET it does nothing.
+func SdNotify(state string) (bool, error) {
+	name := os.Getenv("NOTIFY_SOCKET")
+	if name == "" {
+		return false, nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if name[0] == '@' {
+		name = "\x00" + name[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SdWatchdogInterval returns how often the service manager expects watchdog
+// pings, from $WATCHDOG_USEC, or 0 when the watchdog is off or meant for
+// another process
+func SdWatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// StartSdWatchdog pings the service manager's watchdog at half its interval
+// until ctx is done. It does nothing when the watchdog is off.
+func StartSdWatchdog(ctx context.Context) {
+	startSdWatchdog(ctx, nil)
+}
+
+// StartSdProgressWatchdog is StartSdWatchdog for a loop reporting its
+// progress with the returned function: the watchdog is only pinged while
+// progress was reported within its interval, so a loop that is stuck, e.g. on
+// a dead connection, gets the service restarted.
+func StartSdProgressWatchdog(ctx context.Context) func() {
+	var last int64
+	progress := func() { atomic.StoreInt64(&last, time.Now().UnixNano()) }
+	progress()
+	startSdWatchdog(ctx, func(interval time.Duration) bool {
+		return time.Since(time.Unix(0, atomic.LoadInt64(&last))) < interval
+	})
+	return progress
+}
+
+// startSdWatchdog does the work of StartSdWatchdog, pinging only when alive,
+// if set, reports progress within the watchdog interval
+func startSdWatchdog(ctx context.Context, alive func(time.Duration) bool) {
+	interval := SdWatchdogInterval()
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		stalled := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if alive != nil && !alive(interval) {
+					if !stalled {
+						logrus.Warnf("No progress for %v, no longer pinging the service manager watchdog", interval)
+						stalled = true
+					}
+					continue
+				}
+				stalled = false
+				if _, err := SdNotify(SdNotifyWatchdog); err != nil {
+					logrus.Debugf("Failed to ping the service manager watchdog: %v", err)
+				}
+			}
+		}
+	}()
+}
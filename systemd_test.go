@@ -0,0 +1,106 @@
+//go:build !windows
+// +build !windows
+
+package gottyclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSdNotify(t *testing.T) {
+	Convey("Testing service manager notifications", t, func() {
+		// Unix socket paths are short, t.TempDir may be too long
+		dir, err := ioutil.TempDir("", "sd")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "notify")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+		So(err, ShouldBeNil)
+		defer conn.Close()
+
+		for _, name := range []string{"NOTIFY_SOCKET", "WATCHDOG_USEC", "WATCHDOG_PID"} {
+			old, had := os.LookupEnv(name)
+			defer func(name string) {
+				if had {
+					os.Setenv(name, old)
+				} else {
+					os.Unsetenv(name)
+				}
+			}(name)
+			os.Unsetenv(name)
+		}
+		receive := func() string {
+			buf := make([]byte, 256)
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			n, err := conn.Read(buf)
+			So(err, ShouldBeNil)
+			return string(buf[:n])
+		}
+
+		Convey("Nothing is sent outside a service", func() {
+			sent, err := SdNotify(SdNotifyReady)
+			So(err, ShouldBeNil)
+			So(sent, ShouldBeFalse)
+			So(SdWatchdogInterval(), ShouldEqual, 0)
+		})
+
+		Convey("States are sent to $NOTIFY_SOCKET", func() {
+			os.Setenv("NOTIFY_SOCKET", path)
+			sent, err := SdNotify(SdNotifyReady + "\nSTATUS=up")
+			So(err, ShouldBeNil)
+			So(sent, ShouldBeTrue)
+			So(receive(), ShouldEqual, "READY=1\nSTATUS=up")
+		})
+
+		Convey("The watchdog is pinged until the context is done", func() {
+			os.Setenv("NOTIFY_SOCKET", path)
+			os.Setenv("WATCHDOG_USEC", "100000")
+			os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+			So(SdWatchdogInterval(), ShouldEqual, 100*time.Millisecond)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			StartSdWatchdog(ctx)
+			So(receive(), ShouldEqual, SdNotifyWatchdog)
+			So(receive(), ShouldEqual, SdNotifyWatchdog)
+			cancel()
+
+			os.Setenv("WATCHDOG_PID", "1")
+			So(SdWatchdogInterval(), ShouldEqual, 0)
+		})
+
+		Convey("The progress watchdog is only pinged while there is progress", func() {
+			os.Setenv("NOTIFY_SOCKET", path)
+			os.Setenv("WATCHDOG_USEC", "100000")
+			os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+			// silent reports whether nothing arrives for d, dropping what does
+			silent := func(d time.Duration) bool {
+				buf := make([]byte, 256)
+				conn.SetReadDeadline(time.Now().Add(d))
+				_, err := conn.Read(buf)
+				return err != nil
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			progress := StartSdProgressWatchdog(ctx)
+			So(receive(), ShouldEqual, SdNotifyWatchdog)
+
+			time.Sleep(150 * time.Millisecond)
+			for !silent(10 * time.Millisecond) {
+			}
+			So(silent(150*time.Millisecond), ShouldBeTrue)
+
+			progress()
+			So(receive(), ShouldEqual, SdNotifyWatchdog)
+		})
+	})
+}
@@ -0,0 +1,196 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSessionTagsUnsupported is returned when the server has no session tag API
+var ErrSessionTagsUnsupported = fmt.Errorf("server does not support session tags")
+
+// ParseTags parses key=value pairs into a tag map. An empty value is kept so
+// it can be used to remove a tag.
+func ParseTags(pairs []string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid tag %q, expected key=value", pair)
+		}
+		tags[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return tags, nil
+}
+
+// FormatTags renders tags as a sorted, comma separated list of key=value pairs
+func FormatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+tags[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// MatchTags reports whether tags contain every key=value pair of filter
+func MatchTags(tags, filter map[string]string) bool {
+	for key, value := range filter {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SetSessionTags sets tags on a session. Tags with an empty value are removed.
+func (s *SessionsClient) SetSessionTags(sessionName string, tags map[string]string) (*SessionActionResponse, error) {
+	query := url.Values{}
+	query.Set("name", sessionName)
+	for key, value := range tags {
+		query.Add("tag", key+"="+value)
+	}
+	req, err := s.newAPIRequest("POST", "/api/sessions/tags", query)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Debugf("Tagging session: %q", req.URL.String())
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var actionResp SessionActionResponse
+		if err := json.Unmarshal(body, &actionResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %v", err)
+		}
+		return &actionResp, nil
+	case apiUnsupported(resp.StatusCode, body):
+		return nil, ErrSessionTagsUnsupported
+	default:
+		return nil, apiError("failed to tag session", resp.StatusCode, body)
+	}
+}
+
+// TagStore keeps session tags locally, keyed by host and session name, for
+// servers without a tag API
+type TagStore struct {
+	path string
+	Tags map[string]map[string]string `json:"tags"`
+}
+
+// GetDefaultTagStorePath returns the default local tag store path
+func GetDefaultTagStorePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gotty-client", "tags.json")
+}
+
+// LoadTagStore loads the local tag store, returning an empty store if the file does not exist
+// or path is empty
+func LoadTagStore(path string) (*TagStore, error) {
+	store := &TagStore{path: path, Tags: make(map[string]map[string]string)}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read tag store: %v", err)
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to decode tag store: %v", err)
+	}
+	if store.Tags == nil {
+		store.Tags = make(map[string]map[string]string)
+	}
+	return store, nil
+}
+
+func tagStoreKey(host, sessionName string) string {
+	return host + "/" + sessionName
+}
+
+// Get returns the local tags of a session
+func (s *TagStore) Get(host, sessionName string) map[string]string {
+	return s.Tags[tagStoreKey(host, sessionName)]
+}
+
+// Set merges tags into the local tags of a session. Tags with an empty value are removed.
+func (s *TagStore) Set(host, sessionName string, tags map[string]string) {
+	key := tagStoreKey(host, sessionName)
+	current := s.Tags[key]
+	if current == nil {
+		current = make(map[string]string)
+	}
+	for name, value := range tags {
+		if value == "" {
+			delete(current, name)
+		} else {
+			current[name] = value
+		}
+	}
+	if len(current) == 0 {
+		delete(s.Tags, key)
+		return
+	}
+	s.Tags[key] = current
+}
+
+// Apply fills in the local tags of the sessions of host the server reported
+// without tags, so listings can be filtered on them
+func (s *TagStore) Apply(host string, sessions *SessionListResponse) {
+	for i, session := range sessions.Sessions {
+		if len(session.Tags) == 0 {
+			sessions.Sessions[i].Tags = s.Get(host, session.Name)
+		}
+	}
+}
+
+// Save writes the local tag store back to disk
+func (s *TagStore) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create tag store directory: %v", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write tag store: %v", err)
+	}
+	return nil
+}
+
+// Host returns the host part of the client URL, used to key local session data
+func (c *Client) Host() string {
+	target, err := url.Parse(c.URL)
+	if err != nil {
+		return c.URL
+	}
+	return target.Host
+}
@@ -0,0 +1,124 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTags(t *testing.T) {
+	Convey("Testing session tags", t, func() {
+		Convey("ParseTags", func() {
+			tags, err := ParseTags([]string{"band=40m", "operator = bob", "purpose="})
+			So(err, ShouldBeNil)
+			So(tags, ShouldResemble, map[string]string{"band": "40m", "operator": "bob", "purpose": ""})
+
+			_, err = ParseTags([]string{"band"})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("MatchTags and FormatTags", func() {
+			tags := map[string]string{"band": "40m", "operator": "bob"}
+			So(MatchTags(tags, map[string]string{"band": "40m"}), ShouldBeTrue)
+			So(MatchTags(tags, map[string]string{"band": "20m"}), ShouldBeFalse)
+			So(MatchTags(nil, map[string]string{}), ShouldBeTrue)
+			So(FormatTags(tags), ShouldEqual, "band=40m,operator=bob")
+		})
+		Convey("Local tag store", func() {
+			path := filepath.Join(t.TempDir(), "tags.json")
+			store, err := LoadTagStore(path)
+			So(err, ShouldBeNil)
+
+			store.Set("example.com:8080", "ft8", map[string]string{"band": "40m", "operator": "bob"})
+			store.Set("example.com:8080", "ft8", map[string]string{"operator": ""})
+			So(store.Save(), ShouldBeNil)
+
+			store, err = LoadTagStore(path)
+			So(err, ShouldBeNil)
+			So(store.Get("example.com:8080", "ft8"), ShouldResemble, map[string]string{"band": "40m"})
+			So(store.Get("other:8080", "ft8"), ShouldBeNil)
+		})
+		Convey("A missing store is empty, a corrupt one is an error", func() {
+			dir := t.TempDir()
+			store, err := LoadTagStore(filepath.Join(dir, "missing.json"))
+			So(err, ShouldBeNil)
+			So(store.Tags, ShouldBeEmpty)
+
+			path := filepath.Join(dir, "corrupt.json")
+			So(os.WriteFile(path, []byte("{"), 0600), ShouldBeNil)
+			_, err = LoadTagStore(path)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Removing the last tag forgets the session", func() {
+			store, err := LoadTagStore(filepath.Join(t.TempDir(), "tags.json"))
+			So(err, ShouldBeNil)
+			store.Set("example.com", "ft8", map[string]string{"band": "40m"})
+			store.Set("example.com", "ft8", map[string]string{"band": ""})
+			So(store.Tags, ShouldBeEmpty)
+		})
+		Convey("Local tags fill in listings and filter them", func() {
+			store, err := LoadTagStore(filepath.Join(t.TempDir(), "tags.json"))
+			So(err, ShouldBeNil)
+			store.Set("example.com", "ft8", map[string]string{"band": "40m"})
+			store.Set("example.com", "wspr", map[string]string{"band": "20m"})
+			store.Set("other.com", "js8", map[string]string{"band": "40m"})
+
+			sessions := &SessionListResponse{Sessions: []SessionInfo{
+				{Name: "ft8"},
+				{Name: "wspr"},
+				{Name: "js8"},
+				{Name: "cw", Tags: map[string]string{"band": "40m"}},
+			}}
+			store.Apply("example.com", sessions)
+			So(sessions.Sessions[0].Tags, ShouldResemble, map[string]string{"band": "40m"})
+			So(sessions.Sessions[2].Tags, ShouldBeNil)
+
+			filtered := sessions.Filter(SessionFilter{Tags: map[string]string{"band": "40m"}})
+			So(filtered.Count, ShouldEqual, 2)
+			So(filtered.Sessions[0].Name, ShouldEqual, "ft8")
+			So(filtered.Sessions[1].Name, ShouldEqual, "cw")
+
+			So(sessions.Filter(SessionFilter{}).Count, ShouldEqual, 4)
+			So(sessions.Filter(SessionFilter{Tags: map[string]string{"band": "80m"}}).Count, ShouldEqual, 0)
+		})
+		Convey("Servers without a tag API are detected for the local fallback", func() {
+			var tagged []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/sessions/tags" {
+					http.NotFound(w, r)
+					return
+				}
+				if r.URL.Query().Get("name") != "ft8" {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"success":false,"message":"session not found"}`))
+					return
+				}
+				tagged = r.URL.Query()["tag"]
+				w.Write([]byte(`{"success":true,"message":"tagged"}`))
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			_, err = client.SetSessionTags("ft8", map[string]string{"band": "40m"})
+			So(err, ShouldBeNil)
+			So(tagged, ShouldResemble, []string{"band=40m"})
+
+			client, err = NewClient(server.URL + "/old/")
+			So(err, ShouldBeNil)
+			_, err = client.SetSessionTags("ft8", map[string]string{"band": "40m"})
+			So(err, ShouldEqual, ErrSessionTagsUnsupported)
+
+			// A session that does not exist is not a missing API
+			client, err = NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			_, err = client.SetSessionTags("wspr", map[string]string{"band": "40m"})
+			So(err, ShouldNotBeNil)
+			So(err, ShouldNotEqual, ErrSessionTagsUnsupported)
+			So(err.Error(), ShouldEqual, "failed to tag session: session not found")
+		})
+	})
+}
@@ -0,0 +1,56 @@
+package gottyclient
+
+import (
+	"os"
+	"strings"
+)
+
+// TerminalHint is the TERM and COLORTERM advertised to the server, so
+// remote programs do not assume an 8 color xterm
+type TerminalHint struct {
+	Term      string
+	ColorTerm string
+}
+
+// truecolorPrograms maps $TERM_PROGRAM values of known terminals to whether
+// they support 24-bit color; all of them support 256 colors
+var truecolorPrograms = map[string]bool{
+	"iTerm.app":      true,
+	"WezTerm":        true,
+	"vscode":         true,
+	"Hyper":          true,
+	"ghostty":        true,
+	"Apple_Terminal": false,
+}
+
+// DetectTerminal returns the hint matching the local terminal. Terminals
+// with their own terminfo entry, such as kitty, are advertised as
+// xterm-256color since the server rarely has that entry installed.
+func DetectTerminal() TerminalHint {
+	return detectTerminal(os.Getenv)
+}
+
+func detectTerminal(getenv func(string) string) TerminalHint {
+	hint := TerminalHint{Term: getenv("TERM"), ColorTerm: strings.ToLower(getenv("COLORTERM"))}
+	if hint.ColorTerm == "24bit" {
+		hint.ColorTerm = "truecolor"
+	}
+
+	truecolor, known := truecolorPrograms[getenv("TERM_PROGRAM")]
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "" || strings.HasPrefix(hint.Term, "xterm-kitty"):
+		hint.Term = "xterm-256color"
+		hint.ColorTerm = "truecolor"
+	case known:
+		hint.Term = "xterm-256color"
+		if truecolor {
+			hint.ColorTerm = "truecolor"
+		}
+	case hint.Term == "" || hint.Term == "dumb":
+		return TerminalHint{}
+	case hint.Term == "xterm" && hint.ColorTerm == "truecolor":
+		// Terminals advertising truecolor handle 256 colors too
+		hint.Term = "xterm-256color"
+	}
+	return hint
+}
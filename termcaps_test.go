@@ -0,0 +1,38 @@
+package gottyclient
+
+import (
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDetectTerminal(t *testing.T) {
+	Convey("Testing terminal detection", t, func() {
+		detect := func(env map[string]string) TerminalHint {
+			return detectTerminal(func(name string) string { return env[name] })
+		}
+
+		Convey("TERM and COLORTERM are passed through", func() {
+			So(detect(map[string]string{"TERM": "screen-256color", "COLORTERM": "24bit"}), ShouldResemble, TerminalHint{Term: "screen-256color", ColorTerm: "truecolor"})
+		})
+		Convey("Plain xterm advertising truecolor gets 256 colors", func() {
+			So(detect(map[string]string{"TERM": "xterm", "COLORTERM": "truecolor"}).Term, ShouldEqual, "xterm-256color")
+		})
+		Convey("kitty and iTerm2 are advertised as truecolor xterms", func() {
+			So(detect(map[string]string{"TERM": "xterm-kitty"}), ShouldResemble, TerminalHint{Term: "xterm-256color", ColorTerm: "truecolor"})
+			So(detect(map[string]string{"TERM": "xterm", "TERM_PROGRAM": "iTerm.app"}), ShouldResemble, TerminalHint{Term: "xterm-256color", ColorTerm: "truecolor"})
+			So(detect(map[string]string{"TERM": "xterm", "TERM_PROGRAM": "Apple_Terminal"}), ShouldResemble, TerminalHint{Term: "xterm-256color"})
+		})
+		Convey("Dumb terminals advertise nothing", func() {
+			So(detect(map[string]string{"TERM": "dumb"}), ShouldResemble, TerminalHint{})
+			So(detect(map[string]string{}), ShouldResemble, TerminalHint{})
+		})
+		Convey("The hint takes precedence over forwarded variables", func() {
+			client := &Client{SendEnv: []string{"TERM"}, Terminal: TerminalHint{Term: "xterm-256color", ColorTerm: "truecolor"}}
+			query := url.Values{}
+			client.addEnvArguments(query)
+			So(query["env"], ShouldResemble, []string{"TERM=xterm-256color", "COLORTERM=truecolor"})
+		})
+	})
+}
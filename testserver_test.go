@@ -0,0 +1,40 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// serveTerminal upgrades r to a terminal websocket which, like GoTTY, sets
+// the window title once the client has authenticated, then reads until the
+// client goes away
+func serveTerminal(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.ReadMessage()
+	conn.WriteMessage(websocket.TextMessage, []byte{SetWindowTitle})
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// newEchoServer serves an auth token and, on every other path, a terminal
+// websocket
+func newEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "auth_token.js") {
+			w.Write([]byte("var gotty_auth_token = 'token'"))
+			return
+		}
+		serveTerminal(w, r)
+	}))
+}
@@ -0,0 +1,122 @@
+package gottyclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// tlsVersions maps the accepted version names to crypto/tls constants
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses a TLS version such as "1.2" or "TLS1.2". An empty
+// string returns 0, leaving the crypto/tls default in place.
+func ParseTLSVersion(version string) (uint16, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return 0, nil
+	}
+	name := strings.TrimPrefix(strings.ToLower(version), "tls")
+	name = strings.TrimLeft(name, " v")
+	if v, ok := tlsVersions[name]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("invalid TLS version %q, expected one of 1.0, 1.1, 1.2, 1.3", version)
+}
+
+// ParseCipherSuites parses a comma-separated list of TLS cipher suite names
+// as crypto/tls names them, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+// insecure ones included for old embedded servers. They only apply up to TLS
+// 1.2, as TLS 1.3 suites are not configurable. An empty list returns nil,
+// leaving the crypto/tls default in place.
+func ParseCipherSuites(list string) ([]uint16, error) {
+	names := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		names[suite.Name] = suite.ID
+	}
+	var suites []uint16
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		id, ok := names[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS cipher suite %q", name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// tlsConfig returns the TLS settings for connections to host, a URL's
+// host[:port], or nil when the defaults apply. Certificates are checked
+// against the known_hosts entry of the host actually dialed, so each host
+// needs its own settings.
+func (c *Client) tlsConfig(host string) *tls.Config {
+	tofu := c.KnownHosts != nil && !c.SkipTLSVerify
+	if !c.SkipTLSVerify && !tofu && c.TLSMinVersion == 0 && c.TLSMaxVersion == 0 && len(c.TLSCipherSuites) == 0 && c.ServerName == "" {
+		return nil
+	}
+	conf := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.SkipTLSVerify,
+		MinVersion:         c.TLSMinVersion,
+		MaxVersion:         c.TLSMaxVersion,
+		CipherSuites:       c.TLSCipherSuites,
+	}
+	if tofu {
+		// Verification is done by verifyConnection, which falls back to
+		// known_hosts for certificates the system roots do not trust
+		conf.InsecureSkipVerify = true
+		conf.VerifyConnection = func(cs tls.ConnectionState) error {
+			return c.verifyConnection(host, cs)
+		}
+	}
+	return conf
+}
+
+// hostTransport sends each request through an http.Transport of its own
+// host, made by newTransport, so that redirects and requests to other
+// hosts are verified against the right known_hosts entry
+type hostTransport struct {
+	newTransport func(host string) *http.Transport
+
+	mutex      sync.Mutex
+	transports map[string]*http.Transport
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.transport(req.URL.Host).RoundTrip(req)
+}
+
+func (t *hostTransport) transport(host string) *http.Transport {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.transports == nil {
+		t.transports = make(map[string]*http.Transport)
+	}
+	tr, ok := t.transports[host]
+	if !ok {
+		tr = t.newTransport(host)
+		t.transports[host] = tr
+	}
+	return tr
+}
+
+// CloseIdleConnections closes the idle connections of every host
+func (t *hostTransport) CloseIdleConnections() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, tr := range t.transports {
+		tr.CloseIdleConnections()
+	}
+}
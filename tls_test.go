@@ -0,0 +1,81 @@
+package gottyclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	Convey("Testing ParseTLSVersion", t, func() {
+		for input, expected := range map[string]uint16{
+			"":        0,
+			"1.0":     tls.VersionTLS10,
+			"1.2":     tls.VersionTLS12,
+			"TLS1.3":  tls.VersionTLS13,
+			"tlsv1.1": tls.VersionTLS11,
+		} {
+			version, err := ParseTLSVersion(input)
+			So(err, ShouldBeNil)
+			So(version, ShouldEqual, expected)
+		}
+
+		_, err := ParseTLSVersion("1.4")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Testing ParseCipherSuites", t, func() {
+		suites, err := ParseCipherSuites("")
+		So(err, ShouldBeNil)
+		So(suites, ShouldBeNil)
+
+		suites, err = ParseCipherSuites("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls_rsa_with_aes_128_cbc_sha")
+		So(err, ShouldBeNil)
+		So(suites, ShouldResemble, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_128_CBC_SHA})
+
+		_, err = ParseCipherSuites("TLS_RSA_WITH_NOTHING")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Testing Client.tlsConfig", t, func() {
+		client := &Client{}
+		So(client.tlsConfig("sdr.example.com"), ShouldBeNil)
+
+		client.TLSMinVersion = tls.VersionTLS12
+		conf := client.tlsConfig("sdr.example.com")
+		So(conf, ShouldNotBeNil)
+		So(conf.MinVersion, ShouldEqual, tls.VersionTLS12)
+		So(conf.InsecureSkipVerify, ShouldBeFalse)
+
+		client = &Client{TLSCipherSuites: []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA}}
+		conf = client.tlsConfig("sdr.example.com")
+		So(conf, ShouldNotBeNil)
+		So(conf.CipherSuites, ShouldResemble, []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA})
+	})
+}
+
+func TestServerNameOverride(t *testing.T) {
+	Convey("Testing SNI and Host header overrides", t, func() {
+		var host, serverName string
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host = r.Host
+			serverName = r.TLS.ServerName
+			w.Write([]byte("var gotty_auth_token = 'token'"))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		client.SkipTLSVerify = true
+		client.ServerName = "sdr.example.com"
+		client.HostHeader = "sdr.example.com:8443"
+
+		_, err = client.GetAuthToken()
+		So(err, ShouldBeNil)
+		So(serverName, ShouldEqual, "sdr.example.com")
+		So(host, ShouldEqual, "sdr.example.com:8443")
+	})
+}
@@ -0,0 +1,191 @@
+package gottyclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TransportOptions are the network settings a Transport should honor when dialing
+type TransportOptions struct {
+	TLSConfig      *tls.Config
+	Proxy          func(*http.Request) (*url.URL, error)
+	Jar            http.CookieJar
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// HandshakeTimeout bounds the dial and handshake; 0 means no limit
+	HandshakeTimeout time.Duration
+}
+
+// Transport carries GoTTY protocol messages between the client and the
+// server. The default is WebsocketTransport; other implementations can be
+// set on Client.Transport before Connect.
+type Transport interface {
+	// Dial connects to the websocket URL target with the given request
+	// headers, giving up when ctx is done
+	Dial(ctx context.Context, target string, header http.Header, opts TransportOptions) error
+	// Read blocks until the next message arrives
+	Read() ([]byte, error)
+	// Write sends one message; it is never called concurrently
+	Write(data []byte) error
+	Close() error
+}
+
+// ErrNotDialed is returned by the methods of a Transport that was not dialed
+var ErrNotDialed = errors.New("transport not connected")
+
+// HandshakeError is returned by WebsocketTransport.Dial when the server
+// answers the upgrade request with an HTTP error, e.g. 401 for a stale auth
+// token
+type HandshakeError struct {
+	StatusCode int
+	Header     http.Header
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("%v (%d %s)", websocket.ErrBadHandshake, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// WebsocketTransport is the default Transport, built on gorilla/websocket
+type WebsocketTransport struct {
+	Dialer *websocket.Dialer
+	Conn   *websocket.Conn
+	// OnPong is called with the round-trip time when a pong control frame
+	// answers a Ping
+	OnPong func(rtt time.Duration)
+}
+
+// Dial implements Transport
+func (t *WebsocketTransport) Dial(ctx context.Context, target string, header http.Header, opts TransportOptions) error {
+	if t.Dialer == nil {
+		t.Dialer = &websocket.Dialer{}
+	}
+	if opts.TLSConfig != nil {
+		t.Dialer.TLSClientConfig = opts.TLSConfig
+	}
+	t.Dialer.Proxy = opts.Proxy
+	if opts.Jar != nil {
+		t.Dialer.Jar = opts.Jar
+	}
+	if opts.NetDialContext != nil {
+		t.Dialer.NetDialContext = opts.NetDialContext
+	}
+	if opts.HandshakeTimeout > 0 {
+		t.Dialer.HandshakeTimeout = opts.HandshakeTimeout
+	}
+
+	conn, resp, err := dialWebsocket(ctx, *t.Dialer, target, header)
+	if err != nil {
+		if err == websocket.ErrBadHandshake && resp != nil {
+			return &HandshakeError{StatusCode: resp.StatusCode, Header: resp.Header}
+		}
+		return err
+	}
+	t.Conn = conn
+	conn.SetPongHandler(func(appData string) error {
+		sent, err := strconv.ParseInt(appData, 10, 64)
+		if err == nil && t.OnPong != nil {
+			t.OnPong(time.Since(time.Unix(0, sent)))
+		}
+		return nil
+	})
+	return nil
+}
+
+// dialWebsocket dials with dialer, closing the connection if ctx is
+// cancelled during the handshake, which the dialer only bounds by the
+// context's deadline
+func dialWebsocket(ctx context.Context, dialer websocket.Dialer, target string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	var mutex sync.Mutex
+	var netConn net.Conn
+	finished := false
+	netDial := dialer.NetDialContext
+	switch {
+	case netDial != nil:
+	case dialer.NetDial != nil:
+		netDial = func(_ context.Context, network, addr string) (net.Conn, error) {
+			return dialer.NetDial(network, addr)
+		}
+	default:
+		netDial = (&net.Dialer{}).DialContext
+	}
+	dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := netDial(ctx, network, addr)
+		mutex.Lock()
+		defer mutex.Unlock()
+		netConn = conn
+		return conn, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			mutex.Lock()
+			defer mutex.Unlock()
+			if !finished && netConn != nil {
+				netConn.Close()
+			}
+		case <-done:
+		}
+	}()
+
+	conn, resp, err := dialer.DialContext(ctx, target, header)
+	mutex.Lock()
+	finished = true
+	mutex.Unlock()
+	return conn, resp, err
+}
+
+// Read implements Transport
+func (t *WebsocketTransport) Read() ([]byte, error) {
+	if t.Conn == nil {
+		return nil, ErrNotDialed
+	}
+	_, data, err := t.Conn.ReadMessage()
+	return data, err
+}
+
+// Write implements Transport
+func (t *WebsocketTransport) Write(data []byte) error {
+	if t.Conn == nil {
+		return ErrNotDialed
+	}
+	return t.Conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Ping sends an RFC 6455 ping control frame carrying the send time, so the
+// pong reports the round-trip time. Some reverse proxies only reset their
+// idle timers on control frames.
+func (t *WebsocketTransport) Ping() error {
+	if t.Conn == nil {
+		return ErrNotDialed
+	}
+	payload := []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+	return t.Conn.WriteControl(websocket.PingMessage, payload, time.Now().Add(10*time.Second))
+}
+
+// SetReadDeadline sets the deadline for the next Read
+func (t *WebsocketTransport) SetReadDeadline(deadline time.Time) error {
+	if t.Conn == nil {
+		return ErrNotDialed
+	}
+	return t.Conn.SetReadDeadline(deadline)
+}
+
+// Close implements Transport; closing a transport never dialed does nothing
+func (t *WebsocketTransport) Close() error {
+	if t.Conn == nil {
+		return nil
+	}
+	return t.Conn.Close()
+}
@@ -0,0 +1,243 @@
+package gottyclient
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeTransport records dials and written messages
+type fakeTransport struct {
+	target  string
+	mutex   sync.Mutex
+	written []string
+	// fail makes writes fail, as on a broken connection
+	fail bool
+}
+
+func (t *fakeTransport) Dial(ctx context.Context, target string, header http.Header, opts TransportOptions) error {
+	t.target = target
+	return nil
+}
+
+func (t *fakeTransport) Read() ([]byte, error) { return nil, io.EOF }
+
+func (t *fakeTransport) Write(data []byte) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.fail {
+		return io.ErrClosedPipe
+	}
+	t.written = append(t.written, string(data))
+	return nil
+}
+
+// Written returns the messages written so far and forgets them
+func (t *fakeTransport) Written() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	written := t.written
+	t.written = nil
+	return written
+}
+
+func (t *fakeTransport) Close() error { return nil }
+
+func TestCustomTransport(t *testing.T) {
+	Convey("Testing a custom Transport", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("var gotty_auth_token = 'token'"))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		transport := &fakeTransport{}
+		client.Transport = transport
+		client.V2 = true
+
+		So(client.Connect(), ShouldBeNil)
+		defer client.Close()
+		So(transport.target, ShouldStartWith, "ws://")
+		written := transport.Written()
+		So(len(written), ShouldBeGreaterThan, 0)
+		So(strings.Contains(written[0], `"AuthToken":"token"`), ShouldBeTrue)
+		So(client.Conn, ShouldBeNil)
+	})
+}
+
+func TestSSEFallback(t *testing.T) {
+	Convey("Testing the HTTP streaming fallback", t, func() {
+		posted := make(chan string, 10)
+		info := `{"version":"1.5.0","transports":["sse/1"]}`
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/auth_token.js":
+				w.Write([]byte("var gotty_auth_token = 'token'"))
+			case r.URL.Path == "/api/info":
+				w.Write([]byte(info))
+			case r.URL.Path == "/sse" && r.Method == "GET":
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(": hello\ndata: 1aGVsbG8=\n\n"))
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			case r.URL.Path == "/sse" && r.Method == "POST":
+				body, _ := io.ReadAll(r.Body)
+				posted <- string(body)
+			default:
+				http.Error(w, "websockets are blocked", http.StatusForbidden)
+			}
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+		client.V2 = true
+
+		Convey("Without --allow-fallback the websocket error is returned", func() {
+			So(client.Connect(), ShouldNotBeNil)
+		})
+
+		Convey("With --allow-fallback messages go over HTTP", func() {
+			client.AllowFallback = true
+			So(client.Connect(), ShouldBeNil)
+			defer client.Transport.Close()
+			So(<-posted, ShouldContainSubstring, `"AuthToken":"token"`)
+
+			data, err := client.readFrame(client.Transport)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "1aGVsbG8=")
+		})
+
+		Convey("Servers not listing the transport are not fallen back to", func() {
+			client.AllowFallback = true
+			client.RetryPolicy = NoRetry
+			for _, info = range []string{`{"version":"1.4.0"}`, `{"version":"1.5.0","transports":["sse/2"]}`} {
+				err := client.Connect()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, ErrFallbackUnsupported.Error())
+				So(posted, ShouldBeEmpty)
+			}
+		})
+	})
+}
+
+// blockingTransport never receives anything
+type blockingTransport struct {
+	fakeTransport
+	closed chan struct{}
+}
+
+func (t *blockingTransport) Read() ([]byte, error) {
+	<-t.closed
+	return nil, io.EOF
+}
+
+func TestReadWatchdog(t *testing.T) {
+	Convey("Testing the read watchdog", t, func() {
+		client, err := NewClient("http://localhost/")
+		So(err, ShouldBeNil)
+		transport := &blockingTransport{closed: make(chan struct{})}
+		defer close(transport.closed)
+		client.Transport = transport
+		client.ReadTimeout = 60 * time.Millisecond
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		start := time.Now()
+		client.readLoop(wg)
+		So(client.loopErr, ShouldEqual, ErrConnectionStale)
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+	})
+}
+
+func TestWebsocketPing(t *testing.T) {
+	Convey("Testing websocket ping control frames", t, func() {
+		upgrader := websocket.Upgrader{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			// The default ping handler answers with a pong while reading
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}))
+		defer server.Close()
+
+		rtts := make(chan time.Duration, 1)
+		transport := &WebsocketTransport{OnPong: func(rtt time.Duration) { rtts <- rtt }}
+		So(transport.Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), http.Header{}, TransportOptions{}), ShouldBeNil)
+		defer transport.Close()
+		go transport.Read()
+
+		So(transport.Ping(), ShouldBeNil)
+		select {
+		case rtt := <-rtts:
+			So(rtt, ShouldBeGreaterThan, 0)
+		case <-time.After(5 * time.Second):
+			So("no pong received", ShouldBeEmpty)
+		}
+	})
+}
+
+func TestWebsocketTransportDial(t *testing.T) {
+	Convey("Testing dialing the websocket transport", t, func() {
+		Convey("A transport never dialed fails instead of panicking", func() {
+			transport := &WebsocketTransport{}
+			_, err := transport.Read()
+			So(err, ShouldEqual, ErrNotDialed)
+			So(transport.Write([]byte("x")), ShouldEqual, ErrNotDialed)
+			So(transport.Ping(), ShouldEqual, ErrNotDialed)
+			So(transport.SetReadDeadline(time.Now()), ShouldEqual, ErrNotDialed)
+			So(transport.Close(), ShouldBeNil)
+
+			sse := &SSETransport{}
+			_, err = sse.Read()
+			So(err, ShouldEqual, ErrNotDialed)
+			So(sse.Write([]byte("x")), ShouldEqual, ErrNotDialed)
+			So(sse.Close(), ShouldBeNil)
+		})
+
+		Convey("Dialing gives up once the context is done", func() {
+			// The server never answers the upgrade request
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer listener.Close()
+			go func() {
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					defer conn.Close()
+				}
+			}()
+
+			// Only a cancellation, which the websocket dialer does not
+			// watch by itself
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			time.AfterFunc(100*time.Millisecond, cancel)
+			start := time.Now()
+			transport := &WebsocketTransport{}
+			err = transport.Dial(ctx, "ws://"+listener.Addr().String()+"/ws", http.Header{}, TransportOptions{})
+			So(err, ShouldNotBeNil)
+			So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+			So(transport.Conn, ShouldBeNil)
+		})
+	})
+}
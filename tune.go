@@ -0,0 +1,124 @@
+package gottyclient
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Modes are the demodulation modes understood by --mode, in the lower case
+// UberSDR uses
+var Modes = []string{"usb", "lsb", "am", "sam", "fm", "nfm", "wfm", "cw", "cwu", "cwl", "iq"}
+
+var frequencyUnits = []struct {
+	name       string
+	multiplier float64
+}{
+	{"GHz", 1e9},
+	{"MHz", 1e6},
+	{"kHz", 1e3},
+	{"Hz", 1},
+}
+
+// ParseFrequency parses a frequency such as 7.074MHz, 14074 kHz or 7074000
+// into Hz. Units are case-insensitive, a number without one is in Hz.
+func ParseFrequency(s string) (int64, error) {
+	value := strings.ToLower(strings.Replace(strings.TrimSpace(s), " ", "", -1))
+	multiplier := 1.0
+	hasUnit := false
+	for _, unit := range frequencyUnits {
+		if suffix := strings.ToLower(unit.name); strings.HasSuffix(value, suffix) {
+			value = strings.TrimSuffix(value, suffix)
+			multiplier = unit.multiplier
+			hasUnit = true
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("invalid frequency %q, expected e.g. 7.074MHz, 7074kHz or 7074000", s)
+	}
+	if !hasUnit && number != math.Trunc(number) {
+		return 0, fmt.Errorf("invalid frequency %q: fractions need a unit, e.g. %sMHz", s, value)
+	}
+	hz := math.Round(number * multiplier)
+	if hz <= 0 || hz > math.MaxInt64/2 {
+		return 0, fmt.Errorf("invalid frequency %q: out of range", s)
+	}
+	return int64(hz), nil
+}
+
+// FormatFrequency formats a frequency in Hz with the largest unit it has at
+// least one of, e.g. 7.074MHz
+func FormatFrequency(hz int64) string {
+	for _, unit := range frequencyUnits {
+		if float64(hz) >= unit.multiplier {
+			return strconv.FormatFloat(float64(hz)/unit.multiplier, 'f', -1, 64) + unit.name
+		}
+	}
+	return strconv.FormatInt(hz, 10) + "Hz"
+}
+
+// ParseMode checks a demodulation mode against Modes, case-insensitively,
+// returning it in lower case
+func ParseMode(s string) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(s))
+	for _, known := range Modes {
+		if mode == known {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown mode %q, expected one of %s", s, strings.ToUpper(strings.Join(Modes, ", ")))
+}
+
+// Bookmark is a named frequency and mode of a host, connected to as
+// ALIAS@NAME
+type Bookmark struct {
+	Name string
+	// Freq is in Hz
+	Freq int64
+	// Mode is in lower case, or empty to keep the receiver's
+	Mode string
+	// Label describes the bookmark, e.g. FT8 40m
+	Label string
+}
+
+// ParseBookmark parses the value of a Bookmark option, NAME FREQ [MODE]
+// [LABEL...], e.g. ft8-40m 7.074MHz USB FT8 on 40m
+func ParseBookmark(value string) (Bookmark, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return Bookmark{}, fmt.Errorf("invalid Bookmark %q, expected NAME FREQ [MODE] [LABEL]", value)
+	}
+	if strings.ContainsAny(fields[0], "@*?") {
+		return Bookmark{}, fmt.Errorf("invalid Bookmark name %q: @, * and ? are not allowed", fields[0])
+	}
+	freq, err := ParseFrequency(fields[1])
+	if err != nil {
+		return Bookmark{}, err
+	}
+	bookmark := Bookmark{Name: fields[0], Freq: freq}
+	label := fields[2:]
+	if len(label) > 0 {
+		if mode, err := ParseMode(label[0]); err == nil {
+			bookmark.Mode = mode
+			label = label[1:]
+		}
+	}
+	bookmark.Label = strings.Join(label, " ")
+	return bookmark, nil
+}
+
+// String formats the bookmark as the value of a Bookmark option
+func (b Bookmark) String() string {
+	fields := []string{b.Name, FormatFrequency(b.Freq)}
+	if b.Mode != "" {
+		fields = append(fields, strings.ToUpper(b.Mode))
+	}
+	if b.Label != "" {
+		fields = append(fields, b.Label)
+	}
+	return strings.Join(fields, " ")
+}
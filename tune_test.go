@@ -0,0 +1,66 @@
+package gottyclient
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTune(t *testing.T) {
+	Convey("Testing frequency and mode parsing", t, func() {
+		Convey("Frequencies are read in any unit", func() {
+			for input, hz := range map[string]int64{
+				"7.074MHz":   7074000,
+				"7074kHz":    7074000,
+				"7074 khz":   7074000,
+				"7074000":    7074000,
+				"7074000Hz":  7074000,
+				"14.0745mhz": 14074500,
+				"1.2965GHz":  1296500000,
+				"0.198MHz":   198000,
+			} {
+				parsed, err := ParseFrequency(input)
+				So(err, ShouldBeNil)
+				So(parsed, ShouldEqual, hz)
+			}
+		})
+
+		Convey("Ambiguous and invalid frequencies are refused", func() {
+			for _, input := range []string{"", "7.074", "MHz", "-7MHz", "0", "7.074MHz USB", "NaN"} {
+				_, err := ParseFrequency(input)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("Frequencies are formatted in the largest whole unit", func() {
+			So(FormatFrequency(7074000), ShouldEqual, "7.074MHz")
+			So(FormatFrequency(198000), ShouldEqual, "198kHz")
+			So(FormatFrequency(1296500000), ShouldEqual, "1.2965GHz")
+			So(FormatFrequency(500), ShouldEqual, "500Hz")
+		})
+
+		Convey("Bookmarks have an optional mode and label", func() {
+			bookmark, err := ParseBookmark("ft8-40m 7.074MHz USB FT8 on 40m")
+			So(err, ShouldBeNil)
+			So(bookmark, ShouldResemble, Bookmark{Name: "ft8-40m", Freq: 7074000, Mode: "usb", Label: "FT8 on 40m"})
+			So(bookmark.String(), ShouldEqual, "ft8-40m 7.074MHz USB FT8 on 40m")
+
+			bookmark, err = ParseBookmark("wwv 10MHz Time signal")
+			So(err, ShouldBeNil)
+			So(bookmark, ShouldResemble, Bookmark{Name: "wwv", Freq: 10000000, Label: "Time signal"})
+
+			for _, value := range []string{"ft8", "ft8 USB", "ft8@40m 7.074MHz", "ft8 7.074"} {
+				_, err := ParseBookmark(value)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("Modes are case-insensitive", func() {
+			mode, err := ParseMode("USB")
+			So(err, ShouldBeNil)
+			So(mode, ShouldEqual, "usb")
+			_, err = ParseMode("SSB")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
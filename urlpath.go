@@ -0,0 +1,45 @@
+package gottyclient
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// terminalEndpoint returns the URL of one of the files served next to the
+// terminal at httpURL, e.g. "ws": name, or override if set, resolved against
+// the terminal's directory, whatever prefix a reverse proxy mounts it under.
+// An absolute path replaces the URL's path. The query string is kept.
+func terminalEndpoint(httpURL, name, override string) (*url.URL, error) {
+	target, err := url.Parse(httpURL)
+	if err != nil {
+		return nil, err
+	}
+
+	escaped := name
+	if override != "" {
+		escaped = override
+	}
+	if !strings.HasPrefix(escaped, "/") {
+		dir := target.EscapedPath()
+		if !strings.HasSuffix(dir, "/") {
+			dir += "/"
+		}
+		escaped = dir + escaped
+	}
+	if override != "" {
+		// Resolve the ../ of overrides pointing outside the terminal
+		cleaned := path.Clean(escaped)
+		if strings.HasSuffix(escaped, "/") && cleaned != "/" {
+			cleaned += "/"
+		}
+		escaped = cleaned
+	}
+	unescaped, err := url.PathUnescape(escaped)
+	if err != nil {
+		return nil, err
+	}
+	// RawPath keeps escapes such as %2F in the prefix intact
+	target.Path, target.RawPath = unescaped, escaped
+	return target, nil
+}
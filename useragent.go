@@ -0,0 +1,48 @@
+package gottyclient
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"runtime"
+)
+
+// ClientVersion is the version reported in the User-Agent; the command sets
+// it to its own
+var ClientVersion = "dev"
+
+// InstanceHeader carries the client instance ID, so server logs can tell the
+// requests of one running client apart from others behind the same address
+const InstanceHeader = "X-Client-Instance"
+
+// processInstanceID is the random UUID new clients identify with; all the
+// clients of a process share it
+var processInstanceID = newUUID()
+
+// DefaultUserAgent returns the User-Agent of clients not setting their own,
+// e.g. uberterm/1.2.0 (linux/amd64)
+func DefaultUserAgent() string {
+	return fmt.Sprintf("uberterm/%s (%s/%s)", ClientVersion, runtime.GOOS, runtime.GOARCH)
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// identify sets the User-Agent and instance ID headers of a request
+func identify(header http.Header, userAgent, instanceID string) {
+	if userAgent == "" {
+		userAgent = DefaultUserAgent()
+	}
+	header.Set("User-Agent", userAgent)
+	if instanceID != "" {
+		header.Set(InstanceHeader, instanceID)
+	}
+}
@@ -0,0 +1,57 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"runtime"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUserAgent(t *testing.T) {
+	Convey("Testing client identification", t, func() {
+		Convey("Default User-Agent and instance ID", func() {
+			So(DefaultUserAgent(), ShouldEqual, "uberterm/dev ("+runtime.GOOS+"/"+runtime.GOARCH+")")
+			So(processInstanceID, ShouldNotEqual, newUUID())
+			So(regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(processInstanceID), ShouldBeTrue)
+		})
+
+		Convey("Every request is identified", func() {
+			var mu sync.Mutex
+			seen := map[string][2]string{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				seen[r.URL.Path] = [2]string{r.UserAgent(), r.Header.Get(InstanceHeader)}
+				mu.Unlock()
+				switch r.URL.Path {
+				case "/auth_token.js":
+					w.Write([]byte("var gotty_auth_token = 'token'"))
+				case "/ws":
+					serveTerminal(w, r)
+				default:
+					w.Write([]byte(`{"sessions":[],"count":0}`))
+				}
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			client.V2 = true
+			client.UserAgent = "monitor/1.0"
+			So(client.Connect(), ShouldBeNil)
+			defer client.Close()
+			_, err = client.ListSessions()
+			So(err, ShouldBeNil)
+
+			mu.Lock()
+			defer mu.Unlock()
+			expected := [2]string{"monitor/1.0", processInstanceID}
+			So(seen["/auth_token.js"], ShouldResemble, expected)
+			So(seen["/ws"], ShouldResemble, expected)
+			So(seen["/api/sessions"], ShouldResemble, expected)
+		})
+	})
+}
@@ -0,0 +1,112 @@
+package gottyclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ErrInstanceFull is returned when the registry reports no free client slot
+// on an instance
+var ErrInstanceFull = fmt.Errorf("instance has no free client slot")
+
+// WaitPolicy controls how long and how often --wait tries to connect
+type WaitPolicy struct {
+	// Timeout gives up after this long; 0 waits until the context is done
+	Timeout time.Duration
+	// Backoff is the delay before the second try, doubled on each try
+	Backoff time.Duration
+	// MaxBackoff caps the delay between tries
+	MaxBackoff time.Duration
+}
+
+// DefaultWaitPolicy retries every second at first, then every 30s at most
+var DefaultWaitPolicy = &WaitPolicy{
+	Backoff:    time.Second,
+	MaxBackoff: 30 * time.Second,
+}
+
+// Full reports whether the registry lists no free client slot on the
+// instance
+func (i *Instance) Full() bool {
+	return i.MaxClients > 0 && i.AvailableClients <= 0
+}
+
+// LookupError is a failure to find a server, e.g. to resolve a callsign
+// through the instance registry, which waiting may clear
+type LookupError struct {
+	Err error
+}
+
+func (e *LookupError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *LookupError) Unwrap() error {
+	return e.Err
+}
+
+// WaitRetryable reports whether setting up a connection failed for a reason
+// waiting may clear: a failed lookup, a network error or a full instance.
+// Other errors, e.g. an invalid frequency or an unknown alias, are in the
+// settings and do not go away by waiting.
+func WaitRetryable(err error) bool {
+	var lookup *LookupError
+	var netErr net.Error
+	return errors.Is(err, ErrInstanceFull) || errors.As(err, &lookup) || errors.As(err, &netErr)
+}
+
+// WaitPermanent reports whether err is one waiting does not help with:
+// refused credentials or a missing one-time code
+func WaitPermanent(err error) bool {
+	if _, ok := err.(*AuthError); ok {
+		return true
+	}
+	return err == ErrOTPRequired
+}
+
+// Wait calls try until it succeeds, fails permanently (see WaitPermanent),
+// ctx is done or the timeout elapses, returning try's last error in the
+// latter cases. retrying, if set, is told about every failed try before
+// waiting for the next one.
+func (p *WaitPolicy) Wait(ctx context.Context, try func() error, retrying func(attempt int, err error, wait time.Duration)) error {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	backoff := p.Backoff
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		err := try()
+		if err == nil || WaitPermanent(err) {
+			return err
+		}
+
+		wait := backoff
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			wait = time.Until(deadline)
+		}
+		if ctx.Err() != nil || wait <= 0 {
+			return fmt.Errorf("gave up waiting after %d attempts: %v", attempt, err)
+		}
+		if retrying != nil {
+			retrying(attempt, err, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up waiting after %d attempts: %v", attempt, err)
+		case <-time.After(wait):
+		}
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package gottyclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWait(t *testing.T) {
+	Convey("Testing waiting until connected", t, func() {
+		policy := &WaitPolicy{Backoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+		var waits []time.Duration
+		retrying := func(attempt int, err error, wait time.Duration) {
+			waits = append(waits, wait)
+		}
+
+		Convey("Failures are retried with backoff until success", func() {
+			tries := 0
+			err := policy.Wait(context.Background(), func() error {
+				if tries++; tries < 5 {
+					return fmt.Errorf("connection refused")
+				}
+				return nil
+			}, retrying)
+			So(err, ShouldBeNil)
+			So(tries, ShouldEqual, 5)
+			So(waits, ShouldResemble, []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond})
+		})
+
+		Convey("Refused credentials are not retried", func() {
+			tries := 0
+			err := policy.Wait(context.Background(), func() error {
+				tries++
+				return &AuthError{StatusCode: 401}
+			}, retrying)
+			So(err, ShouldHaveSameTypeAs, &AuthError{})
+			So(tries, ShouldEqual, 1)
+		})
+
+		Convey("Waiting gives up after the timeout", func() {
+			policy.Timeout = 30 * time.Millisecond
+			start := time.Now()
+			err := policy.Wait(context.Background(), func() error {
+				return ErrInstanceFull
+			}, nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrInstanceFull.Error())
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+		})
+
+		Convey("Only lookup, network and full instance errors are worth waiting for", func() {
+			So(WaitRetryable(ErrInstanceFull), ShouldBeTrue)
+			So(WaitRetryable(fmt.Errorf("failed to resolve callsign M9PSY: %w", &LookupError{Err: fmt.Errorf("no instance found")})), ShouldBeTrue)
+			So(WaitRetryable(&net.DNSError{Err: "no such host", Name: "sdr.example"}), ShouldBeTrue)
+			_, err := http.Get("http://127.0.0.1:1/")
+			So(WaitRetryable(err), ShouldBeTrue)
+
+			_, err = ParseFrequency("abc")
+			So(WaitRetryable(err), ShouldBeFalse)
+			So(WaitRetryable(fmt.Errorf("host config 'lab' has neither URL nor Callsign")), ShouldBeFalse)
+		})
+
+		Convey("Instances without a free slot are full", func() {
+			So((&Instance{MaxClients: 4, AvailableClients: 0}).Full(), ShouldBeTrue)
+			So((&Instance{MaxClients: 4, AvailableClients: 1}).Full(), ShouldBeFalse)
+			So((&Instance{}).Full(), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,86 @@
+package gottyclient
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrConnectionStale is returned by Loop when nothing was received from the
+// server for longer than the read timeout, e.g. after the network dropped
+var ErrConnectionStale = errors.New("connection stale: no data received from server")
+
+// DefaultReadTimeout is used when Client.ReadTimeout is zero. The client
+// pings every 30 seconds, so this allows two missed pongs.
+const DefaultReadTimeout = 90 * time.Second
+
+// pinger is implemented by transports with protocol-level pings
+type pinger interface {
+	Ping() error
+}
+
+// handlePong records the round-trip time of a ping control frame
+func (c *Client) handlePong(rtt time.Duration) {
+	c.statsMutex.Lock()
+	c.rtt = rtt
+	c.statsMutex.Unlock()
+	logrus.Debugf("Pong received, RTT %v", rtt)
+	c.touchRead()
+}
+
+// RTT returns the last measured round-trip time, 0 if none was measured yet
+func (c *Client) RTT() time.Duration {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	return c.rtt
+}
+
+// readDeadliner is implemented by transports supporting read deadlines
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// readTimeout returns the effective read timeout, 0 when disabled
+func (c *Client) readTimeout() time.Duration {
+	switch {
+	case c.ReadTimeout < 0:
+		return 0
+	case c.ReadTimeout == 0:
+		return DefaultReadTimeout
+	}
+	return c.ReadTimeout
+}
+
+// touchRead records inbound activity and pushes the transport read deadline back
+func (c *Client) touchRead() {
+	c.statsMutex.Lock()
+	c.lastRead = time.Now()
+	c.statsMutex.Unlock()
+
+	timeout := c.readTimeout()
+	if timeout == 0 {
+		return
+	}
+	if deadliner, ok := c.transport().(readDeadliner); ok {
+		_ = deadliner.SetReadDeadline(time.Now().Add(timeout))
+	}
+}
+
+// readStale reports whether nothing was received for longer than the read timeout
+func (c *Client) readStale() bool {
+	timeout := c.readTimeout()
+	if timeout == 0 {
+		return false
+	}
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	return time.Since(c.lastRead) > timeout
+}
+
+// isTimeout reports whether a read error is a deadline expiry
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
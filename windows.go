@@ -0,0 +1,96 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrWindowsUnsupported is returned when the server has no window API
+var ErrWindowsUnsupported = fmt.Errorf("server does not support window management")
+
+// WindowInfo represents a tmux window within a session
+type WindowInfo struct {
+	Index  int    `json:"index"`
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+	Panes  int    `json:"panes"`
+}
+
+// WindowListResponse represents the response for listing the windows of a session
+type WindowListResponse struct {
+	Session string       `json:"session"`
+	Windows []WindowInfo `json:"windows"`
+	Count   int          `json:"count"`
+}
+
+// ListWindows lists the windows of a session
+func (s *SessionsClient) ListWindows(sessionName string) (*WindowListResponse, error) {
+	query := url.Values{}
+	query.Set("name", sessionName)
+	req, err := s.newAPIRequest("GET", "/api/sessions/windows", query)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Debugf("Listing windows: %q", req.URL.String())
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var windows WindowListResponse
+		if err := json.Unmarshal(body, &windows); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %v", err)
+		}
+		return &windows, nil
+	case apiUnsupported(resp.StatusCode, body):
+		return nil, ErrWindowsUnsupported
+	default:
+		return nil, apiError("failed to list windows", resp.StatusCode, body)
+	}
+}
+
+// RenameWindow renames a window of a session. window is the window index or
+// current name; if empty, the session's active window is renamed.
+func (s *SessionsClient) RenameWindow(sessionName, window, newName string) (*SessionActionResponse, error) {
+	query := url.Values{}
+	query.Set("name", sessionName)
+	if window != "" {
+		query.Set("window", window)
+	}
+	query.Set("to", newName)
+	req, err := s.newAPIRequest("POST", "/api/sessions/windows/rename", query)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Debugf("Renaming window: %q", req.URL.String())
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var actionResp SessionActionResponse
+		if err := json.Unmarshal(body, &actionResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %v", err)
+		}
+		return &actionResp, nil
+	case apiUnsupported(resp.StatusCode, body):
+		return nil, ErrWindowsUnsupported
+	default:
+		return nil, apiError("failed to rename window", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,63 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWindows(t *testing.T) {
+	Convey("Testing the window API", t, func() {
+		var renamed string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			switch {
+			case strings.HasPrefix(r.URL.Path, "/api/sessions/windows") && query.Get("name") == "missing":
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"success":false,"message":"session missing not found"}`))
+			case r.URL.Path == "/api/sessions/windows" && r.Method == "GET":
+				w.Write([]byte(`{"session":"` + query.Get("name") + `","windows":[{"index":0,"name":"bash","active":true,"panes":1},{"index":1,"name":"logs","panes":2}],"count":2}`))
+			case r.URL.Path == "/api/sessions/windows/rename" && r.Method == "POST":
+				renamed = query.Get("window") + "->" + query.Get("to")
+				w.Write([]byte(`{"success":true,"message":"window renamed"}`))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer server.Close()
+
+		client, err := NewClient(server.URL + "/")
+		So(err, ShouldBeNil)
+
+		windows, err := client.ListWindows("ft8")
+		So(err, ShouldBeNil)
+		So(windows.Session, ShouldEqual, "ft8")
+		So(windows.Windows, ShouldResemble, []WindowInfo{
+			{Index: 0, Name: "bash", Active: true, Panes: 1},
+			{Index: 1, Name: "logs", Panes: 2},
+		})
+
+		resp, err := client.RenameWindow("ft8", "1", "syslog")
+		So(err, ShouldBeNil)
+		So(resp.Success, ShouldBeTrue)
+		So(renamed, ShouldEqual, "1->syslog")
+
+		_, err = client.ListWindows("missing")
+		So(err, ShouldNotBeNil)
+		So(err, ShouldNotEqual, ErrWindowsUnsupported)
+		So(err.Error(), ShouldEqual, "failed to list windows: session missing not found")
+		_, err = client.RenameWindow("missing", "", "syslog")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "failed to rename window: session missing not found")
+
+		client, err = NewClient(server.URL + "/old/")
+		So(err, ShouldBeNil)
+		_, err = client.ListWindows("ft8")
+		So(err, ShouldEqual, ErrWindowsUnsupported)
+		_, err = client.RenameWindow("ft8", "", "syslog")
+		So(err, ShouldEqual, ErrWindowsUnsupported)
+	})
+}
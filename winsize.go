@@ -0,0 +1,63 @@
+package gottyclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WinSize is a terminal size, as sent in resize messages
+type WinSize struct {
+	Rows    uint16 `json:"rows"`
+	Columns uint16 `json:"columns"`
+}
+
+// ParseWinSize parses a size given as COLUMNSxROWS, e.g. "120x40"
+func ParseWinSize(value string) (*WinSize, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(value)), "x")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid terminal size %q, expected COLUMNSxROWS", value)
+	}
+	columns, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil || columns == 0 {
+		return nil, fmt.Errorf("invalid terminal size %q, expected COLUMNSxROWS", value)
+	}
+	rows, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil || rows == 0 {
+		return nil, fmt.Errorf("invalid terminal size %q, expected COLUMNSxROWS", value)
+	}
+	return &WinSize{Rows: uint16(rows), Columns: uint16(columns)}, nil
+}
+
+// String formats the size as COLUMNSxROWS
+func (s WinSize) String() string {
+	return fmt.Sprintf("%dx%d", s.Columns, s.Rows)
+}
+
+// initialWinSize returns the size to create the remote terminal at:
+// InitialWinSize, else the local terminal's size, else nil
+func (c *Client) initialWinSize() *WinSize {
+	if c.InitialWinSize != nil {
+		return c.InitialWinSize
+	}
+	b, err := syscallTIOCGWINSZ()
+	if err != nil {
+		return nil
+	}
+	var size WinSize
+	if json.Unmarshal(b, &size) != nil || size.Rows == 0 || size.Columns == 0 {
+		return nil
+	}
+	return &size
+}
+
+// winSizePayload returns the resize payload for the local terminal's size,
+// or for InitialWinSize where there is no terminal, e.g. in programs
+func (c *Client) winSizePayload() ([]byte, error) {
+	b, err := syscallTIOCGWINSZ()
+	if err != nil && c.InitialWinSize != nil {
+		return json.Marshal(c.InitialWinSize)
+	}
+	return b, err
+}
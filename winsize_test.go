@@ -0,0 +1,55 @@
+package gottyclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInitialWinSize(t *testing.T) {
+	Convey("Testing the initial terminal size", t, func() {
+		Convey("Sizes are parsed as COLUMNSxROWS", func() {
+			size, err := ParseWinSize("120x40")
+			So(err, ShouldBeNil)
+			So(*size, ShouldResemble, WinSize{Rows: 40, Columns: 120})
+			So(size.String(), ShouldEqual, "120x40")
+
+			for _, invalid := range []string{"", "120", "0x40", "120x", "x40", "120x40x2", "70000x40"} {
+				_, err := ParseWinSize(invalid)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("The size is sent with the auth message", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("var gotty_auth_token = 'token'"))
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL + "/")
+			So(err, ShouldBeNil)
+			transport := &fakeTransport{}
+			client.Transport = transport
+			client.V2 = true
+			client.InitialWinSize = &WinSize{Rows: 40, Columns: 120}
+			client.Screen = NewScreen(24, 80)
+
+			So(client.Connect(), ShouldBeNil)
+			defer client.Close()
+			written := transport.Written()
+			So(written[0], ShouldContainSubstring, `"Columns":120,"Rows":40`)
+			rows, cols := client.Screen.Size()
+			So(rows, ShouldEqual, 40)
+			So(cols, ShouldEqual, 120)
+
+			// Without a terminal, resizes keep to the initial size
+			if _, err := syscallTIOCGWINSZ(); err != nil {
+				payload, err := client.winSizePayload()
+				So(err, ShouldBeNil)
+				So(string(payload), ShouldEqual, `{"rows":40,"columns":120}`)
+			}
+		})
+	})
+}